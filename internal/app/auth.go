@@ -0,0 +1,231 @@
+package app
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/handlers"
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// authRealm is the realm announced to browsers for basic authentication
+const authRealm = "NoteFlow"
+
+// newAuthMiddleware returns a handler that requires a signed-in session, a
+// password (via HTTP basic auth) or an API token (via a bearer header).
+// Configured users sign in with their own name and password; the shared
+// password accepts any username. The authenticated user, if any, is recorded
+// on the request. Browsers asking for a page are sent to the login page.
+func newAuthMiddleware(config *models.AuthConfig, sessions *services.SessionStore, root string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Let CORS preflight requests and the login page through
+		if c.Method() == fiber.MethodOptions || publicPaths[c.Path()] || strings.HasPrefix(c.Path(), "/static/") || automationPath(c.Path()) {
+			return c.Next()
+		}
+
+		if user, ok := sessionUser(config, sessions, c.Cookies(sessionCookie)); ok {
+			handlers.SetUser(c, user)
+			return c.Next()
+		}
+
+		if user, ok := authenticate(config, c.Get(fiber.HeaderAuthorization)); ok {
+			handlers.SetUser(c, user)
+			return c.Next()
+		}
+
+		if fromBrowser(c) || c.Cookies(sessionCookie) != "" {
+			if c.Method() == fiber.MethodGet && strings.Contains(c.Get(fiber.HeaderAccept), fiber.MIMETextHTML) {
+				next := handlers.URLPrefix(c) + c.Path()
+				if query := c.Request().URI().QueryString(); len(query) > 0 {
+					next += "?" + string(query)
+				}
+				return c.Redirect(root + "/login?next=" + url.QueryEscape(next))
+			}
+		} else {
+			c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="`+authRealm+`", charset="UTF-8"`)
+		}
+		return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+	}
+}
+
+// requireAPIToken lets through only requests carrying one of the configured
+// API tokens as a bearer header, for endpoints that browser extensions and
+// other clients call from outside NoteFlow's pages
+func requireAPIToken(config *models.AuthConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := c.Get(fiber.HeaderAuthorization)
+		if scheme, _, _ := strings.Cut(header, " "); !strings.EqualFold(scheme, "bearer") {
+			return fiber.NewError(fiber.StatusUnauthorized, "An API token is required")
+		}
+		user, ok := authenticate(config, header)
+		if !ok {
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid API token")
+		}
+		handlers.SetUser(c, user)
+		return c.Next()
+	}
+}
+
+// requireAutomationKey lets through only requests carrying one of the
+// configured API tokens, for the automation endpoints. Zapier, IFTTT and
+// similar services can't always set an Authorization header, so the token may
+// also be sent as an X-API-Key header or a key query parameter.
+func (a *App) requireAutomationKey(c *fiber.Ctx) error {
+	header := c.Get(fiber.HeaderAuthorization)
+	if scheme, _, _ := strings.Cut(header, " "); !strings.EqualFold(scheme, "bearer") {
+		header = ""
+	}
+	if header == "" {
+		if key := c.Get("X-API-Key", c.Query("key")); key != "" {
+			header = "Bearer " + key
+		}
+	}
+	if header == "" {
+		return fiber.NewError(fiber.StatusUnauthorized, "An API key is required")
+	}
+	user, ok := authenticate(&a.started.Auth, header)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid API key")
+	}
+	handlers.SetUser(c, user)
+
+	// The sign-in check skips automation paths, so the project was chosen
+	// before the key's user was known. Without a /p/<name>/ prefix they get
+	// their own project; either way it must be one they may use.
+	if handlers.URLPrefix(c) == a.root {
+		handlers.SetNoteManager(c, a.projectFor(c))
+	}
+	if !user.CanAccess(handlers.CurrentNoteManager(c).GetBasePath(), a.basePath) {
+		return fiber.NewError(fiber.StatusNotFound, "Project not found")
+	}
+	return c.Next()
+}
+
+// requireSharedCredentials lets through only requests made with the shared
+// password or tokens, or without authentication, for what affects every
+// user: the settings, themes and fonts, and stopping the server. Configured
+// users could otherwise also reach each other's notes, such as by pointing
+// webhooks or notifications at themselves.
+func requireSharedCredentials(c *fiber.Ctx) error {
+	if handlers.CurrentUser(c) != nil {
+		return fiber.NewError(fiber.StatusForbidden, "Only the shared password or tokens may do this")
+	}
+	return c.Next()
+}
+
+// automationPath reports whether path is an automation endpoint, which
+// requireAutomationKey guards instead of the sign-in check. The sign-in check
+// runs before projects are mounted, so path may start with /p/<name>.
+func automationPath(path string) bool {
+	if rest, ok := strings.CutPrefix(path, "/p/"); ok {
+		if _, project, ok := strings.Cut(rest, "/"); ok {
+			path = "/" + project
+		}
+	}
+	return strings.HasPrefix(path, "/api/automation/") || strings.HasPrefix(path, "/api/v1/automation/")
+}
+
+// publicPaths are served without signing in. Slack commands are signed
+// with the Slack app's signing secret instead.
+var publicPaths = map[string]bool{
+	"/login":                true,
+	"/api/login":            true,
+	"/api/v1/login":         true,
+	"/api/slack/command":    true,
+	"/api/v1/slack/command": true,
+	"/favicon.ico":          true,
+	"/sw.js":                true,
+}
+
+// sessionUser returns the user signed in with a session token, nil for the
+// shared password
+func sessionUser(config *models.AuthConfig, sessions *services.SessionStore, token string) (*models.User, bool) {
+	session, ok := sessions.Get(token)
+	if !ok {
+		return nil, false
+	}
+	if session.User == "" {
+		return nil, true
+	}
+
+	// Sessions end when their user is removed from the configuration
+	user := config.FindUser(session.User)
+	return user, user != nil
+}
+
+// authenticate checks an Authorization header against the configured
+// credentials, returning the matching user (nil for the shared credentials)
+func authenticate(config *models.AuthConfig, header string) (*models.User, bool) {
+	scheme, credentials, ok := strings.Cut(header, " ")
+	if !ok {
+		return nil, false
+	}
+
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		token := strings.TrimSpace(credentials)
+		for _, user := range config.Users {
+			if validToken(user.Tokens, token) {
+				return user, true
+			}
+		}
+		return nil, validToken(config.Tokens, token)
+	case "basic":
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(credentials))
+		if err != nil {
+			return nil, false
+		}
+		username, password, ok := strings.Cut(string(decoded), ":")
+		if !ok {
+			return nil, false
+		}
+		return checkPassword(config, username, password)
+	}
+
+	return nil, false
+}
+
+// checkPassword checks a username and password, returning the matching user
+// (nil for the shared password, which accepts any username)
+func checkPassword(config *models.AuthConfig, username, password string) (*models.User, bool) {
+	if user := config.FindUser(username); user != nil {
+		return user, validPassword(user.Password, password)
+	}
+	return nil, validPassword(config.Password, password)
+}
+
+// validToken reports whether token matches one of the given API tokens
+func validToken(tokens []string, token string) bool {
+	if token == "" {
+		return false
+	}
+
+	valid := false
+	for _, expected := range tokens {
+		if expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// validPassword reports whether password matches the expected password, which
+// may be plain text or "sha256:<hex digest>"
+func validPassword(expected, password string) bool {
+	if expected == "" || password == "" {
+		return false
+	}
+
+	if digest, ok := strings.CutPrefix(expected, "sha256:"); ok {
+		sum := sha256.Sum256([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(digest))) == 1
+	}
+
+	return subtle.ConstantTimeCompare([]byte(password), []byte(expected)) == 1
+}
@@ -0,0 +1,20 @@
+package app
+
+import "testing"
+
+func TestAutomationPath(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/api/automation/actions/create-note":          true,
+		"/api/v1/automation/actions/create-note":       true,
+		"/p/team/api/automation/actions/create-note":   true,
+		"/p/team/api/v1/automation/triggers/new-notes": true,
+		"/api/notes":                                 false,
+		"/p/team/api/notes":                          false,
+		"/p/api/automation/actions/create-note":      false,
+		"/static/api/automation/actions/create-note": false,
+	} {
+		if got := automationPath(path); got != want {
+			t.Errorf("automationPath(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
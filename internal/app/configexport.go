@@ -0,0 +1,169 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// exportSecrets are the settings, as section and key, that hold credentials
+// for other services. Like the auth section, they are left out of exports.
+var exportSecrets = [][2]string{
+	{"telegram", "token"},
+	{"slack", "signing_secret"},
+	{"slack", "notify_url"},
+	{"todoist", "token"},
+	{"ai", "api_key"},
+	{"uploads", "transcribe_api_key"},
+	{"translation", "api_key"},
+	{"notifications", "discord_webhook_url"},
+	{"notifications", "matrix_access_token"},
+}
+
+// exportEntrySecrets are the credentials kept in each entry of a list or map
+// of settings: the headers and cookies sent to archived domains, which hold
+// session tokens, and the secrets webhooks sign their deliveries with
+var exportEntrySecrets = []struct {
+	path []string
+	keys []string
+}{
+	{[]string{"archive", "domains"}, []string{"headers", "cookies"}},
+	{[]string{"webhooks"}, []string{"secret"}},
+}
+
+// settingEntries returns the entries of the list or map of settings at
+// path, keyed by their map key or, for lists, their "id"
+func settingEntries(settings map[string]interface{}, path []string) map[string]map[string]interface{} {
+	value, _ := lookupSetting(settings, path)
+	entries := make(map[string]map[string]interface{})
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, entry := range value {
+			if entry, ok := entry.(map[string]interface{}); ok {
+				entries[key] = entry
+			}
+		}
+	case []interface{}:
+		for _, entry := range value {
+			if entry, ok := entry.(map[string]interface{}); ok {
+				if id, ok := entry["id"].(string); ok {
+					entries[id] = entry
+				}
+			}
+		}
+	}
+	return entries
+}
+
+// removeSecrets leaves the credentials in exportSecrets and
+// exportEntrySecrets out of settings
+func removeSecrets(settings map[string]interface{}) {
+	for _, secret := range exportSecrets {
+		deleteSetting(settings, secret[:])
+	}
+	for _, secrets := range exportEntrySecrets {
+		for _, entry := range settingEntries(settings, secrets.path) {
+			for _, key := range secrets.keys {
+				delete(entry, key)
+			}
+		}
+	}
+}
+
+// keepSecrets copies the credentials in exportSecrets and exportEntrySecrets
+// that settings lacks from current, matching entries by key or id
+func keepSecrets(settings, current map[string]interface{}) {
+	for _, secret := range exportSecrets {
+		if _, ok := lookupSetting(settings, secret[:]); ok {
+			continue
+		}
+		if value, ok := lookupSetting(current, secret[:]); ok && value != nil {
+			setSetting(settings, secret[:], value)
+		}
+	}
+	for _, secrets := range exportEntrySecrets {
+		entries := settingEntries(settings, secrets.path)
+		for key, currentEntry := range settingEntries(current, secrets.path) {
+			entry, ok := entries[key]
+			if !ok {
+				continue
+			}
+			for _, key := range secrets.keys {
+				if _, ok := entry[key]; !ok && currentEntry[key] != nil {
+					entry[key] = currentEntry[key]
+				}
+			}
+		}
+	}
+}
+
+// exportConfig downloads the configuration as one JSON document, in the
+// format of the config file, for moving a setup to another machine. The auth
+// section and the other credentials in exportSecrets and exportEntrySecrets
+// are left out so they never leave the server, and so are the other settings
+// only the config file may change, which an import could not set.
+// GET /api/config/export
+func (a *App) exportConfig(c *fiber.Ctx) error {
+	settings, err := a.readSettings(a.config.Current())
+	if err != nil {
+		return err
+	}
+	for _, path := range fileOnlySettings {
+		deleteSetting(settings, path)
+	}
+	removeSecrets(settings)
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to export config")
+	}
+
+	c.Set("Content-Type", fiber.MIMEApplicationJSONCharsetUTF8)
+	c.Set("Content-Disposition", `attachment; filename="noteflow-config.json"`)
+	return c.Send(append(data, '\n'))
+}
+
+// importConfig replaces the configuration with an exported document, sent
+// as the request body or as the "file" field of a multipart form. Settings
+// missing from it return to their defaults, except for the settings in
+// fileOnlySettings, which are kept and cannot be imported, and the
+// credentials in exportSecrets and exportEntrySecrets, which are kept unless
+// the document has them.
+// POST /api/config/export
+func (a *App) importConfig(c *fiber.Ctx) error {
+	data := c.Body()
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "No file provided")
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to open file")
+		}
+		defer reader.Close()
+		if data, err = io.ReadAll(reader); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to read file")
+		}
+	}
+
+	var settings map[string]interface{}
+	if err := json.Unmarshal(data, &settings); err != nil || settings == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid config document")
+	}
+	if setting := fileOnlySetting(settings); setting != "" {
+		return fiber.NewError(fiber.StatusForbidden, setting+" settings can only be changed in the config file")
+	}
+
+	restart, err := a.storeSettings(func(current map[string]interface{}) map[string]interface{} {
+		keepFileOnlySettings(settings, current)
+		keepSecrets(settings, current)
+		return settings
+	})
+	if err != nil {
+		return err
+	}
+	return configApplied(c, restart)
+}
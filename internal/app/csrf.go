@@ -0,0 +1,95 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CSRF protection uses a double-submit cookie: pages read the token from the
+// cookie and echo it in a header or form field, which a page on another site
+// cannot do. The token is not stored on the server, so it survives restarts
+// and is shared by servers on other ports of the same host.
+const (
+	csrfCookie    = "noteflow_csrf"
+	csrfHeader    = "X-CSRF-Token"
+	csrfFormField = "_csrf"
+	csrfCookieAge = 365 * 24 * time.Hour
+)
+
+// newCSRFMiddleware rejects browser requests that change data without the
+// CSRF token, unless they carry one of the API tokens in auth. root is the
+// URL prefix the server is mounted under.
+func newCSRFMiddleware(root string, auth *models.AuthConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token := c.Cookies(csrfCookie)
+
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions, fiber.MethodTrace:
+			if token == "" {
+				if err := setCSRFCookie(c, root); err != nil {
+					return err
+				}
+			}
+			return c.Next()
+		}
+
+		if !fromBrowser(c) || apiTokenRequest(auth, c) {
+			return c.Next()
+		}
+
+		submitted := c.Get(csrfHeader)
+		if submitted == "" {
+			submitted = c.FormValue(csrfFormField)
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+			return fiber.NewError(fiber.StatusForbidden, "Missing or invalid CSRF token; reload the page and try again")
+		}
+		return c.Next()
+	}
+}
+
+// setCSRFCookie issues a new CSRF token. The cookie is readable by scripts so
+// pages can send it back.
+func setCSRFCookie(c *fiber.Ctx, root string) error {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to generate CSRF token")
+	}
+	token := hex.EncodeToString(raw)
+
+	c.Cookie(&fiber.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     root + "/",
+		MaxAge:   int(csrfCookieAge.Seconds()),
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteStrictMode,
+	})
+	return nil
+}
+
+// fromBrowser reports whether a request may have been sent by a browser on
+// behalf of a web page. Browsers send Origin, Referer or Sec-Fetch-Site with
+// cross-site requests; scripts and API clients don't.
+func fromBrowser(c *fiber.Ctx) bool {
+	return c.Get(fiber.HeaderOrigin) != "" || c.Get(fiber.HeaderReferer) != "" || c.Get("Sec-Fetch-Site") != ""
+}
+
+// apiTokenRequest reports whether a request carries one of the API tokens in
+// auth as a bearer header. Browsers never attach those on their own and
+// other sites can't know them, so such requests are not subject to CSRF; any
+// other bearer header, which a page can set, is not enough.
+func apiTokenRequest(auth *models.AuthConfig, c *fiber.Ctx) bool {
+	header := c.Get(fiber.HeaderAuthorization)
+	if scheme, _, _ := strings.Cut(header, " "); !strings.EqualFold(scheme, "bearer") {
+		return false
+	}
+	_, ok := authenticate(auth, header)
+	return ok
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCSRFMiddlewareRequiresKnownBearerTokens(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		auth   models.AuthConfig
+		header string
+		want   int
+	}{
+		{"no auth, made-up token", models.AuthConfig{}, "Bearer x", fiber.StatusForbidden},
+		{"wrong token", models.AuthConfig{Tokens: []string{"secret"}}, "Bearer x", fiber.StatusForbidden},
+		{"shared token", models.AuthConfig{Tokens: []string{"secret"}}, "Bearer secret", fiber.StatusOK},
+		{"user token", models.AuthConfig{Users: []*models.User{{Name: "alice", Tokens: []string{"ta"}}}}, "Bearer ta", fiber.StatusOK},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(newCSRFMiddleware("", &test.auth))
+			app.Post("/api/notes", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+			// A page on another site posting with a bearer header of its own
+			req := httptest.NewRequest(fiber.MethodPost, "/api/notes", nil)
+			req.Header.Set(fiber.HeaderOrigin, "https://evil.example")
+			req.Header.Set(fiber.HeaderAuthorization, test.header)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.want {
+				t.Errorf("got status %d, want %d", resp.StatusCode, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package app
+
+import (
+	"net"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
+)
+
+// setupDebug serves the Go profiler under /debug/pprof, as started with
+// --debug, for go tool pprof
+func (a *App) setupDebug() {
+	a.fiber.Use("/debug", a.debugGuard)
+	a.fiber.Use(pprof.New())
+}
+
+// debugGuard keeps the debug endpoints from clients that aren't signed in.
+// Without authentication configured, only clients on this machine may use
+// them, since profiles reveal what the server is doing.
+func (a *App) debugGuard(c *fiber.Ctx) error {
+	if a.started.Auth.Enabled() {
+		// The authentication middleware let the request through
+		return c.Next()
+	}
+	if ip := net.ParseIP(c.IP()); ip == nil || !ip.IsLoopback() {
+		return fiber.NewError(fiber.StatusForbidden, "Debug endpoints are only served to this machine without authentication")
+	}
+	return c.Next()
+}
@@ -0,0 +1,277 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mdnsService is the DNS-SD service type NoteFlow advertises on the local network
+const mdnsService = "_noteflow._tcp.local."
+
+// mdnsServices is the DNS-SD name browsers query to list every service type
+const mdnsServices = "_services._dns-sd._udp.local."
+
+// mdnsTTL is how long, in seconds, other devices may cache the advertisement
+const mdnsTTL = 120
+
+// mdnsGroup is the multicast address mDNS queries and answers are sent to
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// mdnsAdvertiser answers mDNS queries for the running server so other devices
+// on the LAN can find it by service type instead of by address and port
+type mdnsAdvertiser struct {
+	conn     *net.UDPConn
+	service  dnsmessage.Name
+	services dnsmessage.Name
+	instance dnsmessage.Name
+	host     dnsmessage.Name
+	port     uint16
+	txt      []string
+	ips      []net.IP
+	done     chan struct{}
+}
+
+// startDiscovery advertises the server on the local network over mDNS. It
+// returns nil when discovery is turned off or the server only listens on the
+// loopback interface, where other devices could not reach it anyway.
+func (a *App) startDiscovery(host string, port int, scheme string) *mdnsAdvertiser {
+	if a.started.Server.MDNS.Disabled || a.options.NoMDNS {
+		return nil
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+
+	ips, err := advertisedIPs(host)
+	if err != nil || len(ips) == 0 {
+		slog.Warn("not advertising over mDNS: no usable network address")
+		return nil
+	}
+
+	hostname, _ := os.Hostname()
+	hostname, _, _ = strings.Cut(hostname, ".")
+	if hostname == "" {
+		hostname = "noteflow"
+	}
+
+	name := a.started.Server.MDNS.Name
+	if name == "" {
+		name = "NoteFlow on " + hostname
+	}
+	name = strings.ReplaceAll(name, ".", "-")
+
+	advertiser, err := newMDNSAdvertiser(name, hostname, port, ips, []string{
+		"path=" + a.root + "/",
+		"scheme=" + scheme,
+		"version=" + currentAPIVersion,
+	})
+	if err != nil {
+		slog.Warn("not advertising over mDNS", "error", err)
+		return nil
+	}
+
+	slog.Info("advertising on the local network", "name", name, "service", strings.TrimSuffix(mdnsService, "."))
+	return advertiser
+}
+
+// advertisedIPs returns the IPv4 addresses other devices can reach the server
+// on: the listen address itself, or every non-loopback interface address when
+// listening on all interfaces
+func advertisedIPs(host string) ([]net.IP, error) {
+	if host != "" && host != "0.0.0.0" && host != "::" {
+		if ip := net.ParseIP(host); ip != nil {
+			if ip4 := ip.To4(); ip4 != nil {
+				return []net.IP{ip4}, nil
+			}
+			return nil, nil
+		}
+		addrs, err := net.LookupIP(host)
+		if err != nil {
+			return nil, err
+		}
+		var ips []net.IP
+		for _, ip := range addrs {
+			if ip4 := ip.To4(); ip4 != nil && !ip4.IsLoopback() {
+				ips = append(ips, ip4)
+			}
+		}
+		return ips, nil
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ip4 := ipNet.IP.To4(); ip4 != nil && !ip4.IsLoopback() && !ip4.IsLinkLocalUnicast() {
+			ips = append(ips, ip4)
+		}
+	}
+	return ips, nil
+}
+
+// newMDNSAdvertiser joins the mDNS multicast group, announces the service and
+// starts answering queries for it
+func newMDNSAdvertiser(name, hostname string, port int, ips []net.IP, txt []string) (*mdnsAdvertiser, error) {
+	instance, err := dnsmessage.NewName(name + "." + mdnsService)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service name %q: %w", name, err)
+	}
+	host, err := dnsmessage.NewName(hostname + ".local.")
+	if err != nil {
+		return nil, fmt.Errorf("invalid host name %q: %w", hostname, err)
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &mdnsAdvertiser{
+		conn:     conn,
+		service:  dnsmessage.MustNewName(mdnsService),
+		services: dnsmessage.MustNewName(mdnsServices),
+		instance: instance,
+		host:     host,
+		port:     uint16(port),
+		txt:      txt,
+		ips:      ips,
+		done:     make(chan struct{}),
+	}
+
+	go m.serve()
+	go m.announce()
+	return m, nil
+}
+
+// announce sends unsolicited answers so devices already browsing see the
+// server right away. RFC 6762 asks for at least two, one second apart.
+func (m *mdnsAdvertiser) announce() {
+	for i := 0; i < 2; i++ {
+		if packet, err := m.response(0, mdnsTTL); err == nil {
+			m.conn.WriteToUDP(packet, mdnsGroup)
+		}
+
+		select {
+		case <-m.done:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// serve answers queries for the service until the advertiser is closed
+func (m *mdnsAdvertiser) serve() {
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := m.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-m.done:
+				return
+			default:
+				continue
+			}
+		}
+
+		var parser dnsmessage.Parser
+		header, err := parser.Start(buf[:n])
+		if err != nil || header.Response {
+			continue
+		}
+		questions, err := parser.AllQuestions()
+		if err != nil || !m.wanted(questions) {
+			continue
+		}
+
+		// Queries from a port other than 5353 come from simple resolvers
+		// expecting a unicast reply that echoes the query ID (RFC 6762 §6.7)
+		id, to := uint16(0), mdnsGroup
+		if from.Port != mdnsGroup.Port {
+			id, to = header.ID, from
+		}
+
+		packet, err := m.response(id, mdnsTTL)
+		if err != nil {
+			continue
+		}
+		m.conn.WriteToUDP(packet, to)
+	}
+}
+
+// wanted reports whether any question asks about the service, this instance
+// or its host
+func (m *mdnsAdvertiser) wanted(questions []dnsmessage.Question) bool {
+	for _, q := range questions {
+		name := strings.ToLower(q.Name.String())
+		switch name {
+		case strings.ToLower(m.service.String()), strings.ToLower(m.instance.String()),
+			strings.ToLower(m.host.String()), mdnsServices:
+			return true
+		}
+	}
+	return false
+}
+
+// response builds an answer listing the service instance, its port, TXT
+// record and addresses. A TTL of zero withdraws them.
+func (m *mdnsAdvertiser) response(id uint16, ttl uint32) ([]byte, error) {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, Response: true, Authoritative: true})
+	builder.EnableCompression()
+
+	header := func(name dnsmessage.Name, typ dnsmessage.Type, cacheFlush bool) dnsmessage.ResourceHeader {
+		class := dnsmessage.ClassINET
+		if cacheFlush {
+			// Top bit of the class tells caches to replace older records
+			class |= 1 << 15
+		}
+		return dnsmessage.ResourceHeader{Name: name, Type: typ, Class: class, TTL: ttl}
+	}
+
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if err := builder.PTRResource(header(m.services, dnsmessage.TypePTR, false), dnsmessage.PTRResource{PTR: m.service}); err != nil {
+		return nil, err
+	}
+	if err := builder.PTRResource(header(m.service, dnsmessage.TypePTR, false), dnsmessage.PTRResource{PTR: m.instance}); err != nil {
+		return nil, err
+	}
+	if err := builder.SRVResource(header(m.instance, dnsmessage.TypeSRV, true), dnsmessage.SRVResource{Target: m.host, Port: m.port}); err != nil {
+		return nil, err
+	}
+	if err := builder.TXTResource(header(m.instance, dnsmessage.TypeTXT, true), dnsmessage.TXTResource{TXT: m.txt}); err != nil {
+		return nil, err
+	}
+	for _, ip := range m.ips {
+		var addr [4]byte
+		copy(addr[:], ip.To4())
+		if err := builder.AResource(header(m.host, dnsmessage.TypeA, true), dnsmessage.AResource{A: addr}); err != nil {
+			return nil, err
+		}
+	}
+	return builder.Finish()
+}
+
+// Close withdraws the advertisement and stops answering queries
+func (m *mdnsAdvertiser) Close() {
+	close(m.done)
+	if packet, err := m.response(0, 0); err == nil {
+		m.conn.WriteToUDP(packet, mdnsGroup)
+	}
+	m.conn.Close()
+}
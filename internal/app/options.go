@@ -0,0 +1,129 @@
+package app
+
+import (
+	"path"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Default listen address. Only the local machine can connect unless another
+// host is configured.
+const (
+	defaultHost = "127.0.0.1"
+	defaultPort = 8000
+)
+
+// Options holds command-line settings. Non-empty values override the
+// configuration file for this run without being saved to it.
+type Options struct {
+	Host          string
+	Port          int
+	TLSCertFile   string
+	TLSKeyFile    string
+	AutocertHosts []string
+	BasePath      string
+	ReadOnly      bool
+	NoMDNS        bool
+
+	// OpenBrowser opens the web UI in the default browser once the server
+	// is listening
+	OpenBrowser bool
+
+	// Projects are additional folders to serve under /p/<name>/
+	Projects []string
+
+	// WebDir serves templates and static files from disk instead of the
+	// binary, for developing the web UI
+	WebDir string
+
+	// Debug serves the Go profiler under /debug/pprof and runtime statistics
+	// under /api/debug/runtime, to clients that are signed in or, without
+	// authentication, on this machine
+	Debug bool
+}
+
+// urlRoot returns the URL prefix the server is mounted under, such as
+// "/noteflow", or "" when it is served from the root. The command-line value
+// overrides the configured one.
+func urlRoot(config *models.ServerConfig, options Options) string {
+	basePath := config.BasePath
+	if options.BasePath != "" {
+		basePath = options.BasePath
+	}
+
+	basePath = strings.TrimSpace(basePath)
+	if basePath == "" {
+		return ""
+	}
+	basePath = path.Clean("/" + basePath)
+	if basePath == "/" {
+		return ""
+	}
+	return basePath
+}
+
+// readOnly reports whether the server must not change anything, set in the
+// configuration file or on the command line
+func (a *App) readOnly() bool {
+	return a.started.Server.ReadOnly || a.options.ReadOnly
+}
+
+// tlsConfig returns the TLS settings from the configuration file with any
+// command-line overrides applied
+func (a *App) tlsConfig() models.TLSConfig {
+	tls := a.started.Server.TLS
+	if a.options.TLSCertFile != "" {
+		tls.CertFile = a.options.TLSCertFile
+		tls.KeyFile = a.options.TLSKeyFile
+	}
+	if len(a.options.AutocertHosts) > 0 {
+		tls.AutocertHosts = a.options.AutocertHosts
+		if a.options.TLSCertFile == "" {
+			tls.CertFile, tls.KeyFile = "", ""
+		}
+	}
+	return tls
+}
+
+// listenAddress returns the host and port to listen on. Without explicit
+// settings the server binds to localhost on port 8000, or to all interfaces
+// on port 443 when using Let's Encrypt, which must reach it from the internet.
+func (a *App) listenAddress() (string, int) {
+	host, port := defaultHost, defaultPort
+	if tls := a.tlsConfig(); tls.Autocert() {
+		host, port = "", 443
+	}
+
+	if a.started.Server.Host != "" {
+		host = a.started.Server.Host
+	}
+	if a.started.Server.Port != 0 {
+		port = a.started.Server.Port
+	}
+	if a.options.Host != "" {
+		host = a.options.Host
+	}
+	if a.options.Port != 0 {
+		port = a.options.Port
+	}
+
+	// Allow "*" to mean every interface
+	if host == "*" {
+		host = ""
+	}
+	return host, port
+}
+
+// bodyLimit returns the largest request body accepted, leaving room for the
+// multipart encoding around an upload of the maximum size, or for the base64
+// encoding of a pasted image. Requests other than uploads keep at least
+// Fiber's default limit.
+func bodyLimit(uploads *models.UploadConfig) int {
+	limit := uploads.LargestBytes() * 4 / 3
+	if limit < fiber.DefaultBodyLimit {
+		limit = fiber.DefaultBodyLimit
+	}
+	return int(limit) + 1<<20
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Rate limit defaults used when the configuration leaves a value at zero
+const (
+	defaultRequestsPerMinute  = 120
+	defaultRequestBurst       = 30
+	defaultExpensivePerMinute = 10
+	defaultExpensiveBurst     = 5
+)
+
+// bucketIdleTimeout is how long an unused client bucket is kept
+const bucketIdleTimeout = 10 * time.Minute
+
+// expensiveEndpoints are API paths (without the /api/<version> prefix) that
+// download, archive or process large amounts of data, or wait on a language
+// model
+var expensiveEndpoints = map[string]bool{
+	"/archive":         true,
+	"/archive-all":     true,
+	"/archive-refresh": true,
+	"/archives/export": true,
+	"/archives/import": true,
+	"/clip":            true,
+	"/notes/suggest":   true,
+	"/paste-image":     true,
+	"/upload-file":     true,
+	"/uploads":         true,
+}
+
+// tokenBucket allows bursts of up to capacity requests, refilled at rate per second
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// bucketLimiter keeps one token bucket per client IP
+type bucketLimiter struct {
+	rate      float64
+	capacity  float64
+	buckets   map[string]*tokenBucket
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// newBucketLimiter creates a limiter allowing perMinute requests per client
+// on average, with bursts of up to burst requests
+func newBucketLimiter(perMinute, burst int) *bucketLimiter {
+	return &bucketLimiter{
+		rate:      float64(perMinute) / 60,
+		capacity:  float64(burst),
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow takes a token from the client's bucket. When the bucket is empty it
+// returns false and how long until the next token is available.
+func (l *bucketLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	bucket, exists := l.buckets[client]
+	if !exists {
+		bucket = &tokenBucket{tokens: l.capacity, last: now}
+		l.buckets[client] = bucket
+	}
+
+	bucket.tokens = math.Min(l.capacity, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep forgets clients that have been idle long enough to have a full bucket
+func (l *bucketLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketIdleTimeout {
+		return
+	}
+	l.lastSweep = now
+
+	for client, bucket := range l.buckets {
+		if now.Sub(bucket.last) > bucketIdleTimeout {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// newRateLimitMiddleware limits mutating API requests, and more strictly
+// expensive ones, per client IP. Reads other than expensive ones are not limited.
+func newRateLimitMiddleware(config *models.RateLimitConfig) fiber.Handler {
+	requests := newBucketLimiter(
+		valueOrDefault(config.RequestsPerMinute, defaultRequestsPerMinute),
+		valueOrDefault(config.Burst, defaultRequestBurst))
+	expensive := newBucketLimiter(
+		valueOrDefault(config.ExpensivePerMinute, defaultExpensivePerMinute),
+		valueOrDefault(config.ExpensiveBurst, defaultExpensiveBurst))
+
+	return func(c *fiber.Ctx) error {
+		var limiter *bucketLimiter
+		switch {
+		case expensiveEndpoints[apiEndpoint(c.Path())]:
+			limiter = expensive
+		case strings.HasPrefix(apiEndpoint(c.Path()), "/notes/") && (strings.HasSuffix(c.Path(), "/summarize") || strings.HasSuffix(c.Path(), "/translate")):
+			// Summaries and translations wait on a language model or a
+			// translation service
+			limiter = expensive
+		case c.Method() == fiber.MethodPatch && strings.HasPrefix(apiEndpoint(c.Path()), "/uploads/"):
+			// Chunks of an upload are bounded by its size, checked when it started
+			return c.Next()
+		case c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead && c.Method() != fiber.MethodOptions:
+			limiter = requests
+		default:
+			return c.Next()
+		}
+
+		if ok, wait := limiter.allow(c.IP(), time.Now()); !ok {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			return fiber.NewError(fiber.StatusTooManyRequests, "Too many requests, please slow down")
+		}
+		return c.Next()
+	}
+}
+
+// apiEndpoint strips the /api or /api/<version> prefix from an API path
+func apiEndpoint(path string) string {
+	endpoint := strings.TrimPrefix(path, "/api")
+	version, rest, _ := strings.Cut(strings.TrimPrefix(endpoint, "/"), "/")
+	if apiVersions[version] {
+		endpoint = "/" + rest
+	}
+	return endpoint
+}
+
+// valueOrDefault returns value, or fallback when value is not positive
+func valueOrDefault(value, fallback int) int {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,341 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/i18n"
+	"github.com/darren/noteflow-go/internal/logging"
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/themes"
+	"github.com/gofiber/fiber/v2"
+)
+
+// configPollInterval is how often the config file is checked for changes
+const configPollInterval = 2 * time.Second
+
+// configStamp identifies a version of the config file
+type configStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// statConfig returns the stamp of the config file, or the zero stamp when it
+// cannot be read
+func statConfig(path string) configStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return configStamp{}
+	}
+	return configStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// watchConfig reloads the config file whenever it changes, until done is
+// closed. A file that does not parse or validate is ignored, leaving the
+// running settings in place.
+func (a *App) watchConfig(done <-chan struct{}) {
+	last := statConfig(a.configPath)
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		stamp := statConfig(a.configPath)
+		if stamp == last || stamp == (configStamp{}) {
+			continue
+		}
+		last = stamp
+
+		if _, err := a.reloadConfig(); err != nil {
+			slog.Warn("ignoring config changes", "path", a.configPath, "error", err)
+		}
+	}
+}
+
+// reloadConfig reads the config file and applies it, returning the changed
+// settings that need a restart
+func (a *App) reloadConfig() ([]string, error) {
+	data, err := os.ReadFile(a.configPath)
+	if err != nil {
+		return nil, err
+	}
+	config, err := decodeConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Saving settings through the API also changes the file
+	if !a.config.Load(config) {
+		return nil, nil
+	}
+	return a.applied(config), nil
+}
+
+// decodeConfig parses a config file strictly and checks that the server
+// could use it
+func decodeConfig(data []byte) (*models.Config, error) {
+	config, err := models.DecodeConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := themes.CheckConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// configChanged passes changed settings on to the services that keep their
+// own copy. Themes, page settings, archiving, uploads, the trash, webhooks,
+// Slack, AI, translation and notifications take effect at once; services
+// read the rest on start.
+func (a *App) configChanged(previous, config *models.Config) {
+	a.renders.SetTheme(config.Theme)
+	a.slack.Reload(config.Slack)
+	a.notifications.Reload(config.Notifications)
+	a.fetcher.Reload(config.Fetch)
+	logging.Setup(config.Log)
+
+	// Notes already read keep the timezone they were read in
+	timeConfig := config.Time
+	timeConfig.Timezone = a.started.Time.Timezone
+	models.SetTimeConfig(timeConfig)
+	if config.Locale != previous.Locale {
+		if err := i18n.SetLocale(config.Locale); err != nil {
+			slog.Warn("unsupported locale", "error", err)
+		}
+	}
+}
+
+// applied logs that config was applied and returns the names of the changed
+// settings that are only read on start, warning about them
+func (a *App) applied(config *models.Config) []string {
+	var restart []string
+	if !reflect.DeepEqual(config.Server, a.started.Server) {
+		restart = append(restart, "server")
+	}
+	if !reflect.DeepEqual(config.Auth, a.started.Auth) {
+		restart = append(restart, "auth")
+	}
+	if !reflect.DeepEqual(config.Projects, a.started.Projects) {
+		restart = append(restart, "projects")
+	}
+	if !reflect.DeepEqual(config.Telegram, a.started.Telegram) {
+		restart = append(restart, "telegram")
+	}
+	if !reflect.DeepEqual(config.Todoist, a.started.Todoist) {
+		restart = append(restart, "todoist")
+	}
+	if config.Time.Timezone != a.started.Time.Timezone {
+		restart = append(restart, "time.timezone")
+	}
+	if bodyLimit(&config.Uploads) > a.fiber.Config().BodyLimit {
+		restart = append(restart, "uploads.max_size_mb")
+	}
+
+	slog.Info("reloaded configuration", "path", a.configPath)
+	for _, key := range restart {
+		slog.Warn("restart the server to apply the changed settings", "settings", key)
+	}
+	return restart
+}
+
+// fileOnlySettings are the settings, as paths of keys, that only the config
+// file may change: the auth section, the commands run on uploads and
+// screenshots, and the custom files added to pages. Changing them through
+// the API would let a signed-in user run programs or serve files of the
+// server's choosing.
+var fileOnlySettings = [][]string{
+	{"auth"},
+	{"uploads", "ocr_command"},
+	{"uploads", "pdf_text_command"},
+	{"uploads", "transcribe_command"},
+	{"uploads", "heic_command"},
+	{"archive", "screenshot_command"},
+	{"ui", "custom_css"},
+	{"ui", "custom_js"},
+	{"ui", "projects"},
+}
+
+// fileOnlySetting returns the name of the first setting in fileOnlySettings
+// that settings has, or ""
+func fileOnlySetting(settings map[string]interface{}) string {
+	for _, path := range fileOnlySettings {
+		if _, ok := lookupSetting(settings, path); ok {
+			return strings.Join(path, ".")
+		}
+	}
+	return ""
+}
+
+// keepFileOnlySettings copies the settings in fileOnlySettings from current
+// to settings
+func keepFileOnlySettings(settings, current map[string]interface{}) {
+	for _, path := range fileOnlySettings {
+		if value, ok := lookupSetting(current, path); ok {
+			setSetting(settings, path, value)
+		}
+	}
+}
+
+// lookupSetting returns the setting at path in settings
+func lookupSetting(settings map[string]interface{}, path []string) (interface{}, bool) {
+	for _, key := range path[:len(path)-1] {
+		section, ok := settings[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		settings = section
+	}
+	value, ok := settings[path[len(path)-1]]
+	return value, ok
+}
+
+// setSetting sets the setting at path in settings, adding the sections
+// leading to it
+func setSetting(settings map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		section, ok := settings[key].(map[string]interface{})
+		if !ok {
+			section = make(map[string]interface{})
+			settings[key] = section
+		}
+		settings = section
+	}
+	settings[path[len(path)-1]] = value
+}
+
+// deleteSetting removes the setting at path from settings
+func deleteSetting(settings map[string]interface{}, path []string) {
+	for _, key := range path[:len(path)-1] {
+		section, ok := settings[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		settings = section
+	}
+	delete(settings, path[len(path)-1])
+}
+
+// updateConfig changes settings while the server runs. The body holds the
+// settings to change, such as {"theme": "dark-blue"} or
+// {"archive": {"concurrency": 4}}; objects are merged into the current
+// settings, other values replace them. An empty body reloads the config file.
+// The settings in fileOnlySettings cannot be changed this way.
+// POST /api/config
+func (a *App) updateConfig(c *fiber.Ctx) error {
+	var restart []string
+	if len(bytes.TrimSpace(c.Body())) == 0 {
+		var err error
+		if restart, err = a.reloadConfig(); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	} else {
+		var changes map[string]interface{}
+		if err := json.Unmarshal(c.Body(), &changes); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+		}
+		if setting := fileOnlySetting(changes); setting != "" {
+			return fiber.NewError(fiber.StatusForbidden, setting+" settings can only be changed in the config file")
+		}
+
+		var err error
+		restart, err = a.storeSettings(func(current map[string]interface{}) map[string]interface{} {
+			return mergeSettings(current, changes)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return configApplied(c, restart)
+}
+
+// readSettings returns the saved settings as JSON values, or those of config
+// when nothing is saved yet
+func (a *App) readSettings(config *models.Config) (map[string]interface{}, error) {
+	data, err := os.ReadFile(a.configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to read config")
+	}
+	if len(data) == 0 {
+		data, _ = json.Marshal(config)
+	}
+	var settings map[string]interface{}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to read config: "+err.Error())
+	}
+	return settings, nil
+}
+
+// storeSettings replaces the saved settings with those change makes of
+// them, checks them, saves them to the config file and applies them,
+// returning the changed settings that need a restart
+func (a *App) storeSettings(change func(current map[string]interface{}) map[string]interface{}) ([]string, error) {
+	config, err := a.config.Update(func(config *models.Config) error {
+		current, err := a.readSettings(config)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(change(current))
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		decoded, err := decodeConfig(data)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		*config = *decoded
+		return nil
+	})
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to save config")
+	}
+	return a.applied(config), nil
+}
+
+// configApplied responds that settings were applied, listing those that need
+// a restart
+func configApplied(c *fiber.Ctx, restart []string) error {
+	message := "Configuration applied"
+	if restart == nil {
+		restart = []string{}
+	} else if len(restart) > 0 {
+		message = fmt.Sprintf("Configuration applied; restart the server to apply the changed %v settings", restart)
+	}
+	return c.JSON(models.APIResponse{
+		Status:  "success",
+		Message: message,
+		Data:    map[string]interface{}{"restart_required": restart},
+	})
+}
+
+// mergeSettings merges changes into settings, recursing into objects present
+// in both
+func mergeSettings(settings, changes map[string]interface{}) map[string]interface{} {
+	for key, value := range changes {
+		current, currentIsObject := settings[key].(map[string]interface{})
+		change, changeIsObject := value.(map[string]interface{})
+		if currentIsObject && changeIsObject {
+			settings[key] = mergeSettings(current, change)
+		} else {
+			settings[key] = value
+		}
+	}
+	return settings
+}
@@ -1,55 +1,143 @@
 package app
 
 import (
+	"context"
 	"embed"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/darren/noteflow-go/internal/handlers"
+	"github.com/darren/noteflow-go/internal/i18n"
+	"github.com/darren/noteflow-go/internal/logging"
 	"github.com/darren/noteflow-go/internal/models"
 	"github.com/darren/noteflow-go/internal/services"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/filesystem"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 )
 
+// currentAPIVersion is the newest API version, served under /api/<version>
+const currentAPIVersion = "v1"
+
+// apiVersions lists every API version still served
+var apiVersions = map[string]bool{
+	"v1": true,
+}
+
+// shutdownTimeout is how long a shutdown waits for in-flight requests
+const shutdownTimeout = 10 * time.Second
+
+// projectNameChars matches characters replaced when naming a project after its folder
+var projectNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
 // App represents the main application
 type App struct {
 	fiber           *fiber.App
 	noteManager     *services.NoteManager
+	projects        map[string]*services.NoteManager // folder -> NoteManager
+	projectNames    map[string]string                // name -> folder
+	projectList     []models.Project
 	templateService *services.TemplateService
+	webFS           fs.FS
 	taskRegistry    *services.TaskRegistryService
-	config          *models.Config
+	events          *services.EventBroker
+	renders         *services.RenderCache
+	fetcher         *services.Fetcher
+	webhooks        *services.WebhookService
+	slack           *services.SlackNotifier
+	notifications   *services.NotificationService
+	telegram        *services.TelegramBot // nil without a bot token
+	todoist         *services.TodoistSync // nil without a Todoist token
+	automation      map[*services.NoteManager]*services.AutomationFeed
+	themes          *handlers.ThemesHandler
+	sessions        *services.SessionStore
+	config          *services.ConfigStore
+	started         *models.Config // settings the server was started with
+	startedAt       time.Time
+	options         Options
 	configPath      string
 	basePath        string
+	root            string // URL prefix the server is mounted under
 	port            int
+
+	// ctx is the parent of every request's context, cancelled by Shutdown
+	// so slow work for requests stops
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // NewApp creates a new application instance
-func NewApp(basePath string, webAssets *embed.FS) (*App, error) {
+func NewApp(basePath string, webAssets *embed.FS, options Options) (*App, error) {
 	// Initialize configuration
-	configPath := getConfigPath()
+	configPath := ConfigPath()
 	config, err := models.LoadConfig(configPath)
 	if err != nil {
-		log.Printf("Warning: Failed to load config: %v", err)
+		slog.Warn("failed to load config", "error", err)
 		config = models.DefaultConfig()
 	}
+	logging.Setup(config.Log)
+	models.SetTimeConfig(config.Time)
+	if err := i18n.SetLocale(config.Locale); err != nil {
+		slog.Warn("unsupported locale", "error", err)
+	}
+
+	// Every change to the settings is saved through one store, which hands
+	// out the current settings to readers
+	store := services.NewConfigStore(config, configPath)
 
 	// Initialize note manager
-	noteManager, err := services.NewNoteManager(basePath)
+	noteManager, err := services.NewNoteManager(basePath, store)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize note manager: %w", err)
 	}
 
+	// Initialize event broker for live updates
+	events := services.NewEventBroker()
+	noteManager.SetEventBroker(events)
+	noteManager.Serve()
+
+	// Rendered notes are cached across projects until they or the theme change
+	renders := services.NewRenderCache(config.Theme)
+	noteManager.SetRenderCache(renders)
+
+	// Requests to other websites are limited across projects
+	fetcher := services.NewFetcher(config.Fetch)
+	noteManager.SetFetcher(fetcher)
+
+	// Serve web assets from the binary, or from disk while developing
+	webFS, err := webFileSystem(webAssets, options.WebDir)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize template service
-	templateService, err := services.NewTemplateService(webAssets)
+	root := urlRoot(&config.Server, options)
+	templateService, err := services.NewTemplateService(webFS, options.WebDir != "", root, filepath.Dir(configPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize template service: %w", err)
 	}
 
+	// Initialize the session store next to the configuration file
+	sessions, err := services.NewSessionStore(filepath.Join(filepath.Dir(configPath), "sessions.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize session store: %w", err)
+	}
+
 	// Initialize task registry service
 	taskRegistry, err := services.NewTaskRegistryService()
 	if err != nil {
@@ -58,17 +146,69 @@ func NewApp(basePath string, webAssets *embed.FS) (*App, error) {
 
 	// Register this folder with the task registry
 	if err := taskRegistry.RegisterFolder(basePath, noteManager); err != nil {
-		log.Printf("Warning: failed to register folder for global tasks: %v", err)
+		slog.Warn("failed to register folder for global tasks", "error", err)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	app := &App{
+		ctx:             ctx,
+		cancel:          cancel,
 		noteManager:     noteManager,
+		projects:        map[string]*services.NoteManager{basePath: noteManager},
+		projectNames:    make(map[string]string),
 		templateService: templateService,
+		webFS:           webFS,
 		taskRegistry:    taskRegistry,
-		config:          config,
+		events:          events,
+		renders:         renders,
+		fetcher:         fetcher,
+		webhooks:        services.NewWebhookService(store, &config.Auth, basePath),
+		slack:           services.NewSlackNotifier(config.Slack),
+		notifications:   services.NewNotificationService(config.Notifications),
+		themes:          handlers.NewThemesHandler(store, templateService),
+		sessions:        sessions,
+		config:          store,
+		started:         config,
+		startedAt:       time.Now(),
+		options:         options,
 		configPath:      configPath,
 		basePath:        basePath,
-		port:            8000, // Start with default, will be updated in Start()
+		root:            root,
+		port:            defaultPort, // Updated in Start()
+	}
+
+	store.Watch(app.configChanged)
+
+	// Open the additional project folders and those of configured users
+	app.nameProject(basePath)
+	if err := app.loadProjects(); err != nil {
+		return nil, err
+	}
+	if err := app.loadUserProjects(); err != nil {
+		return nil, err
+	}
+
+	// Deliver every project's events to webhook subscribers, announce its
+	// completed tasks in Slack and its reminders, due tasks and digests in
+	// the notification channels
+	for _, project := range app.projectList {
+		app.webhooks.Watch(project.Name, project.Folder, app.projects[project.Folder].Events())
+		app.slack.Watch(project.Name, app.projects[project.Folder].Events())
+		app.notifications.Watch(project.Name, app.projects[project.Folder])
+	}
+
+	// Record every project's new notes and completed tasks for the
+	// automation triggers
+	app.automation = make(map[*services.NoteManager]*services.AutomationFeed)
+	for _, noteManager := range app.projects {
+		feed := services.NewAutomationFeed(noteManager)
+		feed.Watch()
+		app.automation[noteManager] = feed
+	}
+
+	// Sync tasks with Todoist, unless nothing may change
+	if config.Todoist.Token != "" && !app.readOnly() {
+		app.todoist = services.NewTodoistSync(config.Todoist, noteManager)
 	}
 
 	app.setupFiber()
@@ -82,88 +222,270 @@ func (a *App) setupFiber() {
 	a.fiber = fiber.New(fiber.Config{
 		AppName:      "NoteFlow",
 		ServerHeader: "NoteFlow/1.0",
+		BodyLimit:    bodyLimit(&a.started.Uploads),
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
 				code = e.Code
 			}
+			if code >= fiber.StatusInternalServerError {
+				slog.ErrorContext(c.UserContext(), "request failed",
+					"method", c.Method(), "path", c.Path(), "status", code, "error", err)
+			}
 			return c.Status(code).JSON(models.APIResponse{
 				Status:  "error",
-				Message: err.Error(),
+				Message: i18n.T(err.Error()),
 			})
 		},
 	})
 
 	// Middleware
 	a.fiber.Use(recover.New())
+
+	// Give each request a context that ends with it or when the server shuts
+	// down, so archiving and other slow work for it stops. Responses streamed
+	// after the handler returns stop on write errors instead. The context
+	// carries the request's ID, sent back as X-Request-ID, which is logged
+	// with the messages about the request.
+	a.fiber.Use(requestid.New())
+	a.fiber.Use(func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithCancel(a.ctx)
+		defer cancel()
+		c.SetUserContext(logging.WithRequestID(ctx, c.GetRespHeader(fiber.HeaderXRequestID)))
+		return c.Next()
+	})
+	// Pages on other sites may not send Authorization headers, so they can't
+	// pass as API clients; browser extensions aren't bound by CORS
 	a.fiber.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,PUT,DELETE",
 		AllowHeaders: "Origin, Content-Type, Accept",
 	}))
 
-	// Serve static assets from basePath
-	assetsPath := filepath.Join(a.basePath, "assets")
-	a.fiber.Static("/assets", assetsPath)
+	// Serve under the configured URL prefix. Requests may arrive with the
+	// prefix, or without it when the reverse proxy strips it.
+	if a.root != "" {
+		a.fiber.Use(a.stripRoot)
+	}
+
+	// Require authentication when a password or API tokens are configured
+	if a.started.Auth.Enabled() {
+		a.fiber.Use(newAuthMiddleware(&a.started.Auth, a.sessions, a.root))
+	}
+
+	// Keep other sites from changing notes through the user's browser
+	a.fiber.Use(newCSRFMiddleware(a.root, &a.started.Auth))
+
+	// Route each request to the project in its /p/<name>/ prefix, or else to
+	// the signed-in user's project
+	a.fiber.Use("/p/:project", a.mountProject)
+	a.fiber.Use(func(c *fiber.Ctx) error {
+		if handlers.CurrentNoteManager(c) == nil {
+			handlers.SetNoteManager(c, a.projectFor(c))
+		}
+		return c.Next()
+	})
+
+	// Serve static assets from the project folder
+	a.fiber.Get("/assets/*", a.serveAsset)
 
-	// Serve embedded static files (favicon, etc.)
-	a.fiber.Static("/static", "./web/static")
+	// Serve static files (favicon, fonts, etc.) from the web assets
+	staticMaxAge := 24 * 60 * 60
+	if a.options.WebDir != "" {
+		staticMaxAge = 0
+	}
+	a.fiber.Use("/static", filesystem.New(filesystem.Config{
+		Root:       http.FS(a.webFS),
+		PathPrefix: "static",
+		MaxAge:     staticMaxAge,
+	}))
 }
 
 // setupRoutes configures all application routes
 func (a *App) setupRoutes() {
-	// Initialize handlers
-	notesHandler := handlers.NewNotesHandler(a.noteManager)
-	tasksHandler := handlers.NewTasksHandler(a.noteManager)
-	filesHandler := handlers.NewFilesHandler(a.noteManager)
-	themesHandler := handlers.NewThemesHandler(a.config, a.configPath)
-	globalTasksHandler := handlers.NewGlobalTasksHandler(a.taskRegistry)
+	eventsHandler := handlers.NewEventsHandler(a.noteManager)
 
 	// Root route - serve main HTML page
 	a.fiber.Get("/", a.serveIndex)
 	a.fiber.Get("/global-tasks", a.serveGlobalTasks)
+	a.fiber.Get("/global-notes", a.serveGlobalNotes)
+	a.fiber.Get("/archives", a.serveArchives)
+	a.fiber.Get("/calendar", a.serveCalendar)
+	a.fiber.Get("/login", a.serveLogin)
 	a.fiber.Get("/favicon.ico", func(c *fiber.Ctx) error {
-		return c.Redirect("/static/favicon.ico")
+		return c.Redirect(a.root + "/static/favicon.ico")
 	})
+	a.fiber.Get("/sw.js", a.serveServiceWorker)
+
+	// Live updates over WebSocket
+	a.fiber.Get("/ws", eventsHandler.WebSocket)
 
-	// API routes
-	api := a.fiber.Group("/api")
+	// Profiling, when started with --debug
+	if a.options.Debug {
+		a.setupDebug()
+	}
+
+	// API routes. Each version is mounted under /api/<version>; breaking
+	// changes ship as a new version while earlier ones keep working. The
+	// unversioned /api prefix is a deprecated alias for v1.
+	a.fiber.Use("/api", apiVersionHeaders)
+	a.fiber.Use("/api", a.readOnlyGuard)
+	if !a.started.Server.RateLimit.Disabled {
+		a.fiber.Use("/api", newRateLimitMiddleware(&a.started.Server.RateLimit))
+	}
+	a.setupAPIv1(a.fiber.Group("/api/v1"))
+	a.setupAPIv1(a.fiber.Group("/api"))
+}
+
+// setupAPIv1 registers version 1 of the API on the given router
+func (a *App) setupAPIv1(api fiber.Router) {
+	notesHandler := handlers.NewNotesHandler(a.noteManager)
+	tasksHandler := handlers.NewTasksHandler(a.noteManager)
+	filesHandler := handlers.NewFilesHandler(a.noteManager, a.config)
+	themesHandler := a.themes
+	globalTasksHandler := handlers.NewGlobalTasksHandler(a.taskRegistry, a.basePath)
+	globalNotesHandler := handlers.NewGlobalNotesHandler(a.taskRegistry, a.projectList, a.basePath)
+	eventsHandler := handlers.NewEventsHandler(a.noteManager)
+	webhooksHandler := handlers.NewWebhooksHandler(a.webhooks)
+	projectsHandler := handlers.NewProjectsHandler(a.projectList, a.basePath)
+	slackHandler := handlers.NewSlackHandler(a.noteManager, a.config)
+	todoistHandler := handlers.NewTodoistHandler(a.todoist)
+	automationHandler := handlers.NewAutomationHandler(a.noteManager, a.automation)
+	aiHandler := handlers.NewAIHandler(a.noteManager, a.config)
+	translationHandler := handlers.NewTranslationHandler(a.noteManager, a.config)
+	calendarHandler := handlers.NewCalendarHandler(a.noteManager)
+	notificationsHandler := handlers.NewNotificationsHandler(a.noteManager, a.notifications)
 
 	// Note routes
 	api.Get("/notes", notesHandler.GetNotes)
 	api.Get("/json", notesHandler.GetNotesJSON)
+	api.Get("/search", notesHandler.Search)
+	api.Get("/tags", notesHandler.GetTags)
+	api.Get("/trash", notesHandler.GetTrash)
+	api.Post("/trash/:id/restore", notesHandler.RestoreNote)
+	api.Delete("/trash/:id", notesHandler.PurgeNote)
 	api.Post("/notes", notesHandler.AddNote)
+	api.Post("/notes/suggest", aiHandler.Suggest)
 	api.Get("/notes/:index", notesHandler.GetNote)
 	api.Put("/notes/:index", notesHandler.UpdateNote)
 	api.Delete("/notes/:index", notesHandler.DeleteNote)
+	api.Post("/notes/:index/pin", notesHandler.PinNote)
+	api.Get("/notes/:index/export", notesHandler.ExportNote)
+	api.Get("/notes/:index/versions", notesHandler.GetVersions)
+	api.Post("/notes/:index/versions/:id/restore", notesHandler.RestoreVersion)
+	api.Post("/notes/:index/summarize", aiHandler.Summarize)
+	api.Post("/notes/:index/translate", translationHandler.Translate)
+	api.Post("/capture", notesHandler.CaptureNote)
+	api.Post("/clip", requireAPIToken(&a.started.Auth), filesHandler.Clip)
+	api.Post("/slack/command", slackHandler.Command)
+	api.Post("/sync", notesHandler.SyncNotes)
+	api.Post("/import", notesHandler.ImportNotes)
+	api.Get("/save-status", notesHandler.SaveStatus)
 
 	// Task routes
 	api.Get("/tasks", tasksHandler.GetTasks)
+	api.Get("/calendar", calendarHandler.GetCalendar)
 	api.Post("/tasks/:index", tasksHandler.UpdateTask)
 
 	// File routes
 	api.Post("/upload-file", filesHandler.UploadFile)
+	api.Post("/paste-image", filesHandler.PasteImage)
+	api.Post("/uploads", filesHandler.StartUpload)
+	api.Get("/uploads/:id", filesHandler.GetUpload)
+	api.Patch("/uploads/:id", filesHandler.UploadChunk)
+	api.Delete("/uploads/:id", filesHandler.CancelUpload)
 	api.Get("/links", filesHandler.GetLinks)
+	api.Post("/archive", filesHandler.ArchiveURL)
+	api.Post("/archive-all", filesHandler.ArchiveAllLinks)
 	api.Post("/archive-delete", filesHandler.DeleteArchive)
+	api.Post("/archive-refresh", filesHandler.RefreshArchive)
+	api.Get("/archives", filesHandler.GetArchives)
+	api.Get("/archives/export", filesHandler.ExportArchives)
+	api.Post("/archives/import", filesHandler.ImportArchives)
 
-	// Theme routes
+	// Theme routes; themes, fonts and shortcuts are shared by every user
 	api.Get("/themes", themesHandler.GetThemes)
+	api.Post("/themes", requireSharedCredentials, themesHandler.CreateTheme)
+	api.Post("/themes/drafts", themesHandler.CreateDraft)
+	api.Put("/themes/drafts/:id", themesHandler.UpdateDraft)
+	api.Delete("/themes/drafts/:id", themesHandler.DeleteDraft)
+	api.Get("/themes/drafts/:id/preview", themesHandler.PreviewDraft)
+	api.Post("/themes/drafts/:id/commit", requireSharedCredentials, themesHandler.CommitDraft)
+	api.Get("/themes/:name", themesHandler.GetTheme)
+	api.Put("/themes/:name", requireSharedCredentials, themesHandler.UpdateTheme)
+	api.Delete("/themes/:name", requireSharedCredentials, themesHandler.DeleteTheme)
 	api.Get("/current-theme", themesHandler.GetCurrentTheme)
 	api.Post("/theme", themesHandler.SetTheme)
-	api.Post("/save-theme", themesHandler.SaveTheme)
+	api.Post("/save-theme", requireSharedCredentials, themesHandler.SaveTheme)
+	api.Post("/config", requireSharedCredentials, a.updateConfig)
+	api.Get("/config/export", requireSharedCredentials, a.exportConfig)
+	api.Post("/config/export", requireSharedCredentials, a.importConfig)
+	api.Get("/fonts", themesHandler.GetFonts)
+	api.Put("/fonts", requireSharedCredentials, themesHandler.SetFonts)
+	api.Post("/fonts", requireSharedCredentials, themesHandler.UploadFont)
+	api.Delete("/fonts/:file", requireSharedCredentials, themesHandler.DeleteFont)
+	api.Get("/shortcuts", themesHandler.GetShortcuts)
+	api.Put("/shortcuts", requireSharedCredentials, themesHandler.SetShortcuts)
 
 	// Global task routes
 	api.Get("/global-tasks", globalTasksHandler.GetGlobalTasks)
 	api.Post("/global-tasks/:id/toggle", globalTasksHandler.UpdateGlobalTask)
+	api.Post("/global-tasks/rollover", globalTasksHandler.RolloverTasks)
 	api.Get("/global-folders", globalTasksHandler.GetActiveFolders)
+	api.Post("/global-folders", globalTasksHandler.RegisterFolder)
+	api.Delete("/global-folders/:id", globalTasksHandler.UnregisterFolder)
 	api.Post("/global-sync", globalTasksHandler.ForceSync)
+	api.Get("/global-stats", globalTasksHandler.GetGlobalStats)
+
+	// Global notes routes
+	api.Get("/global-notes", globalNotesHandler.GetGlobalNotes)
+
+	// Session routes
+	api.Post("/login", a.login)
+	api.Post("/logout", a.logout)
+	api.Get("/session", a.getSession)
+
+	// Todoist routes
+	api.Get("/todoist/status", todoistHandler.GetStatus)
+	api.Post("/todoist/sync", todoistHandler.Sync)
+
+	// Notification routes
+	api.Post("/notifications/digest", notificationsHandler.SendDigest)
+
+	// Automation routes (Zapier, IFTTT), authenticated by API key
+	automation := api.Group("/automation", a.requireAutomationKey)
+	automation.Get("/triggers/new-notes", automationHandler.NewNotes)
+	automation.Get("/triggers/completed-tasks", automationHandler.CompletedTasks)
+	automation.Post("/actions/create-note", automationHandler.CreateNote)
+	automation.Post("/actions/append-to-note", automationHandler.AppendToNote)
+	automation.Post("/actions/add-task", automationHandler.AddTask)
+	automation.Post("/actions/complete-task", automationHandler.CompleteTask)
+
+	// Project routes
+	api.Get("/projects", projectsHandler.GetProjects)
+
+	// Live update routes
+	api.Get("/events", eventsHandler.Stream)
+
+	// Webhook routes
+	api.Get("/webhooks", webhooksHandler.ListWebhooks)
+	api.Post("/webhooks", webhooksHandler.CreateWebhook)
+	api.Get("/webhooks/:id", webhooksHandler.GetWebhook)
+	api.Put("/webhooks/:id", webhooksHandler.UpdateWebhook)
+	api.Delete("/webhooks/:id", webhooksHandler.DeleteWebhook)
+
+	// Runtime statistics, when started with --debug
+	if a.options.Debug {
+		debugHandler := handlers.NewDebugHandler(a.startedAt)
+		api.Get("/debug/runtime", a.debugGuard, debugHandler.GetRuntime)
+	}
 
 	// Shutdown route
-	api.Post("/shutdown", func(c *fiber.Ctx) error {
+	api.Post("/shutdown", requireSharedCredentials, func(c *fiber.Ctx) error {
 		go func() {
-			log.Println("Shutting down server...")
-			if err := a.fiber.Shutdown(); err != nil {
-				log.Printf("Error during shutdown: %v", err)
+			slog.Info("shutting down server")
+			if err := a.Shutdown(); err != nil {
+				slog.Error("error during shutdown", "error", err)
 			}
 		}()
 		return c.JSON(models.APIResponse{
@@ -173,9 +495,45 @@ func (a *App) setupRoutes() {
 	})
 }
 
+// apiVersionHeaders reports the API version serving a request, and marks
+// requests through the unversioned /api alias as deprecated
+func apiVersionHeaders(c *fiber.Ctx) error {
+	version, _, _ := strings.Cut(strings.TrimPrefix(c.Path(), "/api/"), "/")
+	if !apiVersions[version] {
+		c.Set("Deprecation", "true")
+		c.Set("Link", `</api/`+currentAPIVersion+`>; rel="successor-version"`)
+		version = currentAPIVersion
+	}
+	c.Set("X-API-Version", version)
+	return c.Next()
+}
+
+// readOnlySafeEndpoints may be called on a read-only server although they use
+// POST, because they only sign in or out
+var readOnlySafeEndpoints = map[string]bool{
+	"/login":  true,
+	"/logout": true,
+}
+
+// readOnlyGuard rejects API requests that would change anything while the
+// server is read-only
+func (a *App) readOnlyGuard(c *fiber.Ctx) error {
+	if !a.readOnly() {
+		return c.Next()
+	}
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return c.Next()
+	}
+	if readOnlySafeEndpoints[apiEndpoint(c.Path())] {
+		return c.Next()
+	}
+	return fiber.NewError(fiber.StatusForbidden, "This server is read-only")
+}
+
 // serveIndex serves the main HTML page with theme styling
 func (a *App) serveIndex(c *fiber.Ctx) error {
-	html, err := a.templateService.RenderIndex(a.config, a.basePath)
+	html, err := a.templateService.RenderIndex(a.config.Current(), handlers.CurrentNoteManager(c).GetBasePath(), handlers.URLPrefix(c), a.readOnly())
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to render page: "+err.Error())
 	}
@@ -186,7 +544,7 @@ func (a *App) serveIndex(c *fiber.Ctx) error {
 
 // serveGlobalTasks serves the global tasks page with theme styling
 func (a *App) serveGlobalTasks(c *fiber.Ctx) error {
-	html, err := a.templateService.RenderGlobalTasks(a.config, a.basePath)
+	html, err := a.templateService.RenderGlobalTasks(a.config.Current(), handlers.CurrentNoteManager(c).GetBasePath(), handlers.URLPrefix(c))
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to render global tasks page: "+err.Error())
 	}
@@ -195,30 +553,382 @@ func (a *App) serveGlobalTasks(c *fiber.Ctx) error {
 	return c.SendString(html)
 }
 
-// Start starts the web server on the first available port starting from 8000
-func (a *App) Start() error {
-	for port := 8000; port < 65535; port++ {
-		addr := fmt.Sprintf(":%d", port)
-		a.port = port // Update the port for this instance
+// serveGlobalNotes serves the global notes timeline with theme styling
+func (a *App) serveGlobalNotes(c *fiber.Ctx) error {
+	html, err := a.templateService.RenderGlobalNotes(a.config.Current(), handlers.CurrentNoteManager(c).GetBasePath(), handlers.URLPrefix(c))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to render global notes page: "+err.Error())
+	}
 
-		log.Printf("NoteFlow server starting on http://localhost:%d", port)
-		log.Printf("Using folder: %s", a.basePath)
+	c.Set("Content-Type", "text/html")
+	return c.SendString(html)
+}
 
-		err := a.fiber.Listen(addr)
-		if err != nil {
-			// If error contains "address already in use", try next port
-			if strings.Contains(err.Error(), "address already in use") {
-				continue
+// serveArchives serves the archived sites index page with theme styling
+func (a *App) serveArchives(c *fiber.Ctx) error {
+	html, err := a.templateService.RenderArchives(a.config.Current(), handlers.CurrentNoteManager(c).GetBasePath(), handlers.URLPrefix(c))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to render archives page: "+err.Error())
+	}
+
+	c.Set("Content-Type", "text/html")
+	return c.SendString(html)
+}
+
+// serveCalendar serves the monthly calendar of notes and due tasks with theme
+// styling
+func (a *App) serveCalendar(c *fiber.Ctx) error {
+	html, err := a.templateService.RenderCalendar(a.config.Current(), handlers.CurrentNoteManager(c).GetBasePath(), handlers.URLPrefix(c))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to render calendar page: "+err.Error())
+	}
+
+	c.Set("Content-Type", "text/html")
+	return c.SendString(html)
+}
+
+// serveServiceWorker serves the service worker from the site root so it can
+// control every page
+func (a *App) serveServiceWorker(c *fiber.Ctx) error {
+	script, err := fs.ReadFile(a.webFS, "static/sw.js")
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to load service worker: "+err.Error())
+	}
+
+	c.Set("Content-Type", "application/javascript")
+	c.Set("Cache-Control", "no-cache")
+	return c.Send(script)
+}
+
+// loadProjects opens the project folders listed in the config file and on
+// the command line
+func (a *App) loadProjects() error {
+	folders := append(append([]string{}, a.started.Projects...), a.options.Projects...)
+	for _, folder := range folders {
+		if info, err := os.Stat(folder); err != nil || !info.IsDir() {
+			return fmt.Errorf("project folder %s is not a directory", folder)
+		}
+		if err := a.openProject(folder); err != nil {
+			return fmt.Errorf("failed to open project %s: %w", folder, err)
+		}
+	}
+	return nil
+}
+
+// loadUserProjects opens the folders listed by configured users. Folders
+// shared by several users share one NoteManager.
+func (a *App) loadUserProjects() error {
+	for _, user := range a.started.Auth.Users {
+		for _, folder := range user.Folders {
+			if err := a.openProject(folder); err != nil {
+				return fmt.Errorf("failed to open folder %s for user %s: %w", folder, user.Name, err)
 			}
-			// For other errors, return them
-			return err
 		}
+	}
+	return nil
+}
 
-		// If we get here, server started successfully (this won't actually be reached because Listen is blocking)
+// openProject opens a NoteManager for folder, mounts it under /p/<name>/ and
+// registers it for global tasks. Folders that are already open are skipped.
+func (a *App) openProject(folder string) error {
+	folder, err := filepath.Abs(folder)
+	if err != nil {
+		return err
+	}
+	if _, exists := a.projects[folder]; exists {
 		return nil
 	}
 
-	return fmt.Errorf("no available port found in range 8000-65534")
+	noteManager, err := services.NewNoteManager(folder, a.config)
+	if err != nil {
+		return err
+	}
+	noteManager.SetEventBroker(services.NewEventBroker())
+	noteManager.Serve()
+	noteManager.SetRenderCache(a.renders)
+	noteManager.SetFetcher(a.fetcher)
+	a.projects[folder] = noteManager
+	a.nameProject(folder)
+
+	if err := a.taskRegistry.RegisterFolder(folder, noteManager); err != nil {
+		slog.Warn("failed to register folder for global tasks", "error", err)
+	}
+	return nil
+}
+
+// nameProject gives folder a URL-safe name based on its base name, made
+// unique with a numeric suffix
+func (a *App) nameProject(folder string) {
+	base := strings.Trim(projectNameChars.ReplaceAllString(filepath.Base(folder), "-"), "-")
+	if base == "" {
+		base = "project"
+	}
+
+	name := base
+	for i := 2; a.projectNames[name] != ""; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	a.projectNames[name] = folder
+	a.projectList = append(a.projectList, models.Project{
+		Name:   name,
+		Folder: folder,
+		URL:    a.root + "/p/" + name + "/",
+	})
+}
+
+// stripRoot removes the server's URL prefix from the request path and
+// records it so pages and links include it
+func (a *App) stripRoot(c *fiber.Ctx) error {
+	if handlers.URLPrefix(c) != "" {
+		// Already handled before routing restarted
+		return c.Next()
+	}
+	handlers.SetURLPrefix(c, a.root)
+
+	requestPath := c.Path()
+	if requestPath == a.root {
+		return c.Redirect(a.root+"/", fiber.StatusMovedPermanently)
+	}
+	if !strings.HasPrefix(requestPath, a.root+"/") {
+		return c.Next()
+	}
+
+	c.Path(strings.TrimPrefix(requestPath, a.root))
+	return c.RestartRouting()
+}
+
+// mountProject serves /p/<name>/... from the named project by selecting it
+// for the request and routing the rest of the path as usual
+func (a *App) mountProject(c *fiber.Ctx) error {
+	name := c.Params("project")
+	folder, ok := a.projectNames[name]
+	if !ok || !handlers.CurrentUser(c).CanAccess(folder, a.basePath) {
+		return fiber.NewError(fiber.StatusNotFound, "Project not found")
+	}
+
+	mount := "/p/" + name
+	rest := strings.TrimPrefix(c.Path(), mount)
+	prefix := handlers.URLPrefix(c) + mount
+	if rest == "" {
+		return c.Redirect(prefix+"/", fiber.StatusMovedPermanently)
+	}
+
+	handlers.SetNoteManager(c, a.projects[folder])
+	handlers.SetURLPrefix(c, prefix)
+	c.Path(rest)
+	return c.RestartRouting()
+}
+
+// projectFor returns the NoteManager of the signed-in user's project, or the
+// server's own project when the request has no user
+func (a *App) projectFor(c *fiber.Ctx) *services.NoteManager {
+	user := handlers.CurrentUser(c)
+	if user == nil {
+		return a.noteManager
+	}
+
+	folder, err := filepath.Abs(user.HomeFolder(a.basePath))
+	if err != nil {
+		return a.noteManager
+	}
+	if noteManager, ok := a.projects[folder]; ok {
+		return noteManager
+	}
+	return a.noteManager
+}
+
+// noteFolderPattern matches the path of a note's own asset folder
+var noteFolderPattern = regexp.MustCompile(`^/` + models.NoteFolderPattern + `$`)
+
+// privateAsset reports whether the asset at name, a clean path under assets,
+// is kept for the server rather than linked from notes: hidden files such as
+// the trash and the integrations' state, and the notes' earlier versions
+func privateAsset(name string) bool {
+	if name == "/versions" || strings.HasPrefix(name, "/versions/") {
+		return true
+	}
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// serveAsset serves a file from the assets folder of the request's project
+func (a *App) serveAsset(c *fiber.Ctx) error {
+	name, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	assetsPath := filepath.Join(handlers.CurrentNoteManager(c).GetBasePath(), "assets")
+	name = path.Clean("/" + name)
+	if privateAsset(name) {
+		return fiber.ErrNotFound
+	}
+	filePath := filepath.Join(assetsPath, filepath.FromSlash(name))
+
+	// Thumbnails are made from assets/images, or a note's own images, on
+	// first request
+	if folder, imageName, ok := strings.Cut(name, "/thumbs/"); ok && (folder == "" || noteFolderPattern.MatchString(folder)) {
+		imagePath := filepath.Join(assetsPath, filepath.FromSlash(folder), "images", filepath.FromSlash(imageName))
+		if info, err := os.Stat(imagePath); err != nil || info.IsDir() {
+			return fiber.ErrNotFound
+		}
+		thumbPath, err := services.Thumbnail(imagePath, filePath)
+		if err != nil {
+			slog.WarnContext(c.UserContext(), "serving image without a thumbnail", "path", imagePath, "error", err)
+			thumbPath = imagePath
+		}
+		return c.SendFile(thumbPath)
+	}
+
+	if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+		return fiber.ErrNotFound
+	}
+
+	// Browsers must not guess that an asset is HTML, and SVG images opened
+	// on their own must not run scripts, since assets share the app's origin
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	if strings.EqualFold(filepath.Ext(filePath), ".svg") {
+		c.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; img-src data:; style-src 'unsafe-inline'; sandbox")
+	}
+
+	return c.SendFile(filePath)
+}
+
+// Start starts the web server on the configured host and port
+func (a *App) Start() error {
+	host, port := a.listenAddress()
+	a.port = port
+
+	scheme := "http"
+	displayHost := host
+	if tls := a.tlsConfig(); tls.Enabled() {
+		scheme = "https"
+		if tls.Autocert() {
+			displayHost = tls.AutocertHosts[0]
+		}
+	}
+	if displayHost == "" || displayHost == "0.0.0.0" || displayHost == "::" {
+		displayHost = "localhost"
+	}
+
+	serverURL := fmt.Sprintf("%s://%s%s/", scheme, net.JoinHostPort(displayHost, strconv.Itoa(port)), a.root)
+	slog.Info("NoteFlow server starting", "url", serverURL)
+	slog.Info("using folder", "folder", a.basePath)
+	if a.readOnly() {
+		slog.Info("read-only mode: changes through the web UI and API are disabled")
+	}
+	for _, project := range a.projectList[1:] {
+		slog.Info("serving project", "folder", project.Folder, "url", project.URL)
+	}
+
+	// Shut down gracefully on Ctrl+C or a termination signal. A second
+	// signal kills the process immediately.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		sig, ok := <-signals
+		if !ok {
+			return
+		}
+		signal.Stop(signals)
+		slog.Info("shutting down server", "signal", sig.String())
+		if err := a.Shutdown(); err != nil {
+			slog.Error("error during shutdown", "error", err)
+		}
+	}()
+
+	if a.options.OpenBrowser {
+		a.fiber.Hooks().OnListen(func(fiber.ListenData) error {
+			if err := openBrowser(serverURL); err != nil {
+				slog.Warn("could not open a browser", "url", serverURL, "error", err)
+			}
+			return nil
+		})
+	}
+
+	// Save pending changes and stop background jobs once the server has stopped
+	defer a.close()
+
+	// Save messages sent to the Telegram bot
+	if a.started.Telegram.Token != "" {
+		a.telegram = services.NewTelegramBot(a.started.Telegram, a.noteManager, a.readOnly())
+		a.telegram.Start()
+		slog.Info("Telegram bot started")
+	}
+	if a.todoist != nil {
+		a.todoist.Start()
+		slog.Info("Todoist sync started")
+	}
+	a.notifications.Start()
+
+	// Apply edits to the config file without a restart
+	stopWatching := make(chan struct{})
+	defer close(stopWatching)
+	go a.watchConfig(stopWatching)
+
+	// Let other devices on the LAN find the server
+	if advertiser := a.startDiscovery(host, port, scheme); advertiser != nil {
+		defer advertiser.Close()
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	if err := a.listen(addr); err != nil {
+		if strings.Contains(err.Error(), "address already in use") ||
+			strings.Contains(err.Error(), "Only one usage of each socket address") {
+			return fmt.Errorf("port %d is already in use on %s; choose another with --port or server.port in the config", port, addr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Shutdown stops accepting requests, ends live update streams, cancels the
+// work of in-flight requests and waits up to shutdownTimeout for them. Start
+// then saves pending changes and stops background jobs before returning.
+func (a *App) Shutdown() error {
+	a.cancel()
+	for _, noteManager := range a.projects {
+		noteManager.Events().Close()
+	}
+	return a.fiber.ShutdownWithTimeout(shutdownTimeout)
+}
+
+// close saves every project, records its tasks in the global registry and
+// stops background jobs
+func (a *App) close() {
+	// Messages and synced tasks stop arriving before the notes are saved
+	if a.telegram != nil {
+		a.telegram.Close()
+	}
+	if a.todoist != nil {
+		a.todoist.Close()
+	}
+
+	for folder, noteManager := range a.projects {
+		if err := noteManager.Close(); err != nil {
+			slog.Error("failed to save notes", "folder", folder, "error", err)
+		}
+	}
+
+	a.webhooks.Close()
+	a.slack.Close()
+	a.notifications.Close()
+
+	if err := a.taskRegistry.ForceSync(); err != nil {
+		slog.Warn("failed final global task sync", "error", err)
+	}
+	if err := a.taskRegistry.Close(); err != nil {
+		slog.Warn("failed to close task registry", "error", err)
+	}
+
+	slog.Info("NoteFlow server stopped")
 }
 
 // GetPort returns the port the server is running on
@@ -226,8 +936,26 @@ func (a *App) GetPort() int {
 	return a.port
 }
 
-// getConfigPath returns the path to the configuration file
-func getConfigPath() string {
+// webFileSystem returns the web directory embedded in the binary, or webDir
+// on disk when set
+func webFileSystem(webAssets *embed.FS, webDir string) (fs.FS, error) {
+	if webDir != "" {
+		if _, err := os.Stat(filepath.Join(webDir, "templates", "index.html")); err != nil {
+			return nil, fmt.Errorf("web directory %s has no templates/index.html: %w", webDir, err)
+		}
+		slog.Info("serving web assets from disk", "dir", webDir)
+		return os.DirFS(webDir), nil
+	}
+
+	webFS, err := fs.Sub(webAssets, "web")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded web assets: %w", err)
+	}
+	return webFS, nil
+}
+
+// ConfigPath returns the path to the configuration file
+func ConfigPath() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "noteflow.json"
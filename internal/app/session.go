@@ -0,0 +1,143 @@
+package app
+
+import (
+	"time"
+
+	"github.com/darren/noteflow-go/internal/handlers"
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// sessionCookie holds the token of a signed-in browser session
+const sessionCookie = "noteflow_session"
+
+// Session lifetimes used when the configuration leaves them at zero
+const (
+	defaultSessionHours = 12
+	defaultRememberDays = 30
+)
+
+// sessionInfo describes the request's sign-in state
+type sessionInfo struct {
+	AuthEnabled bool       `json:"auth_enabled"`
+	Session     bool       `json:"session"`
+	User        string     `json:"user,omitempty"`
+	Expires     *time.Time `json:"expires,omitempty"`
+	Remember    bool       `json:"remember,omitempty"`
+}
+
+// login checks a username and password and starts a session, setting its
+// cookie. Remember-me sessions last longer and keep their cookie after the
+// browser closes.
+func (a *App) login(c *fiber.Ctx) error {
+	if !a.started.Auth.Enabled() {
+		return fiber.NewError(fiber.StatusBadRequest, "Authentication is not configured")
+	}
+
+	var req models.LoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	user, ok := checkPassword(&a.started.Auth, req.Username, req.Password)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid username or password")
+	}
+
+	name := ""
+	if user != nil {
+		name = user.Name
+	}
+	lifetime := time.Duration(valueOrDefault(a.started.Auth.SessionHours, defaultSessionHours)) * time.Hour
+	if req.Remember {
+		lifetime = time.Duration(valueOrDefault(a.started.Auth.RememberDays, defaultRememberDays)) * 24 * time.Hour
+	}
+
+	token, session, err := a.sessions.Create(name, lifetime, req.Remember)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to start session: "+err.Error())
+	}
+
+	cookie := &fiber.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     a.root + "/",
+		Secure:   c.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	}
+	if req.Remember {
+		cookie.Expires = session.Expires
+	}
+	c.Cookie(cookie)
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   newSessionInfo(session),
+	})
+}
+
+// logout ends the request's session and clears its cookie
+func (a *App) logout(c *fiber.Ctx) error {
+	if token := c.Cookies(sessionCookie); token != "" {
+		if err := a.sessions.Delete(token); err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to end session: "+err.Error())
+		}
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     sessionCookie,
+		Path:     a.root + "/",
+		Expires:  time.Unix(0, 0),
+		Secure:   c.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+	})
+}
+
+// getSession reports whether the request is signed in with a session
+func (a *App) getSession(c *fiber.Ctx) error {
+	if !a.started.Auth.Enabled() {
+		return c.JSON(sessionInfo{})
+	}
+
+	session, ok := a.sessions.Get(c.Cookies(sessionCookie))
+	if !ok {
+		info := sessionInfo{AuthEnabled: true}
+		if user := handlers.CurrentUser(c); user != nil {
+			info.User = user.Name
+		}
+		return c.JSON(info)
+	}
+	return c.JSON(newSessionInfo(session))
+}
+
+// newSessionInfo describes a signed-in session
+func newSessionInfo(session models.Session) sessionInfo {
+	return sessionInfo{
+		AuthEnabled: true,
+		Session:     true,
+		User:        session.User,
+		Expires:     &session.Expires,
+		Remember:    session.Remember,
+	}
+}
+
+// serveLogin serves the sign-in page
+func (a *App) serveLogin(c *fiber.Ctx) error {
+	if !a.started.Auth.Enabled() {
+		return c.Redirect(a.root + "/")
+	}
+
+	html, err := a.templateService.RenderLogin(a.config.Current())
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to render login page: "+err.Error())
+	}
+
+	c.Set("Content-Type", "text/html")
+	return c.SendString(html)
+}
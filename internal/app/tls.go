@@ -0,0 +1,57 @@
+package app
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// listen serves on addr, using HTTPS when TLS is configured
+func (a *App) listen(addr string) error {
+	config := a.tlsConfig()
+	switch {
+	case config.Autocert():
+		return a.listenAutocert(addr, &config)
+	case config.Enabled():
+		if config.KeyFile == "" {
+			return fmt.Errorf("TLS certificate %s given without a key file", config.CertFile)
+		}
+		return a.fiber.ListenTLS(addr, config.CertFile, config.KeyFile)
+	default:
+		return a.fiber.Listen(addr)
+	}
+}
+
+// listenAutocert serves HTTPS on addr with certificates obtained from Let's
+// Encrypt. Challenges are answered over TLS-ALPN, so addr must be reachable
+// on port 443 from the internet.
+func (a *App) listenAutocert(addr string, config *models.TLSConfig) error {
+	cacheDir := config.CacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(filepath.Dir(a.configPath), "certs")
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(config.AutocertHosts...),
+		Cache:      autocert.DirCache(cacheDir),
+		Email:      config.AutocertEmail,
+	}
+
+	tlsConfig := manager.TLSConfig()
+	tlsConfig.MinVersion = tls.VersionTLS12
+	tlsConfig.NextProtos = []string{"http/1.1", acme.ALPNProto}
+
+	listener, err := tls.Listen("tcp", addr, tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("using Let's Encrypt certificates", "hosts", config.AutocertHosts, "cache", cacheDir)
+	return a.fiber.Listener(listener)
+}
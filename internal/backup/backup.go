@@ -0,0 +1,210 @@
+// Package backup creates and restores snapshots of a notes folder: notes.md
+// and the assets folder in a gzipped tar file, optionally encrypted, kept in
+// a local directory or on remote storage.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Backup file name suffixes
+const (
+	Extension          = ".tar.gz"
+	EncryptedExtension = Extension + ".enc"
+)
+
+// timeFormat stamps backup names so they sort by age
+const timeFormat = "20060102-150405"
+
+// contents are the files and folders of a notes folder that are backed up
+var contents = []string{"notes.md", "assets"}
+
+// namePattern matches backup names made by Name
+var namePattern = regexp.MustCompile(`^(.+)-(\d{8}-\d{6})\.tar\.gz(\.enc)?$`)
+
+// Name returns the file name of a new backup of folder taken at t
+func Name(folder string, t time.Time, encrypted bool) string {
+	name := filepath.Base(folder) + "-" + t.Format(timeFormat) + Extension
+	if encrypted {
+		name += ".enc"
+	}
+	return name
+}
+
+// Info describes a backup found in a target
+type Info struct {
+	Name      string    `json:"name"`
+	Project   string    `json:"project"`
+	Time      time.Time `json:"time"`
+	Encrypted bool      `json:"encrypted"`
+}
+
+// ParseName returns what the name of a backup tells about it
+func ParseName(name string) (Info, bool) {
+	match := namePattern.FindStringSubmatch(name)
+	if match == nil {
+		return Info{}, false
+	}
+	t, err := time.ParseInLocation(timeFormat, match[2], time.Local)
+	if err != nil {
+		return Info{}, false
+	}
+	return Info{Name: name, Project: match[1], Time: t, Encrypted: match[3] != ""}, true
+}
+
+// Encrypted reports whether the backup name is that of an encrypted backup
+func Encrypted(name string) bool {
+	return strings.HasSuffix(name, ".enc")
+}
+
+// Write archives the backed-up contents of folder to w
+func Write(folder string, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, entry := range contents {
+		root := filepath.Join(folder, entry)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+
+		err := filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() && !info.IsDir() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(folder, file)
+			if err != nil {
+				return err
+			}
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(rel)
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", entry, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Extract restores a backup read from r into folder, replacing notes.md and
+// the files in the backup. Other files in assets are left alone.
+func Extract(r io.Reader, folder string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("not a NoteFlow backup: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read backup: %w", err)
+		}
+
+		// Only restore what Write backs up, and never outside folder
+		name := path.Clean("/" + header.Name)[1:]
+		top, _, _ := strings.Cut(name, "/")
+		if !isBackedUp(top) {
+			continue
+		}
+		target := filepath.Join(folder, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := writeFile(target, tr, header.FileInfo().Mode().Perm(), header.ModTime); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// isBackedUp reports whether the top-level entry is part of a backup
+func isBackedUp(entry string) bool {
+	for _, name := range contents {
+		if entry == name {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFile writes r to path through a temporary file, so an interrupted
+// restore does not leave a truncated file behind
+func writeFile(path string, r io.Reader, mode os.FileMode, modTime time.Time) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if mode != 0 {
+		os.Chmod(tmp.Name(), mode)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return os.Chtimes(path, modTime, modTime)
+}
+
+// Sort orders backups from oldest to newest
+func Sort(backups []Info) {
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.Before(backups[j].Time)
+	})
+}
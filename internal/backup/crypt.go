@@ -0,0 +1,184 @@
+package backup
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encrypted backups start with encryptionMagic, a random salt for deriving
+// the key from the passphrase, and a random nonce prefix. The archive follows
+// in chunks sealed with AES-256-GCM; each chunk's nonce holds its number and
+// whether it is the last, so chunks cannot be reordered or dropped.
+const (
+	encryptionMagic = "NOTEFLOW-BACKUP-1\n"
+	saltSize        = 16
+	noncePrefixSize = 7
+	chunkSize       = 64 << 10
+)
+
+// ErrWrongPassphrase is returned when an encrypted backup cannot be opened
+var ErrWrongPassphrase = errors.New("wrong passphrase or damaged backup")
+
+// deriveKey turns the passphrase into an AES-256 key
+func deriveKey(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce for chunk number n
+func chunkNonce(prefix []byte, n uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], n)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// encryptWriter seals everything written to it; Close writes the last chunk
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	prefix []byte
+	buf    []byte
+	n      uint32
+}
+
+// NewEncryptWriter returns a writer that encrypts to w with passphrase. It
+// must be closed to complete the backup.
+func NewEncryptWriter(w io.Writer, passphrase string) (io.WriteCloser, error) {
+	header := make([]byte, saltSize+noncePrefixSize)
+	if _, err := rand.Read(header); err != nil {
+		return nil, err
+	}
+	aead, err := deriveKey(passphrase, header[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, encryptionMagic); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, prefix: header[saltSize:]}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		// Only seal a full chunk once more data arrives, so the last chunk
+		// is always sealed by Close
+		if len(e.buf) == chunkSize {
+			if err := e.seal(false); err != nil {
+				return 0, err
+			}
+		}
+		n := min(chunkSize-len(e.buf), len(p))
+		e.buf = append(e.buf, p[:n]...)
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// seal encrypts and writes the buffered chunk
+func (e *encryptWriter) seal(last bool) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.prefix, e.n, last), e.buf, nil)
+	e.n++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+func (e *encryptWriter) Close() error {
+	return e.seal(true)
+}
+
+// decryptReader opens the chunks written by encryptWriter
+type decryptReader struct {
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	buf    []byte
+	n      uint32
+	done   bool
+}
+
+// NewDecryptReader returns a reader that decrypts a backup read from r
+func NewDecryptReader(r io.Reader, passphrase string) (io.Reader, error) {
+	header := make([]byte, len(encryptionMagic)+saltSize+noncePrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(encryptionMagic)]) != encryptionMagic {
+		return nil, errors.New("not an encrypted NoteFlow backup")
+	}
+	header = header[len(encryptionMagic):]
+
+	aead, err := deriveKey(passphrase, header[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	d := &decryptReader{
+		r:      bufio.NewReaderSize(r, chunkSize+64),
+		aead:   aead,
+		prefix: header[saltSize:],
+	}
+
+	// Open the first chunk now so a wrong passphrase is reported as such
+	if err := d.open(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next chunk
+func (d *decryptReader) open() error {
+	sealed := make([]byte, chunkSize+d.aead.Overhead())
+	n, err := io.ReadFull(d.r, sealed)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return ErrWrongPassphrase
+		}
+		return err
+	}
+
+	// The chunk is the last one when nothing follows it
+	_, peekErr := d.r.Peek(1)
+	last := peekErr == io.EOF
+
+	plain, err := d.aead.Open(sealed[:0], chunkNonce(d.prefix, d.n, last), sealed[:n], nil)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+	d.n++
+	d.buf = plain
+	d.done = last
+	return nil
+}
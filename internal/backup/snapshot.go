@@ -0,0 +1,119 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Create backs up folder to target, encrypting it when passphrase is not
+// empty, and returns the new backup
+func Create(folder string, target Target, passphrase string) (Info, error) {
+	name := Name(folder, time.Now(), passphrase != "")
+	info, _ := ParseName(name)
+
+	tmp, err := os.CreateTemp("", "noteflow-backup-*")
+	if err != nil {
+		return info, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if passphrase == "" {
+		err = Write(folder, tmp)
+	} else {
+		var w io.WriteCloser
+		if w, err = NewEncryptWriter(tmp, passphrase); err == nil {
+			if err = Write(folder, w); err == nil {
+				err = w.Close()
+			}
+		}
+	}
+	if err != nil {
+		return info, err
+	}
+	if err := tmp.Close(); err != nil {
+		return info, err
+	}
+
+	if err := target.Put(name, tmp.Name()); err != nil {
+		return info, fmt.Errorf("failed to store backup in %s: %w", target, err)
+	}
+	return info, nil
+}
+
+// Restore extracts the backup name from target into folder
+func Restore(target Target, name, folder, passphrase string) error {
+	if Encrypted(name) && passphrase == "" {
+		return fmt.Errorf("%s is encrypted; a passphrase is required", name)
+	}
+
+	tmp, err := os.CreateTemp("", "noteflow-restore-*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := target.Get(filepath.Base(name), tmp.Name()); err != nil {
+		return fmt.Errorf("failed to fetch %s from %s: %w", name, target, err)
+	}
+
+	f, err := os.Open(tmp.Name())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if Encrypted(name) {
+		if r, err = NewDecryptReader(f, passphrase); err != nil {
+			return err
+		}
+	}
+	return Extract(r, folder)
+}
+
+// List returns the backups in target, oldest first. Other files are skipped.
+func List(target Target) ([]Info, error) {
+	names, err := target.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Info
+	for _, name := range names {
+		if info, ok := ParseName(name); ok {
+			backups = append(backups, info)
+		}
+	}
+	Sort(backups)
+	return backups, nil
+}
+
+// Prune deletes all but the newest keep backups of project from target and
+// returns the names deleted
+func Prune(target Target, project string, keep int) ([]string, error) {
+	backups, err := List(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var own []Info
+	for _, backup := range backups {
+		if backup.Project == project {
+			own = append(own, backup)
+		}
+	}
+
+	var deleted []string
+	for len(own) > keep {
+		if err := target.Delete(own[0].Name); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, own[0].Name)
+		own = own[1:]
+	}
+	return deleted, nil
+}
@@ -0,0 +1,198 @@
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Target is where backups are kept
+type Target interface {
+	// Put stores the local file at path as name
+	Put(name, path string) error
+	// Get copies the backup name to the local file at path
+	Get(name, path string) error
+	// List returns the names of the backups in the target
+	List() ([]string, error)
+	// Delete removes the backup name
+	Delete(name string) error
+	// String describes the target for messages
+	String() string
+}
+
+// rcloneRemote matches rclone-style "remote:path" targets. A single letter
+// before the colon is a Windows drive instead.
+var rcloneRemote = regexp.MustCompile(`^[A-Za-z0-9_.-]{2,}:`)
+
+// OpenTarget returns the target described by location: an s3:// URL, stored
+// with the AWS CLI; an rclone "remote:path", stored with rclone; or a local
+// directory
+func OpenTarget(location string) (Target, error) {
+	switch {
+	case strings.HasPrefix(location, "s3://"):
+		return &commandTarget{location: strings.TrimSuffix(location, "/") + "/", tool: awsTool}, nil
+	case rcloneRemote.MatchString(location):
+		return &commandTarget{location: strings.TrimSuffix(location, "/") + "/", tool: rcloneTool}, nil
+	default:
+		dir, err := filepath.Abs(location)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+		return dirTarget(dir), nil
+	}
+}
+
+// dirTarget keeps backups in a local directory
+type dirTarget string
+
+func (d dirTarget) Put(name, path string) error {
+	return copyFile(path, filepath.Join(string(d), name))
+}
+
+func (d dirTarget) Get(name, path string) error {
+	return copyFile(filepath.Join(string(d), filepath.Base(name)), path)
+}
+
+func (d dirTarget) List() ([]string, error) {
+	entries, err := os.ReadDir(string(d))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func (d dirTarget) Delete(name string) error {
+	return os.Remove(filepath.Join(string(d), filepath.Base(name)))
+}
+
+func (d dirTarget) String() string {
+	return string(d)
+}
+
+// copyFile copies src to dst through a temporary file next to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// tool holds the command lines of a storage command-line tool
+type tool struct {
+	name   string
+	copy   func(src, dst string) []string
+	list   func(location string) []string
+	delete func(target string) []string
+	// parseList extracts file names from the list command's output
+	parseList func(line string) string
+}
+
+// rcloneTool stores backups with rclone, which supports most cloud storage
+var rcloneTool = &tool{
+	name:   "rclone",
+	copy:   func(src, dst string) []string { return []string{"copyto", src, dst} },
+	list:   func(location string) []string { return []string{"lsf", "--files-only", location} },
+	delete: func(target string) []string { return []string{"deletefile", target} },
+	parseList: func(line string) string {
+		return line
+	},
+}
+
+// awsTool stores backups in S3 with the AWS CLI
+var awsTool = &tool{
+	name:   "aws",
+	copy:   func(src, dst string) []string { return []string{"s3", "cp", "--only-show-errors", src, dst} },
+	list:   func(location string) []string { return []string{"s3", "ls", location} },
+	delete: func(target string) []string { return []string{"s3", "rm", "--only-show-errors", target} },
+	parseList: func(line string) string {
+		// "2026-10-17 17:45:00       1234 name", or "PRE folder/"
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			return ""
+		}
+		return fields[len(fields)-1]
+	},
+}
+
+// commandTarget keeps backups in remote storage reached through a tool
+type commandTarget struct {
+	location string
+	tool     *tool
+}
+
+func (c *commandTarget) Put(name, path string) error {
+	return c.run(c.tool.copy(path, c.location+name)...)
+}
+
+func (c *commandTarget) Get(name, path string) error {
+	return c.run(c.tool.copy(c.location+name, path)...)
+}
+
+func (c *commandTarget) List() ([]string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(c.tool.name, c.tool.list(c.location)...)
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w", c.tool.name, err)
+	}
+
+	var names []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if name := c.tool.parseList(strings.TrimSpace(line)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (c *commandTarget) Delete(name string) error {
+	return c.run(c.tool.delete(c.location + name)...)
+}
+
+func (c *commandTarget) String() string {
+	return c.location
+}
+
+// run runs the tool, passing its errors through
+func (c *commandTarget) run(args ...string) error {
+	cmd := exec.Command(c.tool.name, args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if execErr, ok := err.(*exec.Error); ok {
+			return fmt.Errorf("%s is required for %s: %w", c.tool.name, c.location, execErr.Err)
+		}
+		return fmt.Errorf("%s failed: %w", c.tool.name, err)
+	}
+	return nil
+}
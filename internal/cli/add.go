@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// Add creates a note from its arguments, or from standard input when none
+// are given, so command output can be piped into a note
+func Add(args []string) error {
+	flags, dir := newFlagSet("add", "[--title title] [text ...]")
+	title := flags.String("title", "", "note title")
+	flags.StringVar(title, "t", "", "note title (shorthand)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	content := strings.Join(flags.Args(), " ")
+	if content == "" {
+		if stdinIsTerminal() {
+			fmt.Fprintln(os.Stderr, "Type the note, then press Ctrl+D:")
+		}
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		content = string(data)
+	}
+	content = strings.TrimRight(content, "\n")
+	if strings.TrimSpace(content) == "" {
+		return errors.New("the note is empty")
+	}
+
+	p, err := openProject(*dir)
+	if err != nil {
+		return err
+	}
+	defer p.close()
+
+	if p.client != nil {
+		err = p.client.do(http.MethodPost, "api/v1/notes", models.NoteRequest{Title: *title, Content: content}, nil)
+	} else {
+		p.changed = true
+		err = p.noteManager.AddNote(context.Background(), *title, content, "")
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Added note to %s\n", p.folder)
+	return nil
+}
+
+// stdinIsTerminal reports whether standard input is typed rather than piped
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
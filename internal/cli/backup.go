@@ -0,0 +1,267 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/backup"
+)
+
+// passphraseEnv holds the passphrase for encrypted backups
+const passphraseEnv = "NOTEFLOW_BACKUP_PASSPHRASE"
+
+// Backup creates, lists and restores backups of a notes folder
+func Backup(args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, `Usage: noteflow-go backup <command> [flags]
+
+  create   back up notes.md and assets (see backup create -h)
+  list     list the backups in the target
+  restore  restore a backup, or "latest", into the folder
+
+Targets are a directory, an rclone "remote:path" or an s3:// URL (using the
+aws command). Encrypted backups use the passphrase in %s.
+`, passphraseEnv)
+	}
+	if len(args) == 0 {
+		usage()
+		return errors.New("no backup command given")
+	}
+
+	switch args[0] {
+	case "create":
+		return backupCreate(args[1:])
+	case "list":
+		return backupList(args[1:])
+	case "restore":
+		return backupRestore(args[1:])
+	default:
+		usage()
+		return fmt.Errorf("unknown backup command %q", args[0])
+	}
+}
+
+// backupFlags adds the flags shared by the backup commands
+func backupFlags(name, usage string) (*flagSet, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	flags, dir := newFlagSet("backup "+name, usage)
+	target := config.Backup.Target
+	if target == "" {
+		target = filepath.Join(filepath.Dir(ConfigPath), "backups")
+	}
+	fs := &flagSet{FlagSet: flags, dir: dir}
+	flags.StringVar(&fs.target, "target", target, "directory, rclone remote:path or s3:// URL holding the backups")
+	flags.StringVar(&fs.passphraseFile, "passphrase-file", "", "read the passphrase from this file instead of $"+passphraseEnv)
+	fs.encrypt = config.Backup.Encrypt
+	fs.keep = config.Backup.Keep
+	return fs, nil
+}
+
+// flagSet holds the parsed flags of a backup command
+type flagSet struct {
+	*flag.FlagSet
+	dir            *string
+	target         string
+	passphraseFile string
+	encrypt        bool
+	keep           int
+}
+
+// passphrase returns the passphrase for encrypted backups, or ""
+func (f *flagSet) passphrase() (string, error) {
+	if f.passphraseFile != "" {
+		data, err := os.ReadFile(f.passphraseFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return os.Getenv(passphraseEnv), nil
+}
+
+// folder returns the absolute notes folder given with --dir
+func (f *flagSet) folder() (string, error) {
+	folder := *f.dir
+	if folder == "" {
+		folder = "."
+	}
+	return filepath.Abs(folder)
+}
+
+// backupCreate backs up the folder, then prunes old backups with --keep
+func backupCreate(args []string) error {
+	flags, err := backupFlags("create", "[--target target] [--encrypt] [--keep n] [--quiet]")
+	if err != nil {
+		return err
+	}
+	flags.BoolVar(&flags.encrypt, "encrypt", flags.encrypt, "encrypt the backup with the passphrase")
+	flags.IntVar(&flags.keep, "keep", flags.keep, "keep only this many backups of the folder (0 keeps all)")
+	quiet := flags.Bool("quiet", false, "print nothing unless there is an error (for cron)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	folder, err := flags.folder()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(folder, "notes.md")); err != nil {
+		return fmt.Errorf("%s has no notes.md", folder)
+	}
+
+	passphrase := ""
+	if flags.encrypt {
+		if passphrase, err = flags.passphrase(); err != nil {
+			return err
+		}
+		if passphrase == "" {
+			return fmt.Errorf("encrypting needs a passphrase in $%s or --passphrase-file", passphraseEnv)
+		}
+	}
+
+	target, err := backup.OpenTarget(flags.target)
+	if err != nil {
+		return err
+	}
+	info, err := backup.Create(folder, target, passphrase)
+	if err != nil {
+		return err
+	}
+	if !*quiet {
+		fmt.Printf("Created %s in %s\n", info.Name, target)
+	}
+
+	if flags.keep > 0 {
+		deleted, err := backup.Prune(target, info.Project, flags.keep)
+		if err != nil {
+			return fmt.Errorf("failed to remove old backups: %w", err)
+		}
+		for _, name := range deleted {
+			if !*quiet {
+				fmt.Printf("Removed %s\n", name)
+			}
+		}
+	}
+	return nil
+}
+
+// backupList prints the backups in the target
+func backupList(args []string) error {
+	flags, err := backupFlags("list", "[--target target] [--json]")
+	if err != nil {
+		return err
+	}
+	asJSON := flags.Bool("json", false, "print the backups as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	target, err := backup.OpenTarget(flags.target)
+	if err != nil {
+		return err
+	}
+	backups, err := backup.List(target)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if backups == nil {
+			backups = []backup.Info{}
+		}
+		return printJSON(backups)
+	}
+	for _, b := range backups {
+		lock := ""
+		if b.Encrypted {
+			lock = "  (encrypted)"
+		}
+		fmt.Printf("%s  %s%s\n", b.Time.Format("2006-01-02 15:04:05"), b.Name, lock)
+	}
+	return nil
+}
+
+// backupRestore restores a backup into the folder after backing up its
+// current state
+func backupRestore(args []string) error {
+	flags, err := backupFlags("restore", "[--target target] <name | latest>")
+	if err != nil {
+		return err
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("name the backup to restore, or latest")
+	}
+
+	folder, err := flags.folder()
+	if err != nil {
+		return err
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if findServer(config, folder) != nil {
+		return fmt.Errorf("a NoteFlow server is serving %s; stop it before restoring", folder)
+	}
+
+	target, err := backup.OpenTarget(flags.target)
+	if err != nil {
+		return err
+	}
+
+	name := flags.Arg(0)
+	if name == "latest" {
+		backups, err := backup.List(target)
+		if err != nil {
+			return err
+		}
+		name = ""
+		for _, b := range backups {
+			if b.Project == filepath.Base(folder) {
+				name = b.Name
+			}
+		}
+		if name == "" {
+			return fmt.Errorf("no backups of %s in %s", filepath.Base(folder), target)
+		}
+	}
+
+	passphrase, err := flags.passphrase()
+	if err != nil {
+		return err
+	}
+
+	// Keep the current notes in case the wrong backup is restored
+	if _, err := os.Stat(filepath.Join(folder, "notes.md")); err == nil {
+		current, err := backup.Create(folder, target, passphrase)
+		if err != nil {
+			return fmt.Errorf("failed to back up the current notes first: %w", err)
+		}
+		fmt.Printf("Saved the current notes as %s\n", current.Name)
+	}
+
+	if err := backup.Restore(target, name, folder, passphrase); err != nil {
+		return err
+	}
+	fmt.Printf("Restored %s into %s\n", name, folder)
+
+	// Bring the global task registry up to date with the restored notes
+	p, err := openProject(folder)
+	if err != nil {
+		return err
+	}
+	p.changed = true
+	return p.close()
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// Capture sends a quick note to the running server's own folder, from any
+// directory, for use from hotkey tools and scripts. With --dir it behaves
+// like add.
+func Capture(args []string) error {
+	flags, dir := newFlagSet("capture", "[--title title] [text ...]")
+	title := flags.String("title", "", "note title")
+	flags.StringVar(title, "t", "", "note title (shorthand)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *dir != "" {
+		return Add(args)
+	}
+
+	content := strings.Join(flags.Args(), " ")
+	if content == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		content = string(data)
+	}
+	if strings.TrimSpace(content) == "" {
+		return errors.New("the note is empty")
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	client := newClient(config)
+	err = client.do(http.MethodPost, "api/v1/capture", models.NoteRequest{Title: *title, Content: content}, nil)
+	if err != nil {
+		return fmt.Errorf("no NoteFlow server reachable at %s (%w); start one or use add", strings.TrimSuffix(client.base, "/"), err)
+	}
+
+	fmt.Fprintln(os.Stderr, "Captured")
+	return nil
+}
@@ -0,0 +1,145 @@
+// Package cli implements the subcommands that work with notes from the
+// terminal. Commands go through the API of a server serving the folder when
+// one is running, so its pages and live updates stay current, and open the
+// folder directly otherwise.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+)
+
+// Command runs a subcommand with the arguments following its name
+type Command func(args []string) error
+
+// Commands maps subcommand names to their implementations
+var Commands = map[string]Command{
+	"add":         Add,
+	"backup":      Backup,
+	"capture":     Capture,
+	"config":      ConfigCommand,
+	"list":        List,
+	"search":      Search,
+	"stats":       Stats,
+	"tasks":       Tasks,
+	"doctor":      Doctor,
+	"done":        Done,
+	"edit":        Edit,
+	"export-site": ExportSite,
+}
+
+// ConfigPath is the configuration file commands read server and archive
+// settings from. It is set by the main package.
+var ConfigPath string
+
+// project is the notes folder a command works on
+type project struct {
+	folder string
+	config *models.Config
+
+	// client talks to the server serving folder, or is nil when none is
+	// running and notes are read and written directly
+	client *Client
+
+	noteManager *services.NoteManager
+	// changed is set when the folder was changed directly, so the global
+	// task registry is brought up to date
+	changed bool
+}
+
+// newFlagSet returns the flag set for a subcommand, with the --dir flag every
+// command accepts
+func newFlagSet(name, usage string) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: noteflow-go %s %s\n", name, usage)
+		flags.PrintDefaults()
+	}
+	dir := flags.String("dir", "", "notes folder (default the current directory)")
+	return flags, dir
+}
+
+// openProject finds a running server for folder, or opens it directly
+func openProject(folder string) (*project, error) {
+	folder, err := resolveFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	models.SetTimeConfig(config.Time)
+	p := &project{folder: folder, config: config}
+	p.client = findServer(config, folder)
+	if p.client == nil {
+		// Keep the note manager's progress messages out of command output
+		log.SetOutput(io.Discard)
+		if p.noteManager, err = services.NewNoteManager(folder, services.NewConfigStore(config, "")); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// close saves changes made directly to the folder and records its tasks in
+// the global task registry. A running server does both itself.
+func (p *project) close() error {
+	if p.noteManager == nil {
+		return nil
+	}
+	if err := p.noteManager.Close(); err != nil {
+		return err
+	}
+	if !p.changed {
+		return nil
+	}
+	p.changed = false
+
+	registry, err := services.NewTaskRegistryService()
+	if err != nil {
+		return err
+	}
+	defer registry.Close()
+	return registry.RegisterFolder(p.folder, p.noteManager)
+}
+
+// resolveFolder returns the absolute path of the notes folder given with
+// --dir, or of the current directory
+func resolveFolder(folder string) (string, error) {
+	if folder == "" {
+		var err error
+		if folder, err = os.Getwd(); err != nil {
+			return "", err
+		}
+	}
+	folder, err := filepath.Abs(folder)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(folder); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", folder)
+	}
+	return folder, nil
+}
+
+// loadConfig reads the configuration file without creating it
+func loadConfig() (*models.Config, error) {
+	if _, err := os.Stat(ConfigPath); os.IsNotExist(err) {
+		return models.DefaultConfig(), nil
+	}
+	config, err := models.LoadConfig(ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ConfigPath, err)
+	}
+	return config, nil
+}
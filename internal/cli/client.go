@@ -0,0 +1,180 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// probeTimeout bounds the check for a running server, so commands stay quick
+// when none is running
+const probeTimeout = time.Second
+
+// Client calls the API of a running server for one project
+type Client struct {
+	// base is the project's URL, such as "http://127.0.0.1:8000/p/notes/"
+	base string
+	http *http.Client
+
+	token    string
+	password string
+}
+
+// newClient returns a client for the root of the server configured in
+// config. NOTEFLOW_URL and NOTEFLOW_TOKEN override the address and
+// credentials from the config file.
+func newClient(config *models.Config) *Client {
+	root := os.Getenv("NOTEFLOW_URL")
+	if root == "" {
+		root = serverURL(&config.Server)
+	}
+	root = strings.TrimSuffix(root, "/")
+
+	client := &Client{
+		base: root + "/",
+		http: &http.Client{Timeout: probeTimeout},
+	}
+	if u, err := url.Parse(root); err == nil && isLoopback(u.Hostname()) {
+		// Our own server on this machine; its certificate is usually issued
+		// for a public name rather than localhost
+		client.http.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+
+	client.token = os.Getenv("NOTEFLOW_TOKEN")
+	if client.token == "" && len(config.Auth.Tokens) > 0 {
+		client.token = config.Auth.Tokens[0]
+	}
+	if !strings.HasPrefix(config.Auth.Password, "sha256:") {
+		client.password = config.Auth.Password
+	}
+	return client
+}
+
+// findServer returns a client for the server serving folder, or nil when no
+// server is reachable or it does not serve that folder
+func findServer(config *models.Config, folder string) *Client {
+	client := newClient(config)
+	root := strings.TrimSuffix(client.base, "/")
+
+	var projects []models.Project
+	if err := client.do(http.MethodGet, "api/v1/projects", nil, &projects); err != nil {
+		return nil
+	}
+	for _, project := range projects {
+		if sameFolder(project.Folder, folder) {
+			client.base = root + strings.TrimPrefix(project.URL, rootPath(root))
+			client.http.Timeout = 0
+			return client
+		}
+	}
+	return nil
+}
+
+// serverURL returns the local URL of the server configured in config
+func serverURL(config *models.ServerConfig) string {
+	host, port := "127.0.0.1", 8000
+	scheme := "http"
+	if config.TLS.Enabled() {
+		scheme = "https"
+		if config.TLS.Autocert() {
+			port = 443
+		}
+	}
+	if config.Host != "" && config.Host != "*" && config.Host != "0.0.0.0" && config.Host != "::" {
+		host = config.Host
+	}
+	if config.Port != 0 {
+		port = config.Port
+	}
+
+	basePath := strings.Trim(config.BasePath, "/ ")
+	if basePath != "" {
+		basePath = "/" + basePath
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port)) + basePath
+}
+
+// rootPath returns the path part of the server URL, such as "/noteflow"
+func rootPath(root string) string {
+	u, err := url.Parse(root)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// isLoopback reports whether host names the local machine
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// sameFolder reports whether two folder paths refer to the same location
+func sameFolder(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// do sends a request to path, relative to the project's URL, with body
+// encoded as JSON, and decodes a JSON response into out when it is not nil
+func (c *Client) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.base+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case c.password != "":
+		req.SetBasicAuth("noteflow", c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var apiError models.APIResponse
+		if json.NewDecoder(resp.Body).Decode(&apiError) == nil && apiError.Message != "" {
+			return fmt.Errorf("server: %s", apiError.Message)
+		}
+		return fmt.Errorf("server: %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,282 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/themes"
+)
+
+// ConfigCommand reads and changes settings in the configuration file.
+// Settings are named by their JSON path, such as "server.port".
+func ConfigCommand(args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, `Usage: noteflow-go config <command>
+
+  list               print every setting in the configuration file
+  get <key>          print one setting, such as server.port
+  set <key> <value>  change a setting; JSON values such as 8080, true or
+                     ["a","b"] are parsed, anything else is a string
+  unset <key>        remove a setting, restoring its default
+  path               print the location of the configuration file
+`)
+	}
+	if len(args) == 0 {
+		usage()
+		return errors.New("no config command given")
+	}
+
+	command, args := args[0], args[1:]
+	wantArgs := map[string]int{"list": 0, "path": 0, "get": 1, "unset": 1, "set": 2}
+	if n, ok := wantArgs[command]; !ok || len(args) != n {
+		usage()
+		if !ok {
+			return fmt.Errorf("unknown config command %q", command)
+		}
+		return fmt.Errorf("config %s takes %d argument(s)", command, n)
+	}
+
+	if command == "path" {
+		fmt.Println(ConfigPath)
+		return nil
+	}
+
+	settings, err := readSettings()
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "list":
+		lines := flattenSettings("", settings, nil)
+		sort.Strings(lines)
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+		return nil
+
+	case "get":
+		value, ok := lookupSetting(settings, splitKey(args[0]))
+		if !ok {
+			return fmt.Errorf("%s is not set", args[0])
+		}
+		if s, ok := value.(string); ok {
+			fmt.Println(s)
+			return nil
+		}
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+
+	case "set":
+		if err := assignSetting(settings, splitKey(args[0]), parseSettingValue(args[1])); err != nil {
+			return err
+		}
+
+	case "unset":
+		if err := removeSetting(settings, splitKey(args[0])); err != nil {
+			return err
+		}
+	}
+
+	if err := writeSettings(settings); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Saved; restart a running server to apply the change")
+	return nil
+}
+
+// readSettings returns the configuration file as generic JSON values,
+// starting from the defaults when it does not exist yet
+func readSettings() (map[string]interface{}, error) {
+	data, err := os.ReadFile(ConfigPath)
+	if os.IsNotExist(err) {
+		data, err = json.Marshal(models.DefaultConfig())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var settings map[string]interface{}
+	if err := decoder.Decode(&settings); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ConfigPath, err)
+	}
+	return settings, nil
+}
+
+// writeSettings validates settings and saves them to the configuration file.
+// Unknown keys and values of the wrong type are rejected.
+func writeSettings(settings map[string]interface{}) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	config, err := decodeSettings(data)
+	if err != nil {
+		return err
+	}
+	return models.SaveConfig(config, ConfigPath)
+}
+
+// decodeSettings parses a configuration file strictly and checks that the
+// server could use it
+func decodeSettings(data []byte) (*models.Config, error) {
+	config, err := models.DecodeConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := themes.CheckConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// splitKey splits a dotted setting name into its parts
+func splitKey(key string) []string {
+	return strings.Split(strings.Trim(key, "."), ".")
+}
+
+// parseSettingValue parses value as JSON, or keeps it as a string
+func parseSettingValue(value string) interface{} {
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	var parsed interface{}
+	if err := decoder.Decode(&parsed); err != nil || decoder.More() {
+		return value
+	}
+	return parsed
+}
+
+// flattenSettings appends "key = value" lines for every leaf setting under prefix
+func flattenSettings(prefix string, value interface{}, lines []string) []string {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			lines = flattenSettings(join(key), child, lines)
+		}
+	case []interface{}:
+		for i, child := range v {
+			lines = flattenSettings(join(strconv.Itoa(i)), child, lines)
+		}
+	default:
+		data, _ := json.Marshal(v)
+		lines = append(lines, prefix+" = "+string(data))
+	}
+	return lines
+}
+
+// lookupSetting returns the value at path
+func lookupSetting(value interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			child, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			value = child
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// assignSetting sets the value at path, creating objects along the way
+func assignSetting(settings map[string]interface{}, path []string, value interface{}) error {
+	parent, err := settingParent(settings, path, true)
+	if err != nil {
+		return err
+	}
+
+	key := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[key] = value
+	case []interface{}:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(p) {
+			return fmt.Errorf("%s: no item %s", strings.Join(path[:len(path)-1], "."), key)
+		}
+		p[i] = value
+	}
+	return nil
+}
+
+// removeSetting deletes the value at path
+func removeSetting(settings map[string]interface{}, path []string) error {
+	parent, err := settingParent(settings, path, false)
+	if err != nil {
+		return err
+	}
+
+	key := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := p[key]; !ok {
+			return fmt.Errorf("%s is not set", strings.Join(path, "."))
+		}
+		delete(p, key)
+	case []interface{}:
+		return errors.New("cannot unset a single list item; set the whole list instead")
+	}
+	return nil
+}
+
+// settingParent returns the object or list holding the last element of path,
+// optionally creating missing objects
+func settingParent(settings map[string]interface{}, path []string, create bool) (interface{}, error) {
+	var parent interface{} = settings
+	for i, key := range path[:len(path)-1] {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			child, ok := p[key]
+			if !ok || child == nil {
+				if !create {
+					return nil, fmt.Errorf("%s is not set", strings.Join(path[:i+1], "."))
+				}
+				child = make(map[string]interface{})
+				p[key] = child
+			}
+			parent = child
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(p) {
+				return nil, fmt.Errorf("%s: no item %s", strings.Join(path[:i], "."), key)
+			}
+			parent = p[index]
+		}
+
+		switch parent.(type) {
+		case map[string]interface{}, []interface{}:
+		default:
+			return nil, fmt.Errorf("%s is not an object", strings.Join(path[:i+1], "."))
+		}
+	}
+	return parent, nil
+}
@@ -0,0 +1,511 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// assetLinkPattern matches links to uploaded files and archived pages in
+// notes, with or without a leading slash or base path, in the shared folders
+// or a note's own. Links with a scheme point at other sites and are skipped.
+var assetLinkPattern = regexp.MustCompile(`([^\s()<>"'\[\]]*)assets/((?:` + models.NoteFolderPattern + `/)?(?:images|audio|video|files|sites))/([^\s()<>"'\[\]]+)`)
+
+// noteFolderPattern matches the names of notes' own asset folders
+var noteFolderPattern = regexp.MustCompile(`^` + models.NoteFolderPattern + `$`)
+
+// noteHeaderPattern matches a note heading with a valid timestamp
+var noteHeaderPattern = regexp.MustCompile(`^## \d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?: - |$)`)
+
+// orphanedDir is where --fix moves uploads no note links to
+const orphanedDir = "orphaned"
+
+// finding is a problem found by doctor
+type finding struct {
+	check   string
+	problem string
+	// advice tells the user what to do, or what fix does when it is set
+	advice string
+	fix    func() error
+}
+
+// Doctor checks the folder and configuration for problems and, with --fix,
+// repairs those it can
+func Doctor(args []string) error {
+	flags, dir := newFlagSet("doctor", "[--fix]")
+	fix := flags.Bool("fix", false, "repair the problems that can be repaired automatically")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	folder, err := resolveFolder(*dir)
+	if err != nil {
+		return err
+	}
+
+	config, findings := checkConfig()
+	server := findServer(config, folder)
+	findings = append(findings, checkNotes(folder, server != nil)...)
+	findings = append(findings, checkPort(config, findings)...)
+
+	fmt.Printf("Checked %s and %s\n", folder, ConfigPath)
+	if len(findings) == 0 {
+		fmt.Println("No problems found")
+		return nil
+	}
+
+	remaining, fixable := 0, 0
+	for _, f := range findings {
+		fmt.Printf("\n[%s] %s\n", f.check, f.problem)
+		switch {
+		case f.fix == nil:
+			fmt.Printf("  %s\n", f.advice)
+			remaining++
+		case !*fix:
+			fmt.Printf("  --fix will %s\n", f.advice)
+			remaining++
+			fixable++
+		default:
+			if err := f.fix(); err != nil {
+				fmt.Printf("  Could not %s: %v\n", f.advice, err)
+				remaining++
+			} else {
+				fmt.Printf("  Fixed: %s\n", f.advice)
+			}
+		}
+	}
+
+	fmt.Println()
+	if remaining == 0 {
+		fmt.Println("All problems fixed")
+		return nil
+	}
+	if fixable > 0 {
+		fmt.Printf("Run noteflow-go doctor --fix to repair %d of them\n", fixable)
+	}
+	return fmt.Errorf("%d problem(s) found", remaining)
+}
+
+// checkConfig checks that the configuration file can be read and used. It
+// returns the configuration to check the rest with, the defaults when the
+// file is unusable.
+func checkConfig() (*models.Config, []finding) {
+	data, err := os.ReadFile(ConfigPath)
+	if os.IsNotExist(err) {
+		return models.DefaultConfig(), nil
+	}
+	if err != nil {
+		return models.DefaultConfig(), []finding{{
+			check:   "config",
+			problem: err.Error(),
+			advice:  "Check the file's permissions",
+		}}
+	}
+
+	config, err := decodeSettings(data)
+	if err != nil {
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			return models.DefaultConfig(), []finding{{
+				check:   "config",
+				problem: fmt.Sprintf("%s is not valid JSON: %v", ConfigPath, err),
+				advice:  "Edit the file to correct it; NoteFlow will not start until it is valid",
+			}}
+		}
+		if lenient, lenientErr := models.LoadConfig(ConfigPath); lenientErr == nil {
+			config = lenient
+		} else {
+			config = models.DefaultConfig()
+		}
+		return config, []finding{{
+			check:   "config",
+			problem: err.Error(),
+			advice:  "Correct the setting with noteflow-go config set, or remove it with noteflow-go config unset",
+		}}
+	}
+
+	var findings []finding
+	for _, file := range []struct{ key, path string }{
+		{"server.tls.cert_file", config.Server.TLS.CertFile},
+		{"server.tls.key_file", config.Server.TLS.KeyFile},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			findings = append(findings, finding{
+				check:   "config",
+				problem: fmt.Sprintf("%s: %v", file.key, err),
+				advice:  "Point the setting at an existing file with noteflow-go config set " + file.key + " <path>",
+			})
+		}
+	}
+	return config, findings
+}
+
+// checkNotes checks that notes.md parses cleanly and that the files notes
+// link to exist. Changes to notes.md are left to the user while a server is
+// running for the folder, since the server would overwrite them.
+func checkNotes(folder string, serving bool) []finding {
+	notesPath := filepath.Join(folder, "notes.md")
+	data, err := os.ReadFile(notesPath)
+	if os.IsNotExist(err) {
+		return []finding{{
+			check:   "notes",
+			problem: folder + " has no notes.md",
+			advice:  "Run doctor in a NoteFlow folder, or start NoteFlow here to create one",
+		}}
+	}
+	if err != nil {
+		return []finding{{check: "notes", problem: err.Error(), advice: "Check the file's permissions"}}
+	}
+
+	var findings []finding
+	content := string(data)
+
+	repair := func() error { return repairNotes(notesPath) }
+	if serving {
+		repair = nil
+	}
+	repairAdvice := func(advice string) string {
+		if serving {
+			return "Stop the NoteFlow server for this folder and run doctor --fix to " + advice
+		}
+		return advice
+	}
+
+	if !utf8.ValidString(content) {
+		findings = append(findings, finding{
+			check:   "notes",
+			problem: "notes.md is not valid UTF-8; some characters will show as �",
+			advice:  "Convert the file to UTF-8 with a text editor",
+		})
+	}
+	if strings.Contains(content, "\r\n") {
+		findings = append(findings, finding{
+			check:   "notes",
+			problem: "notes.md has Windows line endings, so notes may run together",
+			advice:  repairAdvice("convert the line endings"),
+			fix:     repair,
+		})
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+	}
+
+	index := 0
+	var links []noteLink
+	for _, section := range splitNotes(content) {
+		text := strings.TrimSpace(section.text)
+		switch {
+		case text == "":
+			continue
+		case !strings.HasPrefix(text, "## "):
+			findings = append(findings, finding{
+				check:   "notes",
+				problem: fmt.Sprintf("text at line %d is outside any note and will be lost when NoteFlow saves", section.line),
+				advice:  repairAdvice("add a heading so it becomes a note"),
+				fix:     repair,
+			})
+			continue
+		case !noteHeaderPattern.MatchString(text):
+			findings = append(findings, finding{
+				check:   "notes",
+				problem: fmt.Sprintf("note at line %d has no valid timestamp, so it gets a new one each time it is loaded", section.line),
+				advice:  repairAdvice("date it with the time notes.md was last changed"),
+				fix:     repair,
+			})
+		}
+
+		links = append(links, assetLinks(text, index, false)...)
+		index++
+	}
+
+	// Uploads of deleted notes stay until the trash is purged
+	if data, err := os.ReadFile(filepath.Join(folder, "assets", ".trash.json")); err == nil {
+		var trashed []models.TrashedNote
+		if err := json.Unmarshal(data, &trashed); err != nil {
+			findings = append(findings, finding{
+				check:   "notes",
+				problem: "assets/.trash.json cannot be read, so deleted notes cannot be restored: " + err.Error(),
+				advice:  "Restore the file from a backup",
+			})
+		}
+		for _, entry := range trashed {
+			links = append(links, assetLinks(entry.Text, -1, true)...)
+		}
+	}
+
+	return append(findings, checkAssets(folder, links)...)
+}
+
+// noteLink is a link from a note to a file under assets
+type noteLink struct {
+	index   int
+	title   string
+	dir     string
+	name    string
+	trashed bool
+}
+
+// assetLinks returns the links to files under assets in the text of the
+// note at index, or of a note in the trash
+func assetLinks(text string, index int, trashed bool) []noteLink {
+	title := "(untitled)"
+	if note, err := models.NewNoteFromText(text); err == nil && note.Title != "" {
+		title = note.Title
+	}
+	var links []noteLink
+	for _, match := range assetLinkPattern.FindAllStringSubmatch(text, -1) {
+		if strings.Contains(match[1], "://") {
+			continue
+		}
+		name, err := url.PathUnescape(match[3])
+		if err != nil {
+			name = match[3]
+		}
+		links = append(links, noteLink{index: index, title: title, dir: match[2], name: name, trashed: trashed})
+	}
+	return links
+}
+
+// checkAssets reports links to missing files, uploads no note links to and
+// archive metadata left behind by deleted archives. Links from notes in the
+// trash keep their uploads but are not checked themselves.
+func checkAssets(folder string, links []noteLink) []finding {
+	var findings []finding
+	linked := make(map[string]bool)
+	for _, link := range links {
+		path := filepath.Join(folder, "assets", filepath.FromSlash(link.dir), filepath.FromSlash(link.name))
+		linked[path] = true
+		if link.trashed {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+
+		advice := "Upload the file again and update the link"
+		if link.dir == "sites" {
+			advice = "Archive the page again by adding its address with a leading +, and remove the old link"
+		}
+		findings = append(findings, finding{
+			check:   "assets",
+			problem: fmt.Sprintf("note [%d] %q links to missing assets/%s/%s", link.index, link.title, link.dir, link.name),
+			advice:  advice,
+		})
+	}
+
+	dirs := []string{"images", "audio", "video", "files"}
+	if entries, err := os.ReadDir(filepath.Join(folder, "assets")); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() && noteFolderPattern.MatchString(entry.Name()) {
+				for _, dir := range []string{"images", "audio", "video", "files"} {
+					dirs = append(dirs, entry.Name()+"/"+dir)
+				}
+			}
+		}
+	}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(filepath.Join(folder, "assets", filepath.FromSlash(dir)))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(folder, "assets", filepath.FromSlash(dir), entry.Name())
+			if linked[path] {
+				continue
+			}
+			// Originals kept of converted HEIC images go with the JPEG
+			if ext := strings.ToLower(filepath.Ext(path)); ext == ".heic" || ext == ".heif" {
+				if linked[strings.TrimSuffix(path, filepath.Ext(path))+".jpg"] {
+					continue
+				}
+			}
+			target := filepath.Join(folder, "assets", orphanedDir, filepath.FromSlash(dir), entry.Name())
+			findings = append(findings, finding{
+				check:   "assets",
+				problem: fmt.Sprintf("assets/%s/%s is not linked from any note", dir, entry.Name()),
+				advice:  fmt.Sprintf("move it to assets/%s/%s", orphanedDir, dir),
+				fix: func() error {
+					if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+						return err
+					}
+					return os.Rename(path, target)
+				},
+			})
+		}
+	}
+
+	sitesDir := filepath.Join(folder, "assets", "sites")
+	entries, _ := os.ReadDir(sitesDir)
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".json" && ext != ".tags" && ext != ".jpg") {
+			continue
+		}
+		page := strings.TrimSuffix(entry.Name(), ext) + ".html"
+		if _, err := os.Stat(filepath.Join(sitesDir, page)); err == nil {
+			continue
+		}
+		path := filepath.Join(sitesDir, entry.Name())
+		findings = append(findings, finding{
+			check:   "assets",
+			problem: fmt.Sprintf("assets/sites/%s describes an archive that no longer exists", entry.Name()),
+			advice:  "delete it",
+			fix:     func() error { return os.Remove(path) },
+		})
+	}
+	return findings
+}
+
+// noteSection is the text between two note separators in notes.md
+type noteSection struct {
+	text string
+	line int
+}
+
+// splitNotes splits notes.md into sections as the storage does, recording the
+// line each starts on
+func splitNotes(content string) []noteSection {
+	var sections []noteSection
+	line := 1
+	for _, text := range strings.Split(content, models.NoteSeparator) {
+		trimmed := strings.TrimLeft(text, " \t\n")
+		start := line + strings.Count(text[:len(text)-len(trimmed)], "\n")
+		sections = append(sections, noteSection{text: text, line: start})
+		line += strings.Count(text, "\n") + strings.Count(models.NoteSeparator, "\n")
+	}
+	return sections
+}
+
+// repairNotes rewrites notes.md with Unix line endings, a heading for text
+// outside any note and a timestamp for notes without one. The original is
+// kept as notes.md.bak.
+func repairNotes(notesPath string) error {
+	data, err := os.ReadFile(notesPath)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(notesPath)
+	if err != nil {
+		return err
+	}
+	timestamp := info.ModTime().Format("2006-01-02 15:04:05")
+
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	sections := splitNotes(content)
+	parts := make([]string, len(sections))
+	for i, section := range sections {
+		text := section.text
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "":
+		case !strings.HasPrefix(trimmed, "## "):
+			text = "## " + timestamp + " - Recovered text\n\n" + trimmed + "\n"
+		case !noteHeaderPattern.MatchString(trimmed):
+			header, rest, _ := strings.Cut(trimmed, "\n")
+			title := strings.TrimSpace(strings.TrimPrefix(header, "## "))
+			header = "## " + timestamp
+			if title != "" {
+				header += " - " + title
+			}
+			text = header + "\n" + rest + "\n"
+		}
+		parts[i] = text
+	}
+	repaired := strings.Join(parts, models.NoteSeparator)
+	if repaired == string(data) {
+		return nil
+	}
+
+	if err := os.WriteFile(notesPath+".bak", data, 0644); err != nil {
+		return err
+	}
+	tmp := notesPath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(repaired), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, notesPath)
+}
+
+// checkPort checks that the configured port is free or used by NoteFlow
+// itself. It offers to move to a free port only when the configuration file
+// had no problems, so fixing it does not rewrite a broken file.
+func checkPort(config *models.Config, findings []finding) []finding {
+	host, port := "127.0.0.1", 8000
+	if config.Server.TLS.Autocert() {
+		host, port = "", 443
+	}
+	if config.Server.Host != "" {
+		host = config.Server.Host
+	}
+	if host == "*" {
+		host = ""
+	}
+	if config.Server.Port != 0 {
+		port = config.Server.Port
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	if listener, err := net.Listen("tcp", addr); err == nil {
+		listener.Close()
+		return nil
+	}
+
+	// A running NoteFlow server is not a conflict
+	var projects []models.Project
+	if newClient(config).do(http.MethodGet, "api/v1/projects", nil, &projects) == nil {
+		return nil
+	}
+
+	result := finding{
+		check:   "port",
+		problem: fmt.Sprintf("%s is in use by another program, so NoteFlow cannot start", addr),
+		advice:  "Stop that program, or choose another port with noteflow-go config set server.port <port> or --port",
+	}
+	configOK := true
+	for _, f := range findings {
+		if f.check == "config" {
+			configOK = false
+		}
+	}
+	if free := freePort(host, port); free != 0 && configOK {
+		result.advice = fmt.Sprintf("set server.port to %d, which is free", free)
+		result.fix = func() error {
+			settings, err := readSettings()
+			if err != nil {
+				return err
+			}
+			if err := assignSetting(settings, []string{"server", "port"}, free); err != nil {
+				return err
+			}
+			return writeSettings(settings)
+		}
+	}
+	return []finding{result}
+}
+
+// freePort returns the first free port after port on host, or 0
+func freePort(host string, port int) int {
+	for candidate := port + 1; candidate <= port+100 && candidate <= 65535; candidate++ {
+		listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(candidate)))
+		if err == nil {
+			listener.Close()
+			return candidate
+		}
+	}
+	return 0
+}
@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// Edit opens a note in the user's editor and saves the changes, unless the
+// note was changed elsewhere in the meantime
+func Edit(args []string) error {
+	flags, dir := newFlagSet("edit", "<index|title>")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ref := strings.TrimSpace(strings.Join(flags.Args(), " "))
+	if ref == "" {
+		flags.Usage()
+		return errors.New("no note given")
+	}
+
+	original, err := loadNote(*dir, ref)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp("", "noteflow-*.md")
+	if err != nil {
+		return err
+	}
+	path := file.Name()
+	text := formatEditedNote(original.Title, original.Content)
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	if err := runEditor(path); err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if string(data) == text {
+		os.Remove(path)
+		fmt.Fprintln(os.Stderr, "No changes")
+		return nil
+	}
+	title, content := parseEditedNote(string(data))
+
+	// The note may have changed, moved or gone while the editor was open, and
+	// a server may have started; look it up afresh
+	p, err := openProject(*dir)
+	if err != nil {
+		return err
+	}
+	defer p.close()
+
+	notes, err := p.notes(models.NoteQuery{Sort: models.SortCreated, Order: models.OrderDesc})
+	if err != nil {
+		return err
+	}
+	current := -1
+	for _, note := range notes {
+		if note.Title == original.Title && note.Content == original.Content && note.Timestamp.Equal(original.Timestamp) {
+			current = note.Index
+			if note.Index == original.Index {
+				break
+			}
+		}
+	}
+	if current < 0 {
+		return fmt.Errorf("the note was changed or deleted while you were editing it; your version is in %s", path)
+	}
+
+	if err := p.updateNote(current, title, content); err != nil {
+		return fmt.Errorf("%w; your version is in %s", err, path)
+	}
+	os.Remove(path)
+	fmt.Fprintf(os.Stderr, "Saved note [%d]\n", current)
+	return nil
+}
+
+// loadNote returns the note in folder with index ref, or whose title matches
+// ref: exactly, ignoring case, or failing that as the only title containing it
+func loadNote(folder, ref string) (*listedNote, error) {
+	p, err := openProject(folder)
+	if err != nil {
+		return nil, err
+	}
+	defer p.close()
+
+	notes, err := p.notes(models.NoteQuery{Sort: models.SortCreated, Order: models.OrderDesc})
+	if err != nil {
+		return nil, err
+	}
+
+	if index, err := strconv.Atoi(ref); err == nil {
+		for i := range notes {
+			if notes[i].Index == index {
+				return &notes[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no note [%d]", index)
+	}
+
+	var exact, partial []*listedNote
+	lower := strings.ToLower(ref)
+	for i := range notes {
+		title := strings.ToLower(notes[i].Title)
+		if title == lower {
+			exact = append(exact, &notes[i])
+		} else if strings.Contains(title, lower) {
+			partial = append(partial, &notes[i])
+		}
+	}
+	matches := exact
+	if len(matches) == 0 {
+		matches = partial
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no note titled %q", ref)
+	case 1:
+		return matches[0], nil
+	}
+	var names []string
+	for _, note := range matches {
+		names = append(names, fmt.Sprintf("[%d] %s", note.Index, note.Title))
+	}
+	return nil, fmt.Errorf("%q matches several notes; give an index instead:\n  %s", ref, strings.Join(names, "\n  "))
+}
+
+// updateNote replaces the title and content of the note with index
+func (p *project) updateNote(index int, title, content string) error {
+	if p.client != nil {
+		return p.client.do(http.MethodPut, "api/v1/notes/"+strconv.Itoa(index), models.NoteRequest{Title: title, Content: content}, nil)
+	}
+	p.changed = true
+	return p.noteManager.UpdateNote(context.Background(), index, title, content, "")
+}
+
+// formatEditedNote writes a note for editing, with its title as a heading
+func formatEditedNote(title, content string) string {
+	return "# " + title + "\n\n" + content + "\n"
+}
+
+// parseEditedNote reads back a note written by formatEditedNote. Without the
+// title heading the whole text is the content.
+func parseEditedNote(text string) (string, string) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if strings.HasPrefix(text, "# ") {
+		heading, rest, _ := strings.Cut(text, "\n")
+		return strings.TrimSpace(strings.TrimPrefix(heading, "# ")), strings.TrimSpace(rest)
+	}
+	return "", strings.TrimSpace(text)
+}
+
+// runEditor opens path in $VISUAL or $EDITOR and waits for it to exit
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// The editor may include arguments, such as "code --wait"
+	words := strings.Fields(editor)
+	cmd := exec.Command(words[0], append(words[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s: %w", words[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/site"
+)
+
+// ExportSite writes the folder's notes as a static website, or as content for
+// a Hugo site
+func ExportSite(args []string) error {
+	flags, dir := newFlagSet("export-site", "[--format html|hugo] [--title title] [--tag tag] [--force] <output folder>")
+	format := flags.String("format", site.FormatHTML, "html for pages ready to publish, hugo for content and static files of a Hugo site")
+	title := flags.String("title", "", "site title in html exports (default the folder's name)")
+	tag := flags.String("tag", "", "only export the notes with this tag")
+	force := flags.Bool("force", false, "write html exports into a folder that isn't empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("give the folder to write the site to")
+	}
+	if *format != site.FormatHTML && *format != site.FormatHugo {
+		return fmt.Errorf("--format must be %s or %s", site.FormatHTML, site.FormatHugo)
+	}
+
+	folder, err := resolveFolder(*dir)
+	if err != nil {
+		return err
+	}
+	out := flags.Arg(0)
+	// Pages of notes since deleted or retitled would stay behind, so an html
+	// export starts from an empty folder. Hugo content goes into an existing
+	// site.
+	if *format == site.FormatHTML && !*force {
+		if entries, err := os.ReadDir(out); err == nil && len(entries) > 0 {
+			return fmt.Errorf("%s is not empty; empty it or use --force", out)
+		}
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	models.SetTimeConfig(config.Time)
+
+	result, err := site.Export(folder, out, site.Options{
+		Format: *format,
+		Title:  *title,
+		Tag:    strings.TrimPrefix(*tag, "#"),
+	})
+	if err != nil {
+		return err
+	}
+	for _, asset := range result.Missing {
+		fmt.Fprintf(os.Stderr, "Warning: missing upload assets/%s\n", asset)
+	}
+	fmt.Printf("Exported %d notes, %d tags and %d uploads to %s\n", result.Notes, result.Tags, result.Assets, out)
+	return nil
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// snippetLength is the number of characters of content shown for each note
+const snippetLength = 100
+
+// listedNote is a note as returned by the JSON notes listing
+type listedNote struct {
+	Index int `json:"index"`
+	models.Note
+}
+
+// List prints the notes in the folder, newest first
+func List(args []string) error {
+	return listNotes("list", "[--tag tag] [--sort created|modified|title] [--order asc|desc] [--limit n] [--json]", args, false)
+}
+
+// Search prints the notes containing every word of the query
+func Search(args []string) error {
+	return listNotes("search", "[--tag tag] [--sort created|modified|title] [--order asc|desc] [--limit n] [--json] query ...", args, true)
+}
+
+// listNotes implements list and search. Search requires a query.
+func listNotes(name, usage string, args []string, search bool) error {
+	flags, dir := newFlagSet(name, usage)
+	tag := flags.String("tag", "", "only notes with this #tag")
+	sortKey := flags.String("sort", models.SortCreated, "sort by: created, modified or title")
+	order := flags.String("order", "", "asc or desc (default desc by time, asc by title)")
+	limit := flags.Int("limit", 0, "show at most this many notes (0 for all)")
+	asJSON := flags.Bool("json", false, "print the notes as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query := models.NoteQuery{
+		Tag:   strings.TrimPrefix(*tag, "#"),
+		Sort:  *sortKey,
+		Order: *order,
+		Limit: *limit,
+	}
+	if err := query.Validate(); err != nil {
+		return errors.New("--" + err.Error())
+	}
+	if search {
+		query.Q = strings.Join(flags.Args(), " ")
+		if strings.TrimSpace(query.Q) == "" {
+			flags.Usage()
+			return errors.New("no search query given")
+		}
+	}
+
+	p, err := openProject(*dir)
+	if err != nil {
+		return err
+	}
+	defer p.close()
+
+	notes, err := p.notes(query)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return printJSON(notes)
+	}
+
+	words := strings.Fields(strings.ToLower(query.Q))
+	for _, note := range notes {
+		title := note.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+		fmt.Printf("[%d] %s  %s\n", note.Index, note.Timestamp.Format("2006-01-02 15:04"), title)
+		if snippet := snippet(note.Content, words); snippet != "" {
+			fmt.Printf("    %s\n", snippet)
+		}
+	}
+	if len(notes) == 0 && search {
+		fmt.Fprintln(os.Stderr, "No notes found")
+	}
+	return nil
+}
+
+// notes returns the notes matching query with their indices
+func (p *project) notes(query models.NoteQuery) ([]listedNote, error) {
+	var notes []listedNote
+	if p.client != nil {
+		values := url.Values{}
+		values.Set("q", query.Q)
+		values.Set("tag", query.Tag)
+		values.Set("sort", query.Sort)
+		values.Set("order", query.Order)
+		values.Set("limit", strconv.Itoa(query.Limit))
+		err := p.client.do(http.MethodGet, "api/v1/json?"+values.Encode(), nil, &notes)
+		return notes, err
+	}
+
+	data, _, err := p.noteManager.RenderNotesJSON(query)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(data), &notes)
+	return notes, err
+}
+
+// snippet returns a single line of content around the first of words found,
+// or its beginning
+func snippet(content string, words []string) string {
+	text := strings.Join(strings.Fields(content), " ")
+
+	start := 0
+	lower := strings.ToLower(text)
+	for _, word := range words {
+		if i := strings.Index(lower, word); i >= 0 {
+			start = i
+			break
+		}
+	}
+	// Show some context before the match, starting at a word boundary
+	if start > snippetLength/3 {
+		start -= snippetLength / 3
+		if space := strings.IndexByte(text[start:], ' '); space >= 0 {
+			start += space + 1
+		}
+	} else {
+		start = 0
+	}
+
+	runes := []rune(text[start:])
+	result := string(runes)
+	if len(runes) > snippetLength {
+		result = string(runes[:snippetLength]) + "…"
+	}
+	if start > 0 {
+		result = "…" + result
+	}
+	return result
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// topTagCount is the number of tags the stats report lists by default
+const topTagCount = 5
+
+// tagCount is how many notes in the period carry a tag
+type tagCount struct {
+	Tag   string `json:"tag"`
+	Notes int    `json:"notes"`
+}
+
+// statsReport summarizes the notes written in a period
+type statsReport struct {
+	Since      *time.Time `json:"since,omitempty"`
+	Notes      int        `json:"notes"`
+	TotalNotes int        `json:"total_notes"`
+	Words      int        `json:"words"`
+
+	// TasksOpened counts the tasks in notes written in the period, and
+	// TasksClosed those of them now checked off. OpenTasks counts every
+	// unchecked task in the folder.
+	TasksOpened int `json:"tasks_opened"`
+	TasksClosed int `json:"tasks_closed"`
+	OpenTasks   int `json:"open_tasks"`
+
+	TopTags []tagCount `json:"top_tags"`
+}
+
+// Stats prints a summary of the notes written in a period, for reviews
+func Stats(args []string) error {
+	flags, dir := newFlagSet("stats", "[--since 30d|2w|12h|2006-01-02] [--tags n] [--json]")
+	since := flags.String("since", "", "only count notes written in this period or after this date (default all notes)")
+	tags := flags.Int("tags", topTagCount, "number of top tags to list")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var start *time.Time
+	if *since != "" {
+		t, err := parseSince(*since, time.Now())
+		if err != nil {
+			return err
+		}
+		start = &t
+	}
+
+	p, err := openProject(*dir)
+	if err != nil {
+		return err
+	}
+	defer p.close()
+
+	notes, err := p.notes(models.NoteQuery{Sort: models.SortCreated, Order: models.OrderDesc})
+	if err != nil {
+		return err
+	}
+
+	report := buildStats(notes, start, *tags)
+	if *asJSON {
+		return printJSON(report)
+	}
+
+	period := "All notes"
+	if start != nil {
+		period = "Since " + start.Format("2006-01-02 15:04")
+	}
+	fmt.Println(period)
+	fmt.Printf("  Notes:     %d of %d\n", report.Notes, report.TotalNotes)
+	fmt.Printf("  Words:     %d\n", report.Words)
+	fmt.Printf("  Tasks:     %d opened, %d closed, %d open overall\n", report.TasksOpened, report.TasksClosed, report.OpenTasks)
+	if len(report.TopTags) > 0 {
+		var parts []string
+		for _, tag := range report.TopTags {
+			parts = append(parts, fmt.Sprintf("#%s (%d)", tag.Tag, tag.Notes))
+		}
+		fmt.Printf("  Top tags:  %s\n", strings.Join(parts, ", "))
+	}
+	return nil
+}
+
+// buildStats summarizes the notes written at or after start, or all notes
+// when start is nil, listing up to topTags tags
+func buildStats(notes []listedNote, start *time.Time, topTags int) statsReport {
+	report := statsReport{Since: start, TotalNotes: len(notes), TopTags: []tagCount{}}
+	tagNotes := make(map[string]int)
+
+	for _, note := range notes {
+		for _, task := range note.Tasks {
+			if !task.Checked {
+				report.OpenTasks++
+			}
+		}
+		if start != nil && note.Timestamp.Before(*start) {
+			continue
+		}
+
+		report.Notes++
+		report.Words += len(strings.Fields(note.Title)) + len(strings.Fields(note.Content))
+		for _, task := range note.Tasks {
+			report.TasksOpened++
+			if task.Checked {
+				report.TasksClosed++
+			}
+		}
+		for _, tag := range note.Tags() {
+			tagNotes[tag]++
+		}
+	}
+
+	for tag, count := range tagNotes {
+		report.TopTags = append(report.TopTags, tagCount{Tag: tag, Notes: count})
+	}
+	sort.Slice(report.TopTags, func(i, j int) bool {
+		if report.TopTags[i].Notes != report.TopTags[j].Notes {
+			return report.TopTags[i].Notes > report.TopTags[j].Notes
+		}
+		return report.TopTags[i].Tag < report.TopTags[j].Tag
+	})
+	if topTags >= 0 && len(report.TopTags) > topTags {
+		report.TopTags = report.TopTags[:topTags]
+	}
+	return report
+}
+
+// parseSince returns the start of a period given as a duration back from now,
+// such as 30d, 2w or 12h, or as a date
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	if len(value) > 1 {
+		if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n >= 0 {
+			switch value[len(value)-1] {
+			case 'h':
+				return now.Add(-time.Duration(n) * time.Hour), nil
+			case 'd':
+				return now.AddDate(0, 0, -n), nil
+			case 'w':
+				return now.AddDate(0, 0, -7*n), nil
+			case 'm':
+				return now.AddDate(0, -n, 0), nil
+			case 'y':
+				return now.AddDate(-n, 0, 0), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: use a period such as 30d, 2w or 12h, or a date such as 2006-01-02", value)
+}
@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+)
+
+// Tasks prints the open tasks in the folder, or in every registered folder
+// with --global
+func Tasks(args []string) error {
+	flags, dir := newFlagSet("tasks", "[--global] [--json]")
+	global := flags.Bool("global", false, "show open tasks from every registered folder")
+	asJSON := flags.Bool("json", false, "print the tasks as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *global {
+		tasks, err := openGlobalTasks()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(tasks)
+		}
+
+		folder := ""
+		for _, task := range tasks {
+			if task.FolderPath != folder {
+				if folder != "" {
+					fmt.Println()
+				}
+				folder = task.FolderPath
+				fmt.Println(folder)
+			}
+			fmt.Printf("  [%d] %s\n", task.ID, cleanTaskText(task.Content))
+		}
+		return nil
+	}
+
+	p, err := openProject(*dir)
+	if err != nil {
+		return err
+	}
+	defer p.close()
+
+	tasks, err := p.tasks()
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(tasks)
+	}
+
+	for _, task := range tasks {
+		fmt.Printf("[%d] %s", task.Index, task.Text)
+		if task.NoteTitle != "" {
+			fmt.Printf("  (%s)", task.NoteTitle)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// Done completes the tasks with the given indices, as shown by the tasks
+// command, or registry IDs with --global
+func Done(args []string) error {
+	flags, dir := newFlagSet("done", "[--global] id ...")
+	global := flags.Bool("global", false, "the IDs are from tasks --global")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("no task given")
+	}
+
+	var ids []int
+	for _, arg := range flags.Args() {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid task %q", arg)
+		}
+		ids = append(ids, id)
+	}
+
+	if *global {
+		return completeGlobalTasks(ids)
+	}
+
+	p, err := openProject(*dir)
+	if err != nil {
+		return err
+	}
+	defer p.close()
+
+	for _, index := range ids {
+		if err := p.completeTask(index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tasks returns the open tasks in the project
+func (p *project) tasks() ([]*models.TaskInfo, error) {
+	if p.client != nil {
+		var tasks []*models.TaskInfo
+		err := p.client.do(http.MethodGet, "api/v1/tasks", nil, &tasks)
+		return tasks, err
+	}
+	return p.noteManager.GetActiveTasks(), nil
+}
+
+// completeTask checks off the task with index
+func (p *project) completeTask(index int) error {
+	if p.client != nil {
+		return p.client.do(http.MethodPost, "api/v1/tasks/"+strconv.Itoa(index), models.TaskUpdate{Checked: true}, nil)
+	}
+	p.changed = true
+	return p.noteManager.UpdateTask(index, true, "")
+}
+
+// openGlobalTasks returns the open tasks of every registered folder, as last
+// recorded in the task registry
+func openGlobalTasks() ([]models.GlobalTask, error) {
+	db, err := services.NewDatabaseService()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	response, err := db.GetGlobalTasks()
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []models.GlobalTask{}
+	for _, task := range response.Tasks {
+		if !task.Completed {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+// completeGlobalTasks checks off tasks by their registry ID, in their own
+// folders
+func completeGlobalTasks(ids []int) error {
+	tasks, err := openGlobalTasks()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		var target *models.GlobalTask
+		for i := range tasks {
+			if tasks[i].ID == id {
+				target = &tasks[i]
+			}
+		}
+		if target == nil {
+			return fmt.Errorf("no open task with ID %d; run tasks --global for current IDs", id)
+		}
+
+		if err := completeTaskIn(target.FolderPath, cleanTaskText(target.Content)); err != nil {
+			return fmt.Errorf("%s: %w", target.FolderPath, err)
+		}
+	}
+	return nil
+}
+
+// completeTaskIn checks off the open task with text in folder
+func completeTaskIn(folder, text string) error {
+	p, err := openProject(folder)
+	if err != nil {
+		return err
+	}
+	defer p.close()
+
+	tasks, err := p.tasks()
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if task.Text == text {
+			return p.completeTask(task.Index)
+		}
+	}
+	return fmt.Errorf("task %q is no longer open", text)
+}
+
+// cleanTaskText removes the checkbox from task text as stored in the registry,
+// matching the text of open tasks
+func cleanTaskText(text string) string {
+	return models.TaskLabel(text)
+}
+
+// printJSON writes value to standard output as indented JSON
+func printJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
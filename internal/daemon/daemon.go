@@ -0,0 +1,130 @@
+// Package daemon runs NoteFlow as a background process and manages its pid
+// file, and generates service definitions for systemd and launchd.
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// startupGrace is how long Start watches a new background process for an
+// early exit, such as the port already being in use
+const startupGrace = 2 * time.Second
+
+// stopTimeout is how long Stop waits for a graceful shutdown to finish
+const stopTimeout = 15 * time.Second
+
+// ErrNotRunning is returned when the pid file names no running process
+var ErrNotRunning = errors.New("NoteFlow is not running")
+
+// Start launches the current executable with args as a background process
+// detached from the terminal. Its output is appended to logFile. It returns
+// the new process ID once the process has survived its first moments.
+func Start(args []string, logFile string) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		return 0, err
+	}
+	logOutput, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer logOutput.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout = logOutput
+	cmd.Stderr = logOutput
+	cmd.SysProcAttr = detachedAttr()
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case err := <-exited:
+		if err == nil {
+			err = errors.New("exited immediately")
+		}
+		return 0, fmt.Errorf("background process failed to start (%v); see %s", err, logFile)
+	case <-time.After(startupGrace):
+		return cmd.Process.Pid, nil
+	}
+}
+
+// WritePIDFile records the current process ID in path. It fails when another
+// running process already holds the file.
+func WritePIDFile(path string) error {
+	if pid, err := ReadPIDFile(path); err == nil && pid != os.Getpid() {
+		return fmt.Errorf("NoteFlow is already running with pid %d (%s)", pid, path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+}
+
+// RemovePIDFile deletes path if it still names the current process
+func RemovePIDFile(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid == os.Getpid() {
+		os.Remove(path)
+	}
+}
+
+// ReadPIDFile returns the process ID recorded in path, or ErrNotRunning when
+// the file is missing or its process has exited
+func ReadPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, ErrNotRunning
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid pid file %s", path)
+	}
+	if !processRunning(pid) {
+		return 0, ErrNotRunning
+	}
+	return pid, nil
+}
+
+// Stop asks the process in pidFile to shut down gracefully, so it saves
+// pending changes, and waits for it to exit
+func Stop(pidFile string) error {
+	pid, err := ReadPIDFile(pidFile)
+	if err != nil {
+		return err
+	}
+	if err := terminate(pid); err != nil {
+		return fmt.Errorf("failed to stop pid %d: %w", pid, err)
+	}
+
+	deadline := time.Now().Add(stopTimeout)
+	for time.Now().Before(deadline) {
+		if !processRunning(pid) {
+			os.Remove(pidFile)
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("pid %d did not exit within %v", pid, stopTimeout)
+}
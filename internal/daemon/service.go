@@ -0,0 +1,171 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+)
+
+// Service describes how the service manager should run NoteFlow
+type Service struct {
+	Executable string
+	Args       []string
+	WorkingDir string
+	LogFile    string
+}
+
+// NewService returns a service running the current executable with args in
+// workingDir
+func NewService(args []string, workingDir, logFile string) (*Service, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+	return &Service{
+		Executable: executable,
+		Args:       args,
+		WorkingDir: workingDir,
+		LogFile:    logFile,
+	}, nil
+}
+
+// systemdUnit runs NoteFlow as a systemd user service. systemd stops it with
+// SIGTERM, giving it time to save and shut down gracefully.
+var systemdUnit = template.Must(template.New("systemd").Parse(`[Unit]
+Description=NoteFlow notes server
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+WorkingDirectory={{.WorkingDir}}
+ExecStart={{.Command}}
+Restart=on-failure
+RestartSec=5
+TimeoutStopSec=20
+
+[Install]
+WantedBy=default.target
+`))
+
+// launchdPlist runs NoteFlow as a launchd user agent that starts at login
+var launchdPlist = template.Must(template.New("launchd").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{{.Label}}</string>
+	<key>ProgramArguments</key>
+	<array>
+{{- range .Arguments}}
+		<string>{{.}}</string>
+{{- end}}
+	</array>
+	<key>WorkingDirectory</key>
+	<string>{{.WorkingDir}}</string>
+	<key>StandardOutPath</key>
+	<string>{{.LogFile}}</string>
+	<key>StandardErrorPath</key>
+	<string>{{.LogFile}}</string>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+</dict>
+</plist>
+`))
+
+// launchdLabel identifies the launchd agent
+const launchdLabel = "com.noteflow.server"
+
+// SystemdUnit returns a systemd unit file for the service
+func (s *Service) SystemdUnit() (string, error) {
+	words := append([]string{s.Executable}, s.Args...)
+	for i, word := range words {
+		words[i] = systemdQuote(word)
+	}
+
+	var buf bytes.Buffer
+	err := systemdUnit.Execute(&buf, map[string]string{
+		"WorkingDir": strings.ReplaceAll(s.WorkingDir, "%", "%%"),
+		"Command":    strings.Join(words, " "),
+	})
+	return buf.String(), err
+}
+
+// LaunchdPlist returns a launchd property list for the service
+func (s *Service) LaunchdPlist() (string, error) {
+	arguments := append([]string{s.Executable}, s.Args...)
+	for i, argument := range arguments {
+		arguments[i] = html.EscapeString(argument)
+	}
+
+	var buf bytes.Buffer
+	err := launchdPlist.Execute(&buf, map[string]interface{}{
+		"Label":      launchdLabel,
+		"Arguments":  arguments,
+		"WorkingDir": html.EscapeString(s.WorkingDir),
+		"LogFile":    html.EscapeString(s.LogFile),
+	})
+	return buf.String(), err
+}
+
+// Install writes the service definition for this platform's service manager
+// and returns its path along with the commands that enable it
+func (s *Service) Install() (string, []string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var path, content string
+	var enable []string
+	switch runtime.GOOS {
+	case "linux":
+		path = filepath.Join(home, ".config", "systemd", "user", "noteflow.service")
+		content, err = s.SystemdUnit()
+		enable = []string{
+			"systemctl --user daemon-reload",
+			"systemctl --user enable --now noteflow",
+			"loginctl enable-linger $USER",
+		}
+	case "darwin":
+		path = filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist")
+		content, err = s.LaunchdPlist()
+		enable = []string{"launchctl load -w " + path}
+	default:
+		return "", nil, fmt.Errorf("installing a service is not supported on %s; use --daemon instead", runtime.GOOS)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", nil, err
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", nil, err
+	}
+	return path, enable, nil
+}
+
+// systemdQuote quotes word for a systemd command line when it contains
+// spaces or characters systemd would interpret
+func systemdQuote(word string) string {
+	if word != "" && !strings.ContainsAny(word, " \t\"'\\$%;") {
+		return word
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `$$`, `%`, `%%`)
+	return `"` + replacer.Replace(word) + `"`
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/darren/noteflow-go/internal/services/ai"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AIHandler summarizes notes and suggests titles and tags with the configured
+// language model
+type AIHandler struct {
+	noteManager *services.NoteManager
+	config      *services.ConfigStore
+}
+
+// NewAIHandler creates an AI handler; the settings in config are read on
+// every request, so changes to them apply at once
+func NewAIHandler(noteManager *services.NoteManager, config *services.ConfigStore) *AIHandler {
+	return &AIHandler{
+		noteManager: noteManager,
+		config:      config,
+	}
+}
+
+// manager returns the project the request operates on
+func (h *AIHandler) manager(c *fiber.Ctx) *services.NoteManager {
+	return noteManagerFor(c, h.noteManager)
+}
+
+// Summarize asks the language model for a summary of a note and stores it
+// with the note
+// POST /api/notes/:index/summarize
+func (h *AIHandler) Summarize(c *fiber.Ctx) error {
+	index, err := strconv.Atoi(c.Params("index"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid note index")
+	}
+
+	manager := h.manager(c)
+	note, err := manager.GetNote(index)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Note not found")
+	}
+
+	summary, err := ai.Summarize(c.UserContext(), h.config.Current().AI, note.Title, note.Content)
+	if errors.Is(err, ai.ErrNotConfigured) {
+		return fiber.NewError(fiber.StatusNotFound, "AI summaries are not configured")
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, "Failed to summarize note: "+err.Error())
+	}
+
+	// Notes added or deleted while the model was busy move the others
+	if current, err := manager.GetNote(index); err != nil || !current.Timestamp.Equal(note.Timestamp) {
+		return fiber.NewError(fiber.StatusConflict, "The note changed while it was summarized")
+	}
+	stored, err := manager.SetSummary(index, summary, currentUserName(c))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save summary: "+err.Error())
+	}
+
+	// Reply with the summary as stored, on one line
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   map[string]interface{}{"index": index, "summary": stored},
+	})
+}
+
+// Suggest asks the language model for a title and tags for a draft note,
+// preferring the tags already used in the project. Nothing is saved.
+// POST /api/notes/suggest
+func (h *AIHandler) Suggest(c *fiber.Ctx) error {
+	var req models.AISuggestRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Content cannot be empty")
+	}
+
+	suggestion, err := ai.Suggest(c.UserContext(), h.config.Current().AI, req.Title, req.Content, h.manager(c).Tags())
+	if errors.Is(err, ai.ErrNotConfigured) {
+		return fiber.NewError(fiber.StatusNotFound, "AI suggestions are not configured")
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, "Failed to suggest a title and tags: "+err.Error())
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   suggestion,
+	})
+}
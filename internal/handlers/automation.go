@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// Automation trigger paging
+const (
+	defaultAutomationLimit = 50
+	maxAutomationLimit     = 200
+)
+
+// defaultTaskNote is the note that tasks added without a note title go to
+const defaultTaskNote = "Tasks"
+
+// AutomationHandler serves the polling triggers and the actions used by
+// Zapier, IFTTT and similar services
+type AutomationHandler struct {
+	noteManager *services.NoteManager
+	feeds       map[*services.NoteManager]*services.AutomationFeed
+}
+
+// NewAutomationHandler creates an automation handler reading each project's
+// triggers from its feed
+func NewAutomationHandler(noteManager *services.NoteManager, feeds map[*services.NoteManager]*services.AutomationFeed) *AutomationHandler {
+	return &AutomationHandler{
+		noteManager: noteManager,
+		feeds:       feeds,
+	}
+}
+
+// manager returns the project selected for the request
+func (h *AutomationHandler) manager(c *fiber.Ctx) *services.NoteManager {
+	return noteManagerFor(c, h.noteManager)
+}
+
+// NewNotes lists the notes created after the cursor query parameter, newest
+// first, up to limit (default 50)
+// GET /api/automation/triggers/new-notes
+func (h *AutomationHandler) NewNotes(c *fiber.Ctx) error {
+	return h.trigger(c, models.AutomationNoteCreated)
+}
+
+// CompletedTasks lists the tasks completed after the cursor query parameter,
+// newest first, up to limit (default 50)
+// GET /api/automation/triggers/completed-tasks
+func (h *AutomationHandler) CompletedTasks(c *fiber.Ctx) error {
+	return h.trigger(c, models.AutomationTaskCompleted)
+}
+
+// trigger lists the recorded items of kind
+func (h *AutomationHandler) trigger(c *fiber.Ctx, kind string) error {
+	var cursor uint64
+	if value := c.Query("cursor"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "cursor must be a non-negative number")
+		}
+		cursor = parsed
+	}
+	limit := defaultAutomationLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return fiber.NewError(fiber.StatusBadRequest, "limit must be a positive number")
+		}
+		limit = min(parsed, maxAutomationLimit)
+	}
+
+	items := make([]models.AutomationItem, 0)
+	if feed := h.feeds[h.manager(c)]; feed != nil {
+		items = feed.Items(kind, cursor, limit)
+	}
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   items,
+	})
+}
+
+// CreateNote adds a note from a JSON or form body with title and content
+// POST /api/automation/actions/create-note
+func (h *AutomationHandler) CreateNote(c *fiber.Ctx) error {
+	var req models.AutomationNoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+	title := strings.TrimSpace(utils.CopyString(req.Title))
+	content := strings.TrimSpace(utils.CopyString(req.Content))
+	if content == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Content cannot be empty")
+	}
+
+	if err := h.manager(c).AddNote(c.UserContext(), title, content, currentUserName(c)); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to add note: "+err.Error())
+	}
+	return c.Status(fiber.StatusCreated).JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Note created",
+		Data:    map[string]interface{}{"title": title, "content": content},
+	})
+}
+
+// AppendToNote adds content to the end of the newest note titled title, or
+// to a new note when there is none
+// POST /api/automation/actions/append-to-note
+func (h *AutomationHandler) AppendToNote(c *fiber.Ctx) error {
+	var req models.AutomationNoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+	title := strings.TrimSpace(utils.CopyString(req.Title))
+	content := strings.TrimSpace(utils.CopyString(req.Content))
+	if title == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Title is required")
+	}
+	if content == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Content cannot be empty")
+	}
+
+	index, created, err := h.manager(c).AppendToNote(c.UserContext(), title, content, currentUserName(c))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update note: "+err.Error())
+	}
+	return c.JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Note updated",
+		Data:    map[string]interface{}{"index": index, "title": title, "created": created},
+	})
+}
+
+// AddTask adds an open task to the note titled note (default "Tasks")
+// POST /api/automation/actions/add-task
+func (h *AutomationHandler) AddTask(c *fiber.Ctx) error {
+	var req models.AutomationTaskRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+	text := strings.Join(strings.Fields(utils.CopyString(req.Text)), " ")
+	if text == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Task text is required")
+	}
+	note := strings.TrimSpace(utils.CopyString(req.Note))
+	if note == "" {
+		note = defaultTaskNote
+	}
+
+	if _, _, err := h.manager(c).AppendToNote(c.UserContext(), note, "- [ ] "+text, currentUserName(c)); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to add task: "+err.Error())
+	}
+	return c.Status(fiber.StatusCreated).JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Task added",
+		Data:    map[string]interface{}{"text": text, "note": note},
+	})
+}
+
+// CompleteTask checks off the open task with the given index, or the first
+// one whose text is text, ignoring case
+// POST /api/automation/actions/complete-task
+func (h *AutomationHandler) CompleteTask(c *fiber.Ctx) error {
+	var req models.AutomationTaskRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+	text := strings.Join(strings.Fields(req.Text), " ")
+	if req.Index == nil && text == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Task text or index is required")
+	}
+
+	manager := h.manager(c)
+	var task *models.TaskInfo
+	for _, open := range manager.GetActiveTasks() {
+		if (req.Index != nil && open.Index == *req.Index) || (req.Index == nil && strings.EqualFold(open.Text, text)) {
+			task = open
+			break
+		}
+	}
+	if task == nil {
+		return fiber.NewError(fiber.StatusNotFound, "No open task matches")
+	}
+
+	if err := manager.UpdateTask(task.Index, true, currentUserName(c)); err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Task not found: "+err.Error())
+	}
+	return c.JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Task completed",
+		Data:    task,
+	})
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CalendarHandler lists notes and due tasks by day
+type CalendarHandler struct {
+	noteManager *services.NoteManager
+}
+
+// NewCalendarHandler creates a new calendar handler
+func NewCalendarHandler(noteManager *services.NoteManager) *CalendarHandler {
+	return &CalendarHandler{
+		noteManager: noteManager,
+	}
+}
+
+// GetCalendar returns every day of the month given as month=YYYY-MM (the
+// current month by default) with the notes written and the tasks due on it
+// GET /api/calendar
+func (h *CalendarHandler) GetCalendar(c *fiber.Ctx) error {
+	now := models.Now()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, models.Location())
+	if value := c.Query("month"); value != "" {
+		parsed, err := time.ParseInLocation(models.CalendarMonthLayout, value, models.Location())
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "month must be in the form YYYY-MM")
+		}
+		month = parsed
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   noteManagerFor(c, h.noteManager).Calendar(month),
+	})
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// uploadOffsetHeader carries the offset a chunk starts at
+const uploadOffsetHeader = "Upload-Offset"
+
+// chunkUploadError maps a chunked upload service error to an HTTP error
+func chunkUploadError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrUploadOffset):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrUploadOverflow):
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, err.Error())
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+}
+
+// StartUpload begins a chunked upload, for large files over unreliable
+// connections. The file's name and size are checked as for a single
+// upload; its content type, when not given, is guessed from its extension.
+// POST /api/uploads
+func (h *FilesHandler) StartUpload(c *fiber.Ctx) error {
+	var req struct {
+		Filename    string `json:"filename"`
+		Size        int64  `json:"size"`
+		ContentType string `json:"content_type"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.Filename == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "No filename provided")
+	}
+	if req.Size <= 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "size must be a positive number")
+	}
+
+	if maxSize := h.uploads().LargestBytes(); req.Size > maxSize {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge,
+			fmt.Sprintf("File too large (max %d MB)", maxSize>>20))
+	}
+	ext := strings.ToLower(filepath.Ext(req.Filename))
+	if !h.uploads().ExtensionAllowed(ext) {
+		return fiber.NewError(fiber.StatusUnsupportedMediaType,
+			fmt.Sprintf("File type %q not allowed (allowed: %s)", ext, strings.Join(h.uploads().Extensions(), ", ")))
+	}
+	// Types only known from the data are checked once it has all arrived
+	if contentType := uploadContentType(req.ContentType, ext, nil); contentType != "" {
+		if err := h.checkType(contentType, req.Size); err != nil {
+			return err
+		}
+	}
+
+	upload, err := h.manager(c).StartUpload(req.Filename, req.Size, req.ContentType)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to start upload: "+err.Error())
+	}
+	return c.Status(fiber.StatusCreated).JSON(models.APIResponse{
+		Status: "success",
+		Data:   upload,
+	})
+}
+
+// GetUpload returns a chunked upload, whose offset is where an interrupted
+// upload resumes
+// GET /api/uploads/:id
+func (h *FilesHandler) GetUpload(c *fiber.Ctx) error {
+	upload, err := h.manager(c).Upload(c.Params("id"))
+	if err != nil {
+		return chunkUploadError(err)
+	}
+	c.Set(uploadOffsetHeader, strconv.FormatInt(upload.Offset, 10))
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   upload,
+	})
+}
+
+// UploadChunk adds the request body to a chunked upload. The Upload-Offset
+// header must give the number of bytes uploaded so far. With the last
+// chunk the file is saved as a single upload would be, and its path
+// returned.
+// PATCH /api/uploads/:id
+func (h *FilesHandler) UploadChunk(c *fiber.Ctx) error {
+	offset, err := strconv.ParseInt(c.Get(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, uploadOffsetHeader+" header must be a non-negative number")
+	}
+
+	manager := h.manager(c)
+	upload, data, err := manager.WriteChunk(c.Params("id"), offset, c.Body())
+	if err != nil {
+		if upload != nil {
+			c.Set(uploadOffsetHeader, strconv.FormatInt(upload.Offset, 10))
+		}
+		return chunkUploadError(err)
+	}
+	c.Set(uploadOffsetHeader, strconv.FormatInt(upload.Offset, 10))
+
+	if upload.Complete() {
+		ext := strings.ToLower(filepath.Ext(upload.Filename))
+		upload.ContentType = uploadContentType(upload.ContentType, ext, data)
+		if err := h.checkType(upload.ContentType, upload.Size); err != nil {
+			return err
+		}
+		if data, err = verifyUpload(data, ext, upload.ContentType); err != nil {
+			return err
+		}
+		upload.FilePath, upload.IsImage, err = manager.SaveFile(upload.Filename, data, upload.ContentType)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to save file: "+err.Error())
+		}
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   upload,
+	})
+}
+
+// CancelUpload removes an unfinished chunked upload
+// DELETE /api/uploads/:id
+func (h *FilesHandler) CancelUpload(c *fiber.Ctx) error {
+	if err := h.manager(c).CancelUpload(c.Params("id")); err != nil {
+		return chunkUploadError(err)
+	}
+	return c.JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Upload cancelled",
+	})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Clip saves a web page clipped by a browser extension as a note: a link to
+// the page, the selected text as a quote and an optional screenshot, which
+// is saved like a pasted image. The page is then archived in the background.
+// POST /api/clip
+func (h *FilesHandler) Clip(c *fiber.Ctx) error {
+	var req models.ClipRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.URL == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "No URL provided")
+	}
+	if _, err := req.PageURL(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	manager := h.manager(c)
+	var screenshotPath string
+	if screenshot := strings.TrimSpace(req.Screenshot); screenshot != "" {
+		data := []byte(screenshot)
+		if !strings.HasPrefix(screenshot, "data:") {
+			decoded, err := base64.StdEncoding.DecodeString(screenshot)
+			if err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Invalid base64 data")
+			}
+			data = decoded
+		}
+		data, contentType, ext, err := h.checkImage(data)
+		if err != nil {
+			return err
+		}
+
+		filename := "clip-" + strings.Replace(models.Now().Format("20060102-150405.000"), ".", "-", 1) + ext
+		screenshotPath, _, err = manager.SaveFile(filename, data, contentType)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to save file: "+err.Error())
+		}
+	}
+
+	title, err := manager.Clip(&req, screenshotPath, currentUserName(c))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to add note: "+err.Error())
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Page clipped",
+		Data: map[string]interface{}{
+			"title":      title,
+			"screenshot": screenshotPath,
+			"archiving":  req.Archive == nil || *req.Archive,
+		},
+	})
+}
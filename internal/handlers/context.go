@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"io"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Keys for request-scoped values stored in fiber.Ctx locals
+const (
+	userLocal        = "user"
+	noteManagerLocal = "noteManager"
+	urlPrefixLocal   = "urlPrefix"
+)
+
+// SetUser records the authenticated user for the request
+func SetUser(c *fiber.Ctx, user *models.User) {
+	c.Locals(userLocal, user)
+}
+
+// CurrentUser returns the authenticated user for the request, or nil when the
+// request is anonymous or used the shared password or tokens
+func CurrentUser(c *fiber.Ctx) *models.User {
+	user, _ := c.Locals(userLocal).(*models.User)
+	return user
+}
+
+// currentUserName returns the name of the authenticated user, or "" if there is none
+func currentUserName(c *fiber.Ctx) string {
+	if user := CurrentUser(c); user != nil {
+		return user.Name
+	}
+	return ""
+}
+
+// projectLocal holds the project selected for a request. fasthttp closes
+// locals that are io.Closers when the request ends, which would close the
+// NoteManager itself and abort its archives.
+type projectLocal struct {
+	noteManager *services.NoteManager
+}
+
+// SetNoteManager selects the project the request operates on
+func SetNoteManager(c *fiber.Ctx, noteManager *services.NoteManager) {
+	c.Locals(noteManagerLocal, projectLocal{noteManager})
+}
+
+// CurrentNoteManager returns the project selected for the request, or nil if none was
+func CurrentNoteManager(c *fiber.Ctx) *services.NoteManager {
+	project, _ := c.Locals(noteManagerLocal).(projectLocal)
+	return project.noteManager
+}
+
+// noteManagerFor returns the project selected for the request, or fallback if none was
+func noteManagerFor(c *fiber.Ctx, fallback *services.NoteManager) *services.NoteManager {
+	if noteManager := CurrentNoteManager(c); noteManager != nil {
+		return noteManager
+	}
+	return fallback
+}
+
+// SetURLPrefix records the path the request's project is mounted under, such
+// as "/p/work"
+func SetURLPrefix(c *fiber.Ctx, prefix string) {
+	c.Locals(urlPrefixLocal, prefix)
+}
+
+// URLPrefix returns the path the request's project is mounted under, or ""
+// for the server's own project at the root
+func URLPrefix(c *fiber.Ctx) string {
+	prefix, _ := c.Locals(urlPrefixLocal).(string)
+	return prefix
+}
+
+// withURLPrefix points the /assets/ links in rendered HTML at the request's project
+func withURLPrefix(c *fiber.Ctx, html string) string {
+	return prefixAssets(URLPrefix(c), html)
+}
+
+// prefixAssets points the /assets/ links in rendered HTML at the project
+// mounted under prefix
+func prefixAssets(prefix, html string) string {
+	if prefix == "" {
+		return html
+	}
+	return strings.ReplaceAll(html, `="/assets/`, `="`+prefix+`/assets/`)
+}
+
+// urlPrefixWriter points the /assets/ links in the HTML written to it at the
+// project mounted under prefix. Each write must hold whole tags, as a
+// rendered note does.
+type urlPrefixWriter struct {
+	w      io.Writer
+	prefix string
+}
+
+// Write implements io.Writer
+func (pw urlPrefixWriter) Write(p []byte) (int, error) {
+	if pw.prefix == "" {
+		return pw.w.Write(p)
+	}
+	if _, err := io.WriteString(pw.w, prefixAssets(pw.prefix, string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// DebugHandler reports runtime statistics of the server, served with --debug
+type DebugHandler struct {
+	started time.Time
+}
+
+// NewDebugHandler creates a debug handler for a server started at started
+func NewDebugHandler(started time.Time) *DebugHandler {
+	return &DebugHandler{
+		started: started,
+	}
+}
+
+// GetRuntime returns the goroutine count, heap and garbage collector
+// statistics of the server process
+// GET /api/debug/runtime
+func (h *DebugHandler) GetRuntime(c *fiber.Ctx) error {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	stats := models.RuntimeStats{
+		GoVersion:      runtime.Version(),
+		StartedAt:      h.started,
+		UptimeSeconds:  int64(time.Since(h.started).Seconds()),
+		NumCPU:         runtime.NumCPU(),
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAlloc:      memStats.HeapAlloc,
+		HeapInuse:      memStats.HeapInuse,
+		HeapObjects:    memStats.HeapObjects,
+		TotalAlloc:     memStats.TotalAlloc,
+		Sys:            memStats.Sys,
+		NumGC:          memStats.NumGC,
+		GCPauseTotalMs: float64(memStats.PauseTotalNs) / float64(time.Millisecond),
+	}
+	if memStats.LastGC != 0 {
+		lastGC := time.Unix(0, int64(memStats.LastGC))
+		stats.LastGC = &lastGC
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   stats,
+	})
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// notModified sets the response's ETag from revision and reports whether the
+// client's If-None-Match already names it, in which case the caller should
+// reply with 304 Not Modified instead of a body
+func notModified(c *fiber.Ctx, revision string) bool {
+	etag := `"` + revision + `"`
+	c.Set(fiber.HeaderETag, etag)
+	// Clients must revalidate, but may reuse the body when it is unchanged
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+
+	for _, candidate := range strings.Split(c.Get(fiber.HeaderIfNoneMatch), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+// keepAliveInterval is how often an idle event stream sends a comment to stay open
+const keepAliveInterval = 15 * time.Second
+
+// sseRetryMillis is how long EventSource clients wait before reconnecting
+const sseRetryMillis = 3000
+
+// EventsHandler streams a project's live update events to clients
+type EventsHandler struct {
+	noteManager *services.NoteManager
+}
+
+// NewEventsHandler creates a new events handler
+func NewEventsHandler(noteManager *services.NoteManager) *EventsHandler {
+	return &EventsHandler{
+		noteManager: noteManager,
+	}
+}
+
+// manager returns the project the request operates on
+func (h *EventsHandler) manager(c *fiber.Ctx) *services.NoteManager {
+	return noteManagerFor(c, h.noteManager)
+}
+
+// eventTypeFilter parses an optional comma-separated list of event types
+func eventTypeFilter(types string) map[string]bool {
+	wanted := make(map[string]bool)
+	for _, eventType := range strings.Split(types, ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			wanted[eventType] = true
+		}
+	}
+	return wanted
+}
+
+// Stream sends events as Server-Sent Events until the client disconnects. It
+// carries the same events as the WebSocket and suits scripts and clients
+// behind proxies that block WebSocket upgrades.
+// GET /api/events?types=archive-progress,...
+func (h *EventsHandler) Stream(c *fiber.Ctx) error {
+	wanted := eventTypeFilter(c.Query("types"))
+	events := h.manager(c).Events()
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	ch := events.Subscribe()
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer events.Unsubscribe(ch)
+
+		keepAlive := time.NewTicker(keepAliveInterval)
+		defer keepAlive.Stop()
+
+		// Tell the client the stream is open and how soon to reconnect
+		fmt.Fprintf(w, ": connected\nretry: %d\n\n", sseRetryMillis)
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case event, ok := <-ch:
+				if !ok {
+					return
+				}
+				if len(wanted) > 0 && !wanted[event.Type] {
+					continue
+				}
+
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+
+			// A flush error means the client has gone away
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// WebSocket sends events as JSON messages over a WebSocket until the client disconnects
+// GET /ws?types=note-created,...
+func (h *EventsHandler) WebSocket(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	wanted := eventTypeFilter(c.Query("types"))
+	events := h.manager(c).Events()
+
+	return websocket.New(func(conn *websocket.Conn) {
+		ch := events.Subscribe()
+		defer events.Unsubscribe(ch)
+
+		// Messages from the client are ignored; reading detects when it goes away
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		keepAlive := time.NewTicker(keepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			var err error
+			select {
+			case event, ok := <-ch:
+				if !ok {
+					return
+				}
+				if len(wanted) > 0 && !wanted[event.Type] {
+					continue
+				}
+				err = conn.WriteJSON(event)
+			case <-keepAlive.C:
+				err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(keepAliveInterval))
+			case <-closed:
+				return
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	})(c)
+}
@@ -1,6 +1,12 @@
 package handlers
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
 	"path/filepath"
 	"strings"
 
@@ -12,15 +18,28 @@ import (
 // FilesHandler handles file upload and management
 type FilesHandler struct {
 	noteManager *services.NoteManager
+	config      *services.ConfigStore
 }
 
-// NewFilesHandler creates a new files handler
-func NewFilesHandler(noteManager *services.NoteManager) *FilesHandler {
+// NewFilesHandler creates a new files handler enforcing the upload limits in
+// config
+func NewFilesHandler(noteManager *services.NoteManager, config *services.ConfigStore) *FilesHandler {
 	return &FilesHandler{
 		noteManager: noteManager,
+		config:      config,
 	}
 }
 
+// uploads returns the current upload limits
+func (h *FilesHandler) uploads() *models.UploadConfig {
+	return &h.config.Current().Uploads
+}
+
+// manager returns the project the request operates on
+func (h *FilesHandler) manager(c *fiber.Ctx) *services.NoteManager {
+	return noteManagerFor(c, h.noteManager)
+}
+
 // UploadFile handles file uploads via drag-and-drop or form submission
 func (h *FilesHandler) UploadFile(c *fiber.Ctx) error {
 	file, err := c.FormFile("file")
@@ -28,6 +47,19 @@ func (h *FilesHandler) UploadFile(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "No file provided")
 	}
 
+	// Validate file size, against the limit for its type once that is known
+	if maxSize := h.uploads().LargestBytes(); file.Size > maxSize {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge,
+			fmt.Sprintf("File too large (max %d MB)", maxSize>>20))
+	}
+
+	// Validate file extension
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !h.uploads().ExtensionAllowed(ext) {
+		return fiber.NewError(fiber.StatusUnsupportedMediaType,
+			fmt.Sprintf("File type %q not allowed (allowed: %s)", ext, strings.Join(h.uploads().Extensions(), ", ")))
+	}
+
 	// Read file data
 	fileHeader, err := file.Open()
 	if err != nil {
@@ -35,65 +67,165 @@ func (h *FilesHandler) UploadFile(c *fiber.Ctx) error {
 	}
 	defer fileHeader.Close()
 
-	// Read file content
-	fileData := make([]byte, file.Size)
-	if _, err := fileHeader.Read(fileData); err != nil {
+	fileData, err := io.ReadAll(fileHeader)
+	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to read file")
 	}
 
-	// Validate file size (max 50MB)
-	maxSize := int64(50 * 1024 * 1024)
-	if file.Size > maxSize {
-		return fiber.NewError(fiber.StatusBadRequest, "File too large (max 50MB)")
+	// Get content type from header, or guess it
+	contentType := uploadContentType(file.Header.Get("Content-Type"), ext, fileData)
+	if err := h.checkType(contentType, file.Size); err != nil {
+		return err
 	}
-
-	// Validate file extension
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	allowedExts := map[string]bool{
-		".jpg":  true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true,
-		".pdf":  true, ".txt": true, ".md": true, ".doc": true, ".docx": true,
-		".zip":  true, ".tar": true, ".gz": true,
-		".json": true, ".xml": true, ".csv": true,
+	if fileData, err = verifyUpload(fileData, ext, contentType); err != nil {
+		return err
 	}
 
-	if !allowedExts[ext] {
-		return fiber.NewError(fiber.StatusBadRequest, "File type not allowed")
+	// Save file
+	filePath, isImage, err := h.manager(c).SaveFile(file.Filename, fileData, contentType)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save file: "+err.Error())
 	}
 
-	// Get content type from header
-	contentType := file.Header.Get("Content-Type")
+	return c.JSON(map[string]interface{}{
+		"filePath":    filePath,
+		"isImage":     isImage,
+		"contentType": contentType,
+	})
+}
+
+// uploadContentType returns the content type of an uploaded file, as the
+// client sent it or else guessed from the extension ext and then from data
+func uploadContentType(contentType, ext string, data []byte) string {
+	if contentType != "" && contentType != "application/octet-stream" {
+		return contentType
+	}
+	contentType = models.MediaType(ext)
 	if contentType == "" {
-		// Try to guess from extension
-		switch ext {
-		case ".jpg", ".jpeg":
-			contentType = "image/jpeg"
-		case ".png":
-			contentType = "image/png"
-		case ".gif":
-			contentType = "image/gif"
-		case ".pdf":
-			contentType = "application/pdf"
-		default:
-			contentType = "application/octet-stream"
+		contentType = mime.TypeByExtension(ext)
+	}
+	if contentType == "" && data != nil {
+		contentType = http.DetectContentType(data)
+	}
+	return contentType
+}
+
+// checkType rejects uploads of a content type that isn't allowed, or larger
+// than the limit for their type
+func (h *FilesHandler) checkType(contentType string, size int64) error {
+	if !h.uploads().TypeAllowed(contentType) {
+		return fiber.NewError(fiber.StatusUnsupportedMediaType,
+			fmt.Sprintf("Content type %q not allowed (allowed: %s)", contentType, strings.Join(h.uploads().AllowedTypes, ", ")))
+	}
+	if maxSize := h.uploads().MaxBytesFor(contentType); size > maxSize {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge,
+			fmt.Sprintf("File too large (max %d MB for %s)", maxSize>>20, contentType))
+	}
+	return nil
+}
+
+// verifyUpload checks that the data of an upload is of the type its
+// extension and content type claim, returning it with SVG images sanitized
+func verifyUpload(data []byte, ext, contentType string) ([]byte, error) {
+	verified, err := services.VerifyUpload(data, ext, contentType)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusUnsupportedMediaType, err.Error())
+	}
+	return verified, nil
+}
+
+// pasteExtensions are the extensions given to pasted images, by the content
+// type detected from their data
+var pasteExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"image/bmp":  ".bmp",
+}
+
+// checkImage decodes image data sent as raw bytes or a base64 data URI and
+// checks it is a PNG, JPEG, GIF, WebP or BMP image that may be uploaded,
+// returning the image, its content type and the extension to save it with.
+// The type is taken from the data, since clipboards often mislabel it.
+func (h *FilesHandler) checkImage(data []byte) ([]byte, string, string, error) {
+	if uri := strings.TrimSpace(string(data)); strings.HasPrefix(uri, "data:") {
+		meta, encoded, found := strings.Cut(uri, ",")
+		if !found || !strings.HasSuffix(meta, ";base64") {
+			return nil, "", "", fiber.NewError(fiber.StatusBadRequest, "Data URI must be base64 encoded")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "", "", fiber.NewError(fiber.StatusBadRequest, "Invalid base64 data")
 		}
+		data = decoded
 	}
 
-	// Save file
-	filePath, isImage, err := h.noteManager.SaveFile(file.Filename, fileData, contentType)
+	contentType := http.DetectContentType(data)
+	ext, isImage := pasteExtensions[contentType]
+	if !isImage {
+		return nil, "", "", fiber.NewError(fiber.StatusUnsupportedMediaType,
+			fmt.Sprintf("Image data is not a PNG, JPEG, GIF, WebP or BMP image (got %q)", contentType))
+	}
+	if maxSize := h.uploads().MaxBytesFor(contentType); int64(len(data)) > maxSize {
+		return nil, "", "", fiber.NewError(fiber.StatusRequestEntityTooLarge,
+			fmt.Sprintf("File too large (max %d MB for %s)", maxSize>>20, contentType))
+	}
+	if !h.uploads().ExtensionAllowed(ext) {
+		return nil, "", "", fiber.NewError(fiber.StatusUnsupportedMediaType,
+			fmt.Sprintf("File type %q not allowed (allowed: %s)", ext, strings.Join(h.uploads().Extensions(), ", ")))
+	}
+	if !h.uploads().TypeAllowed(contentType) {
+		return nil, "", "", fiber.NewError(fiber.StatusUnsupportedMediaType,
+			fmt.Sprintf("Content type %q not allowed (allowed: %s)", contentType, strings.Join(h.uploads().AllowedTypes, ", ")))
+	}
+	return data, contentType, ext, nil
+}
+
+// PasteImage saves an image pasted from the clipboard under assets/images,
+// named after the time it was pasted. The body holds the raw image bytes or
+// a base64 data URI, either as is or as the "data" field of a JSON object.
+// The markdown that embeds the image is returned for inserting into a note.
+// POST /api/paste-image
+func (h *FilesHandler) PasteImage(c *fiber.Ctx) error {
+	data := c.Body()
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		var req struct {
+			Data string `json:"data"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+		}
+		data = []byte(req.Data)
+	}
+	if len(data) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "No image provided")
+	}
+
+	data, contentType, ext, err := h.checkImage(data)
+	if err != nil {
+		return err
+	}
+
+	filename := "pasted-" + strings.Replace(models.Now().Format("20060102-150405.000"), ".", "-", 1) + ext
+	filePath, _, err := h.manager(c).SaveFile(filename, data, contentType)
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save file: "+err.Error())
 	}
 
-	return c.JSON(map[string]interface{}{
-		"filePath":    filePath,
-		"isImage":     isImage,
-		"contentType": contentType,
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data: map[string]interface{}{
+			"filePath":    filePath,
+			"contentType": contentType,
+			"markdown":    fmt.Sprintf("![%s](<%s>)", filename, filePath),
+		},
 	})
 }
 
 // GetLinks returns information about archived links/sites
 func (h *FilesHandler) GetLinks(c *fiber.Ctx) error {
-	linkGroups, err := h.noteManager.GetArchivedLinks()
+	linkGroups, err := h.manager(c).GetArchivedLinks()
 	if err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get links: "+err.Error())
 	}
@@ -113,11 +245,15 @@ func (h *FilesHandler) GetLinks(c *fiber.Ctx) error {
 		for _, archive := range archives {
 			filename := archive["filename"]
 			timestamp := archive["timestamp"]
+			screenshot := ""
+			if archive["screenshot"] != "" {
+				screenshot = `<img class="archive-screenshot" src="/assets/sites/` + archive["screenshot"] + `" alt="" loading="lazy">`
+			}
 
 			htmlParts = append(htmlParts,
 				`<span class="archive-reference">`+
 					`<a href="/assets/sites/`+filename+`" target="_blank">`+
-					`site archive [`+timestamp+`]</a>`+
+					screenshot+`site archive [`+timestamp+`]</a>`+
 					`<span style="color:red;cursor:pointer;font-size:0.5rem; margin-left:5px;" `+
 					`onclick="deleteArchive('`+filename+`')">delete</span>`+
 					`</span>`)
@@ -130,13 +266,157 @@ func (h *FilesHandler) GetLinks(c *fiber.Ctx) error {
 	}
 
 	result := map[string]interface{}{
-		"html":     strings.Join(htmlParts, "\n"),
+		"html":     withURLPrefix(c, strings.Join(htmlParts, "\n")),
 		"markdown": strings.Join(markdownParts, "\n"),
 	}
 
 	return c.JSON(result)
 }
 
+// ArchiveURL archives a single website, optionally with request-specific headers and cookies
+func (h *FilesHandler) ArchiveURL(c *fiber.Ctx) error {
+	var req models.ArchiveRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	if req.URL == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "No URL provided")
+	}
+
+	archiveInfo, err := h.manager(c).ArchiveURL(c.UserContext(), req.URL, &req.ArchiveOptions)
+	if errors.Is(err, services.ErrArchiveDenied) {
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, "Failed to archive website: "+err.Error())
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data: map[string]interface{}{
+			"title":     archiveInfo.Title,
+			"filePath":  archiveInfo.FilePath,
+			"timestamp": models.FormatTimestamp(archiveInfo.Timestamp),
+			"source":    archiveInfo.Source,
+			"markdown":  archiveInfo.Markdown(),
+		},
+	})
+}
+
+// ArchiveAllLinks starts archiving every outbound link in the project's notes
+func (h *FilesHandler) ArchiveAllLinks(c *fiber.Ctx) error {
+	total, err := h.manager(c).StartBulkArchive()
+	if err != nil {
+		if errors.Is(err, services.ErrBulkArchiveRunning) {
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to start bulk archive: "+err.Error())
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(models.APIResponse{
+		Status:  "success",
+		Message: "bulk archive started",
+		Data: map[string]int{
+			"links": total,
+		},
+	})
+}
+
+// GetArchives returns metadata for all archived websites
+func (h *FilesHandler) GetArchives(c *fiber.Ctx) error {
+	archives, err := h.manager(c).ListArchives()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to list archives: "+err.Error())
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   archives,
+	})
+}
+
+// RefreshArchive re-archives the original URL of an archived website
+func (h *FilesHandler) RefreshArchive(c *fiber.Ctx) error {
+	var req struct {
+		Filename string `json:"filename"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	if req.Filename == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "No filename provided")
+	}
+
+	archiveInfo, err := h.manager(c).RefreshArchive(c.UserContext(), req.Filename)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, "Failed to refresh archive: "+err.Error())
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data: map[string]interface{}{
+			"title":    archiveInfo.Title,
+			"filePath": archiveInfo.FilePath,
+			"source":   archiveInfo.Source,
+		},
+	})
+}
+
+// ExportArchives downloads archived websites as a WARC file
+// GET /api/archives/export?gzip=1&filename=...
+func (h *FilesHandler) ExportArchives(c *fiber.Ctx) error {
+	compress := c.QueryBool("gzip", false)
+
+	var filenames []string
+	for _, value := range c.Context().QueryArgs().PeekMulti("filename") {
+		filenames = append(filenames, string(value))
+	}
+
+	name := "noteflow-archives.warc"
+	c.Set("Content-Type", "application/warc")
+	if compress {
+		name += ".gz"
+		c.Set("Content-Type", "application/gzip")
+	}
+	c.Set("Content-Disposition", `attachment; filename="`+name+`"`)
+
+	if err := h.manager(c).ExportArchivesWARC(c.Response().BodyWriter(), filenames, compress); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to export archives: "+err.Error())
+	}
+
+	return nil
+}
+
+// ImportArchives imports the HTML pages of an uploaded WARC file as archives
+// POST /api/archives/import
+func (h *FilesHandler) ImportArchives(c *fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "No file provided")
+	}
+
+	warcFile, err := file.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to open file")
+	}
+	defer warcFile.Close()
+
+	imported, err := h.manager(c).ImportArchivesWARC(warcFile)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Failed to import WARC file: "+err.Error())
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data: map[string]int{
+			"imported": imported,
+		},
+	})
+}
+
 // DeleteArchive deletes an archived website file
 func (h *FilesHandler) DeleteArchive(c *fiber.Ctx) error {
 	var req struct {
@@ -151,7 +431,7 @@ func (h *FilesHandler) DeleteArchive(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "No filename provided")
 	}
 
-	if err := h.noteManager.DeleteArchivedSite(req.Filename); err != nil {
+	if err := h.manager(c).DeleteArchivedSite(req.Filename); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete archive: "+err.Error())
 	}
 
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// fontFaceResponse reports whether a face's file is in the current project
+type fontFaceResponse struct {
+	*models.FontFace
+	Available bool   `json:"available"`
+	URL       string `json:"url"`
+}
+
+// fontsResponse describes the font settings
+type fontsResponse struct {
+	UI    string             `json:"ui"`
+	Code  string             `json:"code"`
+	Faces []fontFaceResponse `json:"faces"`
+}
+
+// fontsDir returns the folder holding the current project's font files
+func fontsDir(c *fiber.Ctx) string {
+	return filepath.Join(CurrentNoteManager(c).GetBasePath(), "assets", "fonts")
+}
+
+// newFontFaceResponse describes face for an API response
+func newFontFaceResponse(c *fiber.Ctx, face *models.FontFace) fontFaceResponse {
+	_, err := os.Stat(filepath.Join(fontsDir(c), face.File))
+	return fontFaceResponse{
+		FontFace:  face,
+		Available: err == nil,
+		URL:       URLPrefix(c) + "/assets/fonts/" + face.File,
+	}
+}
+
+// fontFace returns the index of the face for file in fonts, or -1
+func fontFace(fonts *models.FontConfig, file string) int {
+	for i, face := range fonts.Faces {
+		if face != nil && face.File == file {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetFonts returns the configured fonts and uploaded font faces
+// GET /api/fonts
+func (h *ThemesHandler) GetFonts(c *fiber.Ctx) error {
+	fonts := &h.config.Current().UI.Fonts
+	response := fontsResponse{UI: fonts.UI, Code: fonts.Code, Faces: []fontFaceResponse{}}
+	for _, face := range fonts.Faces {
+		if face != nil {
+			response.Faces = append(response.Faces, newFontFaceResponse(c, face))
+		}
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   response,
+	})
+}
+
+// SetFonts chooses the interface and code fonts, which may name uploaded
+// faces, installed fonts or generic families
+// PUT /api/fonts
+func (h *ThemesHandler) SetFonts(c *fiber.Ctx) error {
+	var req models.FontRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	req.UI, req.Code = strings.TrimSpace(req.UI), strings.TrimSpace(req.Code)
+	err := h.updateConfig("Failed to save fonts", func(config *models.Config) error {
+		fonts := &config.UI.Fonts
+		fonts.UI, fonts.Code = req.UI, req.Code
+		if err := fonts.Validate(); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   models.FontRequest{UI: req.UI, Code: req.Code},
+	})
+}
+
+// UploadFont stores a font file in the project's assets/fonts folder and
+// declares it as a face of a family, named by the "family" form field or
+// else after the file. "weight" and "style" describe the face; uploading a
+// file again replaces it.
+// POST /api/fonts
+func (h *ThemesHandler) UploadFont(c *fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "No file provided")
+	}
+	if maxSize := h.config.Current().Uploads.MaxBytes(); file.Size > maxSize {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge,
+			fmt.Sprintf("File too large (max %d MB)", maxSize>>20))
+	}
+
+	name := strings.ReplaceAll(filepath.Base(file.Filename), " ", "-")
+	face := &models.FontFace{
+		Family: strings.TrimSpace(c.FormValue("family")),
+		File:   name,
+		Weight: c.FormValue("weight"),
+		Style:  c.FormValue("style"),
+	}
+	if face.Family == "" {
+		face.Family = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if err := face.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to open file")
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to read file")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	dir := fontsDir(c)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create fonts directory")
+	}
+	if err := os.WriteFile(filepath.Join(dir, face.File), data, 0644); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save font: "+err.Error())
+	}
+
+	err = h.updateConfig("Failed to save fonts", func(config *models.Config) error {
+		fonts := &config.UI.Fonts
+		if i := fontFace(fonts, face.File); i >= 0 {
+			fonts.Faces[i] = face
+		} else {
+			fonts.Faces = append(fonts.Faces, face)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.APIResponse{
+		Status: "success",
+		Data:   newFontFaceResponse(c, face),
+	})
+}
+
+// DeleteFont removes a font face and deletes its file from the project
+// DELETE /api/fonts/:file
+func (h *ThemesHandler) DeleteFont(c *fiber.Ctx) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file := c.Params("file")
+	err := h.updateConfig("Failed to save fonts", func(config *models.Config) error {
+		fonts := &config.UI.Fonts
+		i := fontFace(fonts, file)
+		if i < 0 {
+			return fiber.NewError(fiber.StatusNotFound, "Font not found")
+		}
+		fonts.Faces = append(fonts.Faces[:i], fonts.Faces[i+1:]...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The name was validated when the face was added, so it stays in the folder
+	if err := os.Remove(filepath.Join(fontsDir(c), file)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete font file: "+err.Error())
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+	})
+}
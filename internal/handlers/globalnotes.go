@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// defaultGlobalNotesLimit is how many notes the timeline returns when the
+// request doesn't say
+const defaultGlobalNotesLimit = 50
+
+// GlobalNotesHandler serves the timeline of notes across registered folders
+type GlobalNotesHandler struct {
+	taskRegistry  *services.TaskRegistryService
+	projects      []models.Project
+	defaultFolder string
+}
+
+// NewGlobalNotesHandler creates a new global notes handler. projects are the
+// folders this server serves, which notes link back to; defaultFolder is the
+// server's own folder, used for users without configured folders.
+func NewGlobalNotesHandler(taskRegistry *services.TaskRegistryService, projects []models.Project, defaultFolder string) *GlobalNotesHandler {
+	return &GlobalNotesHandler{
+		taskRegistry:  taskRegistry,
+		projects:      projects,
+		defaultFolder: defaultFolder,
+	}
+}
+
+// GetGlobalNotes returns the notes of the registered folders the user can
+// access, newest first. offset and limit page through them; the number of
+// notes before paging is sent in X-Total-Count.
+// GET /api/global-notes
+func (h *GlobalNotesHandler) GetGlobalNotes(c *fiber.Ctx) error {
+	paging := map[string]int{"offset": 0, "limit": defaultGlobalNotesLimit}
+	for name := range paging {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, name+" must be a non-negative number")
+		}
+		paging[name] = parsed
+	}
+
+	user := CurrentUser(c)
+	include := func(folderPath string) bool {
+		return user.CanAccess(folderPath, h.defaultFolder)
+	}
+	notes, total, err := h.taskRegistry.GetGlobalNotes(include, paging["offset"], paging["limit"])
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get global notes: "+err.Error())
+	}
+
+	if notes == nil {
+		notes = []models.GlobalNote{}
+	}
+	for i := range notes {
+		note := &notes[i]
+		for _, project := range h.projects {
+			if project.Folder == note.FolderPath {
+				note.ProjectURL = project.URL
+				break
+			}
+		}
+		// Uploaded files are served by the note's own project
+		if note.ProjectURL != "" {
+			note.HTML = strings.ReplaceAll(note.HTML, `="/assets/`, `="`+note.ProjectURL+`assets/`)
+		}
+	}
+
+	c.Set("X-Total-Count", strconv.Itoa(total))
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data: models.GlobalNotesResponse{
+			Notes: notes,
+			Total: total,
+		},
+	})
+}
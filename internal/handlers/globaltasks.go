@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
+	"strings"
 
 	"github.com/darren/noteflow-go/internal/models"
 	"github.com/darren/noteflow-go/internal/services"
@@ -10,19 +12,29 @@ import (
 
 // GlobalTasksHandler handles global task management across folders
 type GlobalTasksHandler struct {
-	taskRegistry *services.TaskRegistryService
+	taskRegistry  *services.TaskRegistryService
+	defaultFolder string
 }
 
-// NewGlobalTasksHandler creates a new global tasks handler
-func NewGlobalTasksHandler(taskRegistry *services.TaskRegistryService) *GlobalTasksHandler {
+// NewGlobalTasksHandler creates a new global tasks handler. defaultFolder is
+// the server's own folder, used for users without configured folders.
+func NewGlobalTasksHandler(taskRegistry *services.TaskRegistryService, defaultFolder string) *GlobalTasksHandler {
 	return &GlobalTasksHandler{
-		taskRegistry: taskRegistry,
+		taskRegistry:  taskRegistry,
+		defaultFolder: defaultFolder,
 	}
 }
 
-// GetGlobalTasks returns all tasks across all registered folders
+// GetGlobalTasks returns the tasks across the registered folders the user can
+// access, narrowed by the folder, tag, context, due and priority query
+// parameters. Summaries cover every task of the listed folders.
 // GET /api/global-tasks
 func (gth *GlobalTasksHandler) GetGlobalTasks(c *fiber.Ctx) error {
+	query, err := globalTaskQuery(c)
+	if err != nil {
+		return err
+	}
+
 	globalTasks, err := gth.taskRegistry.GetGlobalTasks()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.APIResponse{
@@ -31,6 +43,26 @@ func (gth *GlobalTasksHandler) GetGlobalTasks(c *fiber.Ctx) error {
 		})
 	}
 
+	user := CurrentUser(c)
+	today := models.Now().Format("2006-01-02")
+	var tasks []models.GlobalTask
+	for _, task := range globalTasks.Tasks {
+		if user.CanAccess(task.FolderPath, gth.defaultFolder) && query.Matches(task.FolderPath, task.TaskMeta, today) {
+			tasks = append(tasks, task)
+		}
+	}
+	var summaries []models.TaskSummary
+	for _, summary := range globalTasks.Summaries {
+		if user.CanAccess(summary.FolderPath, gth.defaultFolder) && query.MatchesFolder(summary.FolderPath) {
+			summaries = append(summaries, summary)
+		}
+	}
+	globalTasks = &models.GlobalTasksResponse{
+		Tasks:     tasks,
+		Summaries: summaries,
+		Total:     len(tasks),
+	}
+
 	return c.JSON(models.APIResponse{
 		Status: "success",
 		Data:   globalTasks,
@@ -60,7 +92,15 @@ func (gth *GlobalTasksHandler) UpdateGlobalTask(c *fiber.Ctx) error {
 		})
 	}
 
-	err = gth.taskRegistry.UpdateGlobalTaskCompletion(taskID, req.Completed)
+	task, err := gth.taskRegistry.FindGlobalTask(taskID)
+	if err != nil || !CurrentUser(c).CanAccess(task.FolderPath, gth.defaultFolder) {
+		return c.Status(fiber.StatusNotFound).JSON(models.APIResponse{
+			Status:  "error",
+			Message: "Task not found",
+		})
+	}
+
+	err = gth.taskRegistry.UpdateGlobalTaskCompletion(taskID, req.Completed, currentUserName(c))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(models.APIResponse{
 			Status:  "error",
@@ -74,7 +114,76 @@ func (gth *GlobalTasksHandler) UpdateGlobalTask(c *fiber.Ctx) error {
 	})
 }
 
-// GetActiveFolders returns all active registered folders
+// RolloverTasks moves or copies open tasks into a note of another registered
+// folder, such as a weekly plan. The body lists the task_ids, the target
+// folder, the note title and a mode of "move" (the default) or "copy".
+// POST /api/global-tasks/rollover
+func (gth *GlobalTasksHandler) RolloverTasks(c *fiber.Ctx) error {
+	var req models.RolloverRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.APIResponse{
+			Status:  "error",
+			Message: "Invalid request body",
+		})
+	}
+	if err := req.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.APIResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+	}
+	req.Folder = models.ExpandHome(req.Folder)
+
+	user := CurrentUser(c)
+	include := func(folderPath string) bool {
+		return user.CanAccess(folderPath, gth.defaultFolder)
+	}
+	result, err := gth.taskRegistry.RolloverTasks(c.UserContext(), req, include, currentUserName(c))
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrFolderNotFound), errors.Is(err, services.ErrTaskNotFound):
+			status = fiber.StatusNotFound
+		case errors.Is(err, services.ErrTaskCompleted), errors.Is(err, services.ErrSameFolder):
+			status = fiber.StatusBadRequest
+		case errors.Is(err, services.ErrFolderUnavailable):
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(models.APIResponse{
+			Status:  "error",
+			Message: "Failed to roll over tasks: " + err.Error(),
+		})
+	}
+
+	return c.JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Tasks rolled over successfully",
+		Data:    result,
+	})
+}
+
+// GetGlobalStats returns the note counts, open tasks, completion rates and
+// disk usage of the registered folders the user can access, and their sums
+// GET /api/global-stats
+func (gth *GlobalTasksHandler) GetGlobalStats(c *fiber.Ctx) error {
+	user := CurrentUser(c)
+	stats, err := gth.taskRegistry.GetGlobalStats(func(folderPath string) bool {
+		return user.CanAccess(folderPath, gth.defaultFolder)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.APIResponse{
+			Status:  "error",
+			Message: "Failed to get global stats: " + err.Error(),
+		})
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   stats,
+	})
+}
+
+// GetActiveFolders returns the active registered folders the user can access
 // GET /api/global-folders
 func (gth *GlobalTasksHandler) GetActiveFolders(c *fiber.Ctx) error {
 	folders, err := gth.taskRegistry.GetActiveFolders()
@@ -85,12 +194,118 @@ func (gth *GlobalTasksHandler) GetActiveFolders(c *fiber.Ctx) error {
 		})
 	}
 
+	if user := CurrentUser(c); user != nil {
+		var accessible []models.FolderRegistry
+		for _, folder := range folders {
+			if user.CanAccess(folder.Path, gth.defaultFolder) {
+				accessible = append(accessible, folder)
+			}
+		}
+		folders = accessible
+	}
+
 	return c.JSON(models.APIResponse{
 		Status: "success",
 		Data:   folders,
 	})
 }
 
+// RegisterFolder adds a notes folder to the global tasks, so its tasks are
+// listed even though this server wasn't started in it
+// POST /api/global-folders
+func (gth *GlobalTasksHandler) RegisterFolder(c *fiber.Ctx) error {
+	var req struct {
+		Path string `json:"path"`
+	}
+
+	if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.Path) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(models.APIResponse{
+			Status:  "error",
+			Message: "A folder path is required",
+		})
+	}
+
+	path := models.ExpandHome(strings.TrimSpace(req.Path))
+	if !CurrentUser(c).CanAccess(path, gth.defaultFolder) {
+		return c.Status(fiber.StatusForbidden).JSON(models.APIResponse{
+			Status:  "error",
+			Message: "Folder not accessible",
+		})
+	}
+
+	folder, err := gth.taskRegistry.AddFolder(path)
+	if errors.Is(err, services.ErrFolderInvalid) {
+		return c.Status(fiber.StatusBadRequest).JSON(models.APIResponse{
+			Status:  "error",
+			Message: "Folder does not exist or has no notes.md",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.APIResponse{
+			Status:  "error",
+			Message: "Failed to register folder: " + err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.APIResponse{
+		Status: "success",
+		Data:   folder,
+	})
+}
+
+// UnregisterFolder removes a folder and its tasks from the global tasks.
+// Folders open in this server cannot be removed.
+// DELETE /api/global-folders/:id
+func (gth *GlobalTasksHandler) UnregisterFolder(c *fiber.Ctx) error {
+	folderID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.APIResponse{
+			Status:  "error",
+			Message: "Invalid folder ID",
+		})
+	}
+
+	folders, err := gth.taskRegistry.GetActiveFolders()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.APIResponse{
+			Status:  "error",
+			Message: "Failed to get folders: " + err.Error(),
+		})
+	}
+	for _, folder := range folders {
+		if folder.ID == folderID && !CurrentUser(c).CanAccess(folder.Path, gth.defaultFolder) {
+			return c.Status(fiber.StatusNotFound).JSON(models.APIResponse{
+				Status:  "error",
+				Message: "Folder not found",
+			})
+		}
+	}
+
+	err = gth.taskRegistry.RemoveFolder(folderID)
+	switch {
+	case errors.Is(err, services.ErrFolderNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(models.APIResponse{
+			Status:  "error",
+			Message: "Folder not found",
+		})
+	case errors.Is(err, services.ErrFolderOpen):
+		return c.Status(fiber.StatusConflict).JSON(models.APIResponse{
+			Status:  "error",
+			Message: "Folders open in this server cannot be removed",
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(models.APIResponse{
+			Status:  "error",
+			Message: "Failed to remove folder: " + err.Error(),
+		})
+	}
+
+	return c.JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Folder removed",
+	})
+}
+
 // ForceSync forces a sync of all registered folders
 // POST /api/global-sync
 func (gth *GlobalTasksHandler) ForceSync(c *fiber.Ctx) error {
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"io"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ImportNotes converts uploaded markdown files, or zip archives of them, into
+// notes and reports what became of each file. Files are sent in the "files"
+// form field; a single "file" field is also accepted.
+// POST /api/import
+func (h *NotesHandler) ImportNotes(c *fiber.Ctx) error {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Expected a multipart form with files to import")
+	}
+
+	uploads := append(form.File["files"], form.File["file"]...)
+	if len(uploads) == 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "No files provided")
+	}
+
+	var files []models.ImportFile
+	for _, upload := range uploads {
+		read, err := readImportUpload(upload)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, upload.Filename+": "+err.Error())
+		}
+		files = append(files, read...)
+	}
+
+	report, err := h.manager(c).ImportNotes(files, currentUserName(c))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to import notes: "+err.Error())
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   report,
+	})
+}
+
+// readImportUpload returns the files in an uploaded zip archive, or the
+// uploaded file itself
+func readImportUpload(upload *multipart.FileHeader) ([]models.ImportFile, error) {
+	file, err := upload.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(upload.Filename), ".zip") {
+		return services.ReadImportZip(file, upload.Size)
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return []models.ImportFile{{Name: upload.Filename, Content: content}}, nil
+}
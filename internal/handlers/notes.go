@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"bufio"
+	"log/slog"
 	"strconv"
+	"strings"
 
 	"github.com/darren/noteflow-go/internal/models"
 	"github.com/darren/noteflow-go/internal/services"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
 )
 
 // NotesHandler handles note-related HTTP requests
@@ -20,26 +24,126 @@ func NewNotesHandler(noteManager *services.NoteManager) *NotesHandler {
 	}
 }
 
-// GetNotes returns all notes as HTML
+// manager returns the project the request operates on
+func (h *NotesHandler) manager(c *fiber.Ctx) *services.NoteManager {
+	return noteManagerFor(c, h.noteManager)
+}
+
+// GetNotes returns the notes matching the query string as HTML. The number of
+// matches before paging is sent in X-Total-Count, and the notes are streamed
+// as they are rendered.
 func (h *NotesHandler) GetNotes(c *fiber.Ctx) error {
-	html, err := h.noteManager.RenderNotesHTML()
+	query, err := noteQuery(c)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to render notes as html: "+err.Error())
+		return err
+	}
+
+	if notModified(c, h.manager(c).Revision()) {
+		return c.SendStatus(fiber.StatusNotModified)
 	}
 
+	page := h.manager(c).QueryNotes(query)
+	prefix := URLPrefix(c)
+
+	c.Set("X-Total-Count", strconv.Itoa(page.Total))
 	c.Set("Content-Type", "text/html")
-	return c.SendString(html)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := page.WriteHTML(urlPrefixWriter{w: w, prefix: prefix}); err != nil {
+			slog.Warn("failed to stream notes as html", "error", err)
+		}
+	})
+	return nil
 }
 
-// GetNotes returns all notes as JSON
+// GetNotesJSON returns the notes matching the query string as JSON, each with
+// its index. The number of matches before paging is sent in X-Total-Count,
+// and the notes are streamed one at a time.
 func (h *NotesHandler) GetNotesJSON(c *fiber.Ctx) error {
-	json, err := h.noteManager.RenderNotesJSON()
+	query, err := noteQuery(c)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to render notes as json: "+err.Error())
+		return err
+	}
+
+	if notModified(c, h.manager(c).Revision()) {
+		return c.SendStatus(fiber.StatusNotModified)
 	}
 
+	page := h.manager(c).QueryNotes(query)
+
+	c.Set("X-Total-Count", strconv.Itoa(page.Total))
 	c.Set("Content-Type", "application/json")
-	return c.SendString(json)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := page.WriteJSON(w); err != nil {
+			slog.Warn("failed to stream notes as json", "error", err)
+		}
+	})
+	return nil
+}
+
+// GetTags lists the tags used in the project's notes with the number of
+// notes carrying each, most used first. Notes with a tag are listed by
+// GET /api/notes?tag=...
+// GET /api/tags
+func (h *NotesHandler) GetTags(c *fiber.Ctx) error {
+	if notModified(c, h.manager(c).Revision()) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   h.manager(c).TagCounts(),
+	})
+}
+
+// defaultSearchLimit is how many search results are returned without a limit
+const defaultSearchLimit = 20
+
+// Search finds the notes containing every word of q in their title, content,
+// tasks or attached files, best matches first, with snippets of where the
+// words were found. offset and limit page through the results; the number
+// found is sent in X-Total-Count.
+// GET /api/search?q=...
+func (h *NotesHandler) Search(c *fiber.Ctx) error {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "q is required")
+	}
+
+	offset, limit := 0, defaultSearchLimit
+	for name, target := range map[string]*int{
+		"offset": &offset,
+		"limit":  &limit,
+	} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return fiber.NewError(fiber.StatusBadRequest, name+" must be a non-negative number")
+		}
+		*target = parsed
+	}
+
+	if notModified(c, h.manager(c).Revision()) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	results, total := h.manager(c).Search(q, offset, limit)
+	c.Set("X-Total-Count", strconv.Itoa(total))
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   results,
+	})
+}
+
+// SaveStatus reports whether the project's changes have been written to its
+// notes.md
+// GET /api/save-status
+func (h *NotesHandler) SaveStatus(c *fiber.Ctx) error {
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   h.manager(c).SaveStatus(),
+	})
 }
 
 // AddNote creates a new note
@@ -66,12 +170,94 @@ func (h *NotesHandler) AddNote(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Content cannot be empty")
 	}
 
-	if err := h.noteManager.AddNote(title, content); err != nil {
+	if err := h.manager(c).AddNote(c.UserContext(), title, content, currentUserName(c)); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to add note: "+err.Error())
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+	})
+}
+
+// CaptureNote adds a note from a minimal request, for hotkey tools, Shortcuts
+// and curl one-liners. The body is the note as plain text, with an optional
+// title in ?title=, or JSON or form data with title and content.
+func (h *NotesHandler) CaptureNote(c *fiber.Ctx) error {
+	// Copy values kept after the handler returns; Fiber reuses their memory
+	title := utils.CopyString(c.Query("title"))
+	var content string
+
+	switch contentType := c.Get("Content-Type"); {
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationJSON):
+		var req models.NoteRequest
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid JSON request format")
+		}
+		if req.Title != "" {
+			title = req.Title
+		}
+		content = req.Content
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationForm), strings.HasPrefix(contentType, fiber.MIMEMultipartForm):
+		if formTitle := c.FormValue("title"); formTitle != "" {
+			title = utils.CopyString(formTitle)
+		}
+		content = utils.CopyString(c.FormValue("content"))
+	default:
+		content = string(c.Body())
+	}
+
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Content cannot be empty")
+	}
+
+	if err := h.manager(c).AddNote(c.UserContext(), strings.TrimSpace(title), content, currentUserName(c)); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to add note: "+err.Error())
 	}
 
+	return c.Status(fiber.StatusCreated).JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Note captured",
+	})
+}
+
+// SyncNotes adds notes captured while the web UI was offline. Each note is
+// reported separately so the client can drop the ones that were saved and
+// retry the rest; notes it already sent are reported as duplicates.
+func (h *NotesHandler) SyncNotes(c *fiber.Ctx) error {
+	var req models.SyncRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid JSON request format")
+	}
+
+	results := make([]models.SyncResult, 0, len(req.Notes))
+	for _, note := range req.Notes {
+		result := models.SyncResult{ID: note.ID}
+		switch {
+		case note.ID == "":
+			result.Status = "error"
+			result.Error = "note ID is required"
+		case note.Content == "":
+			result.Status = "error"
+			result.Error = "Content cannot be empty"
+		default:
+			created, err := h.manager(c).SyncNote(c.UserContext(), note.ID, note.Title, note.Content, currentUserName(c), note.CapturedAt)
+			switch {
+			case err != nil:
+				result.Status = "error"
+				result.Error = "Failed to add note: " + err.Error()
+			case created:
+				result.Status = "created"
+			default:
+				result.Status = "duplicate"
+			}
+		}
+		results = append(results, result)
+	}
+
 	return c.JSON(models.APIResponse{
 		Status: "success",
+		Data:   results,
 	})
 }
 
@@ -83,15 +269,26 @@ func (h *NotesHandler) GetNote(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid note index")
 	}
 
-	note, err := h.noteManager.GetNote(index)
+	note, err := h.manager(c).GetNote(index)
 	if err != nil {
 		return fiber.NewError(fiber.StatusNotFound, "Note not found")
 	}
 
+	tags := note.Tags()
+	if tags == nil {
+		tags = []string{}
+	}
 	response := map[string]interface{}{
-		"timestamp": note.Timestamp.Format("2006-01-02 15:04:05"),
+		"timestamp": models.FormatTimestamp(note.Timestamp),
+		"modified":  models.FormatTimestamp(note.Modified),
 		"content":   note.Content,
 		"title":     note.Title,
+		"author":    note.Author,
+		"edited_by": note.EditedBy,
+		"tags":      tags,
+	}
+	if note.Summary != "" {
+		response["summary"] = note.Summary
 	}
 
 	return c.JSON(response)
@@ -123,7 +320,7 @@ func (h *NotesHandler) UpdateNote(c *fiber.Ctx) error {
 		content = c.FormValue("content")
 	}
 
-	if err := h.noteManager.UpdateNote(index, title, content); err != nil {
+	if err := h.manager(c).UpdateNote(c.UserContext(), index, title, content, currentUserName(c)); err != nil {
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update note: "+err.Error())
 	}
 
@@ -132,6 +329,48 @@ func (h *NotesHandler) UpdateNote(c *fiber.Ctx) error {
 	})
 }
 
+// PinNote pins or unpins a note
+func (h *NotesHandler) PinNote(c *fiber.Ctx) error {
+	index, err := strconv.Atoi(c.Params("index"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid note index")
+	}
+
+	var req models.PinRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := h.manager(c).SetPinned(index, req.Pinned, currentUserName(c)); err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Note not found")
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+	})
+}
+
+// ExportNote downloads a note with the uploads it links to as a zip file
+// GET /api/notes/:index/export
+func (h *NotesHandler) ExportNote(c *fiber.Ctx) error {
+	index, err := strconv.Atoi(c.Params("index"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid note index")
+	}
+
+	note, err := h.manager(c).GetNote(index)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Note not found")
+	}
+
+	c.Set("Content-Type", "application/zip")
+	c.Set("Content-Disposition", `attachment; filename="note-`+note.AssetFolder()+`.zip"`)
+	if err := h.manager(c).ExportNote(index, c.Response().BodyWriter()); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to export note: "+err.Error())
+	}
+	return nil
+}
+
 // DeleteNote deletes a specific note
 func (h *NotesHandler) DeleteNote(c *fiber.Ctx) error {
 	indexStr := c.Params("index")
@@ -140,7 +379,7 @@ func (h *NotesHandler) DeleteNote(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid note index")
 	}
 
-	if err := h.noteManager.DeleteNote(index); err != nil {
+	if err := h.manager(c).DeleteNote(index, currentUserName(c)); err != nil {
 		return fiber.NewError(fiber.StatusNotFound, "Note not found")
 	}
 
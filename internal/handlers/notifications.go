@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// NotificationsHandler sends notifications on request, to try the channels
+type NotificationsHandler struct {
+	noteManager   *services.NoteManager
+	notifications *services.NotificationService
+}
+
+// NewNotificationsHandler creates a notifications handler
+func NewNotificationsHandler(noteManager *services.NoteManager, notifications *services.NotificationService) *NotificationsHandler {
+	return &NotificationsHandler{
+		noteManager:   noteManager,
+		notifications: notifications,
+	}
+}
+
+// SendDigest sends the project's daily digest to the notification channels
+// now
+// POST /api/notifications/digest
+func (h *NotificationsHandler) SendDigest(c *fiber.Ctx) error {
+	err := h.notifications.SendDigest(noteManagerFor(c, h.noteManager))
+	if errors.Is(err, services.ErrNotificationsOff) {
+		return fiber.NewError(fiber.StatusNotFound, "Notifications are not configured")
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, err.Error())
+	}
+
+	return c.JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Digest sent",
+	})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ProjectsHandler lists the project folders served by this server
+type ProjectsHandler struct {
+	projects      []models.Project
+	defaultFolder string
+}
+
+// NewProjectsHandler creates a new projects handler. defaultFolder is the
+// folder the server was started in, used for users without folders of their own.
+func NewProjectsHandler(projects []models.Project, defaultFolder string) *ProjectsHandler {
+	return &ProjectsHandler{
+		projects:      projects,
+		defaultFolder: defaultFolder,
+	}
+}
+
+// GetProjects returns the projects the user can access, marking the one the
+// request was made in
+func (h *ProjectsHandler) GetProjects(c *fiber.Ctx) error {
+	user := CurrentUser(c)
+	current := ""
+	if noteManager := CurrentNoteManager(c); noteManager != nil {
+		current = noteManager.GetBasePath()
+	}
+
+	projects := make([]models.Project, 0, len(h.projects))
+	for _, project := range h.projects {
+		if !user.CanAccess(project.Folder, h.defaultFolder) {
+			continue
+		}
+		project.Current = project.Folder == current
+		projects = append(projects, project)
+	}
+
+	return c.JSON(projects)
+}
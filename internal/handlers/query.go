@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// noteQuery reads the note filters from the request's query string:
+// q, tag, has_tasks, pinned, archived, sort, order, offset and limit
+func noteQuery(c *fiber.Ctx) (models.NoteQuery, error) {
+	query := models.NoteQuery{
+		Q:     c.Query("q"),
+		Tag:   c.Query("tag"),
+		Sort:  strings.ToLower(c.Query("sort", models.SortCreated)),
+		Order: strings.ToLower(c.Query("order")),
+	}
+	if err := query.Validate(); err != nil {
+		return query, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	for name, target := range map[string]**bool{
+		"has_tasks": &query.HasTasks,
+		"pinned":    &query.Pinned,
+		"archived":  &query.Archived,
+	} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, fiber.NewError(fiber.StatusBadRequest, name+" must be true or false")
+		}
+		*target = &parsed
+	}
+
+	for name, target := range map[string]*int{
+		"offset": &query.Offset,
+		"limit":  &query.Limit,
+	} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return query, fiber.NewError(fiber.StatusBadRequest, name+" must be a non-negative number")
+		}
+		*target = parsed
+	}
+
+	return query, nil
+}
+
+// globalTaskQuery reads the folder, tag, context, due and priority filters of
+// a global task listing from the query string
+func globalTaskQuery(c *fiber.Ctx) (models.GlobalTaskQuery, error) {
+	query := models.GlobalTaskQuery{
+		Folder:   c.Query("folder"),
+		Tag:      c.Query("tag"),
+		Context:  c.Query("context"),
+		Due:      strings.ToLower(c.Query("due")),
+		Priority: strings.ToUpper(c.Query("priority")),
+	}
+	if err := query.Validate(); err != nil {
+		return query, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return query, nil
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetShortcuts returns the keyboard shortcuts, with defaults filled in
+// GET /api/shortcuts
+func (h *ThemesHandler) GetShortcuts(c *fiber.Ctx) error {
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   h.config.Current().UI.Shortcuts.WithDefaults(),
+	})
+}
+
+// SetShortcuts replaces the keyboard shortcuts. An empty or missing field
+// restores that action's default, and "none" turns it off.
+// PUT /api/shortcuts
+func (h *ThemesHandler) SetShortcuts(c *fiber.Ctx) error {
+	var req models.ShortcutConfig
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+	if err := req.Normalize(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err := req.Validate(); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	err := h.updateConfig("Failed to save shortcuts", func(config *models.Config) error {
+		config.UI.Shortcuts = req
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   req.WithDefaults(),
+	})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// slackHelp answers slash commands sent without text
+const slackHelp = "Add a note with `/note remember to ship v2`. Commands named /task add an open task."
+
+// SlackHandler takes notes sent with a Slack slash command
+type SlackHandler struct {
+	noteManager *services.NoteManager
+	config      *services.ConfigStore
+}
+
+// NewSlackHandler creates a Slack handler reading its settings from config,
+// which may change while the server runs
+func NewSlackHandler(noteManager *services.NoteManager, config *services.ConfigStore) *SlackHandler {
+	return &SlackHandler{
+		noteManager: noteManager,
+		config:      config,
+	}
+}
+
+// slackReply is a slash command response, shown only to the user who sent
+// the command
+type slackReply struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// Command saves the text of a slash command signed with the configured
+// signing secret as a note, or as an open task for commands named /task.
+// Notes are appended to the note titled slack.note_title when it is set.
+// POST /api/slack/command
+func (h *SlackHandler) Command(c *fiber.Ctx) error {
+	config := h.config.Current().Slack
+	if config.SigningSecret == "" {
+		return fiber.NewError(fiber.StatusNotFound, "Slack commands are not configured")
+	}
+	err := services.VerifySlackRequest(config.SigningSecret, c.Get("X-Slack-Request-Timestamp"), c.Get("X-Slack-Signature"), c.Body())
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+	}
+
+	var command models.SlackCommand
+	if err := c.BodyParser(&command); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+	text := strings.TrimSpace(utils.CopyString(command.Text))
+	if text == "" {
+		return c.JSON(slackReply{ResponseType: "ephemeral", Text: slackHelp})
+	}
+
+	reply := "✓ Saved"
+	if strings.EqualFold(command.Command, "/task") {
+		text = "- [ ] " + text
+		reply = "✓ Task added"
+	}
+
+	manager := noteManagerFor(c, h.noteManager)
+	if title := config.NoteTitle; title != "" {
+		_, _, err = manager.AppendToNote(c.UserContext(), title, text, "")
+	} else {
+		err = manager.AddNote(c.UserContext(), "", text, "")
+	}
+	if err != nil {
+		return c.JSON(slackReply{ResponseType: "ephemeral", Text: "The note wasn't saved: " + err.Error()})
+	}
+	return c.JSON(slackReply{ResponseType: "ephemeral", Text: reply})
+}
@@ -20,9 +20,18 @@ func NewTasksHandler(noteManager *services.NoteManager) *TasksHandler {
 	}
 }
 
+// manager returns the project the request operates on
+func (h *TasksHandler) manager(c *fiber.Ctx) *services.NoteManager {
+	return noteManagerFor(c, h.noteManager)
+}
+
 // GetTasks returns all active tasks as JSON
 func (h *TasksHandler) GetTasks(c *fiber.Ctx) error {
-	tasks := h.noteManager.GetActiveTasks()
+	if notModified(c, h.manager(c).Revision()) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	tasks := h.manager(c).GetActiveTasks()
 	return c.JSON(tasks)
 }
 
@@ -39,7 +48,7 @@ func (h *TasksHandler) UpdateTask(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
 	}
 
-	if err := h.noteManager.UpdateTask(index, req.Checked); err != nil {
+	if err := h.manager(c).UpdateTask(index, req.Checked, currentUserName(c)); err != nil {
 		return fiber.NewError(fiber.StatusNotFound, "Task not found: "+err.Error())
 	}
 
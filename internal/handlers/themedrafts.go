@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/themes"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Limits on draft themes, which are kept in memory only
+const (
+	themeDraftTTL   = time.Hour
+	maxThemeDrafts  = 20
+	themeDraftIDLen = 8
+)
+
+// themeDraft is a theme being edited, previewed before it is saved
+type themeDraft struct {
+	theme   *models.Theme
+	expires time.Time
+}
+
+// themeDraftResponse describes a draft and where to preview it
+type themeDraftResponse struct {
+	ID         string        `json:"id"`
+	Theme      *models.Theme `json:"theme"`
+	PreviewURL string        `json:"preview_url"`
+}
+
+// newThemeDraftResponse describes the draft with id for an API response
+func newThemeDraftResponse(c *fiber.Ctx, id string, draft *themeDraft) themeDraftResponse {
+	return themeDraftResponse{
+		ID:         id,
+		Theme:      draft.theme,
+		PreviewURL: URLPrefix(c) + "/api/v1/themes/drafts/" + id + "/preview",
+	}
+}
+
+// draft returns the unexpired draft with id. The caller must hold h.mu.
+func (h *ThemesHandler) draft(id string) (*themeDraft, error) {
+	draft, ok := h.drafts[id]
+	if !ok || time.Now().After(draft.expires) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Theme draft not found")
+	}
+	return draft, nil
+}
+
+// CreateDraft starts editing a theme without saving it. The request is a
+// theme as for CreateTheme; naming an existing theme as its base starts from
+// that theme's colors.
+// POST /api/themes/drafts
+func (h *ThemesHandler) CreateDraft(c *fiber.Ctx) error {
+	var req models.ThemeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	theme, err := themes.Draft(h.config.Current(), &req)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	now := time.Now()
+	for id, draft := range h.drafts {
+		if now.After(draft.expires) {
+			delete(h.drafts, id)
+		}
+	}
+	if len(h.drafts) >= maxThemeDrafts {
+		return fiber.NewError(fiber.StatusTooManyRequests, "Too many theme drafts; commit or delete one first")
+	}
+
+	bytes := make([]byte, themeDraftIDLen)
+	if _, err := rand.Read(bytes); err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create draft")
+	}
+	id := hex.EncodeToString(bytes)
+	draft := &themeDraft{theme: theme, expires: now.Add(themeDraftTTL)}
+	h.drafts[id] = draft
+
+	return c.Status(fiber.StatusCreated).JSON(models.APIResponse{
+		Status: "success",
+		Data:   newThemeDraftResponse(c, id, draft),
+	})
+}
+
+// UpdateDraft changes a draft's colors. Colors missing from the request keep
+// their draft values unless it names a base theme.
+// PUT /api/themes/drafts/:id
+func (h *ThemesHandler) UpdateDraft(c *fiber.Ctx) error {
+	var req models.ThemeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	id := c.Params("id")
+	draft, err := h.draft(id)
+	if err != nil {
+		return err
+	}
+
+	if req.Name == "" {
+		req.Name = draft.theme.Name
+	}
+	if req.Base == "" {
+		colors := make(map[string]string, len(draft.theme.Colors)+len(req.Colors))
+		for key, value := range draft.theme.Colors {
+			colors[key] = value
+		}
+		for key, value := range req.Colors {
+			colors[key] = value
+		}
+		req.Colors = colors
+	}
+	theme, err := themes.Draft(h.config.Current(), &req)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	draft.theme = theme
+	draft.expires = time.Now().Add(themeDraftTTL)
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   newThemeDraftResponse(c, id, draft),
+	})
+}
+
+// PreviewDraft renders the main page with a draft theme applied, without
+// saving it. The page is read-only so trying it out cannot change notes.
+// GET /api/themes/drafts/:id/preview
+func (h *ThemesHandler) PreviewDraft(c *fiber.Ctx) error {
+	h.mu.RLock()
+	draft, err := h.draft(c.Params("id"))
+	var theme *models.Theme
+	if err == nil {
+		theme = draft.theme
+	}
+	h.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	html, err := h.templates.PreviewIndex(h.config.Current(), theme, CurrentNoteManager(c).GetBasePath(), URLPrefix(c))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to render preview: "+err.Error())
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.SendString(html)
+}
+
+// CommitDraft saves a draft as a custom theme, replacing a custom theme of
+// the same name, and selects it when asked to
+// POST /api/themes/drafts/:id/commit
+func (h *ThemesHandler) CommitDraft(c *fiber.Ctx) error {
+	var req models.ThemeCommitRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	id := c.Params("id")
+	draft, err := h.draft(id)
+	if err != nil {
+		return err
+	}
+
+	theme := &models.Theme{Name: draft.theme.Name, Colors: draft.theme.Colors}
+	if req.Name != "" {
+		theme.Name = req.Name
+	}
+	if err := themes.Validate(theme); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	err = h.updateConfig("Failed to save theme", func(config *models.Config) error {
+		if i := customTheme(config, theme.Name); i >= 0 {
+			config.Themes[i] = theme
+		} else {
+			config.Themes = append(config.Themes, theme)
+		}
+		if req.Select {
+			config.Theme = theme.Name
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	delete(h.drafts, id)
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   themeResponse{Theme: theme, Custom: true},
+	})
+}
+
+// DeleteDraft discards a draft
+// DELETE /api/themes/drafts/:id
+func (h *ThemesHandler) DeleteDraft(c *fiber.Ctx) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	id := c.Params("id")
+	if _, err := h.draft(id); err != nil {
+		return err
+	}
+	delete(h.drafts, id)
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+	})
+}
@@ -1,39 +1,56 @@
 package handlers
 
 import (
+	"errors"
+	"sync"
+
 	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
 	"github.com/darren/noteflow-go/internal/themes"
 	"github.com/gofiber/fiber/v2"
 )
 
 // ThemesHandler handles theme-related requests
 type ThemesHandler struct {
-	config     *models.Config
-	configPath string
+	config    *services.ConfigStore
+	templates *services.TemplateService
+	// mu guards the drafts and the font files
+	mu sync.RWMutex
+
+	// drafts are themes being edited, by ID, previewed before being saved
+	drafts map[string]*themeDraft
 }
 
-// NewThemesHandler creates a new themes handler
-func NewThemesHandler(config *models.Config, configPath string) *ThemesHandler {
+// NewThemesHandler creates a new themes handler, saving theme and page
+// settings to config. templates renders previews of draft themes.
+func NewThemesHandler(config *services.ConfigStore, templates *services.TemplateService) *ThemesHandler {
 	return &ThemesHandler{
-		config:     config,
-		configPath: configPath,
+		config:    config,
+		templates: templates,
+		drafts:    make(map[string]*themeDraft),
 	}
 }
 
+// updateConfig saves the settings as changed by change. An error from change
+// is returned as it is; failing to save is reported with message.
+func (h *ThemesHandler) updateConfig(message string, change func(config *models.Config) error) error {
+	_, err := h.config.Update(change)
+	var fiberErr *fiber.Error
+	if err != nil && !errors.As(err, &fiberErr) {
+		return fiber.NewError(fiber.StatusInternalServerError, message)
+	}
+	return err
+}
 
-// GetThemes returns the list of available themes
+// GetThemes returns the names of the built-in and custom themes
 func (h *ThemesHandler) GetThemes(c *fiber.Ctx) error {
-	var themeNames []string
-	for name := range themes.AvailableThemes {
-		themeNames = append(themeNames, name)
-	}
-	return c.JSON(themeNames)
+	return c.JSON(themes.Names(h.config.Current()))
 }
 
 // GetCurrentTheme returns the currently active theme
 func (h *ThemesHandler) GetCurrentTheme(c *fiber.Ctx) error {
 	return c.JSON(map[string]string{
-		"theme": h.config.Theme,
+		"theme": h.config.Current().Theme,
 	})
 }
 
@@ -47,8 +64,8 @@ func (h *ThemesHandler) SetTheme(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
 	}
 
-	theme, exists := themes.AvailableThemes[req.Theme]
-	if !exists {
+	theme := themes.Find(h.config.Current(), req.Theme)
+	if theme == nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid theme")
 	}
 
@@ -68,19 +85,139 @@ func (h *ThemesHandler) SaveTheme(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
 	}
 
-	if _, exists := themes.AvailableThemes[req.Theme]; !exists {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid theme")
+	err := h.updateConfig("Failed to save theme preference", func(config *models.Config) error {
+		if themes.Find(config, req.Theme) == nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid theme")
+		}
+		config.Theme = req.Theme
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// Update config
-	h.config.Theme = req.Theme
+	return c.JSON(models.APIResponse{
+		Status: "success",
+	})
+}
+
+// themeResponse describes a theme and whether it is a custom one
+type themeResponse struct {
+	*models.Theme
+	Custom bool `json:"custom"`
+}
 
-	// Save to file
-	if err := models.SaveConfig(h.config, h.configPath); err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save theme preference")
+// GetTheme returns the colors of a theme
+// GET /api/themes/:name
+func (h *ThemesHandler) GetTheme(c *fiber.Ctx) error {
+	name := c.Params("name")
+	theme := themes.Find(h.config.Current(), name)
+	if theme == nil {
+		return fiber.NewError(fiber.StatusNotFound, "Theme not found")
 	}
 
 	return c.JSON(models.APIResponse{
 		Status: "success",
+		Data:   themeResponse{Theme: theme, Custom: !themes.IsBuiltin(name)},
+	})
+}
+
+// CreateTheme adds a custom theme and saves it to the config file, making it
+// selectable right away
+// POST /api/themes
+func (h *ThemesHandler) CreateTheme(c *fiber.Ctx) error {
+	var req models.ThemeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	var theme *models.Theme
+	err := h.updateConfig("Failed to save theme", func(config *models.Config) error {
+		if themes.Find(config, req.Name) != nil {
+			return fiber.NewError(fiber.StatusConflict, "A theme with this name already exists")
+		}
+		var err error
+		if theme, err = themes.New(config, &req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		config.Themes = append(config.Themes, theme)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.APIResponse{
+		Status: "success",
+		Data:   themeResponse{Theme: theme, Custom: true},
 	})
-}
\ No newline at end of file
+}
+
+// UpdateTheme replaces the colors of a custom theme
+// PUT /api/themes/:name
+func (h *ThemesHandler) UpdateTheme(c *fiber.Ctx) error {
+	var req models.ThemeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+	req.Name = c.Params("name")
+
+	var theme *models.Theme
+	err := h.updateConfig("Failed to save theme", func(config *models.Config) error {
+		i := customTheme(config, req.Name)
+		if i < 0 {
+			return fiber.NewError(fiber.StatusNotFound, "Custom theme not found")
+		}
+		var err error
+		if theme, err = themes.New(config, &req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		config.Themes[i] = theme
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   themeResponse{Theme: theme, Custom: true},
+	})
+}
+
+// DeleteTheme removes a custom theme. If it was selected, the default theme
+// is selected instead.
+// DELETE /api/themes/:name
+func (h *ThemesHandler) DeleteTheme(c *fiber.Ctx) error {
+	name := c.Params("name")
+
+	err := h.updateConfig("Failed to save config", func(config *models.Config) error {
+		i := customTheme(config, name)
+		if i < 0 {
+			return fiber.NewError(fiber.StatusNotFound, "Custom theme not found")
+		}
+		config.Themes = append(config.Themes[:i], config.Themes[i+1:]...)
+		if config.Theme == name {
+			config.Theme = themes.DefaultTheme
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+	})
+}
+
+// customTheme returns the position of the custom theme named name in config,
+// or -1
+func customTheme(config *models.Config, name string) int {
+	for i, theme := range config.Themes {
+		if theme != nil && theme.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TodoistHandler reports on and runs the Todoist sync
+type TodoistHandler struct {
+	sync *services.TodoistSync
+}
+
+// NewTodoistHandler creates a Todoist handler; sync is nil when Todoist
+// isn't configured
+func NewTodoistHandler(sync *services.TodoistSync) *TodoistHandler {
+	return &TodoistHandler{
+		sync: sync,
+	}
+}
+
+// GetStatus reports when the tasks were last synced with Todoist, what
+// changed and the last error
+// GET /api/todoist/status
+func (h *TodoistHandler) GetStatus(c *fiber.Ctx) error {
+	if h.sync == nil {
+		return fiber.NewError(fiber.StatusNotFound, "Todoist sync is not configured")
+	}
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   h.sync.Status(),
+	})
+}
+
+// Sync starts a sync with Todoist without waiting for the next interval
+// POST /api/todoist/sync
+func (h *TodoistHandler) Sync(c *fiber.Ctx) error {
+	if h.sync == nil {
+		return fiber.NewError(fiber.StatusNotFound, "Todoist sync is not configured")
+	}
+	h.sync.SyncNow()
+	return c.Status(fiber.StatusAccepted).JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Todoist sync started",
+	})
+}
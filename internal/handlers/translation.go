@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/darren/noteflow-go/internal/services/translate"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TranslationHandler translates notes with the configured translation
+// service
+type TranslationHandler struct {
+	noteManager *services.NoteManager
+	config      *services.ConfigStore
+}
+
+// NewTranslationHandler creates a translation handler; the settings in config
+// are read on every request, so changes to them apply at once
+func NewTranslationHandler(noteManager *services.NoteManager, config *services.ConfigStore) *TranslationHandler {
+	return &TranslationHandler{
+		noteManager: noteManager,
+		config:      config,
+	}
+}
+
+// Translate translates a note to the language given as to=de and stores the
+// translation, replacing an earlier one to that language: in a block at the
+// end of the note, or as a linked note with store=note
+// POST /api/notes/:index/translate
+func (h *TranslationHandler) Translate(c *fiber.Ctx) error {
+	index, err := strconv.Atoi(c.Params("index"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid note index")
+	}
+	language := c.Query("to")
+	if !models.TranslationLanguagePattern.MatchString(language) {
+		return fiber.NewError(fiber.StatusBadRequest, "to must be a language code such as de or pt-BR")
+	}
+	config := h.config.Current()
+	store := c.Query("store", config.Translation.Store)
+	switch store {
+	case "":
+		store = models.TranslationStoreBlock
+	case models.TranslationStoreBlock, models.TranslationStoreNote:
+	default:
+		return fiber.NewError(fiber.StatusBadRequest, "store must be block or note")
+	}
+
+	manager := noteManagerFor(c, h.noteManager)
+	note, err := manager.GetNote(index)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Note not found")
+	}
+
+	translator, err := translate.New(config.Translation, config.AI)
+	if errors.Is(err, translate.ErrNotConfigured) {
+		return fiber.NewError(fiber.StatusNotFound, "Translation is not configured")
+	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+
+	// A block sits under the original's title, so only a linked note needs
+	// one translated
+	title := ""
+	if store == models.TranslationStoreNote {
+		title = note.Title
+	}
+	translatedTitle, text, err := translate.Note(c.UserContext(), translator, title, services.TranslationSource(note.Content), language)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, "Failed to translate note: "+err.Error())
+	}
+	if store == models.TranslationStoreNote && translatedTitle == "" {
+		translatedTitle = note.Title + " (" + language + ")"
+	}
+
+	// Notes added or deleted while the service was busy move the others
+	if current, err := manager.GetNote(index); err != nil || !current.Timestamp.Equal(note.Timestamp) {
+		return fiber.NewError(fiber.StatusConflict, "The note changed while it was translated")
+	}
+	translation, err := manager.SaveTranslation(index, language, store, translatedTitle, text, currentUserName(c))
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to save translation: "+err.Error())
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   translation,
+	})
+}
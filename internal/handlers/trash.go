@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// trashError maps a trash service error to an HTTP error
+func trashError(err error) error {
+	if errors.Is(err, services.ErrNotInTrash) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+}
+
+// GetTrash lists the deleted notes, most recently deleted first, with when
+// each is purged for good
+// GET /api/trash
+func (h *NotesHandler) GetTrash(c *fiber.Ctx) error {
+	items, err := h.manager(c).Trash()
+	if err != nil {
+		return trashError(err)
+	}
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   items,
+	})
+}
+
+// RestoreNote moves a deleted note back among the notes and returns its index
+// POST /api/trash/:id/restore
+func (h *NotesHandler) RestoreNote(c *fiber.Ctx) error {
+	index, err := h.manager(c).RestoreNote(c.Params("id"), currentUserName(c))
+	if err != nil {
+		return trashError(err)
+	}
+	return c.JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Note restored",
+		Data:    map[string]interface{}{"index": index},
+	})
+}
+
+// PurgeNote deletes a note in the trash, and its uploads, for good
+// DELETE /api/trash/:id
+func (h *NotesHandler) PurgeNote(c *fiber.Ctx) error {
+	if err := h.manager(c).PurgeNote(c.Params("id")); err != nil {
+		return trashError(err)
+	}
+	return c.JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Note deleted for good",
+	})
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetVersions lists a note's earlier versions, newest first
+// GET /api/notes/:index/versions
+func (h *NotesHandler) GetVersions(c *fiber.Ctx) error {
+	index, err := strconv.Atoi(c.Params("index"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid note index")
+	}
+
+	manager := h.manager(c)
+	if _, err := manager.GetNote(index); err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Note not found")
+	}
+	versions, err := manager.Versions(index)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   versions,
+	})
+}
+
+// RestoreVersion brings back a note's title and content from an earlier
+// version, keeping what it replaces as a version
+// POST /api/notes/:index/versions/:id/restore
+func (h *NotesHandler) RestoreVersion(c *fiber.Ctx) error {
+	index, err := strconv.Atoi(c.Params("index"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid note index")
+	}
+
+	manager := h.manager(c)
+	if _, err := manager.GetNote(index); err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Note not found")
+	}
+	if err := manager.RestoreVersion(index, c.Params("id"), currentUserName(c)); err != nil {
+		if errors.Is(err, services.ErrVersionNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(models.APIResponse{
+		Status:  "success",
+		Message: "Note version restored",
+	})
+}
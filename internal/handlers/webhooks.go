@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// WebhooksHandler handles webhook subscription management
+type WebhooksHandler struct {
+	webhooks *services.WebhookService
+}
+
+// NewWebhooksHandler creates a new webhooks handler
+func NewWebhooksHandler(webhooks *services.WebhookService) *WebhooksHandler {
+	return &WebhooksHandler{
+		webhooks: webhooks,
+	}
+}
+
+// webhookResponse hides a webhook's secret, reporting only whether one is set
+type webhookResponse struct {
+	models.Webhook
+	HasSecret bool `json:"has_secret"`
+}
+
+// newWebhookResponse converts a webhook for an API response
+func newWebhookResponse(webhook models.Webhook) webhookResponse {
+	response := webhookResponse{Webhook: webhook, HasSecret: webhook.Secret != ""}
+	response.Secret = ""
+	return response
+}
+
+// ownedWebhook returns the webhook with id if the request's user manages
+// it: users manage the webhooks they created, and the shared credentials
+// every webhook
+func (h *WebhooksHandler) ownedWebhook(c *fiber.Ctx, id string) (models.Webhook, error) {
+	webhook, err := h.webhooks.Get(id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if user := CurrentUser(c); user != nil && webhook.Owner != user.Name {
+		return models.Webhook{}, services.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// webhookError maps a webhook service error to an HTTP error
+func webhookError(err error) error {
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	return fiber.NewError(fiber.StatusBadRequest, err.Error())
+}
+
+// ListWebhooks returns the webhook subscriptions the user manages
+// GET /api/webhooks
+func (h *WebhooksHandler) ListWebhooks(c *fiber.Ctx) error {
+	user := CurrentUser(c)
+	var response []webhookResponse
+	for _, webhook := range h.webhooks.List() {
+		if user == nil || webhook.Owner == user.Name {
+			response = append(response, newWebhookResponse(webhook))
+		}
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   response,
+	})
+}
+
+// GetWebhook returns a single webhook subscription
+// GET /api/webhooks/:id
+func (h *WebhooksHandler) GetWebhook(c *fiber.Ctx) error {
+	webhook, err := h.ownedWebhook(c, c.Params("id"))
+	if err != nil {
+		return webhookError(err)
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   newWebhookResponse(webhook),
+	})
+}
+
+// CreateWebhook adds a webhook subscription
+// POST /api/webhooks
+func (h *WebhooksHandler) CreateWebhook(c *fiber.Ctx) error {
+	var req models.WebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	webhook, err := h.webhooks.Create(&req, currentUserName(c))
+	if err != nil {
+		return webhookError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(models.APIResponse{
+		Status: "success",
+		Data:   newWebhookResponse(webhook),
+	})
+}
+
+// UpdateWebhook changes a webhook subscription
+// PUT /api/webhooks/:id
+func (h *WebhooksHandler) UpdateWebhook(c *fiber.Ctx) error {
+	var req models.WebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	if _, err := h.ownedWebhook(c, c.Params("id")); err != nil {
+		return webhookError(err)
+	}
+	webhook, err := h.webhooks.Update(c.Params("id"), &req)
+	if err != nil {
+		return webhookError(err)
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+		Data:   newWebhookResponse(webhook),
+	})
+}
+
+// DeleteWebhook removes a webhook subscription
+// DELETE /api/webhooks/:id
+func (h *WebhooksHandler) DeleteWebhook(c *fiber.Ctx) error {
+	if _, err := h.ownedWebhook(c, c.Params("id")); err != nil {
+		return webhookError(err)
+	}
+	if err := h.webhooks.Delete(c.Params("id")); err != nil {
+		return webhookError(err)
+	}
+
+	return c.JSON(models.APIResponse{
+		Status: "success",
+	})
+}
@@ -0,0 +1,110 @@
+// Package i18n translates the web interface and API messages. A catalog maps
+// English messages to their translation in one locale; messages missing from
+// it are shown in English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is the language messages are written in
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var catalogFiles embed.FS
+
+// The selected locale and its catalog
+var (
+	mu      sync.RWMutex
+	locale  = DefaultLocale
+	catalog map[string]string
+)
+
+// Locales returns the codes of the available locales, sorted
+func Locales() []string {
+	locales := []string{DefaultLocale}
+	entries, _ := catalogFiles.ReadDir("locales")
+	for _, entry := range entries {
+		locales = append(locales, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// resolve returns the locale whose catalog serves code: the code itself, or
+// its language for a regional variant such as "de-AT". ok is false when
+// there is none.
+func resolve(code string) (string, bool) {
+	code = strings.ReplaceAll(strings.ToLower(code), "_", "-")
+	language, _, _ := strings.Cut(code, "-")
+	for _, candidate := range []string{code, language} {
+		for _, available := range Locales() {
+			if candidate == strings.ToLower(available) {
+				return available, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Exists reports whether there is a catalog for code or its language
+func Exists(code string) bool {
+	_, ok := resolve(code)
+	return ok
+}
+
+// SetLocale selects the language of messages. An empty or unknown code
+// selects English.
+func SetLocale(code string) error {
+	resolved, ok := resolve(code)
+	if !ok {
+		resolved = DefaultLocale
+	}
+
+	var messages map[string]string
+	if resolved != DefaultLocale {
+		data, err := catalogFiles.ReadFile(path.Join("locales", resolved+".json"))
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("invalid %s catalog: %w", resolved, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	locale, catalog = resolved, messages
+	if !ok && code != "" {
+		return fmt.Errorf("no translation for locale %q", code)
+	}
+	return nil
+}
+
+// Locale returns the selected locale
+func Locale() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return locale
+}
+
+// T returns message in the selected language
+func T(message string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if translated, ok := catalog[message]; ok && translated != "" {
+		return translated
+	}
+	return message
+}
+
+// Tf translates format and then formats it with args like fmt.Sprintf
+func Tf(format string, args ...interface{}) string {
+	return fmt.Sprintf(T(format), args...)
+}
@@ -0,0 +1,90 @@
+// Package logging sets up the server log: the least severe level written,
+// text or JSON output, and the ID of the request added to messages logged
+// while serving it.
+package logging
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+var (
+	mu    sync.Mutex
+	out   io.Writer // where the log is written, taken from the log package
+	level slog.LevelVar
+)
+
+// Setup writes the log as configured, including the messages of the log
+// package, which are logged at the info level. It can be called again to
+// apply a reloaded configuration.
+func Setup(config models.LogConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Once set up, the log package writes to slog, so its writer is kept
+	if out == nil {
+		out = log.Writer()
+	}
+	level.Set(parseLevel(config.Level))
+
+	options := &slog.HandlerOptions{Level: &level}
+	var handler slog.Handler
+	if config.Format == models.LogFormatJSON {
+		handler = slog.NewJSONHandler(out, options)
+	} else {
+		handler = slog.NewTextHandler(out, options)
+	}
+	slog.SetDefault(slog.New(requestHandler{handler}))
+}
+
+// parseLevel returns the level named name, as validated with the config
+func parseLevel(name string) slog.Level {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the ID of the request it serves
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// requestHandler adds the request ID, if any, to messages logged with a
+// request's context
+type requestHandler struct {
+	slog.Handler
+}
+
+// Handle logs record with the request ID in ctx
+func (h requestHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs returns a handler adding attrs, and the request ID
+func (h requestHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a handler logging in the group name, and adding the
+// request ID
+func (h requestHandler) WithGroup(name string) slog.Handler {
+	return requestHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,42 @@
+package models
+
+// AI providers that notes can be summarized with
+const (
+	// AIProviderOpenAI is any server with OpenAI's chat completions API
+	AIProviderOpenAI = "openai"
+	// AIProviderOllama is a local Ollama server
+	AIProviderOllama = "ollama"
+)
+
+// AIConfig selects the language model that summarizes notes and suggests
+// titles and tags
+type AIConfig struct {
+	// Provider is "openai" or "ollama"; summaries are off when it is empty
+	Provider string `json:"provider,omitempty"`
+	// URL is the API's base URL (empty means https://api.openai.com/v1 or
+	// http://localhost:11434)
+	URL string `json:"url,omitempty"`
+	// Model is the model to use, such as "gpt-4o-mini" or "llama3.2"
+	Model string `json:"model,omitempty"`
+	// APIKey is sent as a bearer token, when set
+	APIKey string `json:"api_key,omitempty"`
+	// Prompt is the instruction given with a note to summarize (empty means
+	// a short summary in the note's language)
+	Prompt string `json:"prompt,omitempty"`
+	// TimeoutSeconds limits how long the model may take (0 means 120)
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// AISuggestRequest is a draft note to suggest a title and tags for
+type AISuggestRequest struct {
+	Title   string `json:"title" form:"title"`
+	Content string `json:"content" form:"content"`
+}
+
+// AISuggestion is a title and tags suggested for a draft note. Tags are
+// without the #, and those already used in the project are listed in Known.
+type AISuggestion struct {
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
+	Known []string `json:"known"`
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// ArchiveConfig holds settings for website archiving
+type ArchiveConfig struct {
+	// Domains maps a domain (e.g. "wiki.example.com") to the rules applied when
+	// fetching pages and resources from that domain or any of its subdomains
+	Domains map[string]*DomainArchiveRule `json:"domains,omitempty"`
+
+	// Wayback controls use of the Internet Archive's Wayback Machine
+	Wayback WaybackConfig `json:"wayback"`
+
+	// Frames controls capture of <iframe> content
+	Frames FrameCaptureConfig `json:"frames"`
+
+	// Concurrency is the maximum number of resources downloaded at once (0 uses the default)
+	Concurrency int `json:"concurrency,omitempty"`
+	// PageTimeoutSeconds is the overall time budget for archiving one page (0 uses the default)
+	PageTimeoutSeconds int `json:"page_timeout_seconds,omitempty"`
+
+	// Deny lists domains that are never archived or fetched from, including their subdomains
+	Deny []string `json:"deny,omitempty"`
+	// StripAnalytics removes known analytics and tracking scripts from archived pages
+	StripAnalytics bool `json:"strip_analytics"`
+	// AnalyticsDomains adds script hosts to remove when StripAnalytics is enabled
+	AnalyticsDomains []string `json:"analytics_domains,omitempty"`
+	// RespectRobots skips pages disallowed by the site's robots.txt
+	RespectRobots bool `json:"respect_robots"`
+	// ScreenshotCommand takes a screenshot of each archived page, such as a
+	// headless browser; "{url}", "{file}" and "{out}" in it are replaced by
+	// the page's URL, the archived file and the image file to write
+	ScreenshotCommand string `json:"screenshot_command,omitempty"`
+}
+
+// FrameCaptureConfig holds settings for capturing embedded frames
+type FrameCaptureConfig struct {
+	// CrossOrigin also captures frames served from a different origin than their parent
+	CrossOrigin bool `json:"cross_origin"`
+	// MaxDepth limits how many levels of nested frames are captured (0 uses the default)
+	MaxDepth int `json:"max_depth,omitempty"`
+}
+
+// WaybackConfig holds Wayback Machine settings
+type WaybackConfig struct {
+	// Fallback archives the most recent snapshot when direct archiving fails
+	Fallback bool `json:"fallback"`
+	// Submit asks web.archive.org to capture each successfully archived URL
+	Submit bool `json:"submit"`
+}
+
+// DomainArchiveRule holds per-domain archiving settings
+type DomainArchiveRule struct {
+	Headers map[string]string `json:"headers,omitempty"`
+	Cookies map[string]string `json:"cookies,omitempty"`
+}
+
+// ArchiveOptions holds per-request archiving options
+type ArchiveOptions struct {
+	Headers map[string]string `json:"headers,omitempty"`
+	Cookies map[string]string `json:"cookies,omitempty"`
+}
+
+// ArchiveRequest represents a request to archive a single URL
+type ArchiveRequest struct {
+	URL string `json:"url"`
+	ArchiveOptions
+}
+
+// ArchiveMetadata describes an archived website stored in assets/sites
+type ArchiveMetadata struct {
+	Filename   string    `json:"filename"`
+	URL        string    `json:"url"`
+	Title      string    `json:"title"`
+	ArchivedAt time.Time `json:"archived_at"`
+	Source     string    `json:"source,omitempty"`
+	Size       int64     `json:"size"`
+	// Screenshot is the file name of a thumbnail of the page in assets/sites
+	Screenshot string `json:"screenshot,omitempty"`
+}
+
+// RuleForHost returns the most specific domain rule matching host, or nil
+func (c *ArchiveConfig) RuleForHost(host string) *DomainArchiveRule {
+	if c == nil {
+		return nil
+	}
+
+	var best *DomainArchiveRule
+	bestLen := -1
+	for domain, rule := range c.Domains {
+		if HostMatches(host, domain) && len(domain) > bestLen {
+			best = rule
+			bestLen = len(domain)
+		}
+	}
+	return best
+}
+
+// HostMatches reports whether host equals domain or is a subdomain of it
+func HostMatches(host, domain string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	domain = strings.ToLower(strings.TrimPrefix(strings.TrimSuffix(domain, "."), "."))
+	if host == "" || domain == "" {
+		return false
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
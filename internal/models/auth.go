@@ -0,0 +1,83 @@
+package models
+
+import "path/filepath"
+
+// AuthConfig holds optional server authentication settings
+type AuthConfig struct {
+	// Password protects the web UI and API. It may be plain text or
+	// "sha256:<hex digest>" to avoid storing the password itself.
+	Password string `json:"password,omitempty"`
+	// Tokens are API tokens accepted as "Authorization: Bearer <token>"
+	Tokens []string `json:"tokens,omitempty"`
+	// Users are named accounts, each with their own credentials and project folders
+	Users []*User `json:"users,omitempty"`
+
+	// SessionHours is how long a browser sign-in lasts (default 12)
+	SessionHours int `json:"session_hours,omitempty"`
+	// RememberDays is how long a "remember me" sign-in lasts (default 30)
+	RememberDays int `json:"remember_days,omitempty"`
+}
+
+// User is a named account that signs in with HTTP basic auth (username and
+// password) or one of its API tokens
+type User struct {
+	Name string `json:"name"`
+	// Password may be plain text or "sha256:<hex digest>"
+	Password string   `json:"password,omitempty"`
+	Tokens   []string `json:"tokens,omitempty"`
+	// Folders are the project folders this user can access. The first one is
+	// opened in the web UI; when empty the server's own folder is used.
+	// Users listing the same folder share it.
+	Folders []string `json:"folders,omitempty"`
+}
+
+// Enabled reports whether authentication is configured
+func (c *AuthConfig) Enabled() bool {
+	return c != nil && (c.Password != "" || len(c.Tokens) > 0 || len(c.Users) > 0)
+}
+
+// FindUser returns the configured user with the given name, or nil
+func (c *AuthConfig) FindUser(name string) *User {
+	for _, user := range c.Users {
+		if user.Name == name {
+			return user
+		}
+	}
+	return nil
+}
+
+// HomeFolder returns the folder opened for the user, falling back to defaultFolder
+func (u *User) HomeFolder(defaultFolder string) string {
+	if len(u.Folders) == 0 || u.Folders[0] == "" {
+		return defaultFolder
+	}
+	return u.Folders[0]
+}
+
+// CanAccess reports whether the user may access folder. A nil user (signed in
+// with the shared password or tokens) can access every folder.
+func (u *User) CanAccess(folder, defaultFolder string) bool {
+	if u == nil {
+		return true
+	}
+
+	if len(u.Folders) == 0 {
+		return sameFolder(folder, defaultFolder)
+	}
+	for _, allowed := range u.Folders {
+		if sameFolder(allowed, folder) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameFolder reports whether two folder paths refer to the same location
+func sameFolder(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
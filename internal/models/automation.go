@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// Kinds of automation trigger items
+const (
+	AutomationNoteCreated   = "note-created"
+	AutomationTaskCompleted = "task-completed"
+)
+
+// AutomationItem is a new note or a completed task, as listed by the polling
+// trigger endpoints for Zapier, IFTTT and similar services. Items are
+// numbered in order, and their Cursor asks for the items after them.
+type AutomationItem struct {
+	ID     string    `json:"id"`
+	Cursor uint64    `json:"cursor"`
+	Kind   string    `json:"kind"`
+	Time   time.Time `json:"time"`
+	User   string    `json:"user,omitempty"`
+
+	// Title and Content are those of a new note
+	Title   string   `json:"title,omitempty"`
+	Content string   `json:"content,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+
+	// Text is that of a completed task
+	Text string `json:"text,omitempty"`
+
+	// Meta identifies the item in the form IFTTT expects
+	Meta AutomationMeta `json:"meta"`
+}
+
+// AutomationMeta is the ID and Unix time of an automation item
+type AutomationMeta struct {
+	ID        string `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// AutomationNoteRequest is the body of the create-note and append-to-note
+// actions
+type AutomationNoteRequest struct {
+	Title   string `json:"title" form:"title"`
+	Content string `json:"content" form:"content"`
+}
+
+// AutomationTaskRequest is the body of the add-task and complete-task
+// actions. Note is the title of the note a task is added to; tasks are
+// completed by their text, or by index.
+type AutomationTaskRequest struct {
+	Text  string `json:"text" form:"text"`
+	Note  string `json:"note" form:"note"`
+	Index *int   `json:"index" form:"index"`
+}
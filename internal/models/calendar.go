@@ -0,0 +1,35 @@
+package models
+
+// CalendarMonthLayout is the form of the month asked for in calendar requests
+const CalendarMonthLayout = "2006-01"
+
+// Calendar lists a month's notes, by the day they were written, and its
+// tasks, by the day they are due
+type Calendar struct {
+	Month string        `json:"month"`
+	Days  []CalendarDay `json:"days"`
+}
+
+// CalendarDay is one day of a calendar month
+type CalendarDay struct {
+	Date  string         `json:"date"`
+	Notes []CalendarNote `json:"notes"`
+	Tasks []CalendarTask `json:"tasks"`
+}
+
+// CalendarNote is a note written on a calendar day
+type CalendarNote struct {
+	Index     int    `json:"index"`
+	Title     string `json:"title"`
+	Timestamp string `json:"timestamp"`
+}
+
+// CalendarTask is a task due on a calendar day, open or done
+type CalendarTask struct {
+	Index     int    `json:"index"`
+	Text      string `json:"text"`
+	Checked   bool   `json:"checked"`
+	Priority  string `json:"priority,omitempty"`
+	NoteIndex int    `json:"note_index"`
+	NoteTitle string `json:"note_title"`
+}
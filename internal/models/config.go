@@ -1,14 +1,152 @@
 package models
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 // Config represents the application configuration
 type Config struct {
-	Theme string `json:"theme"`
+	Theme   string        `json:"theme"`
+	Archive ArchiveConfig `json:"archive"`
+	Auth    AuthConfig    `json:"auth"`
+	Server  ServerConfig  `json:"server"`
+	Uploads UploadConfig  `json:"uploads"`
+	Backup  BackupConfig  `json:"backup"`
+	UI      UIConfig      `json:"ui"`
+	Time    TimeConfig    `json:"time"`
+
+	// Trash sets how long deleted notes are kept
+	Trash TrashConfig `json:"trash"`
+
+	// Fetch limits the requests made to other websites
+	Fetch FetchConfig `json:"fetch"`
+
+	// Log sets the level and format of the server log
+	Log LogConfig `json:"log"`
+
+	// Locale is the language of the web pages and API messages, such as "de"
+	// (empty means English)
+	Locale string `json:"locale,omitempty"`
+
+	// Projects are additional folders served under /p/<name>/ alongside the
+	// folder the server was started in
+	Projects []string `json:"projects,omitempty"`
+
+	Webhooks []*Webhook `json:"webhooks,omitempty"`
+
+	// Telegram runs a bot that saves the messages sent to it as notes
+	Telegram TelegramConfig `json:"telegram"`
+
+	// Slack takes notes from a slash command and announces completed tasks
+	Slack SlackConfig `json:"slack"`
+
+	// Todoist syncs the tasks of the notes with a Todoist project
+	Todoist TodoistConfig `json:"todoist"`
+
+	// AI is the language model that summarizes notes and suggests titles and tags
+	AI AIConfig `json:"ai"`
+
+	// Translation is the service that translates notes
+	Translation TranslationConfig `json:"translation"`
+
+	// Notifications sends reminders, due tasks and daily digests to Discord
+	// or Matrix
+	Notifications NotificationsConfig `json:"notifications"`
+
+	// Themes are custom color themes, selectable alongside the built-in ones
+	Themes []*Theme `json:"themes,omitempty"`
+}
+
+// Clone returns a deep copy of the configuration, which can be changed
+// without changing c
+func (c *Config) Clone() *Config {
+	// Every setting is saved to the config file, so a round trip through
+	// JSON copies them all
+	data, err := json.Marshal(c)
+	var clone Config
+	if err == nil {
+		err = json.Unmarshal(data, &clone)
+	}
+	if err != nil {
+		panic("config does not copy: " + err.Error())
+	}
+	return &clone
+}
+
+// UIConfig holds settings for personalizing the web pages. Relative file
+// paths are resolved against the directory of the configuration file.
+type UIConfig struct {
+	// CustomCSS is a stylesheet added to every page after the theme's styles
+	CustomCSS string `json:"custom_css,omitempty"`
+	// CustomJS is a script run at the end of every page
+	CustomJS string `json:"custom_js,omitempty"`
+
+	// Projects overrides the files above for individual folders, keyed by
+	// folder path
+	Projects map[string]*UIOverride `json:"projects,omitempty"`
+
+	// Fonts chooses the interface and code fonts
+	Fonts FontConfig `json:"fonts"`
+
+	// Shortcuts are the keyboard shortcuts of the notes page
+	Shortcuts ShortcutConfig `json:"shortcuts"`
+}
+
+// UIOverride replaces the custom files for one folder. Empty fields keep the
+// global setting.
+type UIOverride struct {
+	CustomCSS string `json:"custom_css,omitempty"`
+	CustomJS  string `json:"custom_js,omitempty"`
+}
+
+// CustomFiles returns the custom stylesheet and script configured for the
+// project in folder. Without a folder only the global files apply.
+func (c *UIConfig) CustomFiles(folder string) (css, js string) {
+	css, js = c.CustomCSS, c.CustomJS
+	if folder == "" {
+		return css, js
+	}
+	for key, override := range c.Projects {
+		if override == nil || ExpandHome(key) != filepath.Clean(folder) {
+			continue
+		}
+		if override.CustomCSS != "" {
+			css = override.CustomCSS
+		}
+		if override.CustomJS != "" {
+			js = override.CustomJS
+		}
+	}
+	return css, js
+}
+
+// ExpandHome replaces a leading ~ in path with the user's home directory and
+// cleans the result
+func ExpandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	return filepath.Clean(path)
+}
+
+// BackupConfig holds defaults for the backup command
+type BackupConfig struct {
+	// Target is a directory, an rclone "remote:path" or an s3:// URL (empty
+	// means ~/.config/noteflow/backups)
+	Target string `json:"target,omitempty"`
+	// Keep is how many backups of each folder to keep (0 keeps all)
+	Keep int `json:"keep,omitempty"`
+	// Encrypt encrypts backups with the passphrase in NOTEFLOW_BACKUP_PASSPHRASE
+	Encrypt bool `json:"encrypt"`
 }
 
 // Theme represents a color theme
@@ -17,6 +155,21 @@ type Theme struct {
 	Colors map[string]string `json:"colors"`
 }
 
+// ThemeRequest represents a custom theme creation/update request. Colors
+// missing from it are taken from the Base theme when one is named.
+type ThemeRequest struct {
+	Name   string            `json:"name"`
+	Base   string            `json:"base"`
+	Colors map[string]string `json:"colors"`
+}
+
+// ThemeCommitRequest saves a draft theme, optionally under another name, and
+// selects it when Select is set
+type ThemeCommitRequest struct {
+	Name   string `json:"name"`
+	Select bool   `json:"select"`
+}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
@@ -54,6 +207,21 @@ func LoadConfig(configPath string) (*Config, error) {
 	return &config, nil
 }
 
+// DecodeConfig parses a configuration file strictly, rejecting unknown keys
+// and values of the wrong type, and validates it
+func DecodeConfig(data []byte) (*Config, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var config Config
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid setting: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
 // SaveConfig saves configuration to the given file path
 func SaveConfig(config *Config, configPath string) error {
 	data, err := json.MarshalIndent(config, "", "  ")
@@ -70,6 +238,58 @@ type NoteRequest struct {
 	Content string `form:"content" json:"content"`
 }
 
+// PinRequest represents a request to pin or unpin a note
+type PinRequest struct {
+	Pinned bool `form:"pinned" json:"pinned"`
+}
+
+// SyncRequest carries notes captured while the web UI was offline
+type SyncRequest struct {
+	Notes []OfflineNote `json:"notes"`
+}
+
+// OfflineNote is a note captured offline. ID is generated by the client and
+// stays the same when the note is sent again.
+type OfflineNote struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	CapturedAt time.Time `json:"captured_at"`
+}
+
+// SyncResult reports what happened to one offline note: "created",
+// "duplicate" (already synced) or "error"
+type SyncResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ClipRequest is a web page clipped by a browser extension
+type ClipRequest struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	// Selection is the text selected on the page, quoted in the note
+	Selection string `json:"selection"`
+	// Screenshot is a PNG, JPEG or WebP image of the page, as base64 or a
+	// base64 data URI
+	Screenshot string `json:"screenshot"`
+	// Note is the user's own text, added below the clipping
+	Note string   `json:"note"`
+	Tags []string `json:"tags"`
+	// Archive archives the page in the background, unless set to false
+	Archive *bool `json:"archive"`
+}
+
+// PageURL returns the clipped page's URL, which must be an http or https URL
+func (r *ClipRequest) PageURL() (*url.URL, error) {
+	pageURL, err := url.Parse(strings.TrimSpace(r.URL))
+	if err != nil || (pageURL.Scheme != "http" && pageURL.Scheme != "https") || pageURL.Host == "" {
+		return nil, fmt.Errorf("url must be an http or https URL")
+	}
+	return pageURL, nil
+}
+
 // APIResponse represents a standard API response
 type APIResponse struct {
 	Status  string      `json:"status"`
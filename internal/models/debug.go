@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// RuntimeStats describes the server process, for diagnosing performance
+// problems
+type RuntimeStats struct {
+	GoVersion string    `json:"go_version"`
+	StartedAt time.Time `json:"started_at"`
+	// UptimeSeconds is how long the server has been running
+	UptimeSeconds int64 `json:"uptime_seconds"`
+	NumCPU        int   `json:"num_cpu"`
+	Goroutines    int   `json:"goroutines"`
+
+	// Heap and memory figures are in bytes
+	HeapAlloc   uint64 `json:"heap_alloc"`
+	HeapInuse   uint64 `json:"heap_inuse"`
+	HeapObjects uint64 `json:"heap_objects"`
+	TotalAlloc  uint64 `json:"total_alloc"`
+	Sys         uint64 `json:"sys"`
+
+	// NumGC counts the completed garbage collections, which paused the
+	// program for GCPauseTotalMs milliseconds in all
+	NumGC          uint32     `json:"num_gc"`
+	GCPauseTotalMs float64    `json:"gc_pause_total_ms"`
+	LastGC         *time.Time `json:"last_gc,omitempty"`
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+)
+
+// Event types published to live update subscribers
+const (
+	EventArchiveProgress     = "archive-progress"
+	EventBulkArchiveProgress = "bulk-archive-progress"
+	EventNoteCreated         = "note-created"
+	EventNoteUpdated         = "note-updated"
+	EventNoteDeleted         = "note-deleted"
+	EventNoteRestored        = "note-restored"
+	EventNotesImported       = "notes-imported"
+	EventTaskToggled         = "task-toggled"
+)
+
+// Event represents a server-side event delivered to live update subscribers
+type Event struct {
+	ID        uint64      `json:"id"`
+	Type      string      `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// ArchiveProgress reports the progress of an in-flight website archive
+type ArchiveProgress struct {
+	URL              string `json:"url"`
+	Stage            string `json:"stage"` // "page", "resources", "screenshot", "done" or "failed"
+	ResourcesFetched int    `json:"resources_fetched"`
+	ResourcesFailed  int    `json:"resources_failed"`
+	BytesDownloaded  int64  `json:"bytes_downloaded"`
+	Error            string `json:"error,omitempty"`
+}
+
+// BulkArchiveProgress reports the progress of archiving every link in a project
+type BulkArchiveProgress struct {
+	Total    int    `json:"total"`
+	Archived int    `json:"archived"`
+	Skipped  int    `json:"skipped"`
+	Failed   int    `json:"failed"`
+	Current  string `json:"current,omitempty"`
+	Done     bool   `json:"done"`
+}
+
+// NoteChange describes a note that was created, updated, deleted or
+// restored
+type NoteChange struct {
+	Index int    `json:"index"`
+	Title string `json:"title"`
+	User  string `json:"user,omitempty"`
+}
+
+// TaskChange describes a task whose completion status was toggled
+type TaskChange struct {
+	Index   int    `json:"index"`
+	Checked bool   `json:"checked"`
+	Text    string `json:"text"`
+	User    string `json:"user,omitempty"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// FetchConfig limits the requests made to other websites, such as for
+// archiving pages and their resources. The limits are shared by every
+// project served.
+type FetchConfig struct {
+	// Concurrency is the most requests made at once (0 uses the default)
+	Concurrency int `json:"concurrency,omitempty"`
+	// HostConcurrency is the most requests made to one host at once (0 uses
+	// the default)
+	HostConcurrency int `json:"host_concurrency,omitempty"`
+	// HostDelayMS is the least time between the starts of two requests to
+	// the same host, in milliseconds (0 doesn't wait)
+	HostDelayMS int `json:"host_delay_ms,omitempty"`
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FontFormats maps the extensions of font files that can be uploaded to their
+// CSS format names
+var FontFormats = map[string]string{
+	".woff2": "woff2",
+	".woff":  "woff",
+	".ttf":   "truetype",
+	".otf":   "opentype",
+}
+
+// fontFamilyPattern matches a font family list such as "'Inter', sans-serif".
+// Families are written into the page's styles, so anything that could end a
+// declaration or the style block is refused.
+var fontFamilyPattern = regexp.MustCompile(`^[A-Za-z0-9 _,'"-]{1,128}$`)
+
+// fontFilePattern matches the names of uploaded font files
+var fontFilePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,99}$`)
+
+// fontWeightPattern matches the weights a font face may declare
+var fontWeightPattern = regexp.MustCompile(`^(normal|bold|[1-9]00)$`)
+
+// FontConfig chooses the fonts used by the web pages
+type FontConfig struct {
+	// UI is the font family list for the interface and notes (empty keeps
+	// Space Mono)
+	UI string `json:"ui,omitempty"`
+	// Code is the font family list for code (empty keeps the browser's
+	// monospace font)
+	Code string `json:"code,omitempty"`
+
+	// Faces are self-hosted font files, stored in each project's
+	// assets/fonts folder, that UI and Code can name
+	Faces []*FontFace `json:"faces,omitempty"`
+}
+
+// FontFace declares a font file as one weight and style of a family
+type FontFace struct {
+	Family string `json:"family"`
+	File   string `json:"file"`
+	Weight string `json:"weight,omitempty"`
+	Style  string `json:"style,omitempty"`
+}
+
+// Validate checks that the font settings are safe to write into the page's
+// styles
+func (c *FontConfig) Validate() error {
+	for key, value := range map[string]string{"ui.fonts.ui": c.UI, "ui.fonts.code": c.Code} {
+		if value != "" && !fontFamilyPattern.MatchString(value) {
+			return fmt.Errorf("%s may only contain letters, digits, spaces, quotes, commas, dashes and underscores", key)
+		}
+	}
+	files := make(map[string]bool)
+	for i, face := range c.Faces {
+		if face == nil {
+			return fmt.Errorf("ui.fonts.faces.%d is empty", i)
+		}
+		if err := face.Validate(); err != nil {
+			return fmt.Errorf("ui.fonts.faces.%d: %w", i, err)
+		}
+		if files[face.File] {
+			return fmt.Errorf("ui.fonts.faces has two faces for %q", face.File)
+		}
+		files[face.File] = true
+	}
+	return nil
+}
+
+// Validate checks a font face's family, file name, weight and style
+func (f *FontFace) Validate() error {
+	if f.Family == "" || strings.ContainsAny(f.Family, `,'"`) || !fontFamilyPattern.MatchString(f.Family) {
+		return fmt.Errorf("family must be a name of letters, digits, spaces, dashes and underscores")
+	}
+	if !fontFilePattern.MatchString(f.File) {
+		return fmt.Errorf("invalid font file name %q", f.File)
+	}
+	if _, ok := FontFormats[strings.ToLower(filepath.Ext(f.File))]; !ok {
+		return fmt.Errorf("font file must be one of .woff2, .woff, .ttf or .otf")
+	}
+	if f.Weight != "" && !fontWeightPattern.MatchString(f.Weight) {
+		return fmt.Errorf("weight must be normal, bold or 100 to 900")
+	}
+	switch f.Style {
+	case "", "normal", "italic", "oblique":
+	default:
+		return fmt.Errorf("style must be normal, italic or oblique")
+	}
+	return nil
+}
+
+// FontRequest sets the interface and code fonts; an empty value restores the
+// default
+type FontRequest struct {
+	UI   string `json:"ui"`
+	Code string `json:"code"`
+}
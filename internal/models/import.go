@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ImportFile is a markdown file to be converted into a note by a bulk import
+type ImportFile struct {
+	Name     string
+	Content  []byte
+	Modified time.Time
+}
+
+// ImportReport summarizes a bulk import
+type ImportReport struct {
+	Imported int                `json:"imported"`
+	Failed   int                `json:"failed"`
+	Files    []ImportFileResult `json:"files"`
+}
+
+// ImportFileResult reports what became of one imported file
+type ImportFileResult struct {
+	File   string   `json:"file"`
+	Titles []string `json:"titles,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// NotesImported describes the notes added by a bulk import
+type NotesImported struct {
+	Indices []int  `json:"indices"`
+	User    string `json:"user,omitempty"`
+}
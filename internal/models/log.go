@@ -0,0 +1,17 @@
+package models
+
+// Log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// LogConfig controls what the server logs and how
+type LogConfig struct {
+	// Level is the least severe level logged: "debug", "info", "warn" or
+	// "error" (empty means "info")
+	Level string `json:"level,omitempty"`
+	// Format writes each message as key=value text or as a JSON object
+	// (empty means text)
+	Format string `json:"format,omitempty"`
+}
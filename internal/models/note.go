@@ -9,20 +9,72 @@ import (
 
 const NoteSeparator = "\n<!-- note -->\n"
 
+// attributionPattern matches the optional comment recording who created and last edited a note
+var attributionPattern = regexp.MustCompile(`^<!-- author: (.*?)(?:; edited by: (.*?))? -->\n*`)
+
+// modifiedPattern matches the comment recording when a note was last changed
+var modifiedPattern = regexp.MustCompile(`^<!-- modified: (.*?) -->\n*`)
+
+// pinnedPattern matches the comment marking a pinned note
+var pinnedPattern = regexp.MustCompile(`^<!-- pinned -->\n*`)
+
+// idPattern matches the comment recording a note's ID
+var idPattern = regexp.MustCompile(`^<!-- id: ([0-9a-f]+) -->\n*`)
+
+// summaryPattern matches the comment holding a note's AI summary
+var summaryPattern = regexp.MustCompile(`^<!-- summary: (.*?) -->\n*`)
+
+// hashtagPattern matches #tags in note content. A heading's "# " does not
+// match because a tag must start with a letter right after the #.
+var hashtagPattern = regexp.MustCompile(`(?:^|[\s(])#([A-Za-z][\w/-]*)`)
+
+// headerPattern matches the timestamp and optional title in a note's header
+var headerPattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})(?: - (.*))?$`)
+
+// archivedSitePattern matches a markdown link into assets/sites, capturing
+// what comes before assets: nothing for the links the archiver writes, or a
+// leading slash or URL prefix
+var archivedSitePattern = regexp.MustCompile(`\]\(<?([^\s()<>]*?)assets/sites/[^\s()<>]+`)
+
+// checkboxPattern matches the checkbox of a task
+var checkboxPattern = regexp.MustCompile(`\[([xX ])\]`)
+
+// completedByPattern matches the comment appended to a task completed by a named user
+var completedByPattern = regexp.MustCompile(` ?<!-- done by: (.*?) -->`)
+
+// NoteFolderPattern matches the names of notes' asset folders, which are
+// their timestamps in noteFolderLayout
+const NoteFolderPattern = `\d{8}-\d{6}`
+
+// noteFolderLayout formats a note's timestamp as the name of its asset folder
+const noteFolderLayout = "20060102-150405"
+
 // Note represents a single note with content and tasks
 type Note struct {
 	Title     string    `json:"title"`
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
+	// Modified is when the title, content or tasks last changed, the
+	// timestamp until they do
+	Modified  time.Time `json:"modified"`
 	Tasks     []*Task   `json:"tasks"`
+	Author    string    `json:"author,omitempty"`
+	EditedBy  string    `json:"edited_by,omitempty"`
+	Pinned    bool      `json:"pinned"`
+	Summary   string    `json:"summary,omitempty"`
+	// ID tells the note apart from others written in the same second, for
+	// its version history; empty until it has one
+	ID        string    `json:"id,omitempty"`
 }
 
 // NewNote creates a new note with the given title and content
 func NewNote(title, content string) *Note {
+	now := Now()
 	note := &Note{
 		Title:     title,
 		Content:   content,
-		Timestamp: time.Now(),
+		Timestamp: now,
+		Modified:  now,
 		Tasks:     make([]*Task, 0),
 	}
 	note.parseTasks()
@@ -39,23 +91,22 @@ func NewNoteFromText(text string) (*Note, error) {
 	header := strings.TrimPrefix(lines[0], "## ")
 	
 	// Parse timestamp and title from header
-	timestampPattern := regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})(?: - (.*))?$`)
-	matches := timestampPattern.FindStringSubmatch(header)
+	matches := headerPattern.FindStringSubmatch(header)
 	
 	var timestamp time.Time
 	var title string
 	
 	if len(matches) >= 2 {
 		var err error
-		timestamp, err = time.Parse("2006-01-02 15:04:05", matches[1])
+		timestamp, err = ParseTimestamp(matches[1])
 		if err != nil {
-			timestamp = time.Now()
+			timestamp = Now()
 		}
 		if len(matches) >= 3 {
 			title = matches[2]
 		}
 	} else {
-		timestamp = time.Now()
+		timestamp = Now()
 		title = header
 	}
 
@@ -64,11 +115,46 @@ func NewNoteFromText(text string) (*Note, error) {
 		content = strings.TrimSpace(lines[1])
 	}
 
+	// Parse the attribution comment written for multi-user projects
+	var author, editedBy string
+	if matches := attributionPattern.FindStringSubmatch(content); matches != nil {
+		author, editedBy = matches[1], matches[2]
+		content = content[len(matches[0]):]
+	}
+	modified := timestamp
+	if matches := modifiedPattern.FindStringSubmatch(content); matches != nil {
+		if parsed, err := ParseTimestamp(matches[1]); err == nil {
+			modified = parsed
+		}
+		content = content[len(matches[0]):]
+	}
+	pinned := false
+	if match := pinnedPattern.FindString(content); match != "" {
+		pinned = true
+		content = content[len(match):]
+	}
+	var summary string
+	if matches := summaryPattern.FindStringSubmatch(content); matches != nil {
+		summary = matches[1]
+		content = content[len(matches[0]):]
+	}
+	var id string
+	if matches := idPattern.FindStringSubmatch(content); matches != nil {
+		id = matches[1]
+		content = content[len(matches[0]):]
+	}
+
 	note := &Note{
 		Title:     title,
 		Content:   content,
 		Timestamp: timestamp,
+		Modified:  modified,
 		Tasks:     make([]*Task, 0),
+		Author:    author,
+		EditedBy:  editedBy,
+		Pinned:    pinned,
+		Summary:   summary,
+		ID:        id,
 	}
 	note.parseTasks()
 	return note, nil
@@ -78,7 +164,6 @@ func NewNoteFromText(text string) (*Note, error) {
 func (n *Note) parseTasks() {
 	n.Tasks = make([]*Task, 0)
 	
-	checkboxPattern := regexp.MustCompile(`\[([xX ])\]`)
 	matches := checkboxPattern.FindAllStringSubmatchIndex(n.Content, -1)
 	
 	for i, match := range matches {
@@ -90,6 +175,11 @@ func (n *Note) parseTasks() {
 			Checked: checked,
 			Text:    taskText,
 		}
+		if checked {
+			if by := completedByPattern.FindStringSubmatch(taskText); by != nil {
+				task.CompletedBy = by[1]
+			}
+		}
 		n.Tasks = append(n.Tasks, task)
 	}
 }
@@ -104,15 +194,38 @@ func (n *Note) extractTaskText(checkboxPos int) string {
 	return strings.TrimSpace(content[:lineEnd])
 }
 
+// Clone returns a copy of the note, with copies of its tasks, that can be
+// changed without changing the note
+func (n *Note) Clone() *Note {
+	clone := *n
+	clone.Tasks = make([]*Task, len(n.Tasks))
+	for i, task := range n.Tasks {
+		copied := *task
+		clone.Tasks[i] = &copied
+	}
+	return &clone
+}
+
 // Update updates the note's title and content, reparsing tasks
 func (n *Note) Update(title, content string) {
+	previous := n.Tasks
 	n.Title = title
 	n.Content = content
+	n.Modified = Now()
 	n.parseTasks()
+
+	// The tasks keep their indices unless there are more or fewer of them,
+	// when the manager numbers them again
+	if len(n.Tasks) == len(previous) {
+		for i, task := range n.Tasks {
+			task.Index = previous[i].Index
+		}
+	}
 }
 
-// UpdateTask updates a specific task's completion status
-func (n *Note) UpdateTask(taskIndex int, checked bool) bool {
+// UpdateTask updates a specific task's completion status. When user is not
+// empty, completing the task records them as the one who completed it.
+func (n *Note) UpdateTask(taskIndex int, checked bool, user string) bool {
 	for _, task := range n.Tasks {
 		if task.Index == taskIndex {
 			oldMark := "[x]"
@@ -125,13 +238,98 @@ func (n *Note) UpdateTask(taskIndex int, checked bool) bool {
 			// Replace the checkbox in the original task text
 			oldLine := task.Text
 			newLine := strings.Replace(oldLine, oldMark, newMark, 1)
+			newLine = completedByPattern.ReplaceAllString(newLine, "")
+			if checked && user != "" {
+				newLine += " <!-- done by: " + user + " -->"
+			}
 			
 			// Update note content
 			n.Content = strings.Replace(n.Content, oldLine, newLine, 1)
+			n.Modified = Now()
 			
 			// Update task
 			task.Text = newLine
 			task.Checked = checked
+			task.CompletedBy = ""
+			if checked {
+				task.CompletedBy = user
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceTaskText replaces the text of the task with taskIndex, if it is
+// still text, with newText, which must hold the same checkbox. Task indices
+// are kept.
+func (n *Note) ReplaceTaskText(taskIndex int, text, newText string) bool {
+	for _, task := range n.Tasks {
+		if task.Index == taskIndex && task.Text == text {
+			n.Content = strings.Replace(n.Content, text, newText, 1)
+			n.Modified = Now()
+			task.Text = newText
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveTask removes the line holding a task from the note's content. Task
+// indices are reset to their position in the note.
+func (n *Note) RemoveTask(taskIndex int) bool {
+	for _, task := range n.Tasks {
+		if task.Index != taskIndex {
+			continue
+		}
+		lines := strings.Split(n.Content, "\n")
+		for i, line := range lines {
+			if strings.Contains(line, task.Text) {
+				n.Update(n.Title, strings.Join(append(lines[:i], lines[i+1:]...), "\n"))
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Tags returns the distinct #tags in the note's content, lowercased, in order
+// of first appearance
+func (n *Note) Tags() []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, match := range hashtagPattern.FindAllStringSubmatch(n.Content, -1) {
+		tag := strings.ToLower(match[1])
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// HasTag reports whether the note carries tag, ignoring case and a leading #
+func (n *Note) HasTag(tag string) bool {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	for _, t := range n.Tags() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// TagCount is a tag and the number of notes carrying it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// HasArchivedSites reports whether the note links to an archived copy of a website
+func (n *Note) HasArchivedSites() bool {
+	for _, match := range archivedSitePattern.FindAllStringSubmatch(n.Content, -1) {
+		// Links to other sites' assets folders don't count
+		if !strings.Contains(match[1], "://") {
 			return true
 		}
 	}
@@ -143,19 +341,11 @@ func (n *Note) GetUncheckedTasks() []*TaskInfo {
 	var tasks []*TaskInfo
 	for _, task := range n.Tasks {
 		if !task.Checked {
-			// Clean the task text by removing checkbox markers
-			cleanText := strings.TrimSpace(
-				strings.Replace(
-					strings.Replace(task.Text, "[x]", "", 1),
-					"[ ]", "", 1,
-				),
-			)
-			
 			taskInfo := &TaskInfo{
 				Index:     task.Index,
-				Text:      cleanText,
+				Text:      TaskLabel(task.Text),
 				NoteTitle: n.Title,
-				Timestamp: n.Timestamp.Format("2006-01-02 15:04:05"),
+				Timestamp: FormatTimestamp(n.Timestamp),
 			}
 			tasks = append(tasks, taskInfo)
 		}
@@ -163,13 +353,41 @@ func (n *Note) GetUncheckedTasks() []*TaskInfo {
 	return tasks
 }
 
+// AssetFolder returns the name of the folder under assets that holds the
+// note's uploads when they are kept per note
+func (n *Note) AssetFolder() string {
+	return n.Timestamp.Format(noteFolderLayout)
+}
+
 // Render converts the note to markdown format for storage
 func (n *Note) Render() string {
-	timestampStr := n.Timestamp.Format("2006-01-02 15:04:05")
+	timestampStr := FormatTimestamp(n.Timestamp)
 	titleStr := ""
 	if n.Title != "" {
 		titleStr = " - " + n.Title
 	}
 	
-	return fmt.Sprintf("## %s%s\n\n%s\n", timestampStr, titleStr, n.Content)
+	attribution := ""
+	if n.Author != "" || n.EditedBy != "" {
+		attribution = "<!-- author: " + n.Author
+		if n.EditedBy != "" {
+			attribution += "; edited by: " + n.EditedBy
+		}
+		attribution += " -->\n"
+	}
+	// Only a change after the second the note was written is recorded
+	if modified := FormatTimestamp(n.Modified); !n.Modified.IsZero() && modified != timestampStr {
+		attribution += "<!-- modified: " + modified + " -->\n"
+	}
+	if n.Pinned {
+		attribution += "<!-- pinned -->\n"
+	}
+	if n.Summary != "" {
+		attribution += "<!-- summary: " + n.Summary + " -->\n"
+	}
+	if n.ID != "" {
+		attribution += "<!-- id: " + n.ID + " -->\n"
+	}
+
+	return fmt.Sprintf("## %s%s\n\n%s%s\n", timestampStr, titleStr, attribution, n.Content)
 }
\ No newline at end of file
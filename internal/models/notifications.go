@@ -0,0 +1,66 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// NotificationClockLayout is the form of the times of day notifications are
+// sent at
+const NotificationClockLayout = "15:04"
+
+// RemindLayout is the form of the time in a task's "remind:" tag
+const RemindLayout = "2006-01-02T15:04"
+
+// NotificationsConfig selects the chat channels that reminders, due tasks
+// and daily digests are sent to, and when
+type NotificationsConfig struct {
+	// DiscordWebhookURL is a Discord channel's webhook
+	DiscordWebhookURL string `json:"discord_webhook_url,omitempty"`
+
+	// MatrixHomeserver, MatrixRoomID and MatrixAccessToken name a Matrix room
+	// and the account posting to it
+	MatrixHomeserver  string `json:"matrix_homeserver,omitempty"`
+	MatrixRoomID      string `json:"matrix_room_id,omitempty"`
+	MatrixAccessToken string `json:"matrix_access_token,omitempty"`
+
+	// DigestTime is when each day's digest of new notes and due and overdue
+	// tasks is sent, such as "08:00"; there is none when it is empty
+	DigestTime string `json:"digest_time,omitempty"`
+	// DueTime is when the tasks due each day are sent; they aren't when it is
+	// empty
+	DueTime string `json:"due_time,omitempty"`
+}
+
+// Enabled reports whether a channel is configured
+func (c NotificationsConfig) Enabled() bool {
+	return c.DiscordWebhookURL != "" || c.MatrixHomeserver != ""
+}
+
+// Validate reports the first notification setting that can't be used
+func (c NotificationsConfig) Validate() error {
+	if c.DiscordWebhookURL != "" {
+		if u, err := url.Parse(c.DiscordWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("notifications.discord_webhook_url must be an http or https URL")
+		}
+	}
+	if c.MatrixHomeserver != "" || c.MatrixRoomID != "" || c.MatrixAccessToken != "" {
+		if u, err := url.Parse(c.MatrixHomeserver); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("notifications.matrix_homeserver must be an http or https URL")
+		}
+		if c.MatrixRoomID == "" || c.MatrixAccessToken == "" {
+			return fmt.Errorf("notifications.matrix_room_id and notifications.matrix_access_token are required with notifications.matrix_homeserver")
+		}
+	}
+	clocks := map[string]string{
+		"notifications.digest_time": c.DigestTime,
+		"notifications.due_time":    c.DueTime,
+	}
+	for key, value := range clocks {
+		if _, err := time.Parse(NotificationClockLayout, value); value != "" && err != nil {
+			return fmt.Errorf("%s must be a time of day such as 08:00", key)
+		}
+	}
+	return nil
+}
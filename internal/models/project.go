@@ -0,0 +1,9 @@
+package models
+
+// Project is a folder served by the server, mounted at URL
+type Project struct {
+	Name    string `json:"name"`
+	Folder  string `json:"folder"`
+	URL     string `json:"url"`
+	Current bool   `json:"current"`
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Sort keys for note listings
+const (
+	SortCreated  = "created"
+	SortModified = "modified"
+	SortTitle    = "title"
+)
+
+// Directions for note listings
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// NoteQuery selects and orders notes for listing. Zero values match every
+// note; nil booleans do not filter.
+type NoteQuery struct {
+	// Q matches notes whose title, content or task text contains it, ignoring case
+	Q string
+	// Tag matches notes carrying the #tag
+	Tag string
+	// HasTasks matches notes with (or without) tasks
+	HasTasks *bool
+	// Pinned matches pinned (or unpinned) notes
+	Pinned *bool
+	// Archived matches notes with (or without) archived website links
+	Archived *bool
+
+	// Sort is SortCreated (the default, by the time in the notes' headers),
+	// SortModified or SortTitle; notes that compare equal stay in the order
+	// they are stored in
+	Sort string
+	// Order is OrderAsc or OrderDesc; by default titles are listed A to Z
+	// and times newest first
+	Order string
+
+	// Offset skips that many matching notes; Limit caps how many are
+	// returned (0 for all)
+	Offset int
+	Limit  int
+}
+
+// Validate checks the sort key and direction
+func (q *NoteQuery) Validate() error {
+	switch q.Sort {
+	case "", SortCreated, SortModified, SortTitle:
+	default:
+		return fmt.Errorf("sort must be created, modified or title")
+	}
+	switch q.Order {
+	case "", OrderAsc, OrderDesc:
+	default:
+		return fmt.Errorf("order must be asc or desc")
+	}
+	return nil
+}
+
+// Descending reports whether the notes are listed in descending order of
+// their sort key
+func (q *NoteQuery) Descending() bool {
+	switch {
+	case q.Order != "":
+		return q.Order == OrderDesc
+	case q.Sort == SortTitle:
+		return false
+	default:
+		return true
+	}
+}
+
+// Due windows for global task listings
+const (
+	DueOverdue = "overdue"
+	DueToday   = "today"
+	DueWeek    = "week"
+	DueNone    = "none"
+)
+
+// GlobalTaskQuery selects tasks across registered folders. Zero values match
+// every task.
+type GlobalTaskQuery struct {
+	// Folder matches tasks of the folder with this path or base name
+	Folder string
+	// Tag matches tasks carrying the #tag; Context those with the @context
+	Tag     string
+	Context string
+	// Due is DueOverdue (before today), DueToday, DueWeek (today and the six
+	// days after), DueNone (no due date) or a date, matching tasks due by it
+	Due string
+	// Priority matches tasks with this priority or a higher one
+	Priority string
+}
+
+// Validate checks the due window and priority
+func (q *GlobalTaskQuery) Validate() error {
+	switch q.Due {
+	case "", DueOverdue, DueToday, DueWeek, DueNone:
+	default:
+		if _, err := time.Parse("2006-01-02", q.Due); err != nil {
+			return fmt.Errorf("due must be overdue, today, week, none or a date such as 2006-01-02")
+		}
+	}
+	if q.Priority != "" && (len(q.Priority) != 1 || q.Priority[0] < 'A' || q.Priority[0] > 'Z') {
+		return fmt.Errorf("priority must be a letter from A to Z")
+	}
+	return nil
+}
+
+// Matches reports whether the task in folderPath, whose text says meta,
+// matches the query on the day today (formatted as 2006-01-02)
+func (q *GlobalTaskQuery) Matches(folderPath string, meta TaskMeta, today string) bool {
+	if !q.MatchesFolder(folderPath) {
+		return false
+	}
+	if q.Tag != "" && !containsFold(meta.Tags, strings.TrimPrefix(q.Tag, "#")) {
+		return false
+	}
+	if q.Context != "" && !containsFold(meta.Contexts, strings.TrimPrefix(q.Context, "@")) {
+		return false
+	}
+	if q.Priority != "" && (meta.Priority == "" || meta.Priority > q.Priority) {
+		return false
+	}
+
+	// Dates in 2006-01-02 form compare in order as strings
+	switch q.Due {
+	case "":
+		return true
+	case DueNone:
+		return meta.Due == ""
+	case DueOverdue:
+		return meta.Due != "" && meta.Due < today
+	case DueToday:
+		return meta.Due == today
+	case DueWeek:
+		start, _ := time.Parse("2006-01-02", today)
+		return meta.Due >= today && meta.Due < start.AddDate(0, 0, 7).Format("2006-01-02")
+	default:
+		return meta.Due != "" && meta.Due <= q.Due
+	}
+}
+
+// MatchesFolder reports whether the folder filter selects folderPath
+func (q *GlobalTaskQuery) MatchesFolder(folderPath string) bool {
+	return q.Folder == "" || q.Folder == folderPath || q.Folder == filepath.Base(folderPath)
+}
+
+// containsFold reports whether values holds value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"time"
 )
 
@@ -10,6 +14,11 @@ type FolderRegistry struct {
 	Path     string    `json:"path" db:"path"`
 	LastScan time.Time `json:"last_scan" db:"last_scan"`
 	Active   bool      `json:"active" db:"active"`
+
+	// Healthy is unset while the folder can't be read, such as after it was
+	// moved or deleted or while its drive is unmounted; Error says why
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
 }
 
 // GlobalTask represents a task from any registered folder
@@ -24,6 +33,9 @@ type GlobalTask struct {
 	
 	// Joined fields from folder
 	FolderPath  string    `json:"folder_path,omitempty"`
+
+	// Read from the task's text
+	TaskMeta
 }
 
 // TaskSummary provides aggregated task information for a folder
@@ -40,4 +52,110 @@ type GlobalTasksResponse struct {
 	Tasks     []GlobalTask  `json:"tasks"`
 	Summaries []TaskSummary `json:"summaries"`
 	Total     int           `json:"total"`
-}
\ No newline at end of file
+}
+// GlobalNote is a note from any registered folder, as listed in the global
+// notes timeline
+type GlobalNote struct {
+	FolderPath string    `json:"folder_path"`
+	Index      int       `json:"index"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	HTML       string    `json:"html"`
+	Timestamp  time.Time `json:"timestamp"`
+	Author     string    `json:"author,omitempty"`
+
+	// ProjectURL opens the note's project, when this server serves it
+	ProjectURL string `json:"project_url,omitempty"`
+}
+
+// GlobalNotesResponse represents the response for the global notes endpoint
+type GlobalNotesResponse struct {
+	Notes []GlobalNote `json:"notes"`
+	Total int          `json:"total"`
+}
+
+// FolderStats summarizes the notes and tasks of one registered folder
+type FolderStats struct {
+	FolderPath     string  `json:"folder_path"`
+	Healthy        bool    `json:"healthy"`
+	Notes          int     `json:"notes"`
+	OpenTasks      int     `json:"open_tasks"`
+	CompletedTasks int     `json:"completed_tasks"`
+	CompletionRate float64 `json:"completion_rate"` // Percent of tasks completed
+	DiskUsage      int64   `json:"disk_usage"`      // Bytes used by the folder
+}
+
+// GlobalStats sums the statistics of the registered folders
+type GlobalStats struct {
+	Folders        []FolderStats `json:"folders"`
+	Notes          int           `json:"notes"`
+	OpenTasks      int           `json:"open_tasks"`
+	CompletedTasks int           `json:"completed_tasks"`
+	CompletionRate float64       `json:"completion_rate"`
+	DiskUsage      int64         `json:"disk_usage"`
+}
+
+// completionRate returns the percent of tasks completed, to one decimal
+func completionRate(open, completed int) float64 {
+	if open+completed == 0 {
+		return 0
+	}
+	return math.Round(float64(completed)*1000/float64(open+completed)) / 10
+}
+
+// Add counts the statistics of a folder into the totals
+func (s *GlobalStats) Add(folder FolderStats) {
+	folder.CompletionRate = completionRate(folder.OpenTasks, folder.CompletedTasks)
+	s.Folders = append(s.Folders, folder)
+	s.Notes += folder.Notes
+	s.OpenTasks += folder.OpenTasks
+	s.CompletedTasks += folder.CompletedTasks
+	s.CompletionRate = completionRate(s.OpenTasks, s.CompletedTasks)
+	s.DiskUsage += folder.DiskUsage
+}
+
+// Rollover modes, saying whether rolled over tasks leave their note
+const (
+	RolloverMove = "move"
+	RolloverCopy = "copy"
+)
+
+// RolloverRequest moves or copies open global tasks into a note of another
+// registered folder, such as a weekly plan
+type RolloverRequest struct {
+	TaskIDs []int  `json:"task_ids"`
+	Folder  string `json:"folder"` // Registered folder receiving the tasks
+	Note    string `json:"note"`   // Title of the receiving note, created when missing
+	Mode    string `json:"mode"`   // RolloverMove (the default) or RolloverCopy
+}
+
+// Validate checks the request and fills in the default mode
+func (r *RolloverRequest) Validate() error {
+	r.Note = strings.TrimSpace(r.Note)
+	switch {
+	case len(r.TaskIDs) == 0:
+		return errors.New("task_ids must list at least one task")
+	case strings.TrimSpace(r.Folder) == "":
+		return errors.New("folder is required")
+	case r.Note == "":
+		return errors.New("note is required")
+	}
+	switch r.Mode {
+	case "":
+		r.Mode = RolloverMove
+	case RolloverMove, RolloverCopy:
+	default:
+		return fmt.Errorf("unknown mode %q; use %q or %q", r.Mode, RolloverMove, RolloverCopy)
+	}
+	return nil
+}
+
+// RolloverResult describes the note that received rolled over tasks
+type RolloverResult struct {
+	Folder    string `json:"folder"`
+	Note      string `json:"note"`
+	NoteIndex int    `json:"note_index"`
+	Created   bool   `json:"created"` // The note was created for the tasks
+	Mode      string `json:"mode"`
+	Tasks     int    `json:"tasks"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// SaveStatus reports how far a project's changes have been written to its
+// notes.md, and whether it was damaged when loaded
+type SaveStatus struct {
+	// Pending is true while changes wait to be written
+	Pending bool `json:"pending"`
+	// LastSaved is when notes.md was last written
+	LastSaved *time.Time `json:"last_saved,omitempty"`
+	// Failures counts the failed attempts since the last successful write,
+	// which are retried
+	Failures int `json:"failures"`
+	// LastError is why the last attempt failed, while it is being retried
+	LastError string `json:"last_error,omitempty"`
+	// Recovered counts the damaged parts of notes.md, such as merge
+	// conflicts, kept in a recovery note when it was loaded
+	Recovered int `json:"recovered,omitempty"`
+	// Warning explains what was recovered
+	Warning string `json:"warning,omitempty"`
+}
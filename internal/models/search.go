@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Parts of a note a search can find words in
+const (
+	SearchFieldTitle       = "title"
+	SearchFieldContent     = "content"
+	SearchFieldTasks       = "tasks"
+	SearchFieldAttachments = "attachments"
+)
+
+// SearchResult is a note found by a full-text search. Results are listed
+// best match first.
+type SearchResult struct {
+	// Index addresses the note in the rest of the API; ID, the name of its
+	// asset folder, keeps identifying it as other notes are added
+	Index     int       `json:"index"`
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Timestamp time.Time `json:"timestamp"`
+	// Score ranks the result; words in the title count most, then those in
+	// tasks, then the content and the text of attached files
+	Score int `json:"score"`
+	// Fields are the parts of the note the words were found in
+	Fields []string `json:"fields"`
+	// Snippets are the text around the words found, with "…" where it was
+	// cut
+	Snippets []string `json:"snippets"`
+}
@@ -0,0 +1,69 @@
+package models
+
+// ServerConfig holds settings for the HTTP server
+type ServerConfig struct {
+	// Host is the interface to listen on (empty means 127.0.0.1; "*" means all interfaces)
+	Host string `json:"host,omitempty"`
+	// Port is the port to listen on (0 means 8000, or 443 with Let's Encrypt)
+	Port int `json:"port,omitempty"`
+	// BasePath is a URL prefix such as "/noteflow" for serving behind a
+	// reverse proxy that routes by path
+	BasePath string `json:"base_path,omitempty"`
+	// ReadOnly rejects every request that would change notes, settings or
+	// the server, for publishing a project safely
+	ReadOnly bool `json:"read_only,omitempty"`
+
+	TLS TLSConfig `json:"tls"`
+
+	RateLimit RateLimitConfig `json:"rate_limit"`
+
+	MDNS MDNSConfig `json:"mdns"`
+}
+
+// MDNSConfig holds settings for advertising the server on the local network
+// as a _noteflow._tcp service
+type MDNSConfig struct {
+	// Disabled turns the advertisement off
+	Disabled bool `json:"disabled"`
+	// Name is the instance name shown to browsing devices (empty means
+	// "NoteFlow on <hostname>")
+	Name string `json:"name,omitempty"`
+}
+
+// RateLimitConfig holds per-client rate limits for the API. Mutating requests
+// share one limit; archiving, uploads, imports and exports have a stricter one.
+// Zero values use the defaults.
+type RateLimitConfig struct {
+	// Disabled turns rate limiting off
+	Disabled bool `json:"disabled"`
+
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+	Burst             int `json:"burst,omitempty"`
+
+	ExpensivePerMinute int `json:"expensive_per_minute,omitempty"`
+	ExpensiveBurst     int `json:"expensive_burst,omitempty"`
+}
+
+// TLSConfig holds HTTPS settings. Either a certificate and key file or a list
+// of hostnames for automatic Let's Encrypt certificates may be given.
+type TLSConfig struct {
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+
+	// AutocertHosts are the public hostnames to obtain Let's Encrypt certificates for
+	AutocertHosts []string `json:"autocert_hosts,omitempty"`
+	// AutocertEmail is the optional contact address registered with Let's Encrypt
+	AutocertEmail string `json:"autocert_email,omitempty"`
+	// CacheDir stores obtained certificates (empty uses the config directory)
+	CacheDir string `json:"cache_dir,omitempty"`
+}
+
+// Enabled reports whether the server should serve HTTPS
+func (c *TLSConfig) Enabled() bool {
+	return c.CertFile != "" || len(c.AutocertHosts) > 0
+}
+
+// Autocert reports whether certificates are obtained from Let's Encrypt
+func (c *TLSConfig) Autocert() bool {
+	return len(c.AutocertHosts) > 0 && c.CertFile == ""
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Session is a signed-in browser session. User is empty for sessions opened
+// with the shared password.
+type Session struct {
+	User    string    `json:"user"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+	// Remember marks a remember-me session, whose cookie outlives the browser
+	Remember bool `json:"remember"`
+}
+
+// Expired reports whether the session has ended at time now
+func (s *Session) Expired(now time.Time) bool {
+	return !now.Before(s.Expires)
+}
+
+// LoginRequest represents a sign-in request
+type LoginRequest struct {
+	Username string `form:"username" json:"username"`
+	Password string `form:"password" json:"password"`
+	Remember bool   `form:"remember" json:"remember"`
+}
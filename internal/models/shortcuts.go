@@ -0,0 +1,155 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ShortcutDisabled turns a keyboard shortcut off
+const ShortcutDisabled = "none"
+
+// DefaultShortcuts are the keys used for actions without a configured shortcut
+var DefaultShortcuts = ShortcutConfig{
+	NewNote:    "alt+n",
+	Search:     "/",
+	ToggleTask: "alt+x",
+}
+
+// shortcutModifiers are the modifier keys a shortcut may combine, in the
+// order they are written
+var shortcutModifiers = []string{"ctrl", "alt", "shift", "meta"}
+
+// shortcutKeys are the named keys a shortcut may end with, besides a single
+// character and f1 to f12
+var shortcutKeys = map[string]bool{
+	"enter": true, "escape": true, "tab": true, "space": true,
+	"backspace": true, "delete": true, "insert": true, "home": true, "end": true,
+	"pageup": true, "pagedown": true,
+	"arrowup": true, "arrowdown": true, "arrowleft": true, "arrowright": true,
+}
+
+// ShortcutConfig holds the keyboard shortcuts of the web pages, written as
+// modifiers and a key joined by "+", such as "ctrl+alt+n" or "/". Empty
+// fields use DefaultShortcuts; "none" turns a shortcut off.
+type ShortcutConfig struct {
+	// NewNote moves to the title of a new note
+	NewNote string `json:"new_note,omitempty"`
+	// Search filters the notes by words in their title or content
+	Search string `json:"search,omitempty"`
+	// ToggleTask checks or unchecks the focused active task, or else the
+	// first one
+	ToggleTask string `json:"toggle_task,omitempty"`
+}
+
+// fields returns the shortcuts keyed by their setting names
+func (c *ShortcutConfig) fields() map[string]*string {
+	return map[string]*string{
+		"new_note":    &c.NewNote,
+		"search":      &c.Search,
+		"toggle_task": &c.ToggleTask,
+	}
+}
+
+// Normalize writes every shortcut in the canonical form: lower case, without
+// spaces and with the modifiers in a fixed order
+func (c *ShortcutConfig) Normalize() error {
+	for name, shortcut := range c.fields() {
+		normalized, err := normalizeShortcut(*shortcut)
+		if err != nil {
+			return fmt.Errorf("ui.shortcuts.%s: %w", name, err)
+		}
+		*shortcut = normalized
+	}
+	return nil
+}
+
+// Validate checks that every shortcut can be typed and that no two actions
+// share one
+func (c *ShortcutConfig) Validate() error {
+	normalized := *c
+	if err := normalized.Normalize(); err != nil {
+		return err
+	}
+	effective := normalized.WithDefaults()
+	used := make(map[string]string)
+	for name, shortcut := range effective.fields() {
+		if *shortcut == ShortcutDisabled {
+			continue
+		}
+		if other, ok := used[*shortcut]; ok {
+			first, second := other, name
+			if second < first {
+				first, second = second, first
+			}
+			return fmt.Errorf("ui.shortcuts.%s and ui.shortcuts.%s are both %q", first, second, *shortcut)
+		}
+		used[*shortcut] = name
+	}
+	return nil
+}
+
+// WithDefaults returns the shortcuts with empty fields set to their defaults
+func (c ShortcutConfig) WithDefaults() ShortcutConfig {
+	defaults := DefaultShortcuts
+	for name, shortcut := range c.fields() {
+		if *shortcut == "" {
+			*shortcut = *defaults.fields()[name]
+		}
+	}
+	return c
+}
+
+// normalizeShortcut parses a shortcut such as "Ctrl + Alt + N" and writes it
+// as "ctrl+alt+n"
+func normalizeShortcut(shortcut string) (string, error) {
+	shortcut = strings.ToLower(strings.ReplaceAll(shortcut, " ", ""))
+	if shortcut == "" || shortcut == ShortcutDisabled {
+		return shortcut, nil
+	}
+
+	// The key follows the last "+" that is not itself the key, as in "ctrl++"
+	key := shortcut
+	var parts []string
+	if i := strings.LastIndex(strings.TrimSuffix(shortcut, "+"), "+"); i >= 0 {
+		parts, key = strings.Split(shortcut[:i], "+"), shortcut[i+1:]
+	}
+
+	modifiers := make(map[string]bool)
+	for _, part := range parts {
+		known := false
+		for _, modifier := range shortcutModifiers {
+			known = known || part == modifier
+		}
+		if !known {
+			return "", fmt.Errorf("unknown modifier %q; use ctrl, alt, shift or meta", part)
+		}
+		modifiers[part] = true
+	}
+	if !isShortcutKey(key) {
+		return "", fmt.Errorf("unknown key %q; use a single character, a function key or a named key such as enter", key)
+	}
+
+	var normalized []string
+	for _, modifier := range shortcutModifiers {
+		if modifiers[modifier] {
+			normalized = append(normalized, modifier)
+		}
+	}
+	return strings.Join(append(normalized, key), "+"), nil
+}
+
+// isShortcutKey reports whether key names a key a shortcut can end with
+func isShortcutKey(key string) bool {
+	if len([]rune(key)) == 1 {
+		return true
+	}
+	if shortcutKeys[key] {
+		return true
+	}
+	for i := 1; i <= 12; i++ {
+		if key == fmt.Sprintf("f%d", i) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package models
+
+// SlackConfig connects a Slack workspace: a slash command such as /note
+// adds notes, and completed tasks can be announced in a channel
+type SlackConfig struct {
+	// SigningSecret is the Slack app's signing secret, which every slash
+	// command request is verified with; commands are refused without it
+	SigningSecret string `json:"signing_secret,omitempty"`
+	// NoteTitle appends commands to the newest note with this title, created
+	// when there is none (empty adds each command as a note of its own)
+	NoteTitle string `json:"note_title,omitempty"`
+	// NotifyURL is an incoming webhook URL that completed tasks are posted
+	// to (empty sends no notifications)
+	NotifyURL string `json:"notify_url,omitempty"`
+}
+
+// SlackCommand is a slash command request sent by Slack
+type SlackCommand struct {
+	Command  string `form:"command"`
+	Text     string `form:"text"`
+	UserName string `form:"user_name"`
+}
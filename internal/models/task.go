@@ -1,10 +1,18 @@
 package models
 
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
 // Task represents a checkbox task within a note
 type Task struct {
 	Index   int    `json:"index"`   // Unique global identifier
 	Checked bool   `json:"checked"` // Completion state
 	Text    string `json:"text"`    // Full task text including checkbox
+
+	CompletedBy string `json:"completed_by,omitempty"` // User who completed the task, if known
 }
 
 // TaskInfo represents task information for API responses
@@ -18,4 +26,62 @@ type TaskInfo struct {
 // TaskUpdate represents a task update request
 type TaskUpdate struct {
 	Checked bool `json:"checked"`
-}
\ No newline at end of file
+}
+// TaskLabel returns a task's text without its checkbox or the comments
+// naming who completed it and linking it to Todoist
+func TaskLabel(text string) string {
+	text = todoistLinkPattern.ReplaceAllString(completedByPattern.ReplaceAllString(text, ""), "")
+	return strings.TrimSpace(strings.Replace(strings.Replace(text, "[x]", "", 1), "[ ]", "", 1))
+}
+
+// priorityPattern matches a todo.txt style "(A)" priority at the start of a
+// task, after its checkbox
+var priorityPattern = regexp.MustCompile(`^\s*(?:[-*+]\s+)?(?:\[[ xX]\]\s*)?\(([A-Z])\)(?:\s|$)`)
+
+// contextPattern matches @contexts in task text
+var contextPattern = regexp.MustCompile(`(?:^|[\s(])@([A-Za-z][\w/-]*)`)
+
+// duePattern matches a "due:2026-10-20" date in task text
+var duePattern = regexp.MustCompile(`(?:^|\s)due:(\d{4}-\d{2}-\d{2})(?:\s|$)`)
+
+// remindPattern matches a "remind:2026-10-20T09:30" time in task text
+var remindPattern = regexp.MustCompile(`(?:^|\s)remind:(\d{4}-\d{2}-\d{2}T\d{2}:\d{2})(?:\s|$)`)
+
+// TaskMeta is what a task's text says about it besides its description:
+// #tags, @contexts, a "due:2026-10-20" date, a "remind:2026-10-20T09:30"
+// time to be reminded at and a todo.txt style "(A)" priority, A being the
+// highest
+type TaskMeta struct {
+	Tags     []string `json:"tags,omitempty"`
+	Contexts []string `json:"contexts,omitempty"`
+	Due      string   `json:"due,omitempty"`
+	Remind   string   `json:"remind,omitempty"`
+	Priority string   `json:"priority,omitempty"`
+}
+
+// ParseTaskMeta reads the tags, contexts, due date, reminder and priority of
+// a task. Tags and contexts are lowercased; a due date or reminder that isn't
+// a valid time is ignored.
+func ParseTaskMeta(text string) TaskMeta {
+	var meta TaskMeta
+	for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		meta.Tags = append(meta.Tags, strings.ToLower(match[1]))
+	}
+	for _, match := range contextPattern.FindAllStringSubmatch(text, -1) {
+		meta.Contexts = append(meta.Contexts, strings.ToLower(match[1]))
+	}
+	if match := duePattern.FindStringSubmatch(text); match != nil {
+		if _, err := time.Parse("2006-01-02", match[1]); err == nil {
+			meta.Due = match[1]
+		}
+	}
+	if match := remindPattern.FindStringSubmatch(text); match != nil {
+		if _, err := time.Parse(RemindLayout, match[1]); err == nil {
+			meta.Remind = match[1]
+		}
+	}
+	if match := priorityPattern.FindStringSubmatch(text); match != nil {
+		meta.Priority = match[1]
+	}
+	return meta
+}
@@ -0,0 +1,26 @@
+package models
+
+// TelegramConfig connects a Telegram bot, so notes and tasks can be sent
+// from a phone's chat app
+type TelegramConfig struct {
+	// Token is the bot's token from @BotFather; the bot runs when it is set
+	Token string `json:"token,omitempty"`
+	// AllowedChats are the IDs of the chats the bot takes messages from. The
+	// bot answers other chats with their ID, to be added here.
+	AllowedChats []int64 `json:"allowed_chats,omitempty"`
+	// NoteTitle appends messages to the newest note with this title, created
+	// when there is none (empty adds each message as a note of its own)
+	NoteTitle string `json:"note_title,omitempty"`
+	// APIURL is the Bot API server (empty means https://api.telegram.org)
+	APIURL string `json:"api_url,omitempty"`
+}
+
+// ChatAllowed reports whether the bot takes messages from the chat with id
+func (c *TelegramConfig) ChatAllowed(id int64) bool {
+	for _, allowed := range c.AllowedChats {
+		if allowed == id {
+			return true
+		}
+	}
+	return false
+}
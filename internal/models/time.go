@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TimestampLayout is the format of timestamps in note headers and the API
+const TimestampLayout = "2006-01-02 15:04:05"
+
+// TimeConfig controls the timezone of note times and how they are shown
+type TimeConfig struct {
+	// Format is a Go time layout such as "Jan 2, 2006 3:04 PM" for showing
+	// note times (empty means TimestampLayout)
+	Format string `json:"format,omitempty"`
+	// Relative shows note times as "2 hours ago", in the browser's language
+	Relative bool `json:"relative"`
+	// Timezone is an IANA name such as "Europe/Berlin" that note times are
+	// read, written and shown in (empty means the server's local time)
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Validate checks that the timezone exists and the format is a layout
+func (c *TimeConfig) Validate() error {
+	if c.Timezone != "" {
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			return fmt.Errorf("time.timezone: unknown timezone %q", c.Timezone)
+		}
+	}
+	if len(c.Format) > 64 {
+		return fmt.Errorf("time.format must be at most 64 characters")
+	}
+	// A layout without any of the reference time's elements shows the same
+	// text for every time
+	if c.Format != "" && time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC).Format(c.Format) == c.Format {
+		return fmt.Errorf("time.format must be a Go layout such as \"Jan 2, 2006 15:04\"")
+	}
+	return nil
+}
+
+// The time settings in use, shared by everything that reads or shows notes
+var (
+	timeMu       sync.RWMutex
+	timeSettings TimeConfig
+	timeLocation = time.Local
+)
+
+// SetTimeConfig sets the timezone and display format of note times. An
+// unknown timezone falls back to the server's local time.
+func SetTimeConfig(config TimeConfig) {
+	location := time.Local
+	if config.Timezone != "" {
+		if loaded, err := time.LoadLocation(config.Timezone); err == nil {
+			location = loaded
+		}
+	}
+
+	timeMu.Lock()
+	defer timeMu.Unlock()
+	timeSettings = config
+	timeLocation = location
+}
+
+// Location returns the timezone of note times
+func Location() *time.Location {
+	timeMu.RLock()
+	defer timeMu.RUnlock()
+	return timeLocation
+}
+
+// Now returns the current time in the timezone of note times
+func Now() time.Time {
+	return time.Now().In(Location())
+}
+
+// ParseTimestamp parses a timestamp in TimestampLayout as a time in the
+// timezone of note times
+func ParseTimestamp(value string) (time.Time, error) {
+	return time.ParseInLocation(TimestampLayout, value, Location())
+}
+
+// FormatTimestamp formats t in TimestampLayout in the timezone of note times
+func FormatTimestamp(t time.Time) string {
+	return t.In(Location()).Format(TimestampLayout)
+}
+
+// DisplayTime formats t for showing, with the configured format
+func DisplayTime(t time.Time) string {
+	timeMu.RLock()
+	defer timeMu.RUnlock()
+	layout := timeSettings.Format
+	if layout == "" {
+		layout = TimestampLayout
+	}
+	return t.In(timeLocation).Format(layout)
+}
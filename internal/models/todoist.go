@@ -0,0 +1,104 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Conflict rules of the Todoist sync, deciding a task's state when it is
+// checked on one side but not the other and neither is known to have changed
+const (
+	// TodoistConflictCompleted keeps the task completed on both sides
+	TodoistConflictCompleted = "completed"
+	// TodoistConflictNoteFlow takes the state of the task in NoteFlow
+	TodoistConflictNoteFlow = "noteflow"
+	// TodoistConflictTodoist takes the state of the task in Todoist
+	TodoistConflictTodoist = "todoist"
+)
+
+// DefaultTodoistInterval is the default number of minutes between syncs
+const DefaultTodoistInterval = 5
+
+// TodoistConfig syncs the tasks of the notes with a Todoist project
+type TodoistConfig struct {
+	// Token is a Todoist API token; tasks are synced when it is set
+	Token string `json:"token,omitempty"`
+	// ProjectID is the Todoist project the tasks are synced with, required
+	// with Token
+	ProjectID string `json:"project_id,omitempty"`
+	// NoteTitle is the note that tasks added in Todoist are appended to
+	// (empty means "Todoist")
+	NoteTitle string `json:"note_title,omitempty"`
+	// IntervalMinutes is how often Todoist is checked for changes (0 means
+	// every DefaultTodoistInterval minutes). Tasks checked off in NoteFlow
+	// are sent at once.
+	IntervalMinutes int `json:"interval_minutes,omitempty"`
+	// Conflict is the rule for tasks whose state differs and whose history
+	// is unknown, such as after the sync state was lost: "completed" (the
+	// default), "noteflow" or "todoist"
+	Conflict string `json:"conflict,omitempty"`
+	// APIURL is the Todoist API (empty means https://api.todoist.com/api/v1)
+	APIURL string `json:"api_url,omitempty"`
+}
+
+// Interval returns the time between syncs
+func (c *TodoistConfig) Interval() time.Duration {
+	if c.IntervalMinutes <= 0 {
+		return DefaultTodoistInterval * time.Minute
+	}
+	return time.Duration(c.IntervalMinutes) * time.Minute
+}
+
+// TodoistStatus reports the state of the Todoist sync
+type TodoistStatus struct {
+	ProjectID string `json:"project_id,omitempty"`
+	Conflict  string `json:"conflict"`
+	Syncing   bool   `json:"syncing"`
+	// Linked is the number of tasks linked to a Todoist task
+	Linked int `json:"linked"`
+
+	LastSync  *time.Time `json:"last_sync,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	NextSync  *time.Time `json:"next_sync,omitempty"`
+
+	// LastChanges are the changes made by the last successful sync
+	LastChanges TodoistChanges `json:"last_changes"`
+}
+
+// TodoistChanges counts the changes made by a sync
+type TodoistChanges struct {
+	// Exported tasks were added to Todoist, Imported ones to the notes
+	Exported int `json:"exported"`
+	Imported int `json:"imported"`
+	// Pushed completions were sent to Todoist, Pulled ones taken from it
+	Pushed int `json:"pushed"`
+	Pulled int `json:"pulled"`
+	// Conflicts were settled by the conflict rule
+	Conflicts int `json:"conflicts"`
+	// Unlinked tasks were deleted in Todoist and are no longer synced
+	Unlinked int `json:"unlinked"`
+}
+
+// todoistLinkPattern matches the comment linking a task to a Todoist task,
+// capturing the Todoist task ID
+var todoistLinkPattern = regexp.MustCompile(` ?<!-- todoist: (\S+) -->`)
+
+// TodoistTaskID returns the ID of the Todoist task linked to task text, or
+// "" if it isn't linked
+func TodoistTaskID(text string) string {
+	if match := todoistLinkPattern.FindStringSubmatch(text); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// LinkTodoistTask returns task text linked to the Todoist task id, or
+// unlinked when id is empty
+func LinkTodoistTask(text, id string) string {
+	text = todoistLinkPattern.ReplaceAllString(text, "")
+	if id == "" {
+		return text
+	}
+	return strings.TrimRight(text, " ") + " <!-- todoist: " + id + " -->"
+}
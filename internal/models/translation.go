@@ -0,0 +1,52 @@
+package models
+
+import "regexp"
+
+// Translation providers that notes can be translated with
+const (
+	// TranslationProviderAI translates with the language model in the ai
+	// settings
+	TranslationProviderAI = "ai"
+	// TranslationProviderDeepL is DeepL's translation API
+	TranslationProviderDeepL = "deepl"
+	// TranslationProviderLibreTranslate is a LibreTranslate server
+	TranslationProviderLibreTranslate = "libretranslate"
+)
+
+// Ways a note's translation is stored
+const (
+	// TranslationStoreBlock keeps the translation in a block at the end of
+	// the note
+	TranslationStoreBlock = "block"
+	// TranslationStoreNote keeps the translation in a note of its own,
+	// linked to the original
+	TranslationStoreNote = "note"
+)
+
+// TranslationLanguagePattern matches the language codes notes can be
+// translated to, such as "de" or "pt-BR"
+var TranslationLanguagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(?:-[A-Za-z0-9]{2,8})?$`)
+
+// TranslationConfig selects the service that translates notes
+type TranslationConfig struct {
+	// Provider is "ai", "deepl" or "libretranslate"; translation is off when
+	// it is empty
+	Provider string `json:"provider,omitempty"`
+	// URL is the API's base URL (empty means DeepL's API for its key, or
+	// http://localhost:5000 for LibreTranslate)
+	URL string `json:"url,omitempty"`
+	// APIKey authenticates with DeepL or LibreTranslate
+	APIKey string `json:"api_key,omitempty"`
+	// Store is how translations are kept unless a request says otherwise:
+	// "block" (the default) or "note"
+	Store string `json:"store,omitempty"`
+}
+
+// Translation is a note's translation as stored. Index is the note holding
+// it: the translated note itself, or the note linked to it.
+type Translation struct {
+	Index    int    `json:"index"`
+	Language string `json:"language"`
+	Store    string `json:"store"`
+	Text     string `json:"text"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// DefaultTrashPurgeDays is how many days deleted notes are kept unless
+// configured
+const DefaultTrashPurgeDays = 30
+
+// TrashConfig controls how long deleted notes are kept
+type TrashConfig struct {
+	// PurgeDays is how many days deleted notes stay in the trash before they
+	// and their uploads are deleted for good (0 uses the default)
+	PurgeDays int `json:"purge_days,omitempty"`
+}
+
+// Retention returns how long deleted notes are kept
+func (c *TrashConfig) Retention() time.Duration {
+	days := DefaultTrashPurgeDays
+	if c != nil && c.PurgeDays > 0 {
+		days = c.PurgeDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// TrashedNote is a deleted note as kept in the trash
+type TrashedNote struct {
+	ID string `json:"id"`
+	// Text is the note as it was written in notes.md
+	Text      string    `json:"text"`
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy string    `json:"deleted_by,omitempty"`
+}
+
+// TrashItem is a note in the trash as listed, most recently deleted first
+type TrashItem struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy string    `json:"deleted_by,omitempty"`
+	// PurgeAt is when the note is deleted for good
+	PurgeAt time.Time `json:"purge_at"`
+}
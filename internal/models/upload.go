@@ -0,0 +1,211 @@
+package models
+
+import (
+	"mime"
+	"strings"
+	"time"
+)
+
+// DefaultMaxUploadMB is the upload size limit used when none is configured
+const DefaultMaxUploadMB = 50
+
+// DefaultImageQuality is the JPEG quality used when recompressing images
+// without a configured quality
+const DefaultImageQuality = 85
+
+// DefaultUploadExtensions are the file types accepted when none are configured
+var DefaultUploadExtensions = []string{
+	".jpg", ".jpeg", ".png", ".gif", ".webp", ".heic", ".heif",
+	".pdf", ".txt", ".md", ".doc", ".docx",
+	".zip", ".tar", ".gz",
+	".json", ".xml", ".csv",
+	".webm", ".ogg", ".m4a", ".mp3",
+	".mp4", ".mov", ".m4v",
+}
+
+// Subdirectories of assets that uploads are saved in
+const (
+	AssetImages = "images"
+	AssetAudio  = "audio"
+	AssetVideo  = "video"
+	AssetFiles  = "files"
+)
+
+// mediaTypes are the content types of HEIC images and audio and video files
+// by extension, for uploads sent without a usable one. WebM files are taken
+// for voice memos.
+var mediaTypes = map[string]string{
+	".heic": "image/heic",
+	".heif": "image/heif",
+	".webm": "audio/webm",
+	".ogg":  "audio/ogg",
+	".m4a":  "audio/mp4",
+	".mp3":  "audio/mpeg",
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+}
+
+// MediaType returns the content type of HEIC images and audio or video files
+// with extension ext, or "" for other extensions
+func MediaType(ext string) string {
+	return mediaTypes[strings.ToLower(ext)]
+}
+
+// AssetDir returns the subdirectory of assets that uploads of contentType
+// are saved in
+func AssetDir(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return AssetImages
+	case strings.HasPrefix(contentType, "audio/"):
+		return AssetAudio
+	case strings.HasPrefix(contentType, "video/"):
+		return AssetVideo
+	}
+	return AssetFiles
+}
+
+// UploadConfig limits the files that can be attached to notes
+type UploadConfig struct {
+	// MaxSizeMB is the largest file accepted (0 means 50)
+	MaxSizeMB int `json:"max_size_mb,omitempty"`
+	// MaxSizeMBByType overrides MaxSizeMB for MIME types such as
+	// "application/pdf", or for all subtypes with a type such as "video/*"
+	MaxSizeMBByType map[string]int `json:"max_size_mb_by_type,omitempty"`
+	// AllowedExtensions replaces the default list of accepted extensions
+	AllowedExtensions []string `json:"allowed_extensions,omitempty"`
+	// AllowedTypes optionally restricts uploads to these MIME types; a type
+	// such as "image/*" accepts every subtype
+	AllowedTypes []string `json:"allowed_types,omitempty"`
+	// ImageMaxSize scales uploaded JPEG and PNG images down so their longest
+	// side is at most this many pixels (0 keeps their size)
+	ImageMaxSize int `json:"image_max_size,omitempty"`
+	// ImageQuality recompresses uploaded JPEG images at this quality, from 1
+	// to 100 (0 only recompresses scaled images, at 85)
+	ImageQuality int `json:"image_quality,omitempty"`
+	// KeepImageMetadata keeps the EXIF data, such as GPS positions, and other
+	// metadata of uploaded images, which are otherwise removed
+	KeepImageMetadata bool `json:"keep_image_metadata,omitempty"`
+	// OCRCommand reads the text of uploaded images in the background, so
+	// searches find them; it gets the image path, in place of "{}" or as
+	// the last argument, and prints the text
+	OCRCommand string `json:"ocr_command,omitempty"`
+	// PDFTextCommand reads the text of uploaded PDFs the same way
+	PDFTextCommand string `json:"pdf_text_command,omitempty"`
+	// TranscribeCommand transcribes uploaded audio the same way, such as a
+	// script running whisper.cpp. The transcript is appended to the notes
+	// linking to the audio and searched with them.
+	TranscribeCommand string `json:"transcribe_command,omitempty"`
+	// TranscribeURL transcribes uploaded audio with a speech-to-text API
+	// instead, such as OpenAI's audio transcriptions or a whisper.cpp
+	// server's inference endpoint, which is sent the file as a form
+	TranscribeURL string `json:"transcribe_url,omitempty"`
+	// TranscribeModel is sent to TranscribeURL as the model to use
+	TranscribeModel string `json:"transcribe_model,omitempty"`
+	// TranscribeAPIKey is sent to TranscribeURL as a bearer token
+	TranscribeAPIKey string `json:"transcribe_api_key,omitempty"`
+	// HEICCommand converts HEIC images, as iPhones take them, to JPEG;
+	// "{}" in it is replaced by the HEIC file and "{out}" by the JPEG file to
+	// write. heif-convert, ImageMagick or sips is used when it isn't set.
+	HEICCommand string `json:"heic_command,omitempty"`
+	// KeepHEICOriginal also saves converted HEIC images as uploaded
+	KeepHEICOriginal bool `json:"keep_heic_original,omitempty"`
+	// PerNoteFolders keeps the uploads a note links to in a folder of its
+	// own, assets/<note timestamp>/, moving them there when it is saved, so
+	// deleting or exporting the note takes exactly its attachments
+	PerNoteFolders bool `json:"per_note_folders,omitempty"`
+}
+
+// MaxBytes returns the largest upload accepted, in bytes
+func (c *UploadConfig) MaxBytes() int64 {
+	size := c.MaxSizeMB
+	if size <= 0 {
+		size = DefaultMaxUploadMB
+	}
+	return int64(size) << 20
+}
+
+// MaxBytesFor returns the largest upload of MIME type contentType accepted,
+// in bytes
+func (c *UploadConfig) MaxBytesFor(contentType string) int64 {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		kind, _, _ := strings.Cut(mediaType, "/")
+		for _, key := range []string{mediaType, kind + "/*"} {
+			for configured, size := range c.MaxSizeMBByType {
+				if size > 0 && strings.EqualFold(strings.TrimSpace(configured), key) {
+					return int64(size) << 20
+				}
+			}
+		}
+	}
+	return c.MaxBytes()
+}
+
+// LargestBytes returns the largest upload accepted for any type, in bytes
+func (c *UploadConfig) LargestBytes() int64 {
+	largest := c.MaxBytes()
+	for _, size := range c.MaxSizeMBByType {
+		largest = max(largest, int64(size)<<20)
+	}
+	return largest
+}
+
+// Extensions returns the accepted file extensions
+func (c *UploadConfig) Extensions() []string {
+	if len(c.AllowedExtensions) == 0 {
+		return DefaultUploadExtensions
+	}
+	return c.AllowedExtensions
+}
+
+// ExtensionAllowed reports whether files with extension ext may be uploaded
+func (c *UploadConfig) ExtensionAllowed(ext string) bool {
+	for _, allowed := range c.Extensions() {
+		if strings.EqualFold(ext, "."+strings.TrimPrefix(allowed, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// TypeAllowed reports whether files of MIME type contentType may be uploaded
+func (c *UploadConfig) TypeAllowed(contentType string) bool {
+	if len(c.AllowedTypes) == 0 {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range c.AllowedTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// PartialUpload is a file being uploaded in chunks, which can be resumed
+// after a dropped connection from Offset
+type PartialUpload struct {
+	ID          string    `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type,omitempty"`
+	Size        int64     `json:"size"`
+	Offset      int64     `json:"offset"`
+	Created     time.Time `json:"created"`
+	// FilePath is where the file was saved, once all of it has arrived
+	FilePath string `json:"file_path,omitempty"`
+	IsImage  bool   `json:"is_image,omitempty"`
+}
+
+// Complete reports whether every byte of the file has been uploaded
+func (u *PartialUpload) Complete() bool {
+	return u.Offset >= u.Size
+}
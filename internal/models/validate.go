@@ -0,0 +1,179 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/i18n"
+)
+
+// Validate reports the first setting that the server could not use. The
+// theme name is checked by the caller, which knows the available themes.
+func (c *Config) Validate() error {
+	server := &c.Server
+	if server.Port < 0 || server.Port > 65535 {
+		return fmt.Errorf("server.port must be between 1 and 65535")
+	}
+	if server.TLS.CertFile != "" && server.TLS.KeyFile == "" {
+		return fmt.Errorf("server.tls.key_file is required with server.tls.cert_file")
+	}
+
+	limits := map[string]int{
+		"server.rate_limit.requests_per_minute":  server.RateLimit.RequestsPerMinute,
+		"server.rate_limit.burst":                server.RateLimit.Burst,
+		"server.rate_limit.expensive_per_minute": server.RateLimit.ExpensivePerMinute,
+		"server.rate_limit.expensive_burst":      server.RateLimit.ExpensiveBurst,
+		"uploads.max_size_mb":                    c.Uploads.MaxSizeMB,
+		"uploads.image_max_size":                 c.Uploads.ImageMaxSize,
+		"archive.concurrency":                    c.Archive.Concurrency,
+		"archive.page_timeout_seconds":           c.Archive.PageTimeoutSeconds,
+		"archive.frames.max_depth":               c.Archive.Frames.MaxDepth,
+		"fetch.concurrency":                      c.Fetch.Concurrency,
+		"fetch.host_concurrency":                 c.Fetch.HostConcurrency,
+		"fetch.host_delay_ms":                    c.Fetch.HostDelayMS,
+		"auth.session_hours":                     c.Auth.SessionHours,
+		"auth.remember_days":                     c.Auth.RememberDays,
+		"backup.keep":                            c.Backup.Keep,
+		"trash.purge_days":                       c.Trash.PurgeDays,
+		"todoist.interval_minutes":               c.Todoist.IntervalMinutes,
+		"ai.timeout_seconds":                     c.AI.TimeoutSeconds,
+	}
+	for key, value := range limits {
+		if value < 0 {
+			return fmt.Errorf("%s must not be negative", key)
+		}
+	}
+
+	for contentType, value := range c.Uploads.MaxSizeMBByType {
+		if value < 0 {
+			return fmt.Errorf("uploads.max_size_mb_by_type.%s must not be negative", contentType)
+		}
+	}
+
+	if c.Archive.ScreenshotCommand != "" && !strings.Contains(c.Archive.ScreenshotCommand, "{out}") {
+		return fmt.Errorf("archive.screenshot_command must include {out}, the image file to write")
+	}
+
+	if c.Uploads.ImageQuality > 100 {
+		return fmt.Errorf("uploads.image_quality must be between 1 and 100")
+	}
+	if c.Uploads.TranscribeURL != "" {
+		if u, err := url.Parse(c.Uploads.TranscribeURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("uploads.transcribe_url must be an http or https URL")
+		}
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("log.level must be one of debug, info, warn, error")
+	}
+	switch c.Log.Format {
+	case "", LogFormatText, LogFormatJSON:
+	default:
+		return fmt.Errorf("log.format must be %s or %s", LogFormatText, LogFormatJSON)
+	}
+
+	names := make(map[string]bool)
+	for i, user := range c.Auth.Users {
+		if user == nil || user.Name == "" {
+			return fmt.Errorf("auth.users.%d.name is required", i)
+		}
+		if names[user.Name] {
+			return fmt.Errorf("auth.users has two users named %q", user.Name)
+		}
+		names[user.Name] = true
+	}
+
+	for i, webhook := range c.Webhooks {
+		if webhook == nil {
+			return fmt.Errorf("webhooks.%d is empty", i)
+		}
+		if u, err := url.Parse(webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhooks.%d.url must be an http or https URL", i)
+		}
+	}
+
+	if c.Telegram.APIURL != "" {
+		if u, err := url.Parse(c.Telegram.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("telegram.api_url must be an http or https URL")
+		}
+	}
+
+	if c.Slack.NotifyURL != "" {
+		if u, err := url.Parse(c.Slack.NotifyURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("slack.notify_url must be an http or https URL")
+		}
+	}
+
+	if c.Todoist.Token != "" && c.Todoist.ProjectID == "" {
+		return fmt.Errorf("todoist.project_id is required with todoist.token")
+	}
+	switch c.Todoist.Conflict {
+	case "", TodoistConflictCompleted, TodoistConflictNoteFlow, TodoistConflictTodoist:
+	default:
+		return fmt.Errorf("todoist.conflict must be one of %s, %s, %s", TodoistConflictCompleted, TodoistConflictNoteFlow, TodoistConflictTodoist)
+	}
+	if c.Todoist.APIURL != "" {
+		if u, err := url.Parse(c.Todoist.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("todoist.api_url must be an http or https URL")
+		}
+	}
+
+	switch c.AI.Provider {
+	case "", AIProviderOpenAI, AIProviderOllama:
+	default:
+		return fmt.Errorf("ai.provider must be %s or %s", AIProviderOpenAI, AIProviderOllama)
+	}
+	if c.AI.Provider != "" && c.AI.Model == "" {
+		return fmt.Errorf("ai.model is required with ai.provider")
+	}
+	if c.AI.URL != "" {
+		if u, err := url.Parse(c.AI.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("ai.url must be an http or https URL")
+		}
+	}
+
+	switch c.Translation.Provider {
+	case "", TranslationProviderDeepL, TranslationProviderLibreTranslate:
+	case TranslationProviderAI:
+		if c.AI.Provider == "" {
+			return fmt.Errorf("translation.provider %s needs ai.provider", TranslationProviderAI)
+		}
+	default:
+		return fmt.Errorf("translation.provider must be one of %s, %s, %s", TranslationProviderAI, TranslationProviderDeepL, TranslationProviderLibreTranslate)
+	}
+	if c.Translation.Provider == TranslationProviderDeepL && c.Translation.APIKey == "" {
+		return fmt.Errorf("translation.api_key is required with translation.provider %s", TranslationProviderDeepL)
+	}
+	if c.Translation.URL != "" {
+		if u, err := url.Parse(c.Translation.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("translation.url must be an http or https URL")
+		}
+	}
+	switch c.Translation.Store {
+	case "", TranslationStoreBlock, TranslationStoreNote:
+	default:
+		return fmt.Errorf("translation.store must be %s or %s", TranslationStoreBlock, TranslationStoreNote)
+	}
+
+	if err := c.Notifications.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.UI.Fonts.Validate(); err != nil {
+		return err
+	}
+	if err := c.UI.Shortcuts.Validate(); err != nil {
+		return err
+	}
+	if err := c.Time.Validate(); err != nil {
+		return err
+	}
+	if c.Locale != "" && !i18n.Exists(c.Locale) {
+		return fmt.Errorf("locale must be one of %s", strings.Join(i18n.Locales(), ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// NoteVersion is an earlier title and content of a note, kept when an edit
+// replaced it
+type NoteVersion struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	// EditedBy is who wrote this version, when known
+	EditedBy   string    `json:"edited_by,omitempty"`
+	ReplacedAt time.Time `json:"replaced_at"`
+	ReplacedBy string    `json:"replaced_by,omitempty"`
+}
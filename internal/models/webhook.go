@@ -0,0 +1,47 @@
+package models
+
+// Webhook is an outbound subscription that receives events as signed HTTP POSTs
+type Webhook struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Secret signs each delivery with HMAC-SHA256; it is never returned by the API
+	Secret string `json:"secret,omitempty"`
+	// Events lists the event types delivered (empty delivers every event)
+	Events []string `json:"events,omitempty"`
+	Active bool     `json:"active"`
+	// Owner is the user who created the webhook, who alone manages it and
+	// whose projects' events it gets; empty for webhooks created with the
+	// shared credentials, which get every project's events
+	Owner string `json:"owner,omitempty"`
+}
+
+// WebhookRequest represents a webhook creation/update request
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret *string  `json:"secret"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
+
+// WebhookPayload is the body POSTed to webhook subscribers. Project is the
+// name of the project the event happened in.
+type WebhookPayload struct {
+	Project string `json:"project"`
+	Event
+}
+
+// Wants reports whether the webhook is subscribed to eventType
+func (w *Webhook) Wants(eventType string) bool {
+	if !w.Active {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, wanted := range w.Events {
+		if wanted == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+// Package ai summarizes notes and suggests their titles and tags with a
+// language model. A Provider wraps one kind of API: an OpenAI-compatible chat
+// completions server, or a local Ollama server.
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// defaultTimeout limits a summary when the config doesn't
+const defaultTimeout = 120 * time.Second
+
+// defaultPrompt is the instruction given with a note when none is configured
+const defaultPrompt = "Summarize the following note in two or three sentences, in the language it is written in. Reply with the summary only."
+
+// ErrNotConfigured is returned when no provider is configured
+var ErrNotConfigured = errors.New("no AI provider is configured")
+
+// Provider completes a prompt with a language model
+type Provider interface {
+	// Complete returns the model's reply to the instruction system and the
+	// user message text
+	Complete(ctx context.Context, system, text string) (string, error)
+}
+
+// New returns the provider selected by config
+func New(config models.AIConfig) (Provider, error) {
+	timeout := defaultTimeout
+	if config.TimeoutSeconds > 0 {
+		timeout = time.Duration(config.TimeoutSeconds) * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	switch config.Provider {
+	case "":
+		return nil, ErrNotConfigured
+	case models.AIProviderOpenAI:
+		return &openAI{config: config, client: client}, nil
+	case models.AIProviderOllama:
+		return &ollama{config: config, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown AI provider %q", config.Provider)
+	}
+}
+
+// Summarize asks the provider configured in config for a summary of a note's
+// title and content. The reply is returned on one line.
+func Summarize(ctx context.Context, config models.AIConfig, title, content string) (string, error) {
+	provider, err := New(config)
+	if err != nil {
+		return "", err
+	}
+
+	prompt := config.Prompt
+	if prompt == "" {
+		prompt = defaultPrompt
+	}
+	summary, err := provider.Complete(ctx, prompt, noteText(title, content))
+	if err != nil {
+		return "", err
+	}
+	summary = strings.Join(strings.Fields(summary), " ")
+	if summary == "" {
+		return "", errors.New("the model returned an empty summary")
+	}
+	return summary, nil
+}
+
+// noteText is a note as given to the model, with its title as a heading
+func noteText(title, content string) string {
+	if title == "" {
+		return content
+	}
+	return "# " + title + "\n\n" + content
+}
+
+// post sends body as JSON to endpoint and decodes the response into result
+func post(ctx context.Context, client *http.Client, endpoint, apiKey string, body, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded %d", endpoint, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("%s: unexpected response: %w", endpoint, err)
+	}
+	return nil
+}
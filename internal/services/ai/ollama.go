@@ -0,0 +1,40 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// ollamaURL is the default address of a local Ollama server
+const ollamaURL = "http://localhost:11434"
+
+// ollama uses the generate API of an Ollama server
+type ollama struct {
+	config models.AIConfig
+	client *http.Client
+}
+
+// Complete implements Provider
+func (p *ollama) Complete(ctx context.Context, system, text string) (string, error) {
+	baseURL := p.config.URL
+	if baseURL == "" {
+		baseURL = ollamaURL
+	}
+
+	request := map[string]interface{}{
+		"model":  p.config.Model,
+		"system": system,
+		"prompt": text,
+		"stream": false,
+	}
+	var response struct {
+		Response string `json:"response"`
+	}
+	if err := post(ctx, p.client, strings.TrimRight(baseURL, "/")+"/api/generate", p.config.APIKey, request, &response); err != nil {
+		return "", err
+	}
+	return response.Response, nil
+}
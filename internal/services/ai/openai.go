@@ -0,0 +1,55 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// openAIURL is the default base URL of the OpenAI API
+const openAIURL = "https://api.openai.com/v1"
+
+// openAI uses the chat completions API of OpenAI and the many servers
+// compatible with it, such as LM Studio, llama.cpp and vLLM
+type openAI struct {
+	config models.AIConfig
+	client *http.Client
+}
+
+// openAIMessage is a message of a chat completion
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Complete implements Provider
+func (p *openAI) Complete(ctx context.Context, system, text string) (string, error) {
+	baseURL := p.config.URL
+	if baseURL == "" {
+		baseURL = openAIURL
+	}
+
+	request := map[string]interface{}{
+		"model": p.config.Model,
+		"messages": []openAIMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: text},
+		},
+	}
+	var response struct {
+		Choices []struct {
+			Message openAIMessage `json:"message"`
+		} `json:"choices"`
+	}
+	if err := post(ctx, p.client, strings.TrimRight(baseURL, "/")+"/chat/completions", p.config.APIKey, request, &response); err != nil {
+		return "", err
+	}
+
+	if len(response.Choices) == 0 {
+		return "", errors.New("the model returned no reply")
+	}
+	return response.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// Suggestion limits
+const (
+	// maxSuggestedTags is how many tags are suggested at most
+	maxSuggestedTags = 5
+	// maxKnownTags is how many of the project's tags are offered to the model
+	maxKnownTags = 100
+)
+
+// suggestPrompt asks for a title and tags as JSON; %s is the project's tags
+const suggestPrompt = `Suggest a short title and up to 5 tags for the following note, in the language it is written in.
+Prefer these existing tags where they fit: %s.
+Tags are single lowercase words or hyphenated words without "#".
+Reply with JSON only, in the form {"title": "...", "tags": ["...", "..."]}.`
+
+// tagPattern matches a tag that works as a #hashtag in a note
+var tagPattern = regexp.MustCompile(`^[a-z][\w/-]*$`)
+
+// Suggest asks the provider configured in config for a title and tags for a
+// draft note. known are the tags already used in the project, most used
+// first; the model is asked to prefer them.
+func Suggest(ctx context.Context, config models.AIConfig, title, content string, known []string) (models.AISuggestion, error) {
+	provider, err := New(config)
+	if err != nil {
+		return models.AISuggestion{}, err
+	}
+
+	offered := known
+	if len(offered) > maxKnownTags {
+		offered = offered[:maxKnownTags]
+	}
+	tagList := "none yet"
+	if len(offered) > 0 {
+		tagList = strings.Join(offered, ", ")
+	}
+
+	reply, err := provider.Complete(ctx, fmt.Sprintf(suggestPrompt, tagList), noteText(title, content))
+	if err != nil {
+		return models.AISuggestion{}, err
+	}
+
+	// Models often wrap JSON in a code block or add a sentence around it
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return models.AISuggestion{}, fmt.Errorf("the model didn't reply with JSON")
+	}
+	var parsed struct {
+		Title string   `json:"title"`
+		Tags  []string `json:"tags"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &parsed); err != nil {
+		return models.AISuggestion{}, fmt.Errorf("the model didn't reply with JSON: %w", err)
+	}
+
+	isKnown := make(map[string]bool, len(known))
+	for _, tag := range known {
+		isKnown[tag] = true
+	}
+	suggestion := models.AISuggestion{
+		Title: strings.Join(strings.Fields(parsed.Title), " "),
+		Tags:  make([]string, 0, maxSuggestedTags),
+		Known: make([]string, 0, maxSuggestedTags),
+	}
+	seen := make(map[string]bool)
+	for _, tag := range parsed.Tags {
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		tag = strings.Join(strings.Fields(tag), "-")
+		if !tagPattern.MatchString(tag) || seen[tag] || len(suggestion.Tags) == maxSuggestedTags {
+			continue
+		}
+		seen[tag] = true
+		suggestion.Tags = append(suggestion.Tags, tag)
+		if isKnown[tag] {
+			suggestion.Known = append(suggestion.Known, tag)
+		}
+	}
+	return suggestion, nil
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// ErrArchiveDenied is returned when archiving a URL is disallowed by policy
+var ErrArchiveDenied = errors.New("archiving denied by policy")
+
+// robotsUserAgent is the user agent token matched against robots.txt groups
+const robotsUserAgent = "noteflow"
+
+// knownAnalyticsDomains are script hosts removed when strip_analytics is enabled
+var knownAnalyticsDomains = []string{
+	"google-analytics.com",
+	"googletagmanager.com",
+	"googlesyndication.com",
+	"doubleclick.net",
+	"connect.facebook.net",
+	"hotjar.com",
+	"segment.com",
+	"segment.io",
+	"mixpanel.com",
+	"scorecardresearch.com",
+	"quantserve.com",
+	"newrelic.com",
+	"nr-data.net",
+	"clarity.ms",
+	"matomo.cloud",
+	"plausible.io",
+}
+
+// inlineAnalyticsMarkers identify inline analytics snippets
+var inlineAnalyticsMarkers = []string{
+	"GoogleAnalyticsObject",
+	"gtag(",
+	"_gaq.push",
+	"googletagmanager.com",
+	"fbq(",
+	"hj(",
+	"_paq.push",
+	"analytics.load(",
+}
+
+var (
+	scriptTagPattern = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script>`)
+	scriptSrcPattern = regexp.MustCompile(`(?i)\ssrc=["']([^"']+)["']`)
+)
+
+// robotsRules holds the Allow/Disallow rules that apply to the archiver for a host
+type robotsRules struct {
+	allow    []string
+	disallow []string
+}
+
+// robotsCache caches parsed robots.txt rules per origin
+type robotsCache struct {
+	rules map[string]*robotsRules
+	mu    sync.Mutex
+}
+
+// checkPolicy returns ErrArchiveDenied if the page may not be archived
+func (a *Archiver) checkPolicy(ctx context.Context, websiteURL string) error {
+	parsedURL, err := url.Parse(websiteURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+
+	if a.isDeniedHost(parsedURL.Hostname()) {
+		return fmt.Errorf("%w: %s is on the deny list", ErrArchiveDenied, parsedURL.Hostname())
+	}
+
+	if a.config().RespectRobots && !a.robotsAllowed(ctx, parsedURL) {
+		return fmt.Errorf("%w: %s is disallowed by robots.txt", ErrArchiveDenied, websiteURL)
+	}
+
+	return nil
+}
+
+// isDeniedHost reports whether host matches an entry of the deny list
+func (a *Archiver) isDeniedHost(host string) bool {
+	for _, domain := range a.config().Deny {
+		if models.HostMatches(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAnalyticsHost reports whether host serves known or configured analytics scripts
+func (a *Archiver) isAnalyticsHost(host string) bool {
+	for _, domain := range knownAnalyticsDomains {
+		if models.HostMatches(host, domain) {
+			return true
+		}
+	}
+	for _, domain := range a.config().AnalyticsDomains {
+		if models.HostMatches(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripAnalytics removes analytics and tracking scripts from a page
+func (s *archiveSession) stripAnalytics(htmlContent string, baseURL *url.URL) string {
+	if !s.archiver.config().StripAnalytics {
+		return htmlContent
+	}
+
+	return scriptTagPattern.ReplaceAllStringFunc(htmlContent, func(match string) string {
+		parts := scriptTagPattern.FindStringSubmatch(match)
+		attrs, body := parts[1], parts[2]
+
+		if srcMatch := scriptSrcPattern.FindStringSubmatch(" " + attrs); len(srcMatch) > 1 {
+			scriptURL, err := baseURL.Parse(srcMatch[1])
+			if err == nil && s.archiver.isAnalyticsHost(scriptURL.Hostname()) {
+				return "<!-- analytics script removed -->"
+			}
+			return match
+		}
+
+		for _, marker := range inlineAnalyticsMarkers {
+			if strings.Contains(body, marker) {
+				return "<!-- analytics script removed -->"
+			}
+		}
+		return match
+	})
+}
+
+// robotsAllowed reports whether robots.txt of the page's origin allows archiving it
+func (a *Archiver) robotsAllowed(ctx context.Context, pageURL *url.URL) bool {
+	origin := pageURL.Scheme + "://" + pageURL.Host
+
+	a.robots.mu.Lock()
+	rules, cached := a.robots.rules[origin]
+	a.robots.mu.Unlock()
+
+	if !cached {
+		rules = a.fetchRobots(ctx, origin)
+		// A robots.txt not read because the archive was given up is
+		// read again next time
+		if ctx.Err() != nil {
+			return rules.allows(pageURL.EscapedPath())
+		}
+		a.robots.mu.Lock()
+		a.robots.rules[origin] = rules
+		a.robots.mu.Unlock()
+	}
+
+	return rules.allows(pageURL.EscapedPath())
+}
+
+// fetchRobots downloads and parses robots.txt for an origin. A missing or
+// unreadable robots.txt allows everything.
+func (a *Archiver) fetchRobots(ctx context.Context, origin string) *robotsRules {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return &robotsRules{}
+	}
+	resp, err := a.fetcher.Do(req)
+	if err != nil {
+		return &robotsRules{}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &robotsRules{}
+	}
+
+	return parseRobots(io.LimitReader(resp.Body, 512*1024))
+}
+
+// parseRobots parses the groups of a robots.txt that apply to the archiver,
+// preferring a group naming it explicitly over the wildcard group
+func parseRobots(r io.Reader) *robotsRules {
+	specific := &robotsRules{}
+	wildcard := &robotsRules{}
+	var current []*robotsRules
+	inAgents := false
+	foundSpecific := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = nil
+				inAgents = true
+			}
+			agent := strings.ToLower(value)
+			if agent == "*" {
+				current = append(current, wildcard)
+			} else if strings.Contains(agent, robotsUserAgent) {
+				current = append(current, specific)
+				foundSpecific = true
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if value == "" {
+				continue
+			}
+			for _, rules := range current {
+				if key == "allow" {
+					rules.allow = append(rules.allow, value)
+				} else {
+					rules.disallow = append(rules.disallow, value)
+				}
+			}
+		default:
+			inAgents = false
+		}
+	}
+
+	if foundSpecific {
+		return specific
+	}
+	return wildcard
+}
+
+// allows applies the longest matching rule to path; Allow wins ties
+func (r *robotsRules) allows(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+
+	longestAllow := longestRobotsMatch(r.allow, path)
+	longestDisallow := longestRobotsMatch(r.disallow, path)
+	return longestDisallow < 0 || longestAllow >= longestDisallow
+}
+
+// longestRobotsMatch returns the length of the longest rule matching path, or -1
+func longestRobotsMatch(rules []string, path string) int {
+	longest := -1
+	for _, rule := range rules {
+		if robotsRuleMatches(rule, path) && len(rule) > longest {
+			longest = len(rule)
+		}
+	}
+	return longest
+}
+
+// robotsRuleMatches matches a robots.txt path rule supporting * and $ wildcards
+func robotsRuleMatches(rule, path string) bool {
+	pattern := regexp.QuoteMeta(rule)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	if strings.HasSuffix(pattern, `\$`) {
+		pattern = strings.TrimSuffix(pattern, `\$`) + "$"
+	}
+
+	matched, err := regexp.MatchString("^"+pattern, path)
+	return err == nil && matched
+}
@@ -0,0 +1,959 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/storage"
+)
+
+// Archiver downloads websites and stores them as self-contained HTML files
+type Archiver struct {
+	storage  *storage.FileStorage
+	settings *ConfigStore
+	fetcher  *Fetcher
+	events   *EventBroker
+	robots   robotsCache
+
+	// ctx is cancelled by Close to abort in-flight archives
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewArchiver creates a new archiver storing sites under assets/sites of the
+// given storage, following the archive settings in settings
+func NewArchiver(storage *storage.FileStorage, settings *ConfigStore) *Archiver {
+	if settings == nil {
+		settings = NewConfigStore(&models.Config{}, "")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Archiver{
+		storage:  storage,
+		settings: settings,
+		fetcher:  NewFetcher(models.FetchConfig{}),
+		robots:   robotsCache{rules: make(map[string]*robotsRules)},
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// config returns the current archive settings
+func (a *Archiver) config() *models.ArchiveConfig {
+	return &a.settings.Current().Archive
+}
+
+// Close aborts in-flight archives; later archives fail immediately
+func (a *Archiver) Close() {
+	a.cancel()
+}
+
+// SetFetcher sets the fetcher making the archiver's requests, which limits
+// them along with those of other projects
+func (a *Archiver) SetFetcher(fetcher *Fetcher) {
+	a.fetcher = fetcher
+}
+
+// SetEventBroker sets the broker that receives archive progress events
+func (a *Archiver) SetEventBroker(events *EventBroker) {
+	a.events = events
+}
+
+// withClose returns a context that is also cancelled when the archiver is
+// closed. The caller must call the returned cancel function.
+func (a *Archiver) withClose(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(a.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// Archive downloads and archives a website, applying any per-request options.
+// It gives up when ctx is done, such as when the request asking for it ends,
+// or the archiver is closed.
+func (a *Archiver) Archive(ctx context.Context, websiteURL string, opts *models.ArchiveOptions) (*ArchiveInfo, error) {
+	ctx, cancel := a.withClose(ctx)
+	defer cancel()
+
+	if err := a.checkPolicy(ctx, websiteURL); err != nil {
+		return nil, err
+	}
+
+	session := a.newSession(ctx, websiteURL, opts)
+	defer session.close()
+
+	archiveInfo, err := session.archiveWebsite(websiteURL, websiteURL)
+	if err != nil {
+		if !a.config().Wayback.Fallback {
+			session.publishProgress("failed", err)
+			return nil, err
+		}
+
+		slog.InfoContext(ctx, "direct archive failed, trying Wayback Machine", "url", websiteURL, "error", err)
+		waybackInfo, waybackErr := a.archiveFromWayback(ctx, websiteURL)
+		if waybackErr != nil {
+			err = fmt.Errorf("%w (wayback fallback failed: %v)", err, waybackErr)
+			session.publishProgress("failed", err)
+			return nil, err
+		}
+		a.captureScreenshot(ctx, session, websiteURL, waybackInfo)
+		a.saveMetadata(websiteURL, waybackInfo)
+		return waybackInfo, nil
+	}
+
+	a.captureScreenshot(ctx, session, websiteURL, archiveInfo)
+	a.saveMetadata(websiteURL, archiveInfo)
+	session.publishProgress("done", nil)
+
+	if a.config().Wayback.Submit {
+		go a.submitToWayback(websiteURL)
+	}
+
+	return archiveInfo, nil
+}
+
+// saveMetadata records the metadata sidecar for a new archive
+func (a *Archiver) saveMetadata(websiteURL string, archiveInfo *ArchiveInfo) {
+	meta := &models.ArchiveMetadata{
+		Filename:   filepath.Base(archiveInfo.FilePath),
+		URL:        websiteURL,
+		Title:      archiveInfo.Title,
+		ArchivedAt: archiveInfo.Timestamp,
+		Source:     archiveInfo.Source,
+		Size:       archiveInfo.Size,
+		Screenshot: archiveInfo.Screenshot,
+	}
+
+	if err := a.storage.SaveArchiveMetadata(meta); err != nil {
+		slog.Warn("failed to save archive metadata", "url", websiteURL, "error", err)
+	}
+}
+
+// ArchivedURLs returns the set of original URLs that already have an archive
+func (a *Archiver) ArchivedURLs() (map[string]bool, error) {
+	archives, err := a.storage.ListArchiveMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make(map[string]bool, len(archives))
+	for _, archive := range archives {
+		if archive.URL != "" {
+			urls[archive.URL] = true
+		}
+	}
+	return urls, nil
+}
+
+// Defaults for resource downloading when not configured
+const (
+	defaultArchiveConcurrency = 8
+	defaultArchivePageTimeout = 120 * time.Second
+)
+
+// archiveSession holds the state for archiving a single website
+type archiveSession struct {
+	archiver *Archiver
+	opts     *models.ArchiveOptions
+	host     string // host of the page being archived
+	progress models.ArchiveProgress
+	mu       sync.Mutex // Protects progress
+
+	// ctx bounds the whole archive by the configured page time budget, and
+	// ends with the context it was archived for
+	ctx    context.Context
+	cancel context.CancelFunc
+	// slots limits the number of concurrent resource downloads
+	slots chan struct{}
+}
+
+// newSession creates a session for archiving websiteURL within ctx. The
+// caller must call close.
+func (a *Archiver) newSession(ctx context.Context, websiteURL string, opts *models.ArchiveOptions) *archiveSession {
+	concurrency := a.config().Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultArchiveConcurrency
+	}
+
+	timeout := defaultArchivePageTimeout
+	if a.config().PageTimeoutSeconds > 0 {
+		timeout = time.Duration(a.config().PageTimeoutSeconds) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+
+	return &archiveSession{
+		archiver: a,
+		opts:     opts,
+		progress: models.ArchiveProgress{URL: websiteURL},
+		ctx:      ctx,
+		cancel:   cancel,
+		slots:    make(chan struct{}, concurrency),
+	}
+}
+
+// close releases the session's context
+func (s *archiveSession) close() {
+	s.cancel()
+}
+
+// acquireSlot blocks until a download slot is free, returning false if the
+// session's time budget ran out first
+func (s *archiveSession) acquireSlot() bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// releaseSlot frees a download slot
+func (s *archiveSession) releaseSlot() {
+	<-s.slots
+}
+
+// replaceAllConcurrent behaves like re.ReplaceAllStringFunc but runs repl for
+// all matches concurrently. Downloads made by repl are bounded by the session's slots.
+func (s *archiveSession) replaceAllConcurrent(re *regexp.Regexp, src string, repl func(string) string) string {
+	locs := re.FindAllStringIndex(src, -1)
+	if len(locs) == 0 {
+		return src
+	}
+
+	results := make([]string, len(locs))
+	var wg sync.WaitGroup
+	for i, loc := range locs {
+		wg.Add(1)
+		go func(i int, match string) {
+			defer wg.Done()
+			results[i] = repl(match)
+		}(i, src[loc[0]:loc[1]])
+	}
+	wg.Wait()
+
+	var buf strings.Builder
+	last := 0
+	for i, loc := range locs {
+		buf.WriteString(src[last:loc[0]])
+		buf.WriteString(results[i])
+		last = loc[1]
+	}
+	buf.WriteString(src[last:])
+
+	return buf.String()
+}
+
+// recordDownload updates the progress counters after a fetch and publishes them
+func (s *archiveSession) recordDownload(bytes int, ok bool) {
+	s.mu.Lock()
+	if ok {
+		s.progress.ResourcesFetched++
+		s.progress.BytesDownloaded += int64(bytes)
+	} else {
+		s.progress.ResourcesFailed++
+	}
+	s.mu.Unlock()
+
+	s.publishProgress("resources", nil)
+}
+
+// publishProgress publishes the current progress at the given stage
+func (s *archiveSession) publishProgress(stage string, err error) {
+	s.mu.Lock()
+	s.progress.Stage = stage
+	if err != nil {
+		s.progress.Error = err.Error()
+	}
+	progress := s.progress
+	s.mu.Unlock()
+
+	s.archiver.events.Publish(models.EventArchiveProgress, progress)
+}
+
+// get performs a GET request with the configured headers and cookies for the target domain
+func (s *archiveSession) get(targetURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string)
+	cookies := make(map[string]string)
+	host := req.URL.Hostname()
+
+	// Never contact denied hosts, even for page resources
+	if s.archiver.isDeniedHost(host) {
+		return nil, fmt.Errorf("%w: %s is on the deny list", ErrArchiveDenied, host)
+	}
+
+	// Domain rules from config apply first
+	if rule := s.archiver.config().RuleForHost(host); rule != nil {
+		mergeStringMap(headers, rule.Headers)
+		mergeStringMap(cookies, rule.Cookies)
+	}
+
+	// Per-request options override config, but are never sent to third-party hosts
+	if s.opts != nil && models.HostMatches(host, s.host) {
+		mergeStringMap(headers, s.opts.Headers)
+		mergeStringMap(cookies, s.opts.Cookies)
+	}
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	return s.archiver.fetcher.Do(req)
+}
+
+// mergeStringMap copies all entries from src into dst
+func mergeStringMap(dst, src map[string]string) {
+	for key, value := range src {
+		dst[key] = value
+	}
+}
+
+// defaultMaxFrameDepth limits how deeply nested iframes are captured
+const defaultMaxFrameDepth = 3
+
+// Archive sources recorded on ArchiveInfo
+const (
+	ArchiveSourceDirect  = "direct"
+	ArchiveSourceWayback = "wayback"
+)
+
+// ArchiveInfo contains information about an archived website
+type ArchiveInfo struct {
+	Title     string
+	FilePath  string
+	Timestamp time.Time
+	Source    string // ArchiveSourceDirect or ArchiveSourceWayback
+	Size      int64
+	// Screenshot is the file name of the page's screenshot thumbnail, if any
+	Screenshot string
+}
+
+// Markdown returns the note link referencing this archive
+func (ai *ArchiveInfo) Markdown() string {
+	suffix := ""
+	if ai.Source == ArchiveSourceWayback {
+		suffix = " via Wayback Machine"
+	}
+
+	return fmt.Sprintf("[%s](%s) (archived %s%s)",
+		ai.Title,
+		ai.FilePath,
+		ai.Timestamp.Format("2006-01-02 15:04"),
+		suffix)
+}
+
+// archiveWebsite downloads pageURL and archives it with inlined resources.
+// originalURL is the address the user asked for and is used for naming; it
+// differs from pageURL when archiving a Wayback Machine snapshot.
+func (s *archiveSession) archiveWebsite(pageURL, originalURL string) (*ArchiveInfo, error) {
+	// Parse the URLs
+	parsedURL, err := url.Parse(originalURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	parsedPageURL, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	s.host = parsedPageURL.Hostname()
+
+	// Download the webpage
+	s.publishProgress("page", nil)
+	resp, err := s.get(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download webpage: %w", err)
+	}
+
+	// Read the HTML content, closing the response so the page's resources
+	// can have its fetch slot
+	htmlContent, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	s.mu.Lock()
+	s.progress.BytesDownloaded += int64(len(htmlContent))
+	s.mu.Unlock()
+
+	// Extract title from HTML
+	title := s.extractTitle(string(htmlContent), parsedURL.Host)
+
+	// Create filename in format expected by storage: YYYY_MM_DD_HHMMSS_title-domain.html
+	timestamp := time.Now()
+	filename := fmt.Sprintf("%s_%s-%s.html",
+		timestamp.Format("2006_01_02_150405"),
+		s.sanitizeFilename(title),
+		s.sanitizeFilename(parsedURL.Host))
+
+	// Ensure sites directory exists
+	sitesDir := filepath.Join(s.archiver.storage.BasePath, "assets", "sites")
+	if err := os.MkdirAll(sitesDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sites directory: %w", err)
+	}
+
+	// Process HTML to inline all external resources
+	processedHTML := s.inlineAllResources(string(htmlContent), pageURL)
+
+	// Save the archived file
+	filePath := filepath.Join(sitesDir, filename)
+	if err := os.WriteFile(filePath, []byte(processedHTML), 0644); err != nil {
+		return nil, fmt.Errorf("failed to save archived file: %w", err)
+	}
+
+	// Create relative path for linking
+	relativePath := filepath.Join("assets", "sites", filename)
+
+	return &ArchiveInfo{
+		Title:     title,
+		FilePath:  relativePath,
+		Timestamp: timestamp,
+		Source:    ArchiveSourceDirect,
+		Size:      int64(len(processedHTML)),
+	}, nil
+}
+
+// extractTitle extracts the title from HTML content
+func (s *archiveSession) extractTitle(htmlContent, host string) string {
+	// Simple regex to extract title
+	titleRe := regexp.MustCompile(`<title[^>]*>([^<]*)</title>`)
+	matches := titleRe.FindStringSubmatch(htmlContent)
+
+	if len(matches) > 1 && strings.TrimSpace(matches[1]) != "" {
+		return strings.TrimSpace(matches[1])
+	}
+
+	return host
+}
+
+// sanitizeFilename removes invalid characters from filenames
+func (s *archiveSession) sanitizeFilename(filename string) string {
+	// Replace invalid characters with underscores
+	re := regexp.MustCompile(`[<>:"/\\|?*\s]+`)
+	sanitized := re.ReplaceAllString(filename, "_")
+
+	// Limit length
+	if len(sanitized) > 50 {
+		sanitized = sanitized[:50]
+	}
+
+	return strings.Trim(sanitized, "_")
+}
+
+// inlineAllResources performs comprehensive resource inlining
+func (s *archiveSession) inlineAllResources(htmlContent, baseURL string) string {
+	// Add archive header
+	archiveHeader := fmt.Sprintf(`
+<!-- ARCHIVED PAGE - Original URL: %s - Archived: %s -->
+<div style="background: #fff3cd; border: 1px solid #ffeaa7; padding: 10px; margin: 10px 0; border-radius: 4px; font-family: Arial, sans-serif;">
+	📄 <strong>Archived Page</strong> - Original: <a href="%s" target="_blank">%s</a> - Archived: %s
+</div>
+`, baseURL, time.Now().Format("2006-01-02 15:04:05"), baseURL, baseURL, time.Now().Format("2006-01-02 15:04:05"))
+
+	// Parse base URL for resolving relative URLs
+	baseURLParsed, err := url.Parse(baseURL)
+	if err != nil {
+		slog.WarnContext(s.ctx, "failed to parse base URL", "url", baseURL, "error", err)
+		return htmlContent
+	}
+
+	htmlContent = s.inlineResources(htmlContent, baseURLParsed, 0)
+
+	// Insert header after <body> tag
+	bodyRe := regexp.MustCompile(`(<body[^>]*>)`)
+	htmlContent = bodyRe.ReplaceAllString(htmlContent, `$1`+archiveHeader)
+
+	return htmlContent
+}
+
+// inlineResources inlines the external resources of a page or frame at the given frame depth
+func (s *archiveSession) inlineResources(htmlContent string, baseURL *url.URL, depth int) string {
+	// Drop tracking scripts before anything is downloaded
+	htmlContent = s.stripAnalytics(htmlContent, baseURL)
+
+	// Inline CSS stylesheets
+	htmlContent = s.inlineCSS(htmlContent, baseURL)
+
+	// Inline JavaScript files
+	htmlContent = s.inlineJavaScript(htmlContent, baseURL)
+
+	// Inline images as base64 data URIs
+	htmlContent = s.inlineImages(htmlContent, baseURL)
+
+	// Inline web fonts SCO: This is not doing anything at this time
+	// htmlContent = s.inlineWebFonts(htmlContent, baseURL)
+
+	// Process inline CSS styles that may contain background images
+	htmlContent = s.inlineStyleAttributes(htmlContent, baseURL)
+
+	// Capture embedded frames last so their escaped content is not reprocessed
+	htmlContent = s.inlineFrames(htmlContent, baseURL, depth)
+
+	return htmlContent
+}
+
+// inlineFrames captures <iframe> documents recursively and embeds them via srcdoc
+func (s *archiveSession) inlineFrames(htmlContent string, baseURL *url.URL, depth int) string {
+	maxDepth := s.archiver.config().Frames.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = defaultMaxFrameDepth
+	}
+	if depth >= maxDepth {
+		return htmlContent
+	}
+
+	frameRe := regexp.MustCompile(`<iframe[^>]*\ssrc=["']([^"']+)["'][^>]*>`)
+	srcRe := regexp.MustCompile(`\ssrc=["']([^"']+)["']`)
+
+	return s.replaceAllConcurrent(frameRe, htmlContent, func(match string) string {
+		// Frames that already carry inline content need no capture
+		if strings.Contains(match, "srcdoc=") {
+			return match
+		}
+
+		srcMatch := srcRe.FindStringSubmatch(match)
+		if len(srcMatch) < 2 {
+			return match
+		}
+
+		resolvedURL := s.resolveURL(baseURL, html.UnescapeString(srcMatch[1]))
+		if resolvedURL == "" {
+			return match
+		}
+
+		frameURL, err := url.Parse(resolvedURL)
+		if err != nil {
+			return match
+		}
+
+		sameOrigin := frameURL.Scheme == baseURL.Scheme && frameURL.Host == baseURL.Host
+		if !sameOrigin && !s.archiver.config().Frames.CrossOrigin {
+			return match
+		}
+
+		frameContent := s.downloadResource(resolvedURL)
+		if frameContent == "" {
+			return match
+		}
+
+		slog.DebugContext(s.ctx, "captured frame", "url", resolvedURL)
+
+		// Inline the frame's own resources, including nested frames
+		frameContent = s.inlineResources(frameContent, frameURL, depth+1)
+
+		// Keep the original src as a data attribute for reference
+		return srcRe.ReplaceAllString(match, fmt.Sprintf(` srcdoc="%s" data-archived-src="%s"`,
+			html.EscapeString(frameContent), html.EscapeString(resolvedURL)))
+	})
+}
+
+// inlineCSS inlines external CSS stylesheets
+func (s *archiveSession) inlineCSS(htmlContent string, baseURL *url.URL) string {
+	// Match <link> tags for stylesheets
+	linkRe := regexp.MustCompile(`<link[^>]*href=["']([^"']+)["'][^>]*rel=["']stylesheet["'][^>]*>|<link[^>]*rel=["']stylesheet["'][^>]*href=["']([^"']+)["'][^>]*>`)
+
+	return s.replaceAllConcurrent(linkRe, htmlContent, func(match string) string {
+		// Extract href value
+		hrefRe := regexp.MustCompile(`href=["']([^"']+)["']`)
+		hrefMatch := hrefRe.FindStringSubmatch(match)
+		if len(hrefMatch) < 2 {
+			return match // Keep original if we can't extract href
+		}
+
+		cssURL := hrefMatch[1]
+
+		// Resolve relative URLs
+		resolvedURL := s.resolveURL(baseURL, cssURL)
+		if resolvedURL == "" {
+			return match
+		}
+
+		// Download CSS content
+		cssContent := s.downloadResource(resolvedURL)
+		if cssContent == "" {
+			return match
+		}
+
+		// Process CSS to inline any @import and url() references
+		processedCSS := s.processCSS(cssContent, resolvedURL)
+
+		return fmt.Sprintf(`<style type="text/css">
+/* Inlined from: %s */
+%s
+</style>`, resolvedURL, processedCSS)
+	})
+}
+
+// inlineJavaScript inlines external JavaScript files
+func (s *archiveSession) inlineJavaScript(htmlContent string, baseURL *url.URL) string {
+	// Match <script> tags with src attributes
+	scriptRe := regexp.MustCompile(`<script[^>]*src=["']([^"']+)["'][^>]*></script>`)
+
+	return s.replaceAllConcurrent(scriptRe, htmlContent, func(match string) string {
+		// Extract src value
+		srcRe := regexp.MustCompile(`src=["']([^"']+)["']`)
+		srcMatch := srcRe.FindStringSubmatch(match)
+		if len(srcMatch) < 2 {
+			return match
+		}
+
+		jsURL := srcMatch[1]
+
+		// Resolve relative URLs
+		resolvedURL := s.resolveURL(baseURL, jsURL)
+		if resolvedURL == "" {
+			return match
+		}
+
+		// Download JavaScript content
+		jsContent := s.downloadResource(resolvedURL)
+		if jsContent == "" {
+			return match
+		}
+
+		return fmt.Sprintf(`<script type="text/javascript">
+/* Inlined from: %s */
+%s
+</script>`, resolvedURL, jsContent)
+	})
+}
+
+// inlineImages inlines images as base64 data URIs
+func (s *archiveSession) inlineImages(htmlContent string, baseURL *url.URL) string {
+	// Match <img> tags
+	imgRe := regexp.MustCompile(`<img[^>]*src=["']([^"']+)["'][^>]*>`)
+
+	htmlContent = s.replaceAllConcurrent(imgRe, htmlContent, func(match string) string {
+		// Extract src value
+		srcRe := regexp.MustCompile(`src=["']([^"']+)["']`)
+		srcMatch := srcRe.FindStringSubmatch(match)
+		if len(srcMatch) < 2 {
+			return match
+		}
+
+		imgURL := srcMatch[1]
+
+		// Skip data URIs
+		if strings.HasPrefix(imgURL, "data:") {
+			return match
+		}
+
+		slog.DebugContext(s.ctx, "processing image", "url", imgURL)
+
+		// Resolve relative URLs
+		resolvedURL := s.resolveURL(baseURL, imgURL)
+		if resolvedURL == "" {
+			slog.DebugContext(s.ctx, "failed to resolve image URL", "url", imgURL)
+			return match
+		}
+
+		slog.DebugContext(s.ctx, "resolved image URL", "url", resolvedURL)
+
+		// Download and encode image
+		dataURI := s.downloadAndEncodeImage(resolvedURL)
+		if dataURI == "" {
+			slog.DebugContext(s.ctx, "failed to download or encode image", "url", resolvedURL)
+			return match
+		}
+
+		slog.DebugContext(s.ctx, "inlined image", "url", resolvedURL, "data_uri_length", len(dataURI))
+
+		// Replace src with data URI
+		return srcRe.ReplaceAllString(match, fmt.Sprintf(`src="%s"`, dataURI))
+	})
+
+	// Also process JavaScript string references to images
+	jsImgRe := regexp.MustCompile(`['"]([^'"]*\.(?:png|jpg|jpeg|gif|svg|webp))['"]`)
+	htmlContent = s.replaceAllConcurrent(jsImgRe, htmlContent, func(match string) string {
+		quote := match[0:1]
+		imgURL := match[1 : len(match)-1]
+
+		// Skip data URIs
+		if strings.HasPrefix(imgURL, "data:") {
+			return match
+		}
+
+		// Resolve relative URLs
+		resolvedURL := s.resolveURL(baseURL, imgURL)
+		if resolvedURL == "" {
+			return match
+		}
+
+		// Download and encode image
+		dataURI := s.downloadAndEncodeImage(resolvedURL)
+		if dataURI == "" {
+			return match
+		}
+
+		return fmt.Sprintf(`%s%s%s`, quote, dataURI, quote)
+	})
+
+	return htmlContent
+}
+
+// inlineWebFonts inlines web fonts from CSS @font-face rules
+func (s *archiveSession) inlineWebFonts(htmlContent string, baseURL *url.URL) string {
+	// This will be handled within CSS processing
+	// Web fonts in @font-face rules will be inlined when CSS is processed
+	return htmlContent
+}
+
+// inlineStyleAttributes processes inline style attributes to inline background images
+func (s *archiveSession) inlineStyleAttributes(htmlContent string, baseURL *url.URL) string {
+	// Match style attributes
+	styleRe := regexp.MustCompile(`style=["']([^"']*url\([^)]+\)[^"']*)["']`)
+
+	return s.replaceAllConcurrent(styleRe, htmlContent, func(match string) string {
+		// Extract the style content
+		styleMatch := styleRe.FindStringSubmatch(match)
+		if len(styleMatch) < 2 {
+			return match
+		}
+
+		styleContent := styleMatch[1]
+		quote := match[6:7] // Extract the quote character
+
+		// Process URL references in the style
+		processedStyle := s.processInlineCSS(styleContent, baseURL.String())
+
+		return fmt.Sprintf(`style=%s%s%s`, quote, processedStyle, quote)
+	})
+}
+
+// processInlineCSS processes CSS content for inline styles
+func (s *archiveSession) processInlineCSS(cssContent, baseURLStr string) string {
+	baseURL, err := url.Parse(baseURLStr)
+	if err != nil {
+		return cssContent
+	}
+
+	// Process url() references
+	urlRe := regexp.MustCompile(`url\(["']?([^"')\s]+)["']?\)`)
+	return s.replaceAllConcurrent(urlRe, cssContent, func(match string) string {
+		urlMatch := urlRe.FindStringSubmatch(match)
+		if len(urlMatch) < 2 {
+			return match
+		}
+
+		resourceURL := urlMatch[1]
+
+		// Skip data URIs
+		if strings.HasPrefix(resourceURL, "data:") {
+			return match
+		}
+
+		resolvedURL := s.resolveURL(baseURL, resourceURL)
+		if resolvedURL == "" {
+			return match
+		}
+
+		// Download and encode the resource
+		dataURI := s.downloadAndEncodeImage(resolvedURL)
+		if dataURI != "" {
+			return fmt.Sprintf(`url("%s")`, dataURI)
+		}
+
+		return match
+	})
+}
+
+// resolveURL resolves a relative URL against a base URL
+func (s *archiveSession) resolveURL(baseURL *url.URL, targetURL string) string {
+	// Skip data URIs, mailto, tel, etc.
+	if strings.Contains(targetURL, ":") && !strings.HasPrefix(targetURL, "http") && !strings.HasPrefix(targetURL, "//") {
+		return ""
+	}
+
+	resolvedURL, err := baseURL.Parse(targetURL)
+	if err != nil {
+		slog.WarnContext(s.ctx, "failed to resolve URL", "url", targetURL, "base", baseURL.String(), "error", err)
+		return ""
+	}
+
+	return resolvedURL.String()
+}
+
+// downloadResource downloads a resource and returns its content as string
+func (s *archiveSession) downloadResource(resourceURL string) string {
+	if !s.acquireSlot() {
+		s.recordDownload(0, false)
+		return ""
+	}
+	defer s.releaseSlot()
+
+	resp, err := s.get(resourceURL)
+	if err != nil {
+		slog.WarnContext(s.ctx, "failed to download resource", "url", resourceURL, "error", err)
+		s.recordDownload(0, false)
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		slog.WarnContext(s.ctx, "HTTP error downloading resource", "url", resourceURL, "status", resp.StatusCode)
+		s.recordDownload(0, false)
+		return ""
+	}
+
+	// Limit resource size to prevent memory issues (5MB max)
+	const maxSize = 5 * 1024 * 1024
+	limitedReader := io.LimitReader(resp.Body, maxSize)
+
+	content, err := io.ReadAll(limitedReader)
+	if err != nil {
+		slog.WarnContext(s.ctx, "failed to read resource", "url", resourceURL, "error", err)
+		s.recordDownload(0, false)
+		return ""
+	}
+
+	s.recordDownload(len(content), true)
+	return string(content)
+}
+
+// downloadAndEncodeImage downloads an image and returns it as a base64 data URI
+func (s *archiveSession) downloadAndEncodeImage(imageURL string) string {
+	if !s.acquireSlot() {
+		s.recordDownload(0, false)
+		return ""
+	}
+	defer s.releaseSlot()
+
+	resp, err := s.get(imageURL)
+	if err != nil {
+		slog.WarnContext(s.ctx, "failed to download image", "url", imageURL, "error", err)
+		s.recordDownload(0, false)
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		slog.WarnContext(s.ctx, "HTTP error downloading image", "url", imageURL, "status", resp.StatusCode)
+		s.recordDownload(0, false)
+		return ""
+	}
+
+	// Get content type
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		// Try to determine from URL extension
+		ext := strings.ToLower(path.Ext(imageURL))
+		contentType = mime.TypeByExtension(ext)
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+	}
+
+	// Skip very large images (1MB max for images)
+	const maxImageSize = 1 * 1024 * 1024
+	limitedReader := io.LimitReader(resp.Body, maxImageSize)
+
+	imageData, err := io.ReadAll(limitedReader)
+	if err != nil {
+		slog.WarnContext(s.ctx, "failed to read image", "url", imageURL, "error", err)
+		s.recordDownload(0, false)
+		return ""
+	}
+
+	s.recordDownload(len(imageData), true)
+
+	// Encode as base64 data URI
+	encoded := base64.StdEncoding.EncodeToString(imageData)
+	return fmt.Sprintf("data:%s;base64,%s", contentType, encoded)
+}
+
+// processCSS processes CSS content to inline @import and url() references
+func (s *archiveSession) processCSS(cssContent, cssURL string) string {
+	cssBaseURL, err := url.Parse(cssURL)
+	if err != nil {
+		return cssContent
+	}
+
+	// Process @import rules
+	importRe := regexp.MustCompile(`@import\s+(?:url\()?["']([^"']+)["'](?:\))?[^;]*;`)
+	cssContent = s.replaceAllConcurrent(importRe, cssContent, func(match string) string {
+		importMatch := importRe.FindStringSubmatch(match)
+		if len(importMatch) < 2 {
+			return match
+		}
+
+		importURL := s.resolveURL(cssBaseURL, importMatch[1])
+		if importURL == "" {
+			return match
+		}
+
+		importedCSS := s.downloadResource(importURL)
+		if importedCSS == "" {
+			return match
+		}
+
+		// Recursively process imported CSS
+		return fmt.Sprintf("/* Imported from: %s */\n%s", importURL, s.processCSS(importedCSS, importURL))
+	})
+
+	// Process url() references (fonts, background images, etc.)
+	urlRe := regexp.MustCompile(`url\(["']?([^"')\s]+)["']?\)`)
+	cssContent = s.replaceAllConcurrent(urlRe, cssContent, func(match string) string {
+		urlMatch := urlRe.FindStringSubmatch(match)
+		if len(urlMatch) < 2 {
+			return match
+		}
+
+		resourceURL := urlMatch[1]
+
+		// Skip data URIs
+		if strings.HasPrefix(resourceURL, "data:") {
+			return match
+		}
+
+		resolvedURL := s.resolveURL(cssBaseURL, resourceURL)
+		if resolvedURL == "" {
+			return match
+		}
+
+		// Determine if this is likely an image or font
+		ext := strings.ToLower(path.Ext(resourceURL))
+		isImage := ext == ".png" || ext == ".jpg" || ext == ".jpeg" || ext == ".gif" || ext == ".svg" || ext == ".webp"
+		isFont := ext == ".woff" || ext == ".woff2" || ext == ".ttf" || ext == ".otf" || ext == ".eot"
+
+		if isImage || isFont {
+			// Convert to data URI
+			dataURI := s.downloadAndEncodeImage(resolvedURL)
+			if dataURI != "" {
+				return fmt.Sprintf(`url("%s")`, dataURI)
+			}
+		}
+
+		return match
+	})
+
+	return cssContent
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+func TestHasArchivedSitesFindsArchiverLinks(t *testing.T) {
+	archive := &ArchiveInfo{
+		Title:     "Example",
+		FilePath:  filepath.Join("assets", "sites", "example.com_2026-01-01.html"),
+		Timestamp: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+		Source:    ArchiveSourceDirect,
+	}
+	for _, test := range []struct {
+		content string
+		want    bool
+	}{
+		{"Read later: " + archive.Markdown(), true},
+		{"[Example](/assets/sites/example.com.html)", true},
+		{"[Example](</p/team/assets/sites/example.com.html>)", true},
+		{"[Elsewhere](https://example.com/assets/sites/page.html)", false},
+		{"[Image](assets/images/a.png)", false},
+	} {
+		note := models.NewNote("Links", test.content)
+		if got := note.HasArchivedSites(); got != test.want {
+			t.Errorf("HasArchivedSites of %q = %v, want %v", test.content, got, test.want)
+		}
+	}
+}
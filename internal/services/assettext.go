@@ -0,0 +1,126 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// textTimeout bounds how long a text command may take for one file
+const textTimeout = 2 * time.Minute
+
+// textDir is the subdirectory of assets the text read from uploaded files is
+// saved in, under the subdirectory of each file
+const textDir = "text"
+
+// ExtractText runs command on the file at filePath and returns the text it
+// prints. The command may include arguments; "{}" among them is replaced by
+// the file path, which is otherwise appended, so "tesseract {} stdout" reads
+// an image with tesseract.
+func ExtractText(command, filePath string) (string, error) {
+	return runTextCommand(command, filePath, textTimeout)
+}
+
+// runTextCommand runs command on the file at filePath as ExtractText does,
+// for at most timeout
+func runTextCommand(command, filePath string, timeout time.Duration) (string, error) {
+	words := strings.Fields(command)
+	if len(words) == 0 {
+		return "", errors.New("no text command configured")
+	}
+	args, replaced := words[1:], false
+	for i, arg := range args {
+		if arg == "{}" {
+			args[i], replaced = filePath, true
+		}
+	}
+	if !replaced {
+		args = append(args, filePath)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, words[0], args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("text command %s: %w: %s", words[0], err, message)
+		}
+		return "", fmt.Errorf("text command %s: %w", words[0], err)
+	}
+	return strings.Join(strings.Fields(stdout.String()), " "), nil
+}
+
+// loadAssetText reads the text extracted from uploaded files, saved in
+// assets/text as <subdirectory>/<file name>.txt, keyed by the file's path
+// under assets, such as "images/whiteboard.png" or, for a note's own
+// uploads, "20240102-150405/images/whiteboard.png"
+func loadAssetText(textPath string) map[string]string {
+	texts := make(map[string]string)
+	filepath.WalkDir(textPath, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		relative, err := filepath.Rel(textPath, filePath)
+		name, ok := strings.CutSuffix(filepath.ToSlash(relative), ".txt")
+		if err != nil || !ok || !strings.Contains(name, "/") {
+			return nil
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil
+		}
+		texts[name] = strings.ToLower(string(data))
+		return nil
+	})
+	return texts
+}
+
+// extractAssetText runs command on an uploaded file, saved in the assets
+// subdirectory dir, and saves the text it reads in assets/text, so searches
+// find the notes linking to the file. Files are read one at a time.
+func (nm *NoteManager) extractAssetText(dir, name, command string) {
+	nm.extracting.Lock()
+	defer nm.extracting.Unlock()
+
+	assetsPath := filepath.Join(nm.storage.BasePath, "assets")
+	text, err := ExtractText(command, filepath.Join(assetsPath, dir, name))
+	if err != nil {
+		slog.Warn("no text read from asset", "file", dir+"/"+name, "error", err)
+		return
+	}
+	nm.saveAssetText(dir, name, text)
+}
+
+// saveAssetText saves the text read from the upload name, in the assets
+// subdirectory dir, and adds it to the text searched
+func (nm *NoteManager) saveAssetText(dir, name, text string) {
+	textPath := filepath.Join(nm.storage.BasePath, "assets", textDir, dir)
+	if err := os.MkdirAll(textPath, 0755); err != nil {
+		slog.Warn("failed to create text directory", "error", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(textPath, name+".txt"), []byte(text+"\n"), 0644); err != nil {
+		slog.Warn("failed to save text of asset", "file", dir+"/"+name, "error", err)
+		return
+	}
+
+	nm.assetTextMu.Lock()
+	nm.assetText[path.Join(dir, name)] = strings.ToLower(text)
+	nm.assetTextMu.Unlock()
+
+	// Searches may match other notes now
+	nm.revision.Add(1)
+}
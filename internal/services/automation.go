@@ -0,0 +1,145 @@
+package services
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// Automation feed settings
+const (
+	// automationFeedFile keeps, under assets, the recent items and the last
+	// cursor, so item IDs stay unique across restarts
+	automationFeedFile = ".automation.json"
+	// automationFeedSize is how many recent items are kept
+	automationFeedSize = 500
+)
+
+// AutomationFeed records a project's new notes and completed tasks for the
+// polling trigger endpoints used by Zapier, IFTTT and similar services
+type AutomationFeed struct {
+	noteManager *NoteManager
+	path        string
+
+	mu     sync.RWMutex
+	cursor uint64
+	items  []models.AutomationItem
+}
+
+// automationFeedState is the content of the feed file
+type automationFeedState struct {
+	Cursor uint64                  `json:"cursor"`
+	Items  []models.AutomationItem `json:"items"`
+}
+
+// NewAutomationFeed creates the feed of noteManager's project, with the items
+// recorded before a restart
+func NewAutomationFeed(noteManager *NoteManager) *AutomationFeed {
+	feed := &AutomationFeed{
+		noteManager: noteManager,
+		path:        filepath.Join(noteManager.GetBasePath(), "assets", automationFeedFile),
+	}
+
+	if data, err := os.ReadFile(feed.path); err == nil {
+		var state automationFeedState
+		if err := json.Unmarshal(data, &state); err != nil {
+			slog.Warn("ignoring unreadable automation feed", "path", feed.path, "error", err)
+		} else {
+			feed.cursor, feed.items = state.Cursor, state.Items
+		}
+	}
+	return feed
+}
+
+// Watch records the project's new notes and completed tasks until its broker
+// is closed
+func (f *AutomationFeed) Watch() {
+	ch := f.noteManager.Events().Subscribe()
+
+	go func() {
+		for event := range ch {
+			switch change := event.Data.(type) {
+			case models.NoteChange:
+				if event.Type == models.EventNoteCreated {
+					f.addNote(event.Timestamp, change)
+				}
+			case models.TaskChange:
+				if event.Type == models.EventTaskToggled && change.Checked {
+					f.add(models.AutomationItem{
+						Kind: models.AutomationTaskCompleted,
+						Time: event.Timestamp,
+						User: change.User,
+						Text: models.TaskLabel(change.Text),
+					})
+				}
+			}
+		}
+	}()
+}
+
+// addNote records a new note. Its content is looked up by index, which may
+// have changed since if more notes were added.
+func (f *AutomationFeed) addNote(created time.Time, change models.NoteChange) {
+	item := models.AutomationItem{
+		Kind:  models.AutomationNoteCreated,
+		Time:  created,
+		User:  change.User,
+		Title: change.Title,
+	}
+	if note, err := f.noteManager.GetNote(change.Index); err == nil && note.Title == change.Title {
+		item.Content = note.Content
+		item.Tags = note.Tags()
+	}
+	f.add(item)
+}
+
+// add numbers an item, keeps it with the recent ones and saves the feed
+func (f *AutomationFeed) add(item models.AutomationItem) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.cursor++
+	item.Cursor = f.cursor
+	item.ID = strconv.FormatUint(f.cursor, 10)
+	item.Meta = models.AutomationMeta{ID: item.ID, Timestamp: item.Time.Unix()}
+	f.items = append(f.items, item)
+	if len(f.items) > automationFeedSize {
+		f.items = append([]models.AutomationItem(nil), f.items[len(f.items)-automationFeedSize:]...)
+	}
+
+	data, err := json.Marshal(automationFeedState{Cursor: f.cursor, Items: f.items})
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(f.path), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(f.path, data, 0644)
+	}
+	if err != nil {
+		slog.Warn("failed to save the automation feed", "error", err)
+	}
+}
+
+// Items returns up to limit items of kind recorded after cursor, newest
+// first
+func (f *AutomationFeed) Items(kind string, cursor uint64, limit int) []models.AutomationItem {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	items := make([]models.AutomationItem, 0)
+	for i := len(f.items) - 1; i >= 0 && len(items) < limit; i-- {
+		item := f.items[i]
+		if item.Cursor <= cursor {
+			break
+		}
+		if item.Kind == kind {
+			items = append(items, item)
+		}
+	}
+	return items
+}
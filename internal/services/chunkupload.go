@@ -0,0 +1,192 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// partialUploadDir is the subdirectory of assets holding files being
+// uploaded in chunks
+const partialUploadDir = ".uploads"
+
+// partialUploadExpiry is how long an unfinished chunked upload is kept
+const partialUploadExpiry = 24 * time.Hour
+
+// Errors returned for chunked uploads
+var (
+	ErrUploadNotFound = errors.New("upload not found")
+	ErrUploadOffset   = errors.New("chunk offset does not match the bytes uploaded so far")
+	ErrUploadOverflow = errors.New("chunk goes past the size of the file")
+)
+
+// StartUpload begins a chunked upload of a file of size bytes. Unfinished
+// uploads that have expired are removed first.
+func (nm *NoteManager) StartUpload(filename string, size int64, contentType string) (*models.PartialUpload, error) {
+	nm.chunkMu.Lock()
+	defer nm.chunkMu.Unlock()
+
+	dir := filepath.Join(nm.storage.BasePath, "assets", partialUploadDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create uploads directory: %w", err)
+	}
+	removeExpiredUploads(dir)
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate upload ID: %w", err)
+	}
+	upload := &models.PartialUpload{
+		ID:          hex.EncodeToString(id),
+		Filename:    filepath.Base(filename),
+		ContentType: contentType,
+		Size:        size,
+		Created:     time.Now(),
+	}
+
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, upload.ID+".json"), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to start upload: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, upload.ID+".part"), nil, 0644); err != nil {
+		return nil, fmt.Errorf("failed to start upload: %w", err)
+	}
+	return upload, nil
+}
+
+// Upload returns a chunked upload, with the number of bytes received so far
+// as its offset
+func (nm *NoteManager) Upload(id string) (*models.PartialUpload, error) {
+	nm.chunkMu.Lock()
+	defer nm.chunkMu.Unlock()
+	return nm.loadUpload(id)
+}
+
+// WriteChunk adds data to a chunked upload at offset, which must be the
+// number of bytes received so far. Once the file is complete, its contents
+// are returned and the upload is removed.
+func (nm *NoteManager) WriteChunk(id string, offset int64, data []byte) (*models.PartialUpload, []byte, error) {
+	nm.chunkMu.Lock()
+	defer nm.chunkMu.Unlock()
+
+	upload, err := nm.loadUpload(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if offset != upload.Offset {
+		return upload, nil, ErrUploadOffset
+	}
+	if offset+int64(len(data)) > upload.Size {
+		return upload, nil, ErrUploadOverflow
+	}
+
+	partPath := nm.partialUploadPath(id, ".part")
+	file, err := os.OpenFile(partPath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open upload: %w", err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// A partly written chunk leaves the offset for the client to resume from
+		return nil, nil, fmt.Errorf("failed to write chunk: %w", err)
+	}
+	upload.Offset += int64(len(data))
+	if !upload.Complete() {
+		return upload, nil, nil
+	}
+
+	contents, err := os.ReadFile(partPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	nm.removeUpload(id)
+	return upload, contents, nil
+}
+
+// CancelUpload removes an unfinished chunked upload
+func (nm *NoteManager) CancelUpload(id string) error {
+	nm.chunkMu.Lock()
+	defer nm.chunkMu.Unlock()
+
+	if _, err := nm.loadUpload(id); err != nil {
+		return err
+	}
+	nm.removeUpload(id)
+	return nil
+}
+
+// loadUpload reads a chunked upload, taking its offset from the data
+// received. The caller must hold nm.chunkMu.
+func (nm *NoteManager) loadUpload(id string) (*models.PartialUpload, error) {
+	// IDs are hex, so they can't point outside the uploads directory
+	if _, err := hex.DecodeString(id); err != nil || id == "" {
+		return nil, ErrUploadNotFound
+	}
+
+	data, err := os.ReadFile(nm.partialUploadPath(id, ".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	var upload models.PartialUpload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	info, err := os.Stat(nm.partialUploadPath(id, ".part"))
+	if err != nil {
+		return nil, ErrUploadNotFound
+	}
+	upload.Offset = info.Size()
+	return &upload, nil
+}
+
+// removeUpload deletes the files of a chunked upload. The caller must hold
+// nm.chunkMu.
+func (nm *NoteManager) removeUpload(id string) {
+	os.Remove(nm.partialUploadPath(id, ".part"))
+	os.Remove(nm.partialUploadPath(id, ".json"))
+}
+
+// partialUploadPath returns the path of the file of a chunked upload with
+// extension ext
+func (nm *NoteManager) partialUploadPath(id, ext string) string {
+	return filepath.Join(nm.storage.BasePath, "assets", partialUploadDir, id+ext)
+}
+
+// removeExpiredUploads deletes the files of chunked uploads in dir that
+// haven't received data within partialUploadExpiry
+func removeExpiredUploads(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".part") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < partialUploadExpiry {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), ".part")
+		os.Remove(filepath.Join(dir, id+".part"))
+		os.Remove(filepath.Join(dir, id+".json"))
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// Clip adds a note, attributed to author, for a page clipped by a browser
+// extension: a link to the page, the text selected on it as a quote, the
+// screenshot saved at screenshotPath if not empty, and then the clipper's own
+// text and tags. Unless the clip says otherwise, the page is archived in the
+// background and a link to the archive added to the note when it is done.
+// It returns the note's title.
+func (nm *NoteManager) Clip(clip *models.ClipRequest, screenshotPath, author string) (string, error) {
+	pageURL, err := clip.PageURL()
+	if err != nil {
+		return "", err
+	}
+	title := strings.Join(strings.Fields(clip.Title), " ")
+	if title == "" {
+		title = pageURL.Hostname()
+	}
+
+	var content strings.Builder
+	linkText := strings.NewReplacer("[", `\[`, "]", `\]`).Replace(title)
+	fmt.Fprintf(&content, "[%s](<%s>)\n", linkText, pageURL.String())
+	if selection := strings.TrimSpace(clip.Selection); selection != "" {
+		content.WriteString("\n")
+		for _, line := range strings.Split(selection, "\n") {
+			content.WriteString(strings.TrimRight("> "+strings.TrimSpace(line), " ") + "\n")
+		}
+	}
+	if screenshotPath != "" {
+		fmt.Fprintf(&content, "\n![%s](<%s>)\n", linkText, screenshotPath)
+	}
+	if text := strings.TrimSpace(clip.Note); text != "" {
+		content.WriteString("\n" + text + "\n")
+	}
+	var tags []string
+	for _, tag := range clip.Tags {
+		tag = strings.Join(strings.Fields(strings.TrimPrefix(strings.TrimSpace(tag), "#")), "-")
+		if tag != "" {
+			tags = append(tags, "#"+tag)
+		}
+	}
+	if len(tags) > 0 {
+		content.WriteString("\n" + strings.Join(tags, " ") + "\n")
+	}
+
+	nm.lockNotes()
+	note, err := nm.addNote(title, strings.TrimSpace(content.String()), author)
+	nm.unlockNotes()
+	if err != nil {
+		return "", err
+	}
+
+	if clip.Archive == nil || *clip.Archive {
+		nm.archiveClip(note.Timestamp, pageURL.String(), author)
+	}
+	return title, nil
+}
+
+// archiveClip archives a clipped page in the background and adds a link to
+// the archive to the clipping's note, if it still exists
+func (nm *NoteManager) archiveClip(created time.Time, pageURL, author string) {
+	nm.background.Add(1)
+	go func() {
+		defer nm.background.Done()
+
+		archiveInfo, err := nm.archiver.Archive(nm.archiver.ctx, pageURL, nil)
+		if err != nil {
+			slog.Warn("failed to archive clipped page", "url", pageURL, "error", err)
+			return
+		}
+
+		nm.lockNotes()
+		defer nm.unlockNotes()
+
+		// Edits replace the note, so it is found by its timestamp
+		index := slices.IndexFunc(nm.notes, func(n *models.Note) bool {
+			return n.Timestamp.Equal(created)
+		})
+		if index < 0 {
+			return
+		}
+		note := nm.editNote(index)
+		note.Update(note.Title, strings.TrimRight(note.Content, "\n")+"\n\n"+archiveInfo.Markdown())
+		nm.assignTaskIndices()
+
+		nm.needsSave = true
+		if err := nm.save(); err != nil {
+			slog.Warn("failed to add archive to its clipping", "url", pageURL, "error", err)
+			return
+		}
+		nm.events.Publish(models.EventNoteUpdated, models.NoteChange{Index: index, Title: note.Title, User: author})
+	}()
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// ConfigStore holds the running configuration. Readers take the current
+// settings with Current, which are never changed afterwards; every change
+// goes through Update or Load, one at a time, and publishes new settings.
+type ConfigStore struct {
+	path    string // the config file, or empty when changes aren't saved
+	mu      sync.Mutex
+	current atomic.Pointer[models.Config]
+	watches []func(previous, config *models.Config)
+
+	// generation counts the settings published since the store was made
+	generation atomic.Uint64
+}
+
+// NewConfigStore holds config, saving changes to the file at path unless it
+// is empty
+func NewConfigStore(config *models.Config, path string) *ConfigStore {
+	s := &ConfigStore{path: path}
+	s.current.Store(config)
+	return s
+}
+
+// Current returns the current settings. They must not be changed.
+func (s *ConfigStore) Current() *models.Config {
+	return s.current.Load()
+}
+
+// Generation returns a number that changes whenever the settings do, so
+// responses rendered with them can be told apart
+func (s *ConfigStore) Generation() uint64 {
+	return s.generation.Load()
+}
+
+// Watch calls fn with the previous and new settings after each change, in
+// the order they are made. fn must not change the settings itself. Watches
+// are added before the settings change for the first time.
+func (s *ConfigStore) Watch(fn func(previous, config *models.Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watches = append(s.watches, fn)
+}
+
+// Update calls change with a copy of the current settings, then saves the
+// copy to the config file and publishes it, returning it. An error from
+// change is returned as it is, leaving the settings as they were.
+func (s *ConfigStore) Update(change func(config *models.Config) error) (*models.Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	config := s.Current().Clone()
+	if err := change(config); err != nil {
+		return nil, err
+	}
+	if s.path != "" {
+		if err := models.SaveConfig(config, s.path); err != nil {
+			return nil, fmt.Errorf("failed to save config: %w", err)
+		}
+	}
+	s.publish(config)
+	return config, nil
+}
+
+// Load publishes settings read from the config file, which is left as it
+// is. It reports false when they are the settings already running.
+func (s *ConfigStore) Load(config *models.Config) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reflect.DeepEqual(config, s.Current()) {
+		return false
+	}
+	s.publish(config)
+	return true
+}
+
+// publish makes config the current settings and tells the watches. Called
+// with s.mu held.
+func (s *ConfigStore) publish(config *models.Config) {
+	previous := s.current.Swap(config)
+	s.generation.Add(1)
+	for _, watch := range s.watches {
+		watch(previous, config)
+	}
+}
@@ -208,6 +208,7 @@ func (ds *DatabaseService) GetGlobalTasks() (*models.GlobalTasksResponse, error)
 		} else if t, err := time.Parse("2006-01-02 15:04:05", lastUpdated); err == nil {
 			task.LastUpdated = t
 		}
+		task.TaskMeta = models.ParseTaskMeta(task.Content)
 		tasks = append(tasks, task)
 	}
 
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// eventBufferSize is the number of pending events buffered per subscriber
+const eventBufferSize = 64
+
+// EventBroker fans out server-side events to live update subscribers
+type EventBroker struct {
+	subscribers map[chan models.Event]struct{}
+	mu          sync.RWMutex
+	lastID      atomic.Uint64
+	closed      bool
+}
+
+// NewEventBroker creates a new event broker
+func NewEventBroker() *EventBroker {
+	return &EventBroker{
+		subscribers: make(map[chan models.Event]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber and returns its event channel
+func (eb *EventBroker) Subscribe() chan models.Event {
+	ch := make(chan models.Event, eventBufferSize)
+
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	// A closed broker hands out closed channels so streams end at once
+	if eb.closed {
+		close(ch)
+		return ch
+	}
+	eb.subscribers[ch] = struct{}{}
+
+	return ch
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (eb *EventBroker) Unsubscribe(ch chan models.Event) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	if _, exists := eb.subscribers[ch]; exists {
+		delete(eb.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Publish sends an event to all subscribers. Slow subscribers whose buffer is
+// full miss the event rather than blocking the publisher.
+func (eb *EventBroker) Publish(eventType string, data interface{}) {
+	if eb == nil {
+		return
+	}
+
+	event := models.Event{
+		ID:        eb.lastID.Add(1),
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Data:      data,
+	}
+
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+
+	for ch := range eb.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Close disconnects all subscribers, ending their streams
+func (eb *EventBroker) Close() {
+	if eb == nil {
+		return
+	}
+
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	eb.closed = true
+	for ch := range eb.subscribers {
+		delete(eb.subscribers, ch)
+		close(ch)
+	}
+}
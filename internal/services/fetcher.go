@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// Defaults for outbound requests when not configured
+const (
+	defaultFetchConcurrency     = 16
+	defaultFetchHostConcurrency = 6
+	fetchTimeout                = 60 * time.Second
+)
+
+// Fetcher makes the requests to other websites, bounding how many run at
+// once, in all and to each host, and spacing out the starts of those to the
+// same host. One fetcher is shared by the projects, so archiving a note with
+// many links, or several notes at once, doesn't flood the network or a site.
+type Fetcher struct {
+	client *http.Client
+
+	mu     sync.Mutex
+	config models.FetchConfig
+	// slots limits the requests running at once; requests keep the
+	// channel they took a slot from when a reload replaces it
+	slots chan struct{}
+	hosts map[string]*fetchHost
+}
+
+// fetchHost tracks the requests to one host
+type fetchHost struct {
+	slots chan struct{}
+	next  time.Time // when the next request may start
+	users int       // requests waiting for or holding a slot
+}
+
+// NewFetcher creates a fetcher with the limits in config
+func NewFetcher(config models.FetchConfig) *Fetcher {
+	f := &Fetcher{
+		client: &http.Client{Timeout: fetchTimeout},
+		hosts:  make(map[string]*fetchHost),
+	}
+	f.Reload(config)
+	return f
+}
+
+// Reload switches to the limits of a reloaded configuration. Requests
+// already running keep their slots.
+func (f *Fetcher) Reload(config models.FetchConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultFetchConcurrency
+	}
+	if concurrency != cap(f.slots) {
+		f.slots = make(chan struct{}, concurrency)
+	}
+	f.config = config
+}
+
+// Do sends req once the limits allow, waiting no longer than its context.
+// The request's slot is held until the response body is closed.
+func (f *Fetcher) Do(req *http.Request) (*http.Response, error) {
+	release, err := f.acquire(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// acquire waits for a slot for a request to host, and for the host's delay
+// since the last request to it. It returns the function freeing the slot.
+func (f *Fetcher) acquire(ctx context.Context, host string) (func(), error) {
+	f.mu.Lock()
+	h := f.hosts[host]
+	if h == nil {
+		f.pruneHosts()
+		hostConcurrency := f.config.HostConcurrency
+		if hostConcurrency <= 0 {
+			hostConcurrency = defaultFetchHostConcurrency
+		}
+		h = &fetchHost{slots: make(chan struct{}, hostConcurrency)}
+		f.hosts[host] = h
+	}
+	h.users++
+	slots := f.slots
+	delay := time.Duration(f.config.HostDelayMS) * time.Millisecond
+	f.mu.Unlock()
+
+	leave := func() {
+		f.mu.Lock()
+		h.users--
+		f.mu.Unlock()
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		leave()
+		return nil, ctx.Err()
+	}
+
+	// Each request to the host starts at least delay after the one before
+	f.mu.Lock()
+	now := time.Now()
+	start := h.next
+	if start.Before(now) {
+		start = now
+	}
+	h.next = start.Add(delay)
+	f.mu.Unlock()
+
+	if wait := start.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			<-h.slots
+			leave()
+			return nil, ctx.Err()
+		}
+	}
+
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		<-h.slots
+		leave()
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-slots
+		<-h.slots
+		leave()
+	}, nil
+}
+
+// pruneHosts forgets the hosts with no requests whose delay has passed.
+// Called with f.mu held.
+func (f *Fetcher) pruneHosts() {
+	now := time.Now()
+	for host, h := range f.hosts {
+		if h.users == 0 && !h.next.After(now) {
+			delete(f.hosts, host)
+		}
+	}
+}
+
+// releasingBody frees a request's slot when its response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Close closes the body and frees the slot
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
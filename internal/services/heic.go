@@ -0,0 +1,79 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// heicTimeout bounds how long converting one HEIC image may take
+const heicTimeout = time.Minute
+
+// defaultHEICCommands convert HEIC images to JPEG with the first of these
+// tools that is installed: libheif, ImageMagick, or sips on macOS
+var defaultHEICCommands = []string{
+	"heif-convert -q 90 {} {out}",
+	"magick {} {out}",
+	"sips -s format jpeg {} --out {out}",
+}
+
+// ErrNoHEICConverter is returned when no command to convert HEIC images is
+// configured or installed
+var ErrNoHEICConverter = errors.New("no HEIC converter installed (heif-convert, magick or sips)")
+
+// IsHEIC reports whether contentType is that of a HEIC or HEIF image
+func IsHEIC(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/heic") || strings.HasPrefix(contentType, "image/heif")
+}
+
+// ConvertHEIC converts a HEIC image to JPEG with command, or with the first
+// installed default converter when command is empty
+func ConvertHEIC(data []byte, command string) ([]byte, error) {
+	if command == "" {
+		for _, candidate := range defaultHEICCommands {
+			if _, err := exec.LookPath(strings.Fields(candidate)[0]); err == nil {
+				command = candidate
+				break
+			}
+		}
+		if command == "" {
+			return nil, ErrNoHEICConverter
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "noteflow-heic-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	in, out := filepath.Join(dir, "image.heic"), filepath.Join(dir, "image.jpg")
+	if err := os.WriteFile(in, data, 0600); err != nil {
+		return nil, err
+	}
+
+	replacer := strings.NewReplacer("{out}", out, "{}", in)
+	words := strings.Fields(command)
+	for i := range words {
+		words[i] = replacer.Replace(words[i])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), heicTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, words[0], words[1:]...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("HEIC command %s: %w: %s", words[0], err, message)
+		}
+		return nil, fmt.Errorf("HEIC command %s: %w", words[0], err)
+	}
+	return os.ReadFile(out)
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// errMalformedImage is returned for image data whose structure can't be
+// followed, which is then saved as it is
+var errMalformedImage = errors.New("malformed image data")
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks are the PNG chunks holding EXIF data, text such as XMP,
+// and the modification time
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// StripMetadata removes EXIF data, including GPS positions, XMP and other
+// metadata from a JPEG, PNG or WebP image. JPEGs keep their orientation, so
+// they are still shown upright. Data in other formats is returned unchanged.
+func StripMetadata(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return stripJPEGMetadata(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return stripPNGMetadata(data)
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return stripWebPMetadata(data)
+	}
+	return data, nil
+}
+
+// stripJPEGMetadata drops the EXIF, XMP, IPTC and comment segments of a
+// JPEG, adding back an EXIF segment with only the orientation when the
+// image isn't stored upright
+func stripJPEGMetadata(data []byte) ([]byte, error) {
+	orientation := jpegOrientation(data)
+	out := append([]byte{}, data[:2]...)
+	added := orientation == 1
+
+	i := 2
+	for {
+		if i+4 > len(data) || data[i] != 0xFF {
+			return nil, errMalformedImage
+		}
+		marker := data[i+1]
+
+		// The orientation goes after the JFIF header, before other segments
+		if !added && marker != 0xE0 {
+			out = append(out, orientationSegment(orientation)...)
+			added = true
+		}
+		// The image data follows the start of scan, with nothing to strip
+		if marker == 0xDA {
+			return append(out, data[i:]...), nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return nil, errMalformedImage
+		}
+		switch marker {
+		case 0xE1, 0xED, 0xFE: // EXIF or XMP, IPTC, comments
+		default:
+			out = append(out, data[i:i+2+length]...)
+		}
+		i += 2 + length
+	}
+}
+
+// orientationSegment returns a JPEG APP1 segment with EXIF data holding only
+// the orientation
+func orientationSegment(orientation int) []byte {
+	exif := []byte("Exif\x00\x00")
+	exif = append(exif, 'M', 'M', 0, 42, 0, 0, 0, 8) // big endian, directory at 8
+	exif = append(exif, 0, 1)                        // one entry
+	exif = append(exif, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, byte(orientation), 0, 0)
+	exif = append(exif, 0, 0, 0, 0) // no further directories
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(exif)+2))
+	return append(segment, exif...)
+}
+
+// stripPNGMetadata drops the EXIF, text and time chunks of a PNG
+func stripPNGMetadata(data []byte) ([]byte, error) {
+	out := append([]byte{}, pngSignature...)
+	for i := len(pngSignature); i < len(data); {
+		if i+12 > len(data) {
+			return nil, errMalformedImage
+		}
+		length := int64(binary.BigEndian.Uint32(data[i:]))
+		end := int64(i) + 12 + length
+		if end > int64(len(data)) {
+			return nil, errMalformedImage
+		}
+		if !pngMetadataChunks[string(data[i+4:i+8])] {
+			out = append(out, data[i:end]...)
+		}
+		i = int(end)
+	}
+	return out, nil
+}
+
+// stripWebPMetadata drops the EXIF and XMP chunks of a WebP image and clears
+// the flags announcing them
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	out := append([]byte{}, data[:12]...)
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, errMalformedImage
+		}
+		fourCC := string(data[i : i+4])
+		size := int64(binary.LittleEndian.Uint32(data[i+4:]))
+		end := int64(i) + 8 + size + size%2
+		if end > int64(len(data)) {
+			return nil, errMalformedImage
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte{}, data[i:end]...)
+			if len(chunk) > 8 {
+				chunk[8] &^= 0x08 | 0x04 // EXIF and XMP present
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = int(end)
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
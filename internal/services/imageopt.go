@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// OptimizeImage scales an uploaded JPEG or PNG image down to the configured
+// maximum size and recompresses JPEGs at the configured quality. Other
+// formats, and images the settings leave alone, are returned unchanged, as
+// are JPEGs that recompressing alone would not make smaller.
+func OptimizeImage(data []byte, uploads *models.UploadConfig) ([]byte, error) {
+	maxSize, quality := uploads.ImageMaxSize, uploads.ImageQuality
+	if maxSize <= 0 && quality <= 0 {
+		return data, nil
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "jpeg" && format != "png") {
+		return data, nil
+	}
+	scale := maxSize > 0 && (config.Width > maxSize || config.Height > maxSize)
+	if !scale && (format != "jpeg" || quality <= 0) {
+		return data, nil
+	}
+
+	img, err := decodeImage(data, format)
+	if err != nil {
+		return nil, err
+	}
+	if scale {
+		width, height := fitWithin(img.Bounds().Dx(), img.Bounds().Dy(), maxSize)
+		img = scaleImage(img, width, height)
+	}
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		if quality <= 0 {
+			quality = models.DefaultImageQuality
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	} else {
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	if !scale && buf.Len() >= len(data) {
+		return data, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeImage decodes a JPEG or PNG image, turning JPEGs upright as their
+// EXIF orientation says, since the orientation is lost when re-encoding
+func decodeImage(data []byte, format string) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+	return img, nil
+}
+
+// fitWithin returns the size of a width by height image scaled so its
+// longest side is size
+func fitWithin(width, height, size int) (int, int) {
+	if width > height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
+
+// orient flips and rotates img as EXIF orientation 2 to 8 asks; other
+// orientations return it as it is
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = width-1-x, y
+			case 3: // upside down
+				sx, sy = width-1-x, height-1-y
+			case 4: // mirrored upside down
+				sx, sy = x, height-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // needs a quarter turn clockwise
+				sx, sy = y, height-1-x
+			case 7: // transversed
+				sx, sy = width-1-y, height-1-x
+			case 8: // needs a quarter turn counterclockwise
+				sx, sy = width-1-y, x
+			}
+			dst.Set(x, y, img.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+	return dst
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG image, or 1 when it
+// has none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// Walk the segments before the image data, looking for the EXIF one
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation tag from the first image directory
+// of EXIF data, returning 1 when it is missing
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	dir := int64(order.Uint32(tiff[4:]))
+	if dir+2 > int64(len(tiff)) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[dir:]))
+	for i := 0; i < count; i++ {
+		entry := dir + 2 + int64(i)*12
+		if entry+12 > int64(len(tiff)) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// maxImportFileSize caps the size of a single file extracted from an import zip
+const maxImportFileSize = 10 << 20
+
+// maxImportZipSize caps the bytes extracted from an import zip in all
+const maxImportZipSize = 100 << 20
+
+// maxImportZipEntries caps the number of entries in an import zip
+const maxImportZipEntries = 10000
+
+// importExtensions are the file types converted into notes
+var importExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".txt":      true,
+}
+
+// frontMatterPattern matches a YAML front matter block at the start of a file
+var frontMatterPattern = regexp.MustCompile(`(?s)^---\r?\n(.*?)\r?\n---\r?\n*`)
+
+// importTagPattern matches a front matter tag that can be written as a
+// #hashtag
+var importTagPattern = regexp.MustCompile(`^[A-Za-z][\w/-]*$`)
+
+// importDateLayouts are the date formats accepted in front matter
+var importDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ReadImportZip returns the files in a zip archive, skipping directories and
+// hidden files such as macOS resource forks. Files that are not markdown or
+// text are returned without being extracted, and archives with more than
+// maxImportZipEntries entries or maxImportZipSize bytes of files are refused.
+func ReadImportZip(r io.ReaderAt, size int64) ([]models.ImportFile, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip file: %w", err)
+	}
+	if len(archive.File) > maxImportZipEntries {
+		return nil, fmt.Errorf("zip file has more than %d entries", maxImportZipEntries)
+	}
+
+	var files []models.ImportFile
+	var total int64
+	for _, entry := range archive.File {
+		name := entry.Name
+		if entry.FileInfo().IsDir() || hiddenImportPath(name) {
+			continue
+		}
+
+		file := models.ImportFile{Name: name, Modified: entry.Modified}
+		if !importExtensions[strings.ToLower(path.Ext(name))] || entry.UncompressedSize64 > maxImportFileSize {
+			// Reported as an error when the file is converted
+			files = append(files, file)
+			continue
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		file.Content, err = io.ReadAll(io.LimitReader(rc, maxImportFileSize+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		// Sizes in the zip directory may be forged, so count what was read
+		if total += int64(len(file.Content)); total > maxImportZipSize {
+			return nil, fmt.Errorf("zip file holds more than %d MB of files", maxImportZipSize>>20)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// hiddenImportPath reports whether any part of a zip entry's path is hidden
+func hiddenImportPath(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+// notesFromImportFile converts a markdown file into notes. A notes.md file
+// exported from NoteFlow becomes one note per entry; any other file becomes a
+// single note titled by its front matter, its first heading or its name.
+func notesFromImportFile(file models.ImportFile) ([]*models.Note, error) {
+	if !importExtensions[strings.ToLower(path.Ext(file.Name))] {
+		return nil, errors.New("not a markdown or text file")
+	}
+	if len(file.Content) > maxImportFileSize {
+		return nil, fmt.Errorf("larger than %d MB", maxImportFileSize>>20)
+	}
+	if !utf8.Valid(file.Content) {
+		return nil, errors.New("not valid UTF-8 text")
+	}
+
+	text := strings.TrimSpace(strings.ReplaceAll(string(file.Content), "\r\n", "\n"))
+	if text == "" {
+		return nil, errors.New("file is empty")
+	}
+
+	if strings.Contains(text, strings.TrimSpace(models.NoteSeparator)) {
+		var notes []*models.Note
+		for _, entry := range strings.Split(text, models.NoteSeparator) {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			note, err := models.NewNoteFromText(entry)
+			if err != nil {
+				return nil, err
+			}
+			notes = append(notes, note)
+		}
+		return notes, nil
+	}
+
+	title := ""
+	timestamp := file.Modified
+	var tags []string
+	if match := frontMatterPattern.FindStringSubmatch(text); match != nil {
+		title, timestamp, tags = parseFrontMatter(match[1], timestamp)
+		text = strings.TrimSpace(text[len(match[0]):])
+	}
+	if title == "" && strings.HasPrefix(text, "# ") {
+		heading, rest, _ := strings.Cut(text, "\n")
+		title = strings.TrimSpace(strings.TrimPrefix(heading, "# "))
+		text = strings.TrimSpace(rest)
+	}
+	if title == "" {
+		title = strings.TrimSuffix(path.Base(file.Name), path.Ext(file.Name))
+	}
+
+	note := models.NewNote(title, withTags(text, tags))
+	if !timestamp.IsZero() && timestamp.Before(note.Timestamp) {
+		note.Timestamp = timestamp.In(models.Location())
+		note.Modified = note.Timestamp
+	}
+	return []*models.Note{note}, nil
+}
+
+// parseFrontMatter reads the title, date and tags from YAML front matter,
+// keeping timestamp if the date is missing or unrecognised. Tags are given
+// as a list, in brackets or one per line, or separated by commas.
+func parseFrontMatter(frontMatter string, timestamp time.Time) (string, time.Time, []string) {
+	title := ""
+	var tags []string
+	inTags := false
+	for _, line := range strings.Split(frontMatter, "\n") {
+		// Items of a block list follow their key
+		if item, ok := strings.CutPrefix(strings.TrimSpace(line), "- "); ok {
+			if inTags {
+				tags = append(tags, frontMatterTags(item)...)
+			}
+			continue
+		}
+		inTags = false
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "title":
+			title = value
+		case "tags", "tag":
+			inTags = value == ""
+			tags = append(tags, frontMatterTags(strings.Trim(value, "[]"))...)
+		case "date", "created":
+			for _, layout := range importDateLayouts {
+				if parsed, err := time.ParseInLocation(layout, value, models.Location()); err == nil {
+					timestamp = parsed
+					break
+				}
+			}
+		}
+	}
+	return title, timestamp, tags
+}
+
+// frontMatterTags splits a front matter tag list, without brackets, into
+// the tags that can be written as #hashtags. Spaces in a tag become hyphens.
+func frontMatterTags(list string) []string {
+	var tags []string
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimPrefix(strings.Trim(strings.TrimSpace(tag), `"'`), "#")
+		tag = strings.Join(strings.Fields(tag), "-")
+		if importTagPattern.MatchString(tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// withTags returns text with a line of #hashtags added for the tags it
+// doesn't carry yet
+func withTags(text string, tags []string) string {
+	note := &models.Note{Content: text}
+	var missing []string
+	for _, tag := range tags {
+		if !note.HasTag(tag) {
+			missing = append(missing, "#"+tag)
+			// Tags given twice are added once
+			note.Content += " #" + tag
+		}
+	}
+	if len(missing) == 0 {
+		return text
+	}
+	return text + "\n\n" + strings.Join(missing, " ")
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"archive/zip"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// sharedAssetPattern matches links to uploads in the shared asset folders,
+// capturing the folder and the file name as linked
+var sharedAssetPattern = regexp.MustCompile(`/assets/(images|audio|video|files)/([^\s()<>"'\[\]]+)`)
+
+// noteAssetPattern matches links to uploads in any asset folder, shared or
+// a note's own, capturing the file's path under assets
+var noteAssetPattern = regexp.MustCompile(`/assets/((?:` + models.NoteFolderPattern + `/)?(?:images|audio|video|files)/[^\s()<>"'\[\]]+)`)
+
+// collectNoteAssets moves the uploads that note links to from the shared
+// asset folders into its own, as assets/<note folder>/<folder>/<file>, and
+// points its links at them, when uploads are kept per note. Uploads that
+// other notes link to as well are copied. Called with lockNotes held, on a
+// note being edited.
+func (nm *NoteManager) collectNoteAssets(note *models.Note) {
+	if uploads := nm.uploadConfig(); uploads == nil || !uploads.PerNoteFolders {
+		return
+	}
+
+	assetsPath := filepath.Join(nm.storage.BasePath, "assets")
+	folder := note.AssetFolder()
+	content := sharedAssetPattern.ReplaceAllStringFunc(note.Content, func(link string) string {
+		matches := sharedAssetPattern.FindStringSubmatch(link)
+		dir, linked := matches[1], matches[2]
+		name, err := url.PathUnescape(linked)
+		if err != nil || name != path.Base(name) {
+			return link
+		}
+
+		source := filepath.Join(assetsPath, dir, name)
+		if info, err := os.Stat(source); err != nil || info.IsDir() {
+			return link
+		}
+		target := path.Join(folder, dir)
+		keep := nm.linkedElsewhere(note, link)
+		if err := nm.moveAsset(dir, target, name, keep); err != nil {
+			slog.Warn("leaving asset in the shared folder", "file", dir+"/"+name, "error", err)
+			return link
+		}
+		// Originals kept of converted HEIC images go with the JPEG
+		if dir == models.AssetImages && !keep && strings.EqualFold(path.Ext(name), ".jpg") {
+			for _, ext := range []string{".heic", ".HEIC", ".heif", ".HEIF"} {
+				original := strings.TrimSuffix(name, path.Ext(name)) + ext
+				if _, err := os.Stat(filepath.Join(assetsPath, dir, original)); err == nil {
+					if err := nm.moveAsset(dir, target, original, false); err != nil {
+						slog.Warn("leaving asset in the shared folder", "file", dir+"/"+original, "error", err)
+					}
+				}
+			}
+		}
+		return "/assets/" + target + "/" + linked
+	})
+	if content != note.Content {
+		note.Update(note.Title, content)
+	}
+}
+
+// linkedElsewhere reports whether a note other than note links to link
+func (nm *NoteManager) linkedElsewhere(note *models.Note, link string) bool {
+	for _, other := range nm.notes {
+		if other != note && strings.Contains(other.Content, link) {
+			return true
+		}
+	}
+	return false
+}
+
+// moveAsset moves, or copies when keep is set, the upload name from the
+// assets subdirectory dir to target, along with the text read from it
+func (nm *NoteManager) moveAsset(dir, target, name string, keep bool) error {
+	assetsPath := filepath.Join(nm.storage.BasePath, "assets")
+	move := os.Rename
+	if keep {
+		move = copyFile
+	}
+
+	if err := os.MkdirAll(filepath.Join(assetsPath, target), 0755); err != nil {
+		return err
+	}
+	if err := move(filepath.Join(assetsPath, dir, name), filepath.Join(assetsPath, target, name)); err != nil {
+		return err
+	}
+	if !keep && dir == models.AssetImages {
+		os.Remove(filepath.Join(assetsPath, "thumbs", name))
+	}
+
+	textSource := filepath.Join(assetsPath, textDir, dir, name+".txt")
+	if _, err := os.Stat(textSource); err != nil {
+		return nil
+	}
+	textTarget := filepath.Join(assetsPath, textDir, target, name+".txt")
+	if err := os.MkdirAll(filepath.Dir(textTarget), 0755); err != nil {
+		return err
+	}
+	if err := move(textSource, textTarget); err != nil {
+		return err
+	}
+
+	nm.assetTextMu.Lock()
+	nm.assetText[path.Join(target, name)] = nm.assetText[path.Join(dir, name)]
+	if !keep {
+		delete(nm.assetText, path.Join(dir, name))
+	}
+	nm.assetTextMu.Unlock()
+	return nil
+}
+
+// copyFile copies the file at source to target
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// removeNoteAssets deletes the asset folder of a deleted note, unless a
+// remaining note was saved in the same second and shares it. Called with
+// lockNotes held.
+func (nm *NoteManager) removeNoteAssets(note *models.Note) {
+	folder := note.AssetFolder()
+	for _, other := range nm.notes {
+		if other.AssetFolder() == folder {
+			return
+		}
+	}
+
+	assetsPath := filepath.Join(nm.storage.BasePath, "assets")
+	if _, err := os.Stat(filepath.Join(assetsPath, folder)); err != nil {
+		return
+	}
+	for _, dir := range []string{folder, filepath.Join(textDir, folder)} {
+		if err := os.RemoveAll(filepath.Join(assetsPath, dir)); err != nil {
+			slog.Warn("failed to delete asset folder", "dir", "assets/"+dir, "error", err)
+		}
+	}
+
+	nm.assetTextMu.Lock()
+	for asset := range nm.assetText {
+		if strings.HasPrefix(asset, folder+"/") {
+			delete(nm.assetText, asset)
+		}
+	}
+	nm.assetTextMu.Unlock()
+	nm.revision.Add(1)
+}
+
+// ExportNote writes a note, the uploads it links to and the rest of its own
+// asset folder as a zip file to w. The note is saved as note.md, with its
+// links made relative so they point at the uploads under assets/ in the zip
+// file.
+func (nm *NoteManager) ExportNote(index int, w io.Writer) error {
+	note, err := nm.GetNote(index)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: "note.md", Method: zip.Deflate, Modified: note.Timestamp})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(entry, noteAssetPattern.ReplaceAllString(note.Render(), "assets/$1")); err != nil {
+		return err
+	}
+
+	assetsPath := filepath.Join(nm.storage.BasePath, "assets")
+	added := make(map[string]bool)
+	for _, matches := range noteAssetPattern.FindAllStringSubmatch(note.Content, -1) {
+		asset, err := url.PathUnescape(matches[1])
+		if err != nil || added[asset] || asset != path.Clean(asset) {
+			continue
+		}
+		added[asset] = true
+
+		if err := addZipFile(archive, assetsPath, asset); err != nil {
+			return err
+		}
+	}
+
+	// Files kept with the note's uploads, such as HEIC originals, go too
+	folder := note.AssetFolder()
+	for _, dir := range []string{models.AssetImages, models.AssetAudio, models.AssetVideo, models.AssetFiles} {
+		entries, _ := os.ReadDir(filepath.Join(assetsPath, folder, dir))
+		for _, entry := range entries {
+			asset := path.Join(folder, dir, entry.Name())
+			if entry.IsDir() || added[asset] {
+				continue
+			}
+			if err := addZipFile(archive, assetsPath, asset); err != nil {
+				return err
+			}
+		}
+	}
+	return archive.Close()
+}
+
+// addZipFile adds the file asset, a path under assetsPath, to archive as
+// assets/<asset>. Missing files are skipped.
+func addZipFile(archive *zip.Writer, assetsPath, asset string) error {
+	file, err := os.Open(filepath.Join(assetsPath, filepath.FromSlash(asset)))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		return nil
+	}
+
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: "assets/" + asset, Method: zip.Deflate, Modified: info.ModTime()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// newTestManager returns a note manager for an empty project in a temporary
+// folder
+func newTestManager(t *testing.T) *NoteManager {
+	t.Helper()
+	nm, err := NewNoteManager(t.TempDir(), NewConfigStore(&models.Config{}, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nm.Close() })
+	return nm
+}
+
+// outOfOrderNotes is a notes.md with a note moved by hand, so storage order
+// is not creation order
+const outOfOrderNotes = "## 2026-01-05 10:00:00 - five\n\n5\n\n<!-- note -->\n" +
+	"## 2026-01-09 10:00:00 - nine\n\n9\n\n<!-- note -->\n" +
+	"## 2026-01-03 10:00:00 - three\n\n3\n"
+
+// newTestManagerWith returns a note manager for a project in a temporary
+// folder whose notes.md holds text
+func newTestManagerWith(t *testing.T, text string) *NoteManager {
+	t.Helper()
+	folder := t.TempDir()
+	if err := os.WriteFile(filepath.Join(folder, "notes.md"), []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	nm, err := NewNoteManager(folder, NewConfigStore(&models.Config{}, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nm.Close() })
+	return nm
+}
+
+// checkTitles fails unless notes have the titles want, in order
+func checkTitles(t *testing.T, notes []*models.Note, want ...string) {
+	t.Helper()
+	if len(notes) != len(want) {
+		t.Fatalf("got %d notes, want %d", len(notes), len(want))
+	}
+	for i, note := range notes {
+		if note.Title != want[i] {
+			t.Errorf("note %d is %q, want %q", i, note.Title, want[i])
+		}
+	}
+}
+
+// checkTaskIndices fails unless every task of nm has its own index
+func checkTaskIndices(t *testing.T, nm *NoteManager) {
+	t.Helper()
+	seen := make(map[int]string)
+	for _, note := range nm.GetAllNotes() {
+		for _, task := range note.Tasks {
+			if other, ok := seen[task.Index]; ok {
+				t.Errorf("task %q has index %d, as does %q", task.Text, task.Index, other)
+			}
+			seen[task.Index] = task.Text
+		}
+	}
+}
+
+func TestUpdateNoteKeepsTaskIndicesUnique(t *testing.T) {
+	nm := newTestManager(t)
+	ctx := context.Background()
+	if err := nm.AddNote(ctx, "B", "- [ ] b1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := nm.AddNote(ctx, "A", "- [ ] a1\n- [ ] a2\n- [ ] a3", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// A is the newest note, so B comes second
+	if err := nm.UpdateNote(ctx, 1, "B", "- [ ] b1\n- [ ] b2", ""); err != nil {
+		t.Fatal(err)
+	}
+	checkTaskIndices(t, nm)
+
+	if err := nm.UpdateNote(ctx, 1, "B", "- [ ] b1 edited\n- [ ] b2", ""); err != nil {
+		t.Fatal(err)
+	}
+	checkTaskIndices(t, nm)
+}
+
+func TestImportNotesKeepsExistingOrder(t *testing.T) {
+	nm := newTestManagerWith(t, outOfOrderNotes)
+	files := []models.ImportFile{
+		{Name: "four.md", Content: []byte("4"), Modified: time.Date(2026, 1, 4, 10, 0, 0, 0, time.Local)},
+		{Name: "seven.md", Content: []byte("7"), Modified: time.Date(2026, 1, 7, 10, 0, 0, 0, time.Local)},
+		{Name: "one.md", Content: []byte("1"), Modified: time.Date(2026, 1, 1, 10, 0, 0, 0, time.Local)},
+	}
+	report, err := nm.ImportNotes(files, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 3 {
+		t.Fatalf("imported %d notes, want 3", report.Imported)
+	}
+	checkTitles(t, nm.GetAllNotes(), "seven", "five", "nine", "four", "three", "one")
+}
+
+func TestQueryNotesSortsByCreationTime(t *testing.T) {
+	nm := newTestManagerWith(t, outOfOrderNotes)
+	for _, test := range []struct {
+		query models.NoteQuery
+		want  []string
+	}{
+		{models.NoteQuery{}, []string{"nine", "five", "three"}},
+		{models.NoteQuery{Sort: models.SortCreated, Order: models.OrderDesc}, []string{"nine", "five", "three"}},
+		{models.NoteQuery{Sort: models.SortCreated, Order: models.OrderAsc}, []string{"three", "five", "nine"}},
+	} {
+		page := nm.QueryNotes(test.query)
+		var notes []*models.Note
+		for _, index := range page.indices {
+			notes = append(notes, page.notes[index])
+		}
+		checkTitles(t, notes, test.want...)
+	}
+}
+
+func BenchmarkLoadNotes5k(b *testing.B) {
+	folder := b.TempDir()
+	content, _ := longNote(20)
+	var text strings.Builder
+	for i := 0; i < 5000; i++ {
+		if i > 0 {
+			text.WriteString(models.NoteSeparator)
+		}
+		fmt.Fprintf(&text, "## 2026-01-01 10:%02d:%02d - Note %d\n\n%s", i/60%60, i%60, i, content)
+	}
+	if err := os.WriteFile(filepath.Join(folder, "notes.md"), []byte(text.String()), 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		nm, err := NewNoteManager(folder, NewConfigStore(&models.Config{}, ""))
+		if err != nil {
+			b.Fatal(err)
+		}
+		nm.GetAllNotes()
+		nm.Close()
+	}
+}
+
+func TestSyncNoteRemembersClientIDsAcrossRestarts(t *testing.T) {
+	folder := t.TempDir()
+	ctx := context.Background()
+	for i, want := range []bool{true, false} {
+		nm, err := NewNoteManager(folder, NewConfigStore(&models.Config{}, ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		added, err := nm.SyncNote(ctx, "client-1", "Offline", "text", "", time.Time{})
+		nm.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != want {
+			t.Errorf("sync %d added the note: %v, want %v", i+1, added, want)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// notificationInterval is how often reminders and the times of day for
+// digests and due tasks are checked
+const notificationInterval = 30 * time.Second
+
+// digestWindow is how far back the daily digest lists new notes
+const digestWindow = 24 * time.Hour
+
+// ErrNotificationsOff is returned when no notification channel is configured
+var ErrNotificationsOff = errors.New("no notification channel is configured")
+
+// NotificationService sends task reminders, the tasks due each day and a
+// daily digest of every project to the configured chat channels
+type NotificationService struct {
+	mu        sync.RWMutex
+	config    models.NotificationsConfig
+	notifiers []Notifier
+	projects  []notifiedProject
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+}
+
+// notifiedProject is a project whose tasks and notes are announced
+type notifiedProject struct {
+	name        string
+	noteManager *NoteManager
+}
+
+// notifiedTask is an open task with the note it is in
+type notifiedTask struct {
+	label string
+	note  string
+	meta  models.TaskMeta
+}
+
+// NewNotificationService creates a service sending to the channels in config
+func NewNotificationService(config models.NotificationsConfig) *NotificationService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &NotificationService{
+		config:    config,
+		notifiers: NewNotifiers(config),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Reload switches to the channels and times of a reloaded configuration
+func (ns *NotificationService) Reload(config models.NotificationsConfig) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.config = config
+	ns.notifiers = NewNotifiers(config)
+}
+
+// Watch adds the project named name to the notifications. Projects are added
+// before Start.
+func (ns *NotificationService) Watch(name string, noteManager *NoteManager) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.projects = append(ns.projects, notifiedProject{name: name, noteManager: noteManager})
+}
+
+// Start checks for notifications to send until Close is called
+func (ns *NotificationService) Start() {
+	ns.running.Add(1)
+	go func() {
+		defer ns.running.Done()
+
+		ticker := time.NewTicker(notificationInterval)
+		defer ticker.Stop()
+		last := models.Now()
+		for {
+			select {
+			case <-ns.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			now := models.Now()
+			ns.check(last, now)
+			last = now
+		}
+	}()
+}
+
+// Close stops the notifications and waits for those being sent
+func (ns *NotificationService) Close() {
+	ns.cancel()
+	ns.running.Wait()
+}
+
+// SendDigest sends the digest of the project noteManager holds now
+func (ns *NotificationService) SendDigest(noteManager *NoteManager) error {
+	ns.mu.RLock()
+	notifiers := ns.notifiers
+	name := ""
+	for _, project := range ns.projects {
+		if project.noteManager == noteManager {
+			name = project.name
+		}
+	}
+	ns.mu.RUnlock()
+
+	if len(notifiers) == 0 {
+		return ErrNotificationsOff
+	}
+	text, _ := digest(name, noteManager, models.Now())
+	return ns.send(notifiers, text)
+}
+
+// check sends what became due after from, up to to
+func (ns *NotificationService) check(from, to time.Time) {
+	ns.mu.RLock()
+	config := ns.config
+	notifiers := ns.notifiers
+	projects := ns.projects
+	ns.mu.RUnlock()
+
+	if len(notifiers) == 0 {
+		return
+	}
+
+	var messages []string
+	for _, project := range projects {
+		for _, task := range openTasks(project.noteManager) {
+			if task.meta.Remind == "" {
+				continue
+			}
+			at, err := time.ParseInLocation(models.RemindLayout, task.meta.Remind, models.Location())
+			if err == nil && at.After(from) && !at.After(to) {
+				messages = append(messages, fmt.Sprintf("⏰ Reminder in %s: %s (%s)", project.name, task.label, task.note))
+			}
+		}
+		if passed(config.DueTime, from, to) {
+			if text := dueToday(project.name, project.noteManager, to); text != "" {
+				messages = append(messages, text)
+			}
+		}
+		if passed(config.DigestTime, from, to) {
+			if text, empty := digest(project.name, project.noteManager, to); !empty {
+				messages = append(messages, text)
+			}
+		}
+	}
+
+	for _, text := range messages {
+		ns.send(notifiers, text)
+	}
+}
+
+// send posts text to every channel, logging and returning the failures
+func (ns *NotificationService) send(notifiers []Notifier, text string) error {
+	var failures []error
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(ns.ctx, text); err != nil && ns.ctx.Err() == nil {
+			err = fmt.Errorf("%s notification failed: %w", notifier.Name(), err)
+			slog.Warn("notification failed", "channel", notifier.Name(), "error", err)
+			failures = append(failures, err)
+		}
+	}
+	return errors.Join(failures...)
+}
+
+// passed reports whether the time of day clock, such as "08:00", came after
+// from and by to
+func passed(clock string, from, to time.Time) bool {
+	if clock == "" {
+		return false
+	}
+	t, err := time.Parse(models.NotificationClockLayout, clock)
+	if err != nil {
+		return false
+	}
+	for _, day := range []time.Time{from, to} {
+		at := time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location())
+		if at.After(from) && !at.After(to) {
+			return true
+		}
+	}
+	return false
+}
+
+// openTasks returns the unchecked tasks of a project
+func openTasks(noteManager *NoteManager) []notifiedTask {
+	var tasks []notifiedTask
+	for _, note := range noteManager.GetAllNotes() {
+		for _, task := range note.Tasks {
+			if task.Checked {
+				continue
+			}
+			tasks = append(tasks, notifiedTask{
+				label: models.TaskLabel(task.Text),
+				note:  note.Title,
+				meta:  models.ParseTaskMeta(task.Text),
+			})
+		}
+	}
+	return tasks
+}
+
+// dueToday lists the project's open tasks due on now's day, or returns ""
+// when there are none
+func dueToday(name string, noteManager *NoteManager, now time.Time) string {
+	today := now.Format("2006-01-02")
+	var lines []string
+	for _, task := range openTasks(noteManager) {
+		if task.meta.Due == today {
+			lines = append(lines, fmt.Sprintf("- %s (%s)", task.label, task.note))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("📅 Due today in %s:\n%s", name, strings.Join(lines, "\n"))
+}
+
+// digest summarizes the project's notes written in the last day and its
+// overdue tasks and those due on now's day. It reports whether there was
+// nothing to list.
+func digest(name string, noteManager *NoteManager, now time.Time) (string, bool) {
+	var notes []string
+	for _, note := range noteManager.GetAllNotes() {
+		if note.Timestamp.After(now.Add(-digestWindow)) {
+			title := note.Title
+			if title == "" {
+				title = models.FormatTimestamp(note.Timestamp)
+			}
+			notes = append(notes, "- "+title)
+		}
+	}
+
+	today := now.Format("2006-01-02")
+	var overdue, due []string
+	for _, task := range openTasks(noteManager) {
+		switch {
+		case task.meta.Due == "":
+		case task.meta.Due < today:
+			overdue = append(overdue, fmt.Sprintf("- %s (due %s, %s)", task.label, task.meta.Due, task.note))
+		case task.meta.Due == today:
+			due = append(due, fmt.Sprintf("- %s (%s)", task.label, task.note))
+		}
+	}
+
+	text := "📰 Daily digest for " + name
+	sections := []struct {
+		heading string
+		lines   []string
+	}{
+		{"New notes", notes},
+		{"Overdue", overdue},
+		{"Due today", due},
+	}
+	empty := true
+	for _, section := range sections {
+		if len(section.lines) == 0 {
+			continue
+		}
+		empty = false
+		text += fmt.Sprintf("\n\n%s (%d):\n%s", section.heading, len(section.lines), strings.Join(section.lines, "\n"))
+	}
+	if empty {
+		text += "\n\nNothing new or due."
+	}
+	return text, empty
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// notifyTimeout limits how long a chat service may take to accept a message
+const notifyTimeout = 15 * time.Second
+
+// discordMessageLimit is the most characters a Discord message may have
+const discordMessageLimit = 2000
+
+// Notifier delivers messages to a chat channel
+type Notifier interface {
+	// Name identifies the channel in logs and errors
+	Name() string
+	// Notify posts a message, which may use markdown
+	Notify(ctx context.Context, text string) error
+}
+
+// NewNotifiers returns a notifier for each channel configured in config
+func NewNotifiers(config models.NotificationsConfig) []Notifier {
+	client := &http.Client{Timeout: notifyTimeout}
+
+	var notifiers []Notifier
+	if config.DiscordWebhookURL != "" {
+		notifiers = append(notifiers, &DiscordNotifier{webhookURL: config.DiscordWebhookURL, client: client})
+	}
+	if config.MatrixHomeserver != "" {
+		notifiers = append(notifiers, &MatrixNotifier{
+			homeserver:  strings.TrimRight(config.MatrixHomeserver, "/"),
+			roomID:      config.MatrixRoomID,
+			accessToken: config.MatrixAccessToken,
+			client:      client,
+		})
+	}
+	return notifiers
+}
+
+// DiscordNotifier posts messages to a Discord channel's webhook
+type DiscordNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// Name implements Notifier
+func (n *DiscordNotifier) Name() string {
+	return "Discord"
+}
+
+// Notify implements Notifier. Messages longer than Discord allows are cut
+// short.
+func (n *DiscordNotifier) Notify(ctx context.Context, text string) error {
+	if runes := []rune(text); len(runes) > discordMessageLimit {
+		text = string(runes[:discordMessageLimit-1]) + "…"
+	}
+	return sendJSON(ctx, n.client, http.MethodPost, n.webhookURL, "", map[string]string{"content": text})
+}
+
+// MatrixNotifier posts messages to a Matrix room with an account's access
+// token
+type MatrixNotifier struct {
+	homeserver  string
+	roomID      string
+	accessToken string
+	client      *http.Client
+}
+
+// Name implements Notifier
+func (n *MatrixNotifier) Name() string {
+	return "Matrix"
+}
+
+// Notify implements Notifier
+func (n *MatrixNotifier) Notify(ctx context.Context, text string) error {
+	// The transaction ID lets the homeserver drop a message sent twice
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/noteflow-%s",
+		n.homeserver, url.PathEscape(n.roomID), strconv.FormatInt(time.Now().UnixNano(), 36))
+	message := map[string]string{"msgtype": "m.text", "body": text}
+	return sendJSON(ctx, n.client, http.MethodPut, endpoint, n.accessToken, message)
+}
+
+// sendJSON sends body as JSON, with token as a bearer token if set, and
+// checks that it was accepted
+func sendJSON(ctx context.Context, client *http.Client, method, endpoint, token string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("responded %d", resp.StatusCode)
+	}
+	return nil
+}
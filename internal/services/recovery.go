@@ -0,0 +1,55 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/storage"
+)
+
+// recoveryNoteTitle is the title of the note describing what was damaged in
+// notes.md
+const recoveryNoteTitle = "Recovered from notes.md"
+
+// recoveryNote returns a note listing the merge conflicts found in notes.md
+// and quoting the parts that weren't notes as they were, so they are saved
+// with the notes instead of being lost. The damage is reported in the save
+// status.
+func (nm *NoteManager) recoveryNote(damage storage.Damage) *models.Note {
+	var content strings.Builder
+	content.WriteString("notes.md was damaged when it was loaded. Check what is listed below, then delete this note.\n")
+
+	if len(damage.Conflicts) > 0 {
+		content.WriteString("\n### Merge conflicts\n\n" +
+			"The lines of both sides were kept, so the notes around these lines of notes.md may hold both versions:\n\n")
+		for _, conflict := range damage.Conflicts {
+			fmt.Fprintf(&content, "- Lines %d to %d", conflict.FirstLine, conflict.LastLine)
+			if len(conflict.Sides) > 0 {
+				fmt.Fprintf(&content, ", between %s", strings.Join(conflict.Sides, " and "))
+			}
+			content.WriteString("\n")
+		}
+	}
+
+	for i, section := range damage.Sections {
+		// Indented, the note separators in a part aren't read as such again
+		fmt.Fprintf(&content, "\n### Part %d that wasn't a note\n\n", i+1)
+		for _, line := range strings.Split(section, "\n") {
+			content.WriteString("    " + line + "\n")
+		}
+	}
+
+	warning := fmt.Sprintf("notes.md was damaged (%d merge conflict(s), %d part(s) that weren't notes); see the note %q",
+		len(damage.Conflicts), len(damage.Sections), recoveryNoteTitle)
+	slog.Warn(warning, "path", nm.storage.GetNotesFilePath(),
+		"conflicts", len(damage.Conflicts), "sections", len(damage.Sections))
+
+	nm.statusMu.Lock()
+	nm.saveStatus.Recovered = len(damage.Conflicts) + len(damage.Sections)
+	nm.saveStatus.Warning = warning
+	nm.statusMu.Unlock()
+
+	return models.NewNote(recoveryNoteTitle, content.String())
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"sync"
+
+	"github.com/darren/noteflow-go/internal/i18n"
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// renderCacheSize is the most rendered notes kept; the cache starts over
+// when it is full
+const renderCacheSize = 5000
+
+// renderKey identifies a note rendered as HTML: the note, by project folder
+// and note ID, and a hash of everything the HTML is made from
+type renderKey struct {
+	note string
+	hash [sha256.Size]byte
+}
+
+// RenderCache keeps the HTML notes were last rendered to, so notes that
+// haven't changed aren't rendered again on every refresh. A note's entries
+// are dropped when it changes or is deleted, and all of them when the theme
+// changes. One cache is shared by the projects.
+type RenderCache struct {
+	mu        sync.Mutex
+	theme     string
+	fragments map[renderKey]string
+}
+
+// NewRenderCache creates an empty cache for notes shown in theme
+func NewRenderCache(theme string) *RenderCache {
+	return &RenderCache{theme: theme, fragments: make(map[renderKey]string)}
+}
+
+// SetTheme switches the cache to theme, emptying it if the theme changed
+func (rc *RenderCache) SetTheme(theme string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if theme != rc.theme {
+		rc.theme = theme
+		rc.fragments = make(map[renderKey]string)
+	}
+}
+
+// key returns the key of the note with the given ID rendered from content
+// with header at index, and its checkboxes numbered after tasks, in the
+// current theme and locale
+func (rc *RenderCache) key(note, content, header string, index int, tasks []*models.Task) renderKey {
+	rc.mu.Lock()
+	theme := rc.theme
+	rc.mu.Unlock()
+
+	hash := sha256.New()
+	for _, part := range []string{content, header, strconv.Itoa(index), theme, i18n.Locale()} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	for _, task := range tasks {
+		hash.Write(strconv.AppendInt(nil, int64(task.Index), 10))
+		hash.Write([]byte{0})
+	}
+	key := renderKey{note: note}
+	hash.Sum(key.hash[:0])
+	return key
+}
+
+// get returns the HTML stored under key
+func (rc *RenderCache) get(key renderKey) (string, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	html, ok := rc.fragments[key]
+	return html, ok
+}
+
+// put stores the HTML a note was rendered to under key
+func (rc *RenderCache) put(key renderKey, html string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(rc.fragments) >= renderCacheSize {
+		rc.fragments = make(map[renderKey]string)
+	}
+	rc.fragments[key] = html
+}
+
+// forget drops the HTML of the notes with the given IDs
+func (rc *RenderCache) forget(notes map[string]bool) {
+	if len(notes) == 0 {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for key := range rc.fragments {
+		if notes[key.note] {
+			delete(rc.fragments, key)
+		}
+	}
+}
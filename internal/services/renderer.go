@@ -3,9 +3,12 @@ package services
 import (
 	"bytes"
 	"fmt"
+	"path"
 	"regexp"
 	"strings"
 
+	"github.com/darren/noteflow-go/internal/i18n"
+	"github.com/darren/noteflow-go/internal/models"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
@@ -39,10 +42,17 @@ func NewMarkdownRenderer() *MarkdownRenderer {
 	return &MarkdownRenderer{md: md}
 }
 
-// RenderToHTML converts markdown content to HTML
+// RenderToHTML converts markdown content to HTML. Its checkboxes are
+// numbered from 0.
 func (r *MarkdownRenderer) RenderToHTML(content string) (string, error) {
+	return r.render(content, nil)
+}
+
+// render converts markdown content to HTML, giving its checkboxes the
+// indices of tasks
+func (r *MarkdownRenderer) render(content string, tasks []*models.Task) (string, error) {
 	// Pre-process content for custom features
-	content = r.preprocessContent(content)
+	content = r.preprocessContent(content, tasks)
 
 	var buf bytes.Buffer
 	if err := r.md.Convert([]byte(content), &buf); err != nil {
@@ -58,14 +68,16 @@ func (r *MarkdownRenderer) RenderToHTML(content string) (string, error) {
 }
 
 // preprocessContent handles custom markdown features before goldmark processing
-func (r *MarkdownRenderer) preprocessContent(content string) string {
+func (r *MarkdownRenderer) preprocessContent(content string, tasks []*models.Task) string {
+	// Handle custom checkbox rendering with data attributes. This comes
+	// first, so the checkboxes are counted in the content tasks are parsed
+	// from.
+	content = r.preprocessCheckboxes(content, tasks)
+
 	// Handle math expressions (MathJax format)
 	// Protect inline math $...$ from being processed as markdown
 	content = r.protectMathExpressions(content)
 
-	// Handle custom checkbox rendering with data attributes
-	content = r.preprocessCheckboxes(content)
-
 	return content
 }
 
@@ -88,39 +100,70 @@ func (r *MarkdownRenderer) protectMathExpressions(content string) string {
 	return content
 }
 
-// preprocessCheckboxes adds data attributes to checkboxes for JavaScript handling
-func (r *MarkdownRenderer) preprocessCheckboxes(content string) string {
-	lines := strings.Split(content, "\n")
-	taskIndex := 0
-
-	for i, line := range lines {
-		// Match checkbox patterns
-		checkboxPattern := regexp.MustCompile(`^(\s*-\s*)\[([xX ])\](.*)`)
-		if matches := checkboxPattern.FindStringSubmatch(line); len(matches) == 4 {
-			prefix := matches[1]
-			status := matches[2]
-			text := matches[3]
-
-			checked := strings.ToLower(status) == "x"
-			checkedAttr := ""
-			if checked {
-				checkedAttr = " checked"
-			}
-
-			// Replace with custom HTML that goldmark will pass through
-			customCheckbox := fmt.Sprintf(`%s<input type="checkbox" data-checkbox-index="%d" id="task_%d"%s> %s`,
-				prefix, taskIndex, taskIndex, checkedAttr, strings.TrimSpace(text))
-
-			lines[i] = customCheckbox
-			taskIndex++
+// taskCheckboxPattern matches every checkbox a note's tasks are parsed
+// from, in the same way as the note does
+var taskCheckboxPattern = regexp.MustCompile(`\[([xX ])\]`)
+
+// preprocessCheckboxes turns the checkboxes starting list items into inputs
+// with data attributes for JavaScript handling, in one pass over content.
+// Each checkbox gets the index of the task parsed from it, which is unique
+// across the project; without tasks they are numbered from 0.
+func (r *MarkdownRenderer) preprocessCheckboxes(content string, tasks []*models.Task) string {
+	var out strings.Builder
+	done := 0
+
+	// Every match is a task, counted even when it doesn't become an input
+	for ordinal, match := range taskCheckboxPattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := match[0], match[1]
+		lineStart := strings.LastIndexByte(content[:start], '\n') + 1
+		if start < done || !isListItemPrefix(content[lineStart:start]) {
+			continue
+		}
+		lineEnd := strings.IndexByte(content[end:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(content)
+		} else {
+			lineEnd += end
+		}
+
+		taskIndex := ordinal
+		if ordinal < len(tasks) {
+			taskIndex = tasks[ordinal].Index
+		}
+		checkedAttr := ""
+		if content[match[2]] != ' ' {
+			checkedAttr = " checked"
 		}
+
+		// Replace with custom HTML that goldmark will pass through
+		out.WriteString(content[done:start])
+		fmt.Fprintf(&out, `<input type="checkbox" data-checkbox-index="%d" id="task_%d"%s> %s`,
+			taskIndex, taskIndex, checkedAttr, strings.TrimSpace(content[end:lineEnd]))
+		done = lineEnd
+	}
+	if done == 0 {
+		return content
 	}
 
-	return strings.Join(lines, "\n")
+	out.WriteString(content[done:])
+	return out.String()
+}
+
+// isListItemPrefix reports whether prefix, the start of a line, is the
+// marker of a list item: a dash, with only whitespace around it
+func isListItemPrefix(prefix string) bool {
+	rest, ok := strings.CutPrefix(strings.TrimLeft(prefix, " \t"), "-")
+	return ok && strings.TrimLeft(rest, " \t") == ""
 }
 
 // postprocessHTML handles post-processing of the generated HTML
 func (r *MarkdownRenderer) postprocessHTML(html string) string {
+	// Play uploaded audio and video inline, before images are wrapped in links
+	html = r.enhanceMedia(html)
+
+	// Preview uploaded PDFs
+	html = r.enhancePDFs(html)
+
 	// Enhance image handling
 	html = r.enhanceImages(html)
 
@@ -133,6 +176,51 @@ func (r *MarkdownRenderer) postprocessHTML(html string) string {
 	return html
 }
 
+// enhanceMedia turns images and links pointing at uploaded audio or video
+// into inline players
+func (r *MarkdownRenderer) enhanceMedia(html string) string {
+	mediaPattern := regexp.MustCompile(`<img[^>]*?src=["']([^"']*/assets/(?:` + models.NoteFolderPattern + `/)?(audio|video)/[^"']+)["'][^>]*>|` +
+		`<a[^>]*?href=["']([^"']*/assets/(?:` + models.NoteFolderPattern + `/)?(audio|video)/[^"']+)["'][^>]*>(.*?)</a>`)
+
+	return mediaPattern.ReplaceAllStringFunc(html, func(match string) string {
+		matches := mediaPattern.FindStringSubmatch(match)
+		src, element, label := matches[1], matches[2], matches[5]
+		if src == "" {
+			src, element = matches[3], matches[4]
+		}
+		src = strings.Trim(src, "<>")
+		if label == "" {
+			label = path.Base(src)
+		}
+
+		// The link is shown by browsers that can't play the file
+		return fmt.Sprintf(
+			`<%s controls preload="metadata" src="%s"><a href="%s">%s</a></%s>`,
+			element, src, src, label, element,
+		)
+	})
+}
+
+// enhancePDFs adds a lazily loaded preview above links to uploaded PDFs
+func (r *MarkdownRenderer) enhancePDFs(html string) string {
+	pdfPattern := regexp.MustCompile(`<a[^>]*?href=["']([^"']*/assets/(?:` + models.NoteFolderPattern + `/)?files/[^"']+\.(?i:pdf))["'][^>]*>(.*?)</a>`)
+
+	return pdfPattern.ReplaceAllStringFunc(html, func(match string) string {
+		matches := pdfPattern.FindStringSubmatch(match)
+		src, label := strings.Trim(matches[1], "<>"), matches[2]
+
+		return fmt.Sprintf(
+			`<span class="pdf-attachment"><iframe class="pdf-preview" src="%s#view=FitH" loading="lazy" title="%s"></iframe>`+
+				`<a href="%s" target="_blank" rel="noopener noreferrer">📄 %s</a></span>`,
+			src, path.Base(src), src, label,
+		)
+	})
+}
+
+// uploadedImagePattern matches the folder part of links to uploaded images,
+// in the shared folder or a note's own
+var uploadedImagePattern = regexp.MustCompile(`/assets/(?:` + models.NoteFolderPattern + `/)?images/`)
+
 // enhanceImages wraps images in links for lightbox functionality
 func (r *MarkdownRenderer) enhanceImages(html string) string {
 	imgPattern := regexp.MustCompile(`<img([^>]*?)src=["']([^"']+)["']([^>]*?)>`)
@@ -150,8 +238,17 @@ func (r *MarkdownRenderer) enhanceImages(html string) string {
 		// Remove angle brackets if present (from drag-and-drop)
 		src = strings.Trim(src, "<>")
 
+		// Uploaded images are shown as thumbnails, linking to the full image
+		if images := uploadedImagePattern.FindString(src); images != "" && !strings.HasPrefix(src, "http") {
+			thumb := strings.Replace(srcMatches[0], images, strings.TrimSuffix(images, "images/")+"thumbs/", 1)
+			return fmt.Sprintf(
+				`<a href="%s" target="_blank" rel="noopener noreferrer">%s</a>`,
+				src, strings.Replace(match, srcMatches[0], thumb+` loading="lazy"`, 1),
+			)
+		}
+
 		// Wrap in link for lightbox functionality
-		if strings.HasPrefix(src, "http") || strings.Contains(src, "/assets/images/") {
+		if strings.HasPrefix(src, "http") {
 			return fmt.Sprintf(
 				`<a href="%s" target="_blank" rel="noopener noreferrer">%s</a>`,
 				src, match,
@@ -176,9 +273,10 @@ func (r *MarkdownRenderer) fixCheckboxes(html string) string {
 	return html
 }
 
-// RenderNoteHTML renders a complete note with proper styling and structure
-func (r *MarkdownRenderer) RenderNoteHTML(content, timestamp, title string, noteIndex int) (string, error) {
-	renderedContent, err := r.RenderToHTML(content)
+// RenderNoteHTML renders a complete note with proper styling and structure.
+// Its checkboxes get the indices of its tasks.
+func (r *MarkdownRenderer) RenderNoteHTML(content, timestamp, title string, noteIndex int, tasks []*models.Task) (string, error) {
+	renderedContent, err := r.render(content, tasks)
 	if err != nil {
 		return "", err
 	}
@@ -188,17 +286,18 @@ func (r *MarkdownRenderer) RenderNoteHTML(content, timestamp, title string, note
     <div id="note-%d" class="notes-item markdown-body" onclick="toggleNote(%d)">
         <div class="post-header">
             <span class="note-title">%s</span>
-			<span class="delete-label" onclick="event.stopPropagation(); editNote(%d);" style="cursor: pointer;">[edit]</span>
-            <span class="delete-label" onclick="event.stopPropagation(); deleteNote(%d);" style="cursor: pointer;">[delete]</span>
+			<span class="delete-label" onclick="event.stopPropagation(); editNote(%d);" style="cursor: pointer;">[%s]</span>
+            <span class="delete-label" onclick="event.stopPropagation(); deleteNote(%d);" style="cursor: pointer;">[%s]</span>
+            <span class="delete-label" onclick="event.stopPropagation(); exportNote(%d);" style="cursor: pointer;">[%s]</span>
             <div class="section-label-menu section-label-menu-expanded">
-                <button onclick="event.stopPropagation(); toggleNote(%d)">collapse</button>
-                <button onclick="event.stopPropagation(); collapseAll()">collapse all</button>
-                <button onclick="event.stopPropagation(); expandAll()">expand all</button>
-                <button onclick="event.stopPropagation(); collapseOthers(%d)">focus</button>
+                <button onclick="event.stopPropagation(); toggleNote(%d)">%s</button>
+                <button onclick="event.stopPropagation(); collapseAll()">%s</button>
+                <button onclick="event.stopPropagation(); expandAll()">%s</button>
+                <button onclick="event.stopPropagation(); collapseOthers(%d)">%s</button>
             </div>
             <div class="section-label-menu section-label-menu-collapsed" style="display: none;">
-                <button onclick="event.stopPropagation(); toggleNote(%d)">expand</button>
-                <button onclick="event.stopPropagation(); expandAll()">expand all</button>
+                <button onclick="event.stopPropagation(); toggleNote(%d)">%s</button>
+                <button onclick="event.stopPropagation(); expandAll()">%s</button>
             </div>
         </div>
         %s
@@ -211,7 +310,9 @@ func (r *MarkdownRenderer) RenderNoteHTML(content, timestamp, title string, note
         <span>e</span>
     </div>
 	-->
-</div>`, noteIndex, noteIndex, timestamp, noteIndex, noteIndex, noteIndex, noteIndex, noteIndex, renderedContent)
+</div>`, noteIndex, noteIndex, timestamp, noteIndex, i18n.T("edit"), noteIndex, i18n.T("delete"), noteIndex, i18n.T("export"),
+		noteIndex, i18n.T("collapse"), i18n.T("collapse all"), i18n.T("expand all"), noteIndex, i18n.T("focus"),
+		noteIndex, i18n.T("expand"), i18n.T("expand all"), renderedContent)
 
 	return noteHTML, nil
 }
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// longNote returns a note of lines lines, every fourth of them a task
+func longNote(lines int) (string, []*models.Task) {
+	var content strings.Builder
+	var tasks []*models.Task
+	for i := 0; i < lines; i++ {
+		switch i % 4 {
+		case 0:
+			fmt.Fprintf(&content, "- [ ] task %d with some **bold** text\n", i)
+			tasks = append(tasks, &models.Task{Index: 1000 + len(tasks)})
+		case 1:
+			fmt.Fprintf(&content, "A line of text with a [link](https://example.com/%d) and $x^%d$.\n", i, i)
+		case 2:
+			fmt.Fprintf(&content, "* item %d\n", i)
+		default:
+			content.WriteString("\n")
+		}
+	}
+	return content.String(), tasks
+}
+
+func BenchmarkRenderNote10k(b *testing.B) {
+	renderer := NewMarkdownRenderer()
+	content, tasks := longNote(10000)
+	b.SetBytes(int64(len(content)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := renderer.RenderNoteHTML(content, "2024-01-01 10:00:00", "Long", 0, tasks); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPreprocessCheckboxes10k(b *testing.B) {
+	renderer := NewMarkdownRenderer()
+	content, tasks := longNote(10000)
+	b.SetBytes(int64(len(content)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		renderer.preprocessCheckboxes(content, tasks)
+	}
+}
+
+func TestPreprocessCheckboxesUsesTaskIndices(t *testing.T) {
+	renderer := NewMarkdownRenderer()
+	content := "- [ ] one\ntext [x] not a task item\n- [x] three"
+	tasks := []*models.Task{{Index: 7}, {Index: 8}, {Index: 9}}
+
+	html := renderer.preprocessCheckboxes(content, tasks)
+	for _, want := range []string{`data-checkbox-index="7"`, `data-checkbox-index="9" id="task_9" checked`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered %q, want it to contain %q", html, want)
+		}
+	}
+	if strings.Contains(html, `data-checkbox-index="8"`) {
+		t.Errorf("rendered %q, want no input for the checkbox outside a list item", html)
+	}
+}
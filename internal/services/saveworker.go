@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// saveDelay is how long the save worker waits after a change for more
+// changes to write along with it
+const saveDelay = 250 * time.Millisecond
+
+// saveRetryDelays are the pauses before the save worker retries a failed
+// write; the last one repeats until a write succeeds
+var saveRetryDelays = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second}
+
+// ErrNoteManagerClosed is returned for changes made after Close, which would
+// never be saved
+var ErrNoteManagerClosed = errors.New("note manager is closed")
+
+// runSaveWorker writes the notes to notes.md after they change, until Close
+// stops it. Changes made in quick succession are written together, and
+// failed writes are retried, so requests don't wait for the disk.
+func (nm *NoteManager) runSaveWorker() {
+	defer close(nm.saveDone)
+
+	for {
+		select {
+		case <-nm.saveStop:
+			return
+		case <-nm.saveWake:
+		}
+
+		// Changes made meanwhile are written along; Close writes the
+		// rest when stopped early
+		select {
+		case <-nm.saveStop:
+			return
+		case <-time.After(saveDelay):
+		}
+
+		for attempt := 0; nm.flush() != nil; attempt++ {
+			select {
+			case <-nm.saveStop:
+				return
+			case <-time.After(saveRetryDelays[min(attempt, len(saveRetryDelays)-1)]):
+			}
+		}
+	}
+}
+
+// queueSave has the save worker write the notes as published by the change
+// that just ended. Called by unlockNotes.
+func (nm *NoteManager) queueSave() {
+	nm.queued.Add(1)
+	select {
+	case nm.saveWake <- struct{}{}:
+	default:
+		// The worker is already due to write
+	}
+}
+
+// flush writes the notes as of the last finished change to notes.md, if
+// they changed since they were last written
+func (nm *NoteManager) flush() error {
+	nm.saveMu.Lock()
+	defer nm.saveMu.Unlock()
+
+	// The snapshot has at least the changes counted so far
+	queued := nm.queued.Load()
+	if queued == nm.written.Load() {
+		return nil
+	}
+	err := nm.storage.SaveNotes(nm.snapshot())
+
+	nm.statusMu.Lock()
+	defer nm.statusMu.Unlock()
+	if err != nil {
+		nm.saveStatus.Failures++
+		nm.saveStatus.LastError = err.Error()
+		slog.Warn("failed to save notes", "path", nm.storage.GetNotesFilePath(),
+			"attempt", nm.saveStatus.Failures, "error", err)
+		return err
+	}
+
+	nm.written.Store(queued)
+	now := models.Now()
+	nm.saveStatus.LastSaved = &now
+	nm.saveStatus.Failures = 0
+	nm.saveStatus.LastError = ""
+	return nil
+}
+
+// SaveStatus reports whether changes wait to be written to notes.md, and
+// how the last writes went
+func (nm *NoteManager) SaveStatus() models.SaveStatus {
+	nm.statusMu.Lock()
+	defer nm.statusMu.Unlock()
+
+	status := nm.saveStatus
+	status.Pending = nm.HasChanges()
+	return status
+}
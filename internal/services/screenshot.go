@@ -0,0 +1,112 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// Screenshot thumbnails of archived sites are screenshotWidth pixels wide,
+// cropped to the top of the page at 16:10
+const (
+	screenshotWidth  = 480
+	screenshotHeight = screenshotWidth * 10 / 16
+)
+
+// screenshotTimeout bounds how long the screenshot command may take
+const screenshotTimeout = 60 * time.Second
+
+// captureScreenshot runs the configured screenshot command for an archived
+// site and saves a thumbnail of its image next to the archived page, as
+// <page>.jpg. Failures are logged, leaving the archive without a screenshot.
+func (a *Archiver) captureScreenshot(ctx context.Context, session *archiveSession, websiteURL string, archiveInfo *ArchiveInfo) {
+	if a.config().ScreenshotCommand == "" {
+		return
+	}
+	session.publishProgress("screenshot", nil)
+
+	htmlPath := filepath.Join(a.storage.BasePath, archiveInfo.FilePath)
+	data, err := a.runScreenshotCommand(ctx, websiteURL, htmlPath)
+	if err == nil {
+		data, err = screenshotThumbnail(data)
+	}
+	if err == nil {
+		err = os.WriteFile(strings.TrimSuffix(htmlPath, ".html")+".jpg", data, 0644)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "no screenshot of archived page", "url", websiteURL, "error", err)
+		return
+	}
+	archiveInfo.Screenshot = strings.TrimSuffix(filepath.Base(htmlPath), ".html") + ".jpg"
+}
+
+// runScreenshotCommand runs the screenshot command, with "{url}" in its
+// arguments replaced by the site's URL, "{file}" by the archived page and
+// "{out}" by the image file it should write, and returns that image
+func (a *Archiver) runScreenshotCommand(ctx context.Context, websiteURL, htmlPath string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "noteflow-screenshot-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "screenshot.png")
+
+	replacer := strings.NewReplacer("{url}", websiteURL, "{file}", htmlPath, "{out}", out)
+	words := strings.Fields(a.config().ScreenshotCommand)
+	for i := range words {
+		words[i] = replacer.Replace(words[i])
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, screenshotTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, words[0], words[1:]...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("screenshot command %s: %w: %s", words[0], err, message)
+		}
+		return nil, fmt.Errorf("screenshot command %s: %w", words[0], err)
+	}
+	return os.ReadFile(out)
+}
+
+// screenshotThumbnail crops a PNG or JPEG screenshot to the top of the page
+// and scales it to the thumbnail size, returning it as a JPEG
+func screenshotThumbnail(data []byte) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read screenshot: %w", err)
+	}
+
+	// Decoded images can all be cropped in place
+	bounds := img.Bounds()
+	if height := bounds.Dx() * screenshotHeight / screenshotWidth; bounds.Dy() > height {
+		bounds.Max.Y = bounds.Min.Y + height
+		if cropper, ok := img.(interface {
+			SubImage(image.Rectangle) image.Image
+		}); ok {
+			img = cropper.SubImage(bounds)
+		}
+	}
+	if width := bounds.Dx(); width > screenshotWidth {
+		img = scaleImage(img, screenshotWidth, max(1, img.Bounds().Dy()*screenshotWidth/width))
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: models.DefaultImageQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode screenshot: %w", err)
+	}
+	return buf.Bytes(), nil
+}
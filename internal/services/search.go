@@ -0,0 +1,256 @@
+package services
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// Search scores for each word found, by where it was found
+const (
+	searchTitleScore      = 10
+	searchTaskScore       = 4 // for each task, up to searchMaxCounted
+	searchContentScore    = 1 // for each time it appears, up to searchMaxCounted
+	searchAttachmentScore = 1
+	searchPhraseScore     = 5 // for all the words together, as typed
+	searchMaxCounted      = 5
+)
+
+// Search snippet sizes
+const (
+	searchSnippetRadius = 60 // characters kept on either side of a word
+	searchMaxSnippets   = 3
+)
+
+// searchDocument is a note split into the parts searched, in lowercase
+type searchDocument struct {
+	title       string
+	content     string // without the task lines
+	tasks       []string
+	attachments string
+}
+
+// Search finds the notes containing every word of q in their title,
+// content, tasks or attached files, best matches first. It returns the
+// results from offset, at most limit of them (0 for all), and the number
+// found.
+func (nm *NoteManager) Search(q string, offset, limit int) ([]models.SearchResult, int) {
+	words := strings.Fields(strings.ToLower(q))
+	if len(words) == 0 {
+		return []models.SearchResult{}, 0
+	}
+	phrase := strings.Join(words, " ")
+
+	patterns := make([]*regexp.Regexp, len(words))
+	for i, word := range words {
+		patterns[i] = regexp.MustCompile("(?i)" + regexp.QuoteMeta(word))
+	}
+
+	notes := nm.snapshot()
+	results := []models.SearchResult{}
+	for i, note := range notes {
+		doc, content, tasks := nm.searchDocument(note)
+		result, ok := scoreSearch(doc, words, phrase)
+		if !ok {
+			continue
+		}
+		result.Index = i
+		result.ID = note.AssetFolder()
+		result.Title = note.Title
+		result.Timestamp = note.Timestamp
+		result.Snippets = searchSnippets(patterns, content, tasks, doc.attachments)
+		results = append(results, result)
+	}
+
+	// Notes are stored newest first, which breaks ties
+	sort.SliceStable(results, func(a, b int) bool {
+		return results[a].Score > results[b].Score
+	})
+
+	total := len(results)
+	if offset >= len(results) {
+		return []models.SearchResult{}, total
+	}
+	results = results[offset:]
+	if limit > 0 && limit < len(results) {
+		results = results[:limit]
+	}
+	return results, total
+}
+
+// searchDocument returns the parts of note searched, and its content
+// without the task lines and its task labels as written
+func (nm *NoteManager) searchDocument(note *models.Note) (searchDocument, string, []string) {
+	var lines []string
+	for _, line := range strings.Split(note.Content, "\n") {
+		if !isTaskLine(line) {
+			lines = append(lines, line)
+		}
+	}
+	content := strings.Join(lines, "\n")
+
+	tasks := make([]string, len(note.Tasks))
+	doc := searchDocument{
+		title:   strings.ToLower(note.Title),
+		content: strings.ToLower(content),
+		tasks:   make([]string, len(note.Tasks)),
+	}
+	for i, task := range note.Tasks {
+		tasks[i] = models.TaskLabel(task.Text)
+		doc.tasks[i] = strings.ToLower(tasks[i])
+	}
+
+	// The text of attached files is kept in lowercase
+	var attachments []string
+	nm.assetTextMu.RLock()
+	for asset, assetText := range nm.assetText {
+		if strings.Contains(note.Content, "assets/"+asset) {
+			attachments = append(attachments, assetText)
+		}
+	}
+	nm.assetTextMu.RUnlock()
+	sort.Strings(attachments)
+	doc.attachments = strings.Join(attachments, "\n")
+
+	return doc, content, tasks
+}
+
+// isTaskLine reports whether line holds a task's checkbox
+func isTaskLine(line string) bool {
+	return strings.Contains(line, "[ ]") || strings.Contains(line, "[x]") || strings.Contains(line, "[X]")
+}
+
+// scoreSearch scores doc for the lowercase words, reporting false unless
+// every word is found. The result has its score and fields set.
+func scoreSearch(doc searchDocument, words []string, phrase string) (models.SearchResult, bool) {
+	fields := make(map[string]bool)
+	score := 0
+	for _, word := range words {
+		found := false
+		if strings.Contains(doc.title, word) {
+			score += searchTitleScore
+			fields[models.SearchFieldTitle] = true
+			found = true
+		}
+		if count := min(strings.Count(doc.content, word), searchMaxCounted); count > 0 {
+			score += count * searchContentScore
+			fields[models.SearchFieldContent] = true
+			found = true
+		}
+		tasks := 0
+		for _, task := range doc.tasks {
+			if strings.Contains(task, word) {
+				tasks++
+			}
+		}
+		if tasks > 0 {
+			score += min(tasks, searchMaxCounted) * searchTaskScore
+			fields[models.SearchFieldTasks] = true
+			found = true
+		}
+		if strings.Contains(doc.attachments, word) {
+			score += searchAttachmentScore
+			fields[models.SearchFieldAttachments] = true
+			found = true
+		}
+		if !found {
+			return models.SearchResult{}, false
+		}
+	}
+
+	if len(words) > 1 {
+		for _, text := range append([]string{doc.title, doc.content}, doc.tasks...) {
+			if strings.Contains(text, phrase) {
+				score += searchPhraseScore
+				break
+			}
+		}
+	}
+
+	result := models.SearchResult{Score: score, Fields: []string{}}
+	for _, field := range []string{models.SearchFieldTitle, models.SearchFieldTasks, models.SearchFieldContent, models.SearchFieldAttachments} {
+		if fields[field] {
+			result.Fields = append(result.Fields, field)
+		}
+	}
+	return result, true
+}
+
+// searchSnippets returns up to searchMaxSnippets excerpts showing where the
+// patterns were found: the tasks containing them, then the content around
+// each, then the attached files' text
+func searchSnippets(patterns []*regexp.Regexp, content string, tasks []string, attachments string) []string {
+	snippets := []string{}
+	for _, task := range tasks {
+		if len(snippets) == searchMaxSnippets {
+			return snippets
+		}
+		for _, pattern := range patterns {
+			if loc := pattern.FindStringIndex(task); loc != nil {
+				snippets = append(snippets, excerpt(task, loc[0], loc[1]))
+				break
+			}
+		}
+	}
+
+	for _, text := range []string{content, attachments} {
+		var shown [][]int // the words already in an excerpt of text
+	words:
+		for _, pattern := range patterns {
+			if len(snippets) == searchMaxSnippets {
+				return snippets
+			}
+			loc := pattern.FindStringIndex(text)
+			if loc == nil {
+				continue
+			}
+			for _, other := range shown {
+				if loc[0] >= other[0]-searchSnippetRadius && loc[1] <= other[1]+searchSnippetRadius {
+					continue words
+				}
+			}
+			snippets = append(snippets, excerpt(text, loc[0], loc[1]))
+			shown = append(shown, loc)
+		}
+	}
+	return snippets
+}
+
+// excerpt returns the text around text[start:end], up to
+// searchSnippetRadius characters on either side, on one line and marked
+// with "…" where cut
+func excerpt(text string, start, end int) string {
+	from, to := start, end
+	for n := 0; n < searchSnippetRadius && from > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:from])
+		from -= size
+	}
+	for n := 0; n < searchSnippetRadius && to < len(text); n++ {
+		_, size := utf8.DecodeRuneInString(text[to:])
+		to += size
+	}
+
+	// Cut between words where possible
+	if from > 0 {
+		if space := strings.IndexAny(text[from:start], " \n"); space >= 0 {
+			from += space + 1
+		}
+	}
+	if to < len(text) {
+		if space := strings.LastIndexAny(text[end:to], " \n"); space >= 0 {
+			to = end + space
+		}
+	}
+
+	snippet := strings.Join(strings.Fields(text[from:to]), " ")
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if to < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// sessionCheckInterval is how often the session file is checked for changes
+// made by other servers, so sign-ins and sign-outs there apply here
+const sessionCheckInterval = 2 * time.Second
+
+// SessionStore keeps browser sessions in a file so sign-ins survive restarts
+// and are shared by servers using the same configuration directory. Only a
+// hash of each session token is stored.
+type SessionStore struct {
+	path     string
+	sessions map[string]*models.Session // token hash -> session
+	mu       sync.Mutex
+
+	// loaded is the file as last read or written, and checked when it was
+	// last compared with the file on disk
+	loaded  fileState
+	checked time.Time
+}
+
+// fileState identifies a version of a file; the zero value stands for no
+// file
+type fileState struct {
+	modified time.Time
+	size     int64
+}
+
+// statFile returns the state of the file at path
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modified: info.ModTime(), size: info.Size()}
+}
+
+// NewSessionStore opens the session store at path, creating it on first use
+func NewSessionStore(path string) (*SessionStore, error) {
+	store := &SessionStore{
+		path:     path,
+		sessions: make(map[string]*models.Session),
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Create starts a session for user lasting lifetime and returns its token
+func (ss *SessionStore) Create(user string, lifetime time.Duration, remember bool) (string, models.Session, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", models.Session{}, fmt.Errorf("failed to generate session token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	now := time.Now()
+	session := &models.Session{
+		User:     user,
+		Created:  now,
+		Expires:  now.Add(lifetime),
+		Remember: remember,
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	// Pick up sessions created by other servers before writing the file
+	if err := ss.load(); err != nil {
+		return "", models.Session{}, err
+	}
+	ss.sessions[hashSessionToken(token)] = session
+	if err := ss.save(); err != nil {
+		return "", models.Session{}, err
+	}
+	return token, *session, nil
+}
+
+// Get returns the unexpired session for token. Sessions started or ended by
+// other servers are seen within sessionCheckInterval.
+func (ss *SessionStore) Get(token string) (models.Session, bool) {
+	if token == "" {
+		return models.Session{}, false
+	}
+	key := hashSessionToken(token)
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.refresh()
+	session, ok := ss.sessions[key]
+	if !ok || session.Expired(time.Now()) {
+		return models.Session{}, false
+	}
+	return *session, true
+}
+
+// Delete ends the session for token
+func (ss *SessionStore) Delete(token string) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if err := ss.load(); err != nil {
+		return err
+	}
+	delete(ss.sessions, hashSessionToken(token))
+	return ss.save()
+}
+
+// refresh reads the session file again if it changed since it was last read
+// or written, looking at most every sessionCheckInterval so requests with
+// unknown tokens don't each read it. Called with ss.mu held.
+func (ss *SessionStore) refresh() {
+	now := time.Now()
+	if now.Sub(ss.checked) < sessionCheckInterval {
+		return
+	}
+	ss.checked = now
+	if statFile(ss.path) == ss.loaded {
+		return
+	}
+	if err := ss.load(); err != nil {
+		slog.Warn("keeping the sessions already read", "error", err)
+	}
+}
+
+// load reads the session file, dropping expired sessions. Called with ss.mu
+// held.
+func (ss *SessionStore) load() error {
+	state := statFile(ss.path)
+	data, err := os.ReadFile(ss.path)
+	if errors.Is(err, os.ErrNotExist) {
+		ss.sessions = make(map[string]*models.Session)
+		ss.loaded = fileState{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read sessions: %w", err)
+	}
+
+	sessions := make(map[string]*models.Session)
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return fmt.Errorf("failed to parse sessions: %w", err)
+	}
+
+	now := time.Now()
+	for key, session := range sessions {
+		if session.Expired(now) {
+			delete(sessions, key)
+		}
+	}
+	ss.sessions = sessions
+	ss.loaded = state
+	return nil
+}
+
+// save writes the session file, readable only by the current user
+func (ss *SessionStore) save() error {
+	data, err := json.MarshalIndent(ss.sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ss.path), 0755); err != nil {
+		return fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+	if err := os.WriteFile(ss.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save sessions: %w", err)
+	}
+	ss.loaded = statFile(ss.path)
+	return nil
+}
+
+// hashSessionToken returns the key a session token is stored under
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
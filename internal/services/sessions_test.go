@@ -0,0 +1,36 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionStoreSeesOtherServersSignOuts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	here, err := NewSessionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewSessionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := other.Create("alice", time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	here.checked = time.Time{}
+	if _, ok := here.Get(token); !ok {
+		t.Fatal("session started by another server not found")
+	}
+
+	if err := other.Delete(token); err != nil {
+		t.Fatal(err)
+	}
+	here.checked = time.Time{}
+	if _, ok := here.Get(token); ok {
+		t.Error("session ended by another server still valid")
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// Slack settings
+const (
+	// slackMaxClockSkew is how old a signed request may be, so captured
+	// requests can't be replayed later
+	slackMaxClockSkew = 5 * time.Minute
+	slackTimeout      = 10 * time.Second
+)
+
+// ErrSlackSignature is returned for requests that weren't signed by Slack
+// with the configured signing secret
+var ErrSlackSignature = errors.New("invalid Slack request signature")
+
+// VerifySlackRequest checks the X-Slack-Request-Timestamp and
+// X-Slack-Signature headers of a request with body against the app's signing
+// secret. Requests more than five minutes old are rejected.
+func VerifySlackRequest(secret, timestamp, signature string, body []byte) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSlackSignature
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > slackMaxClockSkew || age < -slackMaxClockSkew {
+		return fmt.Errorf("%w: the request is too old", ErrSlackSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrSlackSignature
+	}
+	return nil
+}
+
+// slackEscaper escapes the characters Slack reads as markup in messages
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// SlackNotifier posts the tasks completed in each project to a Slack
+// incoming webhook
+type SlackNotifier struct {
+	notifyURL string
+	client    *http.Client
+	mu        sync.RWMutex
+
+	ctx           context.Context
+	cancel        context.CancelFunc
+	notifications sync.WaitGroup
+}
+
+// NewSlackNotifier creates a notifier posting to config's notify URL
+func NewSlackNotifier(config models.SlackConfig) *SlackNotifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SlackNotifier{
+		notifyURL: config.NotifyURL,
+		client:    &http.Client{Timeout: slackTimeout},
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Reload switches to the notify URL of a reloaded configuration
+func (sn *SlackNotifier) Reload(config models.SlackConfig) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+	sn.notifyURL = config.NotifyURL
+}
+
+// Watch announces the tasks completed in the project named project until
+// its broker is closed
+func (sn *SlackNotifier) Watch(project string, events *EventBroker) {
+	ch := events.Subscribe()
+
+	go func() {
+		for event := range ch {
+			if change, ok := event.Data.(models.TaskChange); ok && event.Type == models.EventTaskToggled && change.Checked {
+				sn.notify(project, change)
+			}
+		}
+	}()
+}
+
+// notify starts posting a completed task to the notify URL, if one is set
+func (sn *SlackNotifier) notify(project string, change models.TaskChange) {
+	sn.mu.RLock()
+	defer sn.mu.RUnlock()
+
+	if sn.notifyURL == "" || sn.ctx.Err() != nil {
+		return
+	}
+
+	text := fmt.Sprintf("✅ Task completed in %s: %s", slackEscaper.Replace(project), slackEscaper.Replace(models.TaskLabel(change.Text)))
+	if change.User != "" {
+		text += " (" + slackEscaper.Replace(change.User) + ")"
+	}
+
+	sn.notifications.Add(1)
+	go func(notifyURL string) {
+		defer sn.notifications.Done()
+		if err := sn.post(notifyURL, text); err != nil && sn.ctx.Err() == nil {
+			slog.Warn("Slack notification failed", "error", err)
+		}
+	}(sn.notifyURL)
+}
+
+// post sends a message to an incoming webhook
+func (sn *SlackNotifier) post(notifyURL, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(sn.ctx, http.MethodPost, notifyURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := sn.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Slack responded %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close abandons pending notifications and waits for those being sent
+func (sn *SlackNotifier) Close() {
+	// Holding the lock ensures no notification starts after this
+	sn.mu.Lock()
+	sn.cancel()
+	sn.mu.Unlock()
+
+	sn.notifications.Wait()
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// syncedFile keeps, under assets, the client IDs of the notes synced by
+// offline clients, so a sync retried after a restart adds nothing twice
+const syncedFile = ".synced.json"
+
+// maxSyncedClients caps the client IDs remembered; the first synced are
+// forgotten first
+const maxSyncedClients = 1000
+
+// syncedPath returns the path of the project's synced client IDs
+func (nm *NoteManager) syncedPath() string {
+	return filepath.Join(nm.storage.BasePath, "assets", syncedFile)
+}
+
+// loadSynced reads the client IDs of the notes synced before
+func (nm *NoteManager) loadSynced() {
+	data, err := os.ReadFile(nm.syncedPath())
+	if err != nil {
+		return
+	}
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		slog.Warn("ignoring unreadable synced client IDs", "path", nm.syncedPath(), "error", err)
+		return
+	}
+	for _, id := range ids {
+		nm.synced[id] = true
+	}
+	nm.syncedOrder = ids
+}
+
+// addSynced remembers the client ID of a synced note and records the IDs,
+// forgetting the oldest beyond maxSyncedClients. Failing to record them is
+// logged, since the note itself is saved. Called with lockNotes held.
+func (nm *NoteManager) addSynced(clientID string) {
+	nm.synced[clientID] = true
+	nm.syncedOrder = append(nm.syncedOrder, clientID)
+	if extra := len(nm.syncedOrder) - maxSyncedClients; extra > 0 {
+		for _, id := range nm.syncedOrder[:extra] {
+			delete(nm.synced, id)
+		}
+		nm.syncedOrder = append([]string(nil), nm.syncedOrder[extra:]...)
+	}
+
+	// The file is replaced whole, so a crash never leaves half of it
+	path := nm.syncedPath()
+	data, err := json.MarshalIndent(nm.syncedOrder, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(path+".tmp", data, 0644)
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		slog.Warn("failed to record synced client IDs", "path", path, "error", err)
+	}
+}
@@ -1,23 +1,54 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/darren/noteflow-go/internal/i18n"
 	"github.com/darren/noteflow-go/internal/models"
 )
 
+// Errors returned when adding or removing folders of the global task registry
+var (
+	ErrFolderInvalid  = errors.New("folder does not exist or has no notes.md")
+	ErrFolderNotFound = errors.New("folder is not registered")
+	ErrFolderOpen     = errors.New("folder is open in this server")
+)
+
+// Errors returned when rolling tasks over into another folder
+var (
+	ErrTaskNotFound      = errors.New("task not found")
+	ErrTaskCompleted     = errors.New("task is already completed")
+	ErrSameFolder        = errors.New("task is already in the target folder")
+	ErrFolderUnavailable = errors.New("folder is unavailable")
+)
+
+// Reasons a registered folder is unhealthy
+var (
+	errFolderMissing = errors.New("folder not found; it may have been moved or deleted, or its drive is not mounted")
+	errFolderDenied  = errors.New("permission denied")
+	errNotFolder     = errors.New("path is not a folder")
+	errNotesMissing  = errors.New("notes.md not found")
+)
+
 // TaskRegistryService manages cross-folder task synchronization
 type TaskRegistryService struct {
 	db         *DatabaseService
 	noteManagers map[string]*NoteManager // folderPath -> NoteManager
 	mu           sync.RWMutex
+	unhealthy    map[string]string // folderPath -> reason it can't be read
+	healthMu     sync.Mutex
 	syncTicker   *time.Ticker
 	stopCh       chan struct{}
+	registering  sync.WaitGroup // initial syncs of registered folders
 }
 
 // NewTaskRegistryService creates a new task registry service
@@ -30,6 +61,7 @@ func NewTaskRegistryService() (*TaskRegistryService, error) {
 	service := &TaskRegistryService{
 		db:           db,
 		noteManagers: make(map[string]*NoteManager),
+		unhealthy:    make(map[string]string),
 		stopCh:       make(chan struct{}),
 	}
 
@@ -53,15 +85,77 @@ func (trs *TaskRegistryService) RegisterFolder(folderPath string, noteManager *N
 	// Store note manager for this folder
 	trs.noteManagers[folderPath] = noteManager
 
-	// Initial sync of tasks for this folder
-	if err := trs.syncFolderTasks(folder.ID, folderPath, noteManager); err != nil {
-		log.Printf("Warning: failed initial sync for folder %s: %v", folderPath, err)
-	}
+	// Initial sync of tasks for this folder, in the background since it
+	// waits for the notes to be parsed
+	trs.registering.Add(1)
+	go func() {
+		defer trs.registering.Done()
+		tasks := noteManager.GetAllTasks()
+		trs.mu.RLock()
+		defer trs.mu.RUnlock()
+		if err := trs.db.SyncFolderTasks(folder.ID, tasks); err != nil {
+			slog.Warn("failed initial sync of folder", "folder", folderPath, "error", err)
+		}
+	}()
 
-	log.Printf("Registered folder for global task management: %s", folderPath)
+	slog.Info("registered folder for global task management", "folder", folderPath)
 	return nil
 }
 
+// AddFolder registers a notes folder for global task management at runtime.
+// Folders this process hasn't opened are read from their notes.md on each
+// sync. The registration is kept in the database across restarts.
+func (trs *TaskRegistryService) AddFolder(folderPath string) (*models.FolderRegistry, error) {
+	folderPath, err := filepath.Abs(folderPath)
+	if err != nil {
+		return nil, err
+	}
+	if checkFolder(folderPath) != nil {
+		return nil, ErrFolderInvalid
+	}
+
+	trs.mu.Lock()
+	defer trs.mu.Unlock()
+
+	folder, err := trs.db.RegisterFolder(folderPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register folder in database: %w", err)
+	}
+	folder.Healthy = true
+	if err := trs.syncFolder(folder.ID, folderPath); err != nil {
+		slog.Warn("failed initial sync of folder", "folder", folderPath, "error", err)
+	}
+
+	slog.Info("registered folder for global task management", "folder", folderPath)
+	return folder, nil
+}
+
+// RemoveFolder unregisters a folder and forgets its tasks. Folders open in
+// this server stay registered while it runs.
+func (trs *TaskRegistryService) RemoveFolder(folderID int) error {
+	trs.mu.Lock()
+	defer trs.mu.Unlock()
+
+	folders, err := trs.db.GetActiveFolders()
+	if err != nil {
+		return fmt.Errorf("failed to get active folders: %w", err)
+	}
+	for _, folder := range folders {
+		if folder.ID != folderID {
+			continue
+		}
+		if _, open := trs.noteManagers[folder.Path]; open {
+			return ErrFolderOpen
+		}
+		if err := trs.db.RemoveFolder(folderID); err != nil {
+			return err
+		}
+		slog.Info("unregistered folder from global task management", "folder", folder.Path)
+		return nil
+	}
+	return ErrFolderNotFound
+}
+
 // syncFolderTasks synchronizes tasks for a specific folder
 func (trs *TaskRegistryService) syncFolderTasks(folderID int, folderPath string, noteManager *NoteManager) error {
 	// Get all tasks from the note manager
@@ -71,29 +165,227 @@ func (trs *TaskRegistryService) syncFolderTasks(folderID int, folderPath string,
 	return trs.db.SyncFolderTasks(folderID, tasks)
 }
 
-// GetGlobalTasks returns all tasks across all registered folders
+// syncFolder synchronizes the tasks of a folder through its NoteManager, or
+// from its notes.md when this process hasn't opened it. The caller must hold
+// trs.mu.
+func (trs *TaskRegistryService) syncFolder(folderID int, folderPath string) error {
+	if noteManager, exists := trs.noteManagers[folderPath]; exists {
+		return trs.syncFolderTasks(folderID, folderPath, noteManager)
+	}
+
+	noteManager, err := openFolder(folderPath)
+	if err != nil {
+		return err
+	}
+	defer noteManager.Close()
+	return trs.syncFolderTasks(folderID, folderPath, noteManager)
+}
+
+// openFolder reads the notes of a folder this process hasn't opened. It is
+// read afresh for every use, since another process may be changing it.
+func openFolder(folderPath string) (*NoteManager, error) {
+	return NewNoteManager(folderPath, NewConfigStore(&models.Config{}, ""))
+}
+
+// notesModified reports whether the notes.md of a folder changed after t
+func notesModified(folderPath string, t time.Time) bool {
+	info, err := os.Stat(filepath.Join(folderPath, "notes.md"))
+	return err == nil && info.ModTime().After(t)
+}
+
+// GetGlobalTasks returns all tasks across the registered folders. Tasks of
+// unhealthy folders are left out until the folder can be read again.
 func (trs *TaskRegistryService) GetGlobalTasks() (*models.GlobalTasksResponse, error) {
-	return trs.db.GetGlobalTasks()
+	globalTasks, err := trs.db.GetGlobalTasks()
+	if err != nil {
+		return nil, err
+	}
+
+	healthy := make(map[string]bool)
+	isHealthy := func(folderPath string) bool {
+		if _, checked := healthy[folderPath]; !checked {
+			healthy[folderPath] = trs.checkHealth(folderPath) == nil
+		}
+		return healthy[folderPath]
+	}
+
+	var tasks []models.GlobalTask
+	for _, task := range globalTasks.Tasks {
+		if isHealthy(task.FolderPath) {
+			tasks = append(tasks, task)
+		}
+	}
+	var summaries []models.TaskSummary
+	for _, summary := range globalTasks.Summaries {
+		if isHealthy(summary.FolderPath) {
+			summaries = append(summaries, summary)
+		}
+	}
+	return &models.GlobalTasksResponse{
+		Tasks:     tasks,
+		Summaries: summaries,
+		Total:     len(tasks),
+	}, nil
 }
 
-// UpdateGlobalTaskCompletion updates task completion and syncs back to the note file
-func (trs *TaskRegistryService) UpdateGlobalTaskCompletion(taskID int, completed bool) error {
-	// First, get the task details to know which folder it belongs to
+// GetGlobalNotes returns the notes of the registered folders accepted by
+// include, newest first, skipping offset notes and returning at most limit,
+// and the number of notes before paging. Folders this process hasn't opened
+// are read from their notes.md.
+func (trs *TaskRegistryService) GetGlobalNotes(include func(folderPath string) bool, offset, limit int) ([]models.GlobalNote, int, error) {
+	folders, err := trs.db.GetActiveFolders()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get active folders: %w", err)
+	}
+
+	trs.mu.RLock()
+	defer trs.mu.RUnlock()
+
+	var notes []models.GlobalNote
+	for _, folder := range folders {
+		if !include(folder.Path) {
+			continue
+		}
+		noteManager, exists := trs.noteManagers[folder.Path]
+		if !exists {
+			if trs.checkHealth(folder.Path) != nil {
+				continue
+			}
+			if noteManager, err = openFolder(folder.Path); err != nil {
+				slog.Warn("failed to read notes of folder", "folder", folder.Path, "error", err)
+				continue
+			}
+		}
+
+		for i, note := range noteManager.GetAllNotes() {
+			notes = append(notes, models.GlobalNote{
+				FolderPath: folder.Path,
+				Index:      i,
+				Title:      note.Title,
+				Content:    note.Content,
+				Timestamp:  note.Timestamp,
+				Author:     note.Author,
+			})
+		}
+
+		if !exists {
+			noteManager.Close()
+		}
+	}
+
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].Timestamp.After(notes[j].Timestamp)
+	})
+
+	total := len(notes)
+	notes = notes[min(offset, total):]
+	notes = notes[:min(limit, len(notes))]
+
+	// Only the returned notes are rendered
+	renderer := NewMarkdownRenderer()
+	for i := range notes {
+		html, err := renderer.RenderToHTML(notes[i].Content)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to render note %d of %s: %w", notes[i].Index, notes[i].FolderPath, err)
+		}
+		notes[i].HTML = html
+	}
+	return notes, total, nil
+}
+
+// GetGlobalStats sums the notes, tasks and disk usage of the registered
+// folders accepted by include. Unhealthy folders are listed without counts.
+func (trs *TaskRegistryService) GetGlobalStats(include func(folderPath string) bool) (*models.GlobalStats, error) {
+	folders, err := trs.db.GetActiveFolders()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active folders: %w", err)
+	}
+
+	trs.mu.RLock()
+	defer trs.mu.RUnlock()
+
+	stats := &models.GlobalStats{Folders: []models.FolderStats{}}
+	for _, folder := range folders {
+		if !include(folder.Path) {
+			continue
+		}
+		folderStats := models.FolderStats{FolderPath: folder.Path}
+		if trs.checkHealth(folder.Path) != nil {
+			stats.Add(folderStats)
+			continue
+		}
+
+		noteManager, exists := trs.noteManagers[folder.Path]
+		if !exists {
+			if noteManager, err = openFolder(folder.Path); err != nil {
+				slog.Warn("failed to read notes of folder", "folder", folder.Path, "error", err)
+				stats.Add(folderStats)
+				continue
+			}
+		}
+		folderStats.Healthy = true
+		folderStats.Notes = len(noteManager.GetAllNotes())
+		for _, task := range noteManager.GetAllTasks() {
+			if task.Checked {
+				folderStats.CompletedTasks++
+			} else {
+				folderStats.OpenTasks++
+			}
+		}
+		if !exists {
+			noteManager.Close()
+		}
+
+		folderStats.DiskUsage = diskUsage(folder.Path)
+		stats.Add(folderStats)
+	}
+	return stats, nil
+}
+
+// diskUsage returns the bytes taken by the files in a folder, skipping those
+// that can't be read
+func diskUsage(folderPath string) int64 {
+	var size int64
+	filepath.WalkDir(folderPath, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// FindGlobalTask returns the registered task with the given ID
+func (trs *TaskRegistryService) FindGlobalTask(taskID int) (*models.GlobalTask, error) {
 	globalTasks, err := trs.db.GetGlobalTasks()
 	if err != nil {
-		return fmt.Errorf("failed to get global tasks: %w", err)
+		return nil, fmt.Errorf("failed to get global tasks: %w", err)
 	}
 
-	var targetTask *models.GlobalTask
 	for _, task := range globalTasks.Tasks {
 		if task.ID == taskID {
-			targetTask = &task
-			break
+			return &task, nil
 		}
 	}
 
-	if targetTask == nil {
-		return fmt.Errorf("task with ID %d not found", taskID)
+	return nil, fmt.Errorf("task with ID %d not found", taskID)
+}
+
+// UpdateGlobalTaskCompletion updates task completion and syncs back to the note file,
+// attributing the change to user if not empty
+func (trs *TaskRegistryService) UpdateGlobalTaskCompletion(taskID int, completed bool, user string) error {
+	// First, get the task details to know which folder it belongs to
+	targetTask, err := trs.FindGlobalTask(taskID)
+	if err != nil {
+		return err
+	}
+	if err := trs.checkHealth(targetTask.FolderPath); err != nil {
+		return fmt.Errorf("folder %s is unavailable: %w", targetTask.FolderPath, err)
 	}
 
 	// Update in database
@@ -106,22 +398,156 @@ func (trs *TaskRegistryService) UpdateGlobalTaskCompletion(taskID int, completed
 	noteManager, exists := trs.noteManagers[targetTask.FolderPath]
 	trs.mu.RUnlock()
 
-	if exists {
-		// Find and update the task in the note manager
-		tasks := noteManager.GetAllTasks()
-		for _, task := range tasks {
-			if task.Text == targetTask.Content {
-				if err := noteManager.UpdateTask(task.Index, completed); err != nil {
-					log.Printf("Warning: failed to update task in note file: %v", err)
-				}
-				break
+	if !exists {
+		// Folders registered at runtime are changed through their notes.md
+		if noteManager, err = openFolder(targetTask.FolderPath); err != nil {
+			slog.Warn("failed to open folder", "folder", targetTask.FolderPath, "error", err)
+			return nil
+		}
+		defer noteManager.Close()
+	}
+
+	// Find and update the task in the note manager
+	tasks := noteManager.GetAllTasks()
+	for _, task := range tasks {
+		if task.Text == targetTask.Content {
+			if err := noteManager.UpdateTask(task.Index, completed, user); err != nil {
+				slog.Warn("failed to update task in note file", "folder", targetTask.FolderPath, "error", err)
 			}
+			break
 		}
 	}
 
 	return nil
 }
 
+// RolloverTasks moves or copies open tasks into a note of another registered
+// folder, appending them to the newest note with the requested title or to a
+// new one. Only folders accepted by include are used, and edits are
+// attributed to user if not empty. Moved tasks are removed from their notes
+// after they were added to the target, so a failure never loses a task. The
+// target note's +http links are archived within ctx.
+func (trs *TaskRegistryService) RolloverTasks(ctx context.Context, req models.RolloverRequest, include func(folderPath string) bool, user string) (*models.RolloverResult, error) {
+	folders, err := trs.db.GetActiveFolders()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active folders: %w", err)
+	}
+	var target *models.FolderRegistry
+	for i := range folders {
+		if folders[i].Path == filepath.Clean(req.Folder) && include(folders[i].Path) {
+			target = &folders[i]
+		}
+	}
+	if target == nil {
+		return nil, ErrFolderNotFound
+	}
+	if err := trs.checkHealth(target.Path); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrFolderUnavailable, target.Path, err)
+	}
+
+	globalTasks, err := trs.db.GetGlobalTasks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global tasks: %w", err)
+	}
+	byID := make(map[int]models.GlobalTask)
+	for _, task := range globalTasks.Tasks {
+		byID[task.ID] = task
+	}
+
+	var tasks []models.GlobalTask
+	var lines []string
+	seen := make(map[int]bool)
+	for _, id := range req.TaskIDs {
+		task, ok := byID[id]
+		switch {
+		case seen[id]:
+			continue
+		case !ok || !include(task.FolderPath):
+			return nil, fmt.Errorf("%w: %d", ErrTaskNotFound, id)
+		case task.Completed:
+			return nil, fmt.Errorf("%w: %d", ErrTaskCompleted, id)
+		case task.FolderPath == target.Path:
+			return nil, fmt.Errorf("%w: %d", ErrSameFolder, id)
+		}
+		if err := trs.checkHealth(task.FolderPath); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrFolderUnavailable, task.FolderPath, err)
+		}
+		seen[id] = true
+		tasks = append(tasks, task)
+		lines = append(lines, "- "+task.Content)
+	}
+
+	trs.mu.RLock()
+	defer trs.mu.RUnlock()
+
+	noteManager, release, err := trs.useFolder(target.Path)
+	if err != nil {
+		return nil, err
+	}
+	index, created, err := noteManager.AppendToNote(ctx, req.Note, strings.Join(lines, "\n"), user)
+	release()
+	if err != nil {
+		return nil, fmt.Errorf("failed to add tasks to %s: %w", target.Path, err)
+	}
+	synced := map[string]int{target.Path: target.ID}
+
+	if req.Mode == models.RolloverMove {
+		for _, task := range tasks {
+			synced[task.FolderPath] = task.FolderID
+			if err := trs.removeTask(task, user); err != nil {
+				slog.WarnContext(ctx, "failed to remove rolled over task", "task", task.ID, "folder", task.FolderPath, "error", err)
+			}
+		}
+	}
+
+	for folderPath, folderID := range synced {
+		if err := trs.syncFolder(folderID, folderPath); err != nil {
+			slog.Warn("failed to sync folder", "folder", folderPath, "error", err)
+		}
+	}
+
+	slog.InfoContext(ctx, "rolled over tasks", "tasks", len(tasks), "note", req.Note, "folder", target.Path, "mode", req.Mode)
+	return &models.RolloverResult{
+		Folder:    target.Path,
+		Note:      req.Note,
+		NoteIndex: index,
+		Created:   created,
+		Mode:      req.Mode,
+		Tasks:     len(tasks),
+	}, nil
+}
+
+// removeTask deletes an open global task from its note. The caller must hold
+// trs.mu.
+func (trs *TaskRegistryService) removeTask(task models.GlobalTask, user string) error {
+	noteManager, release, err := trs.useFolder(task.FolderPath)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	for _, noteTask := range noteManager.GetAllTasks() {
+		if noteTask.Text == task.Content && !noteTask.Checked {
+			return noteManager.RemoveTask(noteTask.Index, user)
+		}
+	}
+	return ErrTaskNotFound
+}
+
+// useFolder returns the NoteManager of a folder, opening it when this process
+// hasn't, and a function to call when done with it. The caller must hold
+// trs.mu.
+func (trs *TaskRegistryService) useFolder(folderPath string) (*NoteManager, func(), error) {
+	if noteManager, exists := trs.noteManagers[folderPath]; exists {
+		return noteManager, func() {}, nil
+	}
+	noteManager, err := openFolder(folderPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open folder %s: %w", folderPath, err)
+	}
+	return noteManager, func() { noteManager.Close() }, nil
+}
+
 // startBackgroundSync starts a background goroutine to periodically sync all folders
 func (trs *TaskRegistryService) startBackgroundSync() {
 	trs.syncTicker = time.NewTicker(30 * time.Second)
@@ -138,49 +564,37 @@ func (trs *TaskRegistryService) startBackgroundSync() {
 	}()
 }
 
-// performBackgroundSync syncs all registered folders and cleans up stale entries
+// performBackgroundSync syncs all registered folders, skipping unhealthy ones
 func (trs *TaskRegistryService) performBackgroundSync() {
 	trs.mu.RLock()
 	defer trs.mu.RUnlock()
 
 	folders, err := trs.db.GetActiveFolders()
 	if err != nil {
-		log.Printf("Warning: failed to get active folders for sync: %v", err)
+		slog.Warn("failed to get active folders for sync", "error", err)
 		return
 	}
 
-	var foldersToRemove []int
-
 	for _, folder := range folders {
-		// Check if folder still exists and has notes.md
-		if !trs.validateFolder(folder.Path) {
-			foldersToRemove = append(foldersToRemove, folder.ID)
-			log.Printf("Marking stale folder for removal: %s", folder.Path)
-			continue
-		}
-
-		noteManager, exists := trs.noteManagers[folder.Path]
-		if !exists {
+		// Folders that can't be read keep their tasks until they are back
+		if trs.checkHealth(folder.Path) != nil {
 			continue
 		}
 
 		// Check if the notes file has been modified since last sync
-		if noteManager.HasChanges() || time.Since(folder.LastScan) > 5*time.Minute {
-			if err := trs.syncFolderTasks(folder.ID, folder.Path, noteManager); err != nil {
-				log.Printf("Warning: failed to sync folder %s: %v", folder.Path, err)
-			}
+		changed := notesModified(folder.Path, folder.LastScan)
+		if noteManager, exists := trs.noteManagers[folder.Path]; exists {
+			changed = changed || noteManager.HasChanges()
 		}
-	}
-
-	// Clean up stale folders
-	for _, folderID := range foldersToRemove {
-		if err := trs.db.RemoveFolder(folderID); err != nil {
-			log.Printf("Warning: failed to remove stale folder %d: %v", folderID, err)
+		if changed || time.Since(folder.LastScan) > 5*time.Minute {
+			if err := trs.syncFolder(folder.ID, folder.Path); err != nil {
+				slog.Warn("failed to sync folder", "folder", folder.Path, "error", err)
+			}
 		}
 	}
 }
 
-// ForceSync forces a sync of all registered folders and cleans up stale entries
+// ForceSync forces a sync of all registered folders, skipping unhealthy ones
 func (trs *TaskRegistryService) ForceSync() error {
 	trs.mu.RLock()
 	defer trs.mu.RUnlock()
@@ -190,57 +604,78 @@ func (trs *TaskRegistryService) ForceSync() error {
 		return fmt.Errorf("failed to get active folders: %w", err)
 	}
 
-	var foldersToRemove []int
-
 	for _, folder := range folders {
-		// Check if folder still exists and has notes.md
-		if !trs.validateFolder(folder.Path) {
-			foldersToRemove = append(foldersToRemove, folder.ID)
-			log.Printf("Marking stale folder for removal during force sync: %s", folder.Path)
-			continue
-		}
-
-		noteManager, exists := trs.noteManagers[folder.Path]
-		if !exists {
+		if trs.checkHealth(folder.Path) != nil {
 			continue
 		}
 
-		if err := trs.syncFolderTasks(folder.ID, folder.Path, noteManager); err != nil {
-			log.Printf("Warning: failed to sync folder %s: %v", folder.Path, err)
-		}
-	}
-
-	// Clean up stale folders
-	for _, folderID := range foldersToRemove {
-		if err := trs.db.RemoveFolder(folderID); err != nil {
-			log.Printf("Warning: failed to remove stale folder %d: %v", folderID, err)
-		} else {
-			log.Printf("Removed stale folder ID %d from global task registry", folderID)
+		if err := trs.syncFolder(folder.ID, folder.Path); err != nil {
+			slog.Warn("failed to sync folder", "folder", folder.Path, "error", err)
 		}
 	}
 
 	return nil
 }
 
-// GetActiveFolders returns all active registered folders
+// GetActiveFolders returns all active registered folders, marking those
+// that can't be read as unhealthy
 func (trs *TaskRegistryService) GetActiveFolders() ([]models.FolderRegistry, error) {
-	return trs.db.GetActiveFolders()
+	folders, err := trs.db.GetActiveFolders()
+	if err != nil {
+		return nil, err
+	}
+	for i := range folders {
+		if err := trs.checkHealth(folders[i].Path); err != nil {
+			folders[i].Error = i18n.T(err.Error())
+		} else {
+			folders[i].Healthy = true
+		}
+	}
+	return folders, nil
 }
 
-// validateFolder checks if a folder still exists and has notes.md
-func (trs *TaskRegistryService) validateFolder(folderPath string) bool {
-	// Check if folder exists
-	if _, err := os.Stat(folderPath); os.IsNotExist(err) {
-		return false
+// checkHealth returns why a registered folder can't be read, or nil, logging
+// when a folder becomes unhealthy or recovers
+func (trs *TaskRegistryService) checkHealth(folderPath string) error {
+	err := checkFolder(folderPath)
+
+	trs.healthMu.Lock()
+	defer trs.healthMu.Unlock()
+	reason, wasUnhealthy := trs.unhealthy[folderPath]
+	switch {
+	case err == nil && wasUnhealthy:
+		delete(trs.unhealthy, folderPath)
+		slog.Info("registered folder is available again", "folder", folderPath)
+	case err != nil && err.Error() != reason:
+		trs.unhealthy[folderPath] = err.Error()
+		slog.Warn("skipping registered folder", "folder", folderPath, "error", err)
 	}
+	return err
+}
 
-	// Check if notes.md exists in the folder
-	notesPath := filepath.Join(folderPath, "notes.md")
-	if _, err := os.Stat(notesPath); os.IsNotExist(err) {
-		return false
+// checkFolder returns why a folder can't be read as a notes folder, or nil
+// when it exists and has notes.md
+func checkFolder(folderPath string) error {
+	info, err := os.Stat(folderPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return errFolderMissing
+	case errors.Is(err, os.ErrPermission):
+		return errFolderDenied
+	case err != nil:
+		return err
+	case !info.IsDir():
+		return errNotFolder
 	}
 
-	return true
+	_, err = os.Stat(filepath.Join(folderPath, "notes.md"))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return errNotesMissing
+	case errors.Is(err, os.ErrPermission):
+		return errFolderDenied
+	}
+	return err
 }
 
 // Close stops the background sync and closes the database connection
@@ -250,6 +685,7 @@ func (trs *TaskRegistryService) Close() error {
 	}
 	
 	close(trs.stopCh)
+	trs.registering.Wait()
 	
 	if trs.db != nil {
 		return trs.db.Close()
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// Telegram Bot API settings
+const (
+	telegramAPIURL = "https://api.telegram.org"
+	// telegramPollSeconds is how long each request for updates waits for one
+	telegramPollSeconds = 50
+	// telegramRetryDelay is how long polling pauses after a failed request
+	telegramRetryDelay = 10 * time.Second
+	// telegramMaxMessage is the longest message the Bot API sends
+	telegramMaxMessage = 4096
+)
+
+// telegramHelp answers /start and /help
+const telegramHelp = `Send me a message and I'll save it as a note.
+
+/task <text> adds a task
+/tasks lists your open tasks`
+
+// TelegramBot saves the messages sent to a Telegram bot as notes and tasks,
+// and answers /tasks with the open tasks. It polls the Bot API for messages,
+// so the server needs no public address.
+type TelegramBot struct {
+	config      models.TelegramConfig
+	noteManager *NoteManager
+	readOnly    bool
+	client      *http.Client
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	polling sync.WaitGroup
+}
+
+// telegramResponse is the envelope of every Bot API response
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Description string          `json:"description"`
+	Result      json.RawMessage `json:"result"`
+}
+
+// telegramUpdate is an incoming update; only messages are asked for
+type telegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *telegramMessage `json:"message"`
+}
+
+// telegramMessage is a message sent to the bot
+type telegramMessage struct {
+	Chat struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	Text string `json:"text"`
+}
+
+// NewTelegramBot creates a bot adding notes to noteManager. A read-only bot
+// only answers commands.
+func NewTelegramBot(config models.TelegramConfig, noteManager *NoteManager, readOnly bool) *TelegramBot {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TelegramBot{
+		config:      config,
+		noteManager: noteManager,
+		readOnly:    readOnly,
+		client:      &http.Client{Timeout: (telegramPollSeconds + 10) * time.Second},
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start polls for messages in the background until Close is called
+func (b *TelegramBot) Start() {
+	b.polling.Add(1)
+	go func() {
+		defer b.polling.Done()
+		b.poll()
+	}()
+}
+
+// Close stops polling and waits for the message being handled
+func (b *TelegramBot) Close() {
+	b.cancel()
+	b.polling.Wait()
+}
+
+// poll fetches and handles messages until the bot is closed
+func (b *TelegramBot) poll() {
+	var offset int64
+	for b.ctx.Err() == nil {
+		updates, err := b.getUpdates(offset)
+		if err != nil {
+			if b.ctx.Err() != nil {
+				return
+			}
+			slog.Warn("Telegram bot failed to get updates", "error", err)
+			select {
+			case <-b.ctx.Done():
+			case <-time.After(telegramRetryDelay):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			if update.Message != nil {
+				b.handle(update.Message)
+			}
+		}
+	}
+}
+
+// getUpdates waits for the updates after offset
+func (b *TelegramBot) getUpdates(offset int64) ([]telegramUpdate, error) {
+	params := map[string]interface{}{
+		"offset":          offset,
+		"timeout":         telegramPollSeconds,
+		"allowed_updates": []string{"message"},
+	}
+	var updates []telegramUpdate
+	if err := b.call("getUpdates", params, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// handle saves a message from an allowed chat, or answers its command
+func (b *TelegramBot) handle(message *telegramMessage) {
+	chatID := message.Chat.ID
+	if !b.config.ChatAllowed(chatID) {
+		slog.Info("Telegram bot ignoring a message from a chat not in telegram.allowed_chats", "chat", chatID)
+		b.reply(chatID, fmt.Sprintf("This chat isn't allowed to add notes. Add its ID, %d, to telegram.allowed_chats in NoteFlow's settings.", chatID))
+		return
+	}
+
+	text := strings.TrimSpace(message.Text)
+	if text == "" {
+		b.reply(chatID, "Only text messages can be saved.")
+		return
+	}
+
+	command, argument := "", text
+	if strings.HasPrefix(text, "/") {
+		command, argument, _ = strings.Cut(text, " ")
+		// Commands in groups may name the bot, as in /tasks@noteflow_bot
+		command, _, _ = strings.Cut(command, "@")
+		argument = strings.TrimSpace(argument)
+	}
+
+	switch command {
+	case "/start", "/help":
+		b.reply(chatID, telegramHelp)
+	case "/tasks":
+		b.reply(chatID, b.openTasks())
+	case "/task":
+		if argument == "" {
+			b.reply(chatID, "Send the task after /task, as in /task Buy milk")
+			return
+		}
+		if err := b.save("- [ ] " + argument); err != nil {
+			b.reply(chatID, "The task wasn't saved: "+err.Error())
+			return
+		}
+		b.reply(chatID, "✓ Task added")
+	case "":
+		if err := b.save(text); err != nil {
+			b.reply(chatID, "The note wasn't saved: "+err.Error())
+			return
+		}
+		b.reply(chatID, "✓ Saved")
+	default:
+		b.reply(chatID, "Unknown command.\n\n"+telegramHelp)
+	}
+}
+
+// save adds content as a note, or to the note titled NoteTitle
+func (b *TelegramBot) save(content string) error {
+	if b.readOnly {
+		return errors.New("this server is read-only")
+	}
+
+	var err error
+	if b.config.NoteTitle != "" {
+		_, _, err = b.noteManager.AppendToNote(b.ctx, b.config.NoteTitle, content, "")
+	} else {
+		err = b.noteManager.AddNote(b.ctx, "", content, "")
+	}
+	if err != nil {
+		slog.Warn("Telegram bot failed to save a message", "error", err)
+		return errors.New("saving failed; see the server log")
+	}
+	return nil
+}
+
+// openTasks lists the open tasks, as much of the list as fits in a message
+func (b *TelegramBot) openTasks() string {
+	tasks := b.noteManager.GetActiveTasks()
+	if len(tasks) == 0 {
+		return "No open tasks 🎉"
+	}
+
+	var list strings.Builder
+	fmt.Fprintf(&list, "%d open tasks:\n", len(tasks))
+	for _, task := range tasks {
+		line := "\n• " + task.Text
+		if list.Len()+len(line) > telegramMaxMessage-4 {
+			list.WriteString("\n…")
+			break
+		}
+		list.WriteString(line)
+	}
+	return list.String()
+}
+
+// reply sends text to a chat, logging failures
+func (b *TelegramBot) reply(chatID int64, text string) {
+	params := map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	}
+	if err := b.call("sendMessage", params, nil); err != nil && b.ctx.Err() == nil {
+		slog.Warn("Telegram bot failed to reply", "chat", chatID, "error", err)
+	}
+}
+
+// call calls a Bot API method with params, decoding its result into result
+// if not nil
+func (b *TelegramBot) call(method string, params, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	apiURL := b.config.APIURL
+	if apiURL == "" {
+		apiURL = telegramAPIURL
+	}
+	endpoint := strings.TrimRight(apiURL, "/") + "/bot" + url.PathEscape(b.config.Token) + "/" + method
+
+	req, err := http.NewRequestWithContext(b.ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		// The error names the URL, which holds the token
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var response telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("%s: unexpected response (HTTP %d)", method, resp.StatusCode)
+	}
+	if !response.OK {
+		return fmt.Errorf("%s: %s", method, response.Description)
+	}
+	if result != nil {
+		return json.Unmarshal(response.Result, result)
+	}
+	return nil
+}
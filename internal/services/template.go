@@ -2,11 +2,16 @@ package services
 
 import (
 	"bytes"
-	"embed"
+	"fmt"
 	"html/template"
+	"io/fs"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/darren/noteflow-go/internal/i18n"
 	"github.com/darren/noteflow-go/internal/models"
 	"github.com/darren/noteflow-go/internal/themes"
 )
@@ -14,14 +19,25 @@ import (
 // TemplateService handles HTML template rendering
 type TemplateService struct {
 	templates map[string]*template.Template
-	assets    *embed.FS
+	assets    fs.FS
+	live      bool
+	root      string
+	configDir string
+	mu        sync.RWMutex
 }
 
-// NewTemplateService creates a new template service
-func NewTemplateService(assets *embed.FS) (*TemplateService, error) {
+// NewTemplateService creates a new template service reading templates and
+// styles from assets, the web directory. With live set, templates are
+// re-read on every render so edits show up without a rebuild. root is the URL
+// prefix the server is mounted under, used for links to static files.
+// Relative paths to custom styles and scripts are resolved against configDir.
+func NewTemplateService(assets fs.FS, live bool, root, configDir string) (*TemplateService, error) {
 	service := &TemplateService{
 		templates: make(map[string]*template.Template),
 		assets:    assets,
+		live:      live,
+		root:      root,
+		configDir: configDir,
 	}
 
 	// Load main template
@@ -32,26 +48,26 @@ func NewTemplateService(assets *embed.FS) (*TemplateService, error) {
 	return service, nil
 }
 
-// loadTemplates loads all templates from embedded filesystem
+// templateFuncs are the helper functions available to page templates. t
+// translates a message; letters splits a label into the letters the page
+// headings show one by one.
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"t":    i18n.T,
+	"letters": func(label string) []string {
+		return strings.Split(label, "")
+	},
+}
+
+// loadTemplates loads all templates from the web assets
 func (ts *TemplateService) loadTemplates() error {
-	var indexHTML []byte
-	var err error
-	
-	// Try to read from embedded assets first, fallback to filesystem
-	if ts.assets != nil {
-		indexHTML, err = ts.assets.ReadFile("web/templates/index.html")
-	} else {
-		indexHTML, err = os.ReadFile("web/templates/index.html")
-	}
-	
+	indexHTML, err := fs.ReadFile(ts.assets, "templates/index.html")
 	if err != nil {
 		return err
 	}
 
 	// Create template with helper functions
-	tmpl := template.New("index.html").Funcs(template.FuncMap{
-		"join": strings.Join,
-	})
+	tmpl := template.New("index.html").Funcs(templateFuncs)
 
 	// Parse the template
 	tmpl, err = tmpl.Parse(string(indexHTML))
@@ -59,18 +75,28 @@ func (ts *TemplateService) loadTemplates() error {
 		return err
 	}
 
+	ts.mu.Lock()
 	ts.templates["index"] = tmpl
+	ts.mu.Unlock()
 	return nil
 }
 
-// RenderIndex renders the main index page with theme and context
-func (ts *TemplateService) RenderIndex(config *models.Config, basePath string) (string, error) {
-	// Get current theme
-	theme := themes.AvailableThemes[config.Theme]
-	if theme == nil {
-		theme = themes.AvailableThemes["dark-orange"]
-	}
+// RenderIndex renders the main index page with theme and context. prefix is
+// the URL path the project is mounted under, prepended to the page's links;
+// readOnly hides the controls for changing notes.
+func (ts *TemplateService) RenderIndex(config *models.Config, basePath, prefix string, readOnly bool) (string, error) {
+	return ts.renderIndex(config, themes.Current(config), basePath, prefix, readOnly)
+}
 
+// PreviewIndex renders the main index page with theme, which need not be
+// saved, for previewing it. The page is read-only so trying it out cannot
+// change any notes.
+func (ts *TemplateService) PreviewIndex(config *models.Config, theme *models.Theme, basePath, prefix string) (string, error) {
+	return ts.renderIndex(config, theme, basePath, prefix, true)
+}
+
+// renderIndex renders the main index page styled with theme
+func (ts *TemplateService) renderIndex(config *models.Config, theme *models.Theme, basePath, prefix string, readOnly bool) (string, error) {
 	// Read font CSS
 	fontCSS, err := ts.getFontCSS()
 	if err != nil {
@@ -83,22 +109,52 @@ func (ts *TemplateService) RenderIndex(config *models.Config, basePath string) (
 		return "", err
 	}
 
+	themedCSS += configuredFontCSS(config, basePath, prefix)
+	customCSS, customJS := ts.customFiles(config, basePath)
+
 	// Template data
 	data := struct {
-		FontFaces    template.CSS
-		ThemedStyles template.CSS
-		CurrentTheme string
-		FolderPath   string
+		FontFaces     template.CSS
+		ThemedStyles  template.CSS
+		CustomCSS     template.CSS
+		CustomJS      template.JS
+		CurrentTheme  string
+		Lang          string
+		RelativeTimes bool
+		FolderPath    string
+		Root          string
+		Prefix        string
+		ReadOnly      bool
+		AI            bool
 	}{
-		FontFaces:    template.CSS(fontCSS),
-		ThemedStyles: template.CSS(themedCSS),
-		CurrentTheme: config.Theme,
-		FolderPath:   basePath,
+		FontFaces:     template.CSS(fontCSS),
+		ThemedStyles:  template.CSS(themedCSS),
+		CustomCSS:     customCSS,
+		CustomJS:      customJS,
+		CurrentTheme:  theme.Name,
+		Lang:          i18n.Locale(),
+		RelativeTimes: config.Time.Relative,
+		FolderPath:    basePath,
+		Root:          ts.root,
+		Prefix:        prefix,
+		ReadOnly:      readOnly,
+		AI:            config.AI.Provider != "",
+	}
+
+	// Pick up template edits when serving assets from disk
+	if ts.live {
+		if err := ts.loadTemplates(); err != nil {
+			return "", err
+		}
 	}
 
 	// Execute template
+	ts.mu.RLock()
+	tmpl := ts.templates["index"]
+	ts.mu.RUnlock()
+
 	var buf bytes.Buffer
-	if err := ts.templates["index"].Execute(&buf, data); err != nil {
+	if err := tmpl.Execute(&buf, data); err != nil {
 		return "", err
 	}
 
@@ -107,32 +163,87 @@ func (ts *TemplateService) RenderIndex(config *models.Config, basePath string) (
 
 // getFontCSS returns the font CSS content
 func (ts *TemplateService) getFontCSS() (string, error) {
-	var fontCSS []byte
-	var err error
-	
-	if ts.assets != nil {
-		fontCSS, err = ts.assets.ReadFile("web/static/css/fonts.css")
-	} else {
-		fontCSS, err = os.ReadFile("web/static/css/fonts.css")
-	}
-	
+	fontCSS, err := fs.ReadFile(ts.assets, "static/css/fonts.css")
 	if err != nil {
 		return "", err
 	}
-	return string(fontCSS), nil
+
+	// The stylesheet links fonts relative to itself; inlined into a page they
+	// need the full path
+	return strings.ReplaceAll(string(fontCSS), "url('../fonts/", "url('"+ts.root+"/static/fonts/"), nil
+}
+
+// configuredFontCSS declares the font files uploaded to the project in
+// basePath and applies the configured fonts. It follows the themed styles so
+// its variables replace their defaults. Faces are left out of pages that
+// belong to no project, such as the sign-in page.
+func configuredFontCSS(config *models.Config, basePath, prefix string) string {
+	fonts := &config.UI.Fonts
+	if err := fonts.Validate(); err != nil {
+		slog.Warn("ignoring font settings", "error", err)
+		return ""
+	}
+
+	var css strings.Builder
+	if basePath != "" {
+		for _, face := range fonts.Faces {
+			if _, err := os.Stat(filepath.Join(basePath, "assets", "fonts", face.File)); err != nil {
+				continue
+			}
+			weight, style := face.Weight, face.Style
+			if weight == "" {
+				weight = "normal"
+			}
+			if style == "" {
+				style = "normal"
+			}
+			fmt.Fprintf(&css, "\n@font-face {\n    font-family: '%s';\n    src: url('%s/assets/fonts/%s') format('%s');\n    font-weight: %s;\n    font-style: %s;\n}\n",
+				face.Family, prefix, face.File, models.FontFormats[strings.ToLower(filepath.Ext(face.File))], weight, style)
+		}
+	}
+
+	if fonts.UI != "" || fonts.Code != "" {
+		css.WriteString("\n:root {\n")
+		if fonts.UI != "" {
+			fmt.Fprintf(&css, "    --ui-font: %s, 'space_monoregular', monospace;\n", fonts.UI)
+		}
+		if fonts.Code != "" {
+			fmt.Fprintf(&css, "    --code-font: %s, monospace;\n", fonts.Code)
+		}
+		css.WriteString("}\n")
+	}
+	return css.String()
+}
+
+// customFiles reads the custom stylesheet and script configured for the
+// project in folder. They are read on every render so edits show up on the
+// next page load; a file that cannot be read is left out.
+func (ts *TemplateService) customFiles(config *models.Config, folder string) (template.CSS, template.JS) {
+	cssPath, jsPath := config.UI.CustomFiles(folder)
+	return template.CSS(ts.readCustomFile(cssPath)), template.JS(ts.readCustomFile(jsPath))
+}
+
+// readCustomFile returns the contents of a custom file, or "" when path is
+// empty or unreadable
+func (ts *TemplateService) readCustomFile(path string) string {
+	if path == "" {
+		return ""
+	}
+	path = models.ExpandHome(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(ts.configDir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		slog.Warn("failed to read custom file", "path", path, "error", err)
+		return ""
+	}
+	return string(data)
 }
 
 // getThemedCSS returns the CSS with theme colors applied
 func (ts *TemplateService) getThemedCSS(colors map[string]string) (string, error) {
-	var cssTemplate []byte
-	var err error
-	
-	if ts.assets != nil {
-		cssTemplate, err = ts.assets.ReadFile("web/static/css/styles.css")
-	} else {
-		cssTemplate, err = os.ReadFile("web/static/css/styles.css")
-	}
-	
+	cssTemplate, err := fs.ReadFile(ts.assets, "static/css/styles.css")
 	if err != nil {
 		return "", err
 	}
@@ -149,23 +260,39 @@ func (ts *TemplateService) getThemedCSS(colors map[string]string) (string, error
 }
 
 // RenderGlobalTasks renders the global tasks page with theme styling
-func (ts *TemplateService) RenderGlobalTasks(config *models.Config, basePath string) (string, error) {
+func (ts *TemplateService) RenderGlobalTasks(config *models.Config, basePath, prefix string) (string, error) {
+	return ts.renderThemedPage("globaltasks", "templates/globaltasks.html", config, basePath, prefix)
+}
+
+// RenderGlobalNotes renders the global notes timeline with theme styling
+func (ts *TemplateService) RenderGlobalNotes(config *models.Config, basePath, prefix string) (string, error) {
+	return ts.renderThemedPage("globalnotes", "templates/globalnotes.html", config, basePath, prefix)
+}
+
+// RenderArchives renders the archived sites index page with theme styling
+func (ts *TemplateService) RenderArchives(config *models.Config, basePath, prefix string) (string, error) {
+	return ts.renderThemedPage("archives", "templates/archives.html", config, basePath, prefix)
+}
+
+// RenderCalendar renders the monthly calendar of notes and due tasks with
+// theme styling
+func (ts *TemplateService) RenderCalendar(config *models.Config, basePath, prefix string) (string, error) {
+	return ts.renderThemedPage("calendar", "templates/calendar.html", config, basePath, prefix)
+}
+
+// RenderLogin renders the sign-in page with theme styling
+func (ts *TemplateService) RenderLogin(config *models.Config) (string, error) {
+	return ts.renderThemedPage("login", "templates/login.html", config, "", ts.root)
+}
+
+// renderThemedPage renders a standalone page template with the themed CSS and
+// theme colors available as template data
+func (ts *TemplateService) renderThemedPage(name, templatePath string, config *models.Config, basePath, prefix string) (string, error) {
 	// Get current theme
-	theme := themes.AvailableThemes[config.Theme]
-	if theme == nil {
-		theme = themes.AvailableThemes["dark-orange"]
-	}
+	theme := themes.Current(config)
 
-	// Read global tasks template
-	var templateHTML []byte
-	var err error
-	
-	if ts.assets != nil {
-		templateHTML, err = ts.assets.ReadFile("web/templates/globaltasks.html")
-	} else {
-		templateHTML, err = os.ReadFile("web/templates/globaltasks.html")
-	}
-	
+	// Read page template
+	templateHTML, err := fs.ReadFile(ts.assets, templatePath)
 	if err != nil {
 		return "", err
 	}
@@ -176,10 +303,18 @@ func (ts *TemplateService) RenderGlobalTasks(config *models.Config, basePath str
 		return "", err
 	}
 
+	themedCSS += configuredFontCSS(config, basePath, prefix)
+	customCSS, customJS := ts.customFiles(config, basePath)
+
 	// Template data combining theme colors and CSS
 	data := map[string]interface{}{
 		"CSS":        template.CSS(themedCSS),
+		"CustomCSS":  customCSS,
+		"CustomJS":   customJS,
 		"WorkingDir": basePath,
+		"Lang":       i18n.Locale(),
+		"Root":       ts.root,
+		"Prefix":     prefix,
 	}
 
 	// Add theme colors to template data
@@ -188,7 +323,7 @@ func (ts *TemplateService) RenderGlobalTasks(config *models.Config, basePath str
 	}
 
 	// Parse and execute template
-	tmpl, err := template.New("globaltasks").Parse(string(templateHTML))
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(string(templateHTML))
 	if err != nil {
 		return "", err
 	}
@@ -199,4 +334,4 @@ func (ts *TemplateService) RenderGlobalTasks(config *models.Config, basePath str
 	}
 
 	return buf.String(), nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// ThumbnailSize is the longest side, in pixels, of the thumbnails shown in
+// note bodies
+const ThumbnailSize = 800
+
+// Thumbnail returns the path of a thumbnail of the image at imagePath,
+// cached at thumbPath and created again when the image is newer. Images
+// already within ThumbnailSize, and GIFs and other formats that aren't
+// scaled, are returned as they are.
+func Thumbnail(imagePath, thumbPath string) (string, error) {
+	imageInfo, err := os.Stat(imagePath)
+	if err != nil {
+		return "", err
+	}
+	if thumbInfo, err := os.Stat(thumbPath); err == nil && !thumbInfo.ModTime().Before(imageInfo.ModTime()) {
+		return thumbPath, nil
+	}
+
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return "", err
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "png" && format != "jpeg") {
+		return imagePath, nil
+	}
+	if config.Width <= ThumbnailSize && config.Height <= ThumbnailSize {
+		return imagePath, nil
+	}
+
+	img, err := decodeImage(data, format)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", imagePath, err)
+	}
+	width, height := fitWithin(img.Bounds().Dx(), img.Bounds().Dy(), ThumbnailSize)
+	thumb := scaleImage(img, width, height)
+
+	if err := os.MkdirAll(filepath.Dir(thumbPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create thumbnails directory: %w", err)
+	}
+	// Written aside and renamed, so concurrent requests never see half a file
+	tmp, err := os.CreateTemp(filepath.Dir(thumbPath), ".thumb-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if format == "jpeg" {
+		err = jpeg.Encode(tmp, thumb, &jpeg.Options{Quality: models.DefaultImageQuality})
+	} else {
+		err = png.Encode(tmp, thumb)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write thumbnail of %s: %w", imagePath, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), thumbPath); err != nil {
+		return "", err
+	}
+	return thumbPath, nil
+}
+
+// scaleImage shrinks src to width by height, averaging the source pixels
+// each thumbnail pixel covers
+func scaleImage(src image.Image, width, height int) *image.RGBA64 {
+	bounds := src.Bounds()
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,459 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// Todoist API settings
+const (
+	todoistAPIURL  = "https://api.todoist.com/api/v1"
+	todoistTimeout = 30 * time.Second
+	// todoistSettleDelay is how long a sync triggered by a checked-off task
+	// waits, so a burst of changes is sent in one sync
+	todoistSettleDelay = 2 * time.Second
+	// todoistStateFile records, under assets, the state of each linked task
+	// at the last sync
+	todoistStateFile = ".todoist.json"
+	// todoistNoteTitle is the default note for tasks added in Todoist
+	todoistNoteTitle = "Todoist"
+	// todoistUser is who the changes taken from Todoist are attributed to
+	todoistUser = "Todoist"
+)
+
+// errTodoistNotFound is returned for Todoist tasks that don't exist
+var errTodoistNotFound = errors.New("Todoist task not found")
+
+// TodoistSync keeps the tasks of the notes in step with a Todoist project.
+// Open tasks are added to Todoist and linked to the Todoist task with a
+// comment in their line; tasks added in Todoist are appended to a note.
+// Completing or reopening a task on either side is copied to the other.
+type TodoistSync struct {
+	config      models.TodoistConfig
+	noteManager *NoteManager
+	client      *http.Client
+	statePath   string
+
+	// synced holds each linked task's completion at the last sync, to tell
+	// which side changed it. It is only used by the sync loop.
+	synced map[string]bool
+
+	mu      sync.Mutex
+	status  models.TodoistStatus
+	trigger chan struct{}
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+}
+
+// todoistTask is a task as returned by the Todoist API
+type todoistTask struct {
+	ID        string `json:"id"`
+	Content   string `json:"content"`
+	Checked   bool   `json:"checked"`
+	IsDeleted bool   `json:"is_deleted"`
+}
+
+// todoistState is the content of the sync state file
+type todoistState struct {
+	Tasks map[string]bool `json:"tasks"`
+}
+
+// NewTodoistSync creates a sync of noteManager's tasks with the Todoist
+// project in config
+func NewTodoistSync(config models.TodoistConfig, noteManager *NoteManager) *TodoistSync {
+	ctx, cancel := context.WithCancel(context.Background())
+	if config.Conflict == "" {
+		config.Conflict = models.TodoistConflictCompleted
+	}
+	return &TodoistSync{
+		config:      config,
+		noteManager: noteManager,
+		client:      &http.Client{Timeout: todoistTimeout},
+		statePath:   filepath.Join(noteManager.GetBasePath(), "assets", todoistStateFile),
+		synced:      make(map[string]bool),
+		status:      models.TodoistStatus{ProjectID: config.ProjectID, Conflict: config.Conflict},
+		trigger:     make(chan struct{}, 1),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start syncs now and then every configured interval, and soon after tasks
+// are checked off, until Close is called
+func (ts *TodoistSync) Start() {
+	ts.loadState()
+
+	events := ts.noteManager.Events().Subscribe()
+	go func() {
+		for event := range events {
+			if change, ok := event.Data.(models.TaskChange); ok && event.Type == models.EventTaskToggled && change.User != todoistUser {
+				ts.SyncNow()
+			}
+		}
+	}()
+
+	ts.running.Add(1)
+	go func() {
+		defer ts.running.Done()
+		ts.run()
+	}()
+}
+
+// SyncNow asks for a sync without waiting for the next interval
+func (ts *TodoistSync) SyncNow() {
+	select {
+	case ts.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the state of the sync
+func (ts *TodoistSync) Status() models.TodoistStatus {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.status
+}
+
+// Close stops syncing and waits for a sync in progress
+func (ts *TodoistSync) Close() {
+	ts.cancel()
+	ts.running.Wait()
+}
+
+// run syncs on every tick and trigger until the sync is closed
+func (ts *TodoistSync) run() {
+	interval := ts.config.Interval()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ts.ctx.Done():
+			return
+		case <-timer.C:
+		case <-ts.trigger:
+			select {
+			case <-ts.ctx.Done():
+				return
+			case <-time.After(todoistSettleDelay):
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+		}
+
+		ts.syncOnce()
+		timer.Reset(interval)
+
+		next := time.Now().Add(interval)
+		ts.mu.Lock()
+		ts.status.NextSync = &next
+		ts.mu.Unlock()
+	}
+}
+
+// syncOnce runs a sync and records its outcome in the status
+func (ts *TodoistSync) syncOnce() {
+	ts.mu.Lock()
+	ts.status.Syncing = true
+	ts.mu.Unlock()
+
+	changes, err := ts.sync()
+	ts.saveState()
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.status.Syncing = false
+	ts.status.Linked = len(ts.synced)
+	if err != nil {
+		if ts.ctx.Err() == nil {
+			slog.Warn("Todoist sync failed", "error", err)
+			ts.status.LastError = err.Error()
+		}
+		return
+	}
+	now := time.Now()
+	ts.status.LastSync = &now
+	ts.status.LastError = ""
+	ts.status.LastChanges = changes
+}
+
+// sync copies the changes made since the last sync between the notes and
+// Todoist
+func (ts *TodoistSync) sync() (models.TodoistChanges, error) {
+	var changes models.TodoistChanges
+	active, err := ts.activeTasks()
+	if err != nil {
+		return changes, err
+	}
+	remote := make(map[string]todoistTask, len(active))
+	for _, task := range active {
+		remote[task.ID] = task
+	}
+
+	linked := make(map[string]bool)
+	for _, task := range ts.noteManager.GetAllTasks() {
+		id := models.TodoistTaskID(task.Text)
+		if id == "" {
+			if task.Checked {
+				continue
+			}
+			created, err := ts.createTask(models.TaskLabel(task.Text))
+			if err != nil {
+				return changes, err
+			}
+			if err := ts.noteManager.ReplaceTaskText(task.Index, task.Text, models.LinkTodoistTask(task.Text, created.ID), todoistUser); err != nil {
+				// The task was edited meanwhile; the next sync adds it as it is now
+				ts.call(http.MethodDelete, "/tasks/"+url.PathEscape(created.ID), nil, nil, nil)
+				continue
+			}
+			ts.synced[created.ID] = false
+			linked[created.ID] = true
+			changes.Exported++
+			continue
+		}
+		linked[id] = true
+
+		// Tasks missing from the active ones are completed or deleted
+		_, open := remote[id]
+		remoteChecked := !open
+		last, known := ts.synced[id]
+		if !open && (!task.Checked || !known) {
+			fetched, err := ts.getTask(id)
+			if errors.Is(err, errTodoistNotFound) || (err == nil && fetched.IsDeleted) {
+				// Deleted in Todoist, so done with; an open task would be
+				// added again by the next sync
+				if err := ts.noteManager.ReplaceTaskText(task.Index, task.Text, models.LinkTodoistTask(task.Text, ""), todoistUser); err == nil {
+					changes.Unlinked++
+					if !task.Checked && ts.noteManager.UpdateTask(task.Index, true, todoistUser) == nil {
+						changes.Pulled++
+					}
+				}
+				delete(ts.synced, id)
+				continue
+			}
+			if err != nil {
+				return changes, err
+			}
+			remoteChecked = fetched.Checked
+		}
+
+		checked := task.Checked
+		switch {
+		case task.Checked == remoteChecked:
+		case known && task.Checked != last:
+			// Changed in NoteFlow
+		case known:
+			checked = remoteChecked
+		default:
+			checked = ts.resolve(task.Checked, remoteChecked)
+			changes.Conflicts++
+		}
+
+		if checked != remoteChecked {
+			if err := ts.setRemoteChecked(id, checked); err != nil {
+				return changes, err
+			}
+			changes.Pushed++
+		}
+		if checked != task.Checked {
+			if err := ts.noteManager.UpdateTask(task.Index, checked, todoistUser); err != nil {
+				return changes, err
+			}
+			changes.Pulled++
+		}
+		ts.synced[id] = checked
+	}
+
+	var imported []string
+	for _, task := range active {
+		if linked[task.ID] {
+			continue
+		}
+		if _, known := ts.synced[task.ID]; known {
+			// Deleted from the notes, so done with
+			if err := ts.setRemoteChecked(task.ID, true); err != nil {
+				return changes, err
+			}
+			delete(ts.synced, task.ID)
+			changes.Pushed++
+			continue
+		}
+		line := "- [ ] " + strings.Join(strings.Fields(task.Content), " ")
+		imported = append(imported, models.LinkTodoistTask(line, task.ID))
+		ts.synced[task.ID] = false
+		changes.Imported++
+	}
+	if len(imported) > 0 {
+		title := ts.config.NoteTitle
+		if title == "" {
+			title = todoistNoteTitle
+		}
+		if _, _, err := ts.noteManager.AppendToNote(ts.ctx, title, strings.Join(imported, "\n"), todoistUser); err != nil {
+			return changes, err
+		}
+	}
+
+	// Tasks gone from both sides are forgotten
+	for id := range ts.synced {
+		if _, open := remote[id]; !open && !linked[id] {
+			delete(ts.synced, id)
+		}
+	}
+	return changes, nil
+}
+
+// resolve applies the conflict rule to a task checked on one side only
+func (ts *TodoistSync) resolve(local, remote bool) bool {
+	switch ts.config.Conflict {
+	case models.TodoistConflictNoteFlow:
+		return local
+	case models.TodoistConflictTodoist:
+		return remote
+	}
+	return local || remote
+}
+
+// activeTasks returns the open tasks of the project
+func (ts *TodoistSync) activeTasks() ([]todoistTask, error) {
+	var tasks []todoistTask
+	query := url.Values{"project_id": {ts.config.ProjectID}, "limit": {"200"}}
+	for {
+		var page struct {
+			Results    []todoistTask `json:"results"`
+			NextCursor string        `json:"next_cursor"`
+		}
+		if err := ts.call(http.MethodGet, "/tasks", query, nil, &page); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, page.Results...)
+		if page.NextCursor == "" {
+			return tasks, nil
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+}
+
+// getTask returns a task, whether open or not
+func (ts *TodoistSync) getTask(id string) (todoistTask, error) {
+	var task todoistTask
+	err := ts.call(http.MethodGet, "/tasks/"+url.PathEscape(id), nil, nil, &task)
+	return task, err
+}
+
+// createTask adds an open task to the project
+func (ts *TodoistSync) createTask(content string) (todoistTask, error) {
+	var task todoistTask
+	body := map[string]string{"content": content, "project_id": ts.config.ProjectID}
+	err := ts.call(http.MethodPost, "/tasks", nil, body, &task)
+	return task, err
+}
+
+// setRemoteChecked completes or reopens a task
+func (ts *TodoistSync) setRemoteChecked(id string, checked bool) error {
+	action := "/reopen"
+	if checked {
+		action = "/close"
+	}
+	err := ts.call(http.MethodPost, "/tasks/"+url.PathEscape(id)+action, nil, nil, nil)
+	if errors.Is(err, errTodoistNotFound) {
+		return nil
+	}
+	return err
+}
+
+// call sends a request to the Todoist API, decoding the response into result
+// if not nil
+func (ts *TodoistSync) call(method, path string, query url.Values, body, result interface{}) error {
+	apiURL := ts.config.APIURL
+	if apiURL == "" {
+		apiURL = todoistAPIURL
+	}
+	endpoint := strings.TrimRight(apiURL, "/") + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ts.ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+ts.config.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errTodoistNotFound
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("%s %s: Todoist responded %d", method, path, resp.StatusCode)
+	case result == nil || resp.StatusCode == http.StatusNoContent:
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("%s %s: unexpected response: %w", method, path, err)
+	}
+	return nil
+}
+
+// loadState reads the state of the linked tasks at the last sync
+func (ts *TodoistSync) loadState() {
+	data, err := os.ReadFile(ts.statePath)
+	if err != nil {
+		return
+	}
+	var state todoistState
+	if err := json.Unmarshal(data, &state); err != nil {
+		slog.Warn("ignoring unreadable Todoist sync state", "path", ts.statePath, "error", err)
+		return
+	}
+	if state.Tasks != nil {
+		ts.synced = state.Tasks
+	}
+}
+
+// saveState records the state of the linked tasks
+func (ts *TodoistSync) saveState() {
+	data, err := json.MarshalIndent(todoistState{Tasks: ts.synced}, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(ts.statePath), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(ts.statePath, data, 0644)
+	}
+	if err != nil {
+		slog.Warn("failed to save the Todoist sync state", "error", err)
+	}
+}
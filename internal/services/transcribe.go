@@ -0,0 +1,242 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// transcribeTimeout bounds how long transcribing one audio file may take;
+// speech-to-text on a CPU can take as long as the recording
+const transcribeTimeout = 30 * time.Minute
+
+// annotationPattern matches the bracketed notes on sounds other than speech
+// in transcripts, such as whisper's [BLANK_AUDIO] or [Music]. They are
+// removed, which also keeps "[ ]" in a transcript from becoming a task.
+var annotationPattern = regexp.MustCompile(`\[[^\]]*\]`)
+
+// Transcribe returns the speech in the audio file at filePath, using the
+// speech-to-text API or the command configured in uploads
+func Transcribe(uploads *models.UploadConfig, filePath string) (string, error) {
+	var text string
+	var err error
+	switch {
+	case uploads.TranscribeURL != "":
+		text, err = transcribeWithAPI(uploads, filePath)
+	case uploads.TranscribeCommand != "":
+		text, err = runTextCommand(uploads.TranscribeCommand, filePath, transcribeTimeout)
+	default:
+		return "", errors.New("no transcription configured")
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(annotationPattern.ReplaceAllString(text, " ")), " "), nil
+}
+
+// transcribeWithAPI sends the audio file at filePath as the file field of a
+// form to the configured API, with the model if set, and returns the text
+// field of its JSON response. OpenAI's audio transcriptions and whisper.cpp's
+// server both work this way.
+func transcribeWithAPI(uploads *models.UploadConfig, filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(filePath))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return "", err
+	}
+	if uploads.TranscribeModel != "" {
+		form.WriteField("model", uploads.TranscribeModel)
+	}
+	form.WriteField("response_format", "json")
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, uploads.TranscribeURL, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if uploads.TranscribeAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+uploads.TranscribeAPIKey)
+	}
+
+	client := &http.Client{Timeout: transcribeTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("transcription API responded %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("transcription API: unexpected response: %w", err)
+	}
+	return result.Text, nil
+}
+
+// transcribes reports whether uploaded audio is transcribed
+func (nm *NoteManager) transcribes() bool {
+	uploads := nm.uploadConfig()
+	return uploads != nil && (uploads.TranscribeURL != "" || uploads.TranscribeCommand != "")
+}
+
+// transcribeAudio transcribes the audio file name, uploaded to the assets
+// subdirectory dir, saves the transcript with the text read from uploads and
+// appends it to the notes linking to the file. Files are transcribed one at
+// a time.
+func (nm *NoteManager) transcribeAudio(dir, name string) {
+	nm.transcribing.Lock()
+	defer nm.transcribing.Unlock()
+
+	// A note saved meanwhile may have taken the file into its own folder
+	asset := nm.findAudio(dir, name)
+	text, err := Transcribe(nm.uploadConfig(), filepath.Join(nm.storage.BasePath, "assets", filepath.FromSlash(asset)))
+	if err != nil {
+		slog.Warn("failed to transcribe audio", "file", asset, "error", err)
+		return
+	}
+	if text == "" {
+		slog.Warn("no speech found in audio", "file", asset)
+		return
+	}
+	asset = nm.findAudio(dir, name)
+	nm.saveAssetText(path.Dir(asset), name, text)
+
+	nm.lockNotes()
+	defer nm.unlockNotes()
+
+	var changed []int
+	for i, note := range nm.notes {
+		if nm.withTranscripts(note.Content, nil) == note.Content {
+			continue
+		}
+		if nm.appendTranscripts(nm.editNote(i), nil) {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+	nm.needsSave = true
+	if err := nm.save(); err != nil {
+		slog.Warn("failed to save transcript", "file", asset, "error", err)
+		return
+	}
+	for _, index := range changed {
+		nm.events.Publish(models.EventNoteUpdated, models.NoteChange{Index: index, Title: nm.notes[index].Title})
+	}
+}
+
+// findAudio returns the path under assets of the audio file name uploaded to
+// the subdirectory dir: dir/name, or the path in the asset folder of a note
+// linking to it
+func (nm *NoteManager) findAudio(dir, name string) string {
+	shared := path.Join(dir, name)
+	if _, err := os.Stat(filepath.Join(nm.storage.BasePath, "assets", filepath.FromSlash(shared))); err == nil {
+		return shared
+	}
+
+	for _, note := range nm.snapshot() {
+		for _, asset := range linkedAudio(note.Content) {
+			if path.Base(asset) == name {
+				return asset
+			}
+		}
+	}
+	return shared
+}
+
+// linkedAudio returns the paths under assets of the audio files content
+// links to
+func linkedAudio(content string) []string {
+	var assets []string
+	for _, matches := range noteAssetPattern.FindAllStringSubmatch(content, -1) {
+		asset, err := url.PathUnescape(matches[1])
+		if err != nil || asset != path.Clean(asset) || path.Base(path.Dir(asset)) != models.AssetAudio {
+			continue
+		}
+		assets = append(assets, asset)
+	}
+	return assets
+}
+
+// transcriptMarker is the comment above the transcript of the audio file
+// name appended to a note
+func transcriptMarker(name string) string {
+	return "<!-- transcript: " + name + " -->"
+}
+
+// appendTranscripts appends to note the saved transcripts of the audio files
+// it links to, other than those named in skip, once each. The note's tasks
+// keep their indices. Called with lockNotes held, on a note being edited.
+func (nm *NoteManager) appendTranscripts(note *models.Note, skip map[string]bool) bool {
+	content := nm.withTranscripts(note.Content, skip)
+	if content == note.Content {
+		return false
+	}
+
+	note.Update(note.Title, content)
+	return true
+}
+
+// withTranscripts returns content with the saved transcripts of the audio
+// files it links to appended, other than those named in skip and those
+// already there
+func (nm *NoteManager) withTranscripts(content string, skip map[string]bool) string {
+	if !nm.transcribes() {
+		return content
+	}
+
+	for _, asset := range linkedAudio(content) {
+		name := path.Base(asset)
+		if skip[name] || strings.Contains(content, transcriptMarker(name)) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(nm.storage.BasePath, "assets", textDir, filepath.FromSlash(asset)+".txt"))
+		if err != nil {
+			continue
+		}
+		content = strings.TrimRight(content, "\n") + "\n\n" + transcriptMarker(name) + "\n> " + strings.TrimSpace(string(data))
+	}
+	return content
+}
+
+// audioNames returns the names of the audio files content links to, for
+// appendTranscripts to skip those a note linked to before an edit
+func audioNames(content string) map[string]bool {
+	names := make(map[string]bool)
+	for _, asset := range linkedAudio(content) {
+		names[path.Base(asset)] = true
+	}
+	return names
+}
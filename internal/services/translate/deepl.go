@@ -0,0 +1,54 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// DeepL's API addresses; keys of free accounts end in ":fx" and work only
+// with the free API
+const (
+	deepLURL     = "https://api.deepl.com"
+	deepLFreeURL = "https://api-free.deepl.com"
+)
+
+// deepL uses DeepL's translate API
+type deepL struct {
+	config models.TranslationConfig
+	client *http.Client
+}
+
+// Translate implements Translator
+func (t *deepL) Translate(ctx context.Context, text, target string) (string, error) {
+	baseURL := t.config.URL
+	if baseURL == "" {
+		baseURL = deepLURL
+		if strings.HasSuffix(t.config.APIKey, ":fx") {
+			baseURL = deepLFreeURL
+		}
+	}
+
+	request := map[string]interface{}{
+		"text":        []string{text},
+		"target_lang": strings.ToUpper(target),
+		// Keeps line breaks, which markdown depends on
+		"preserve_formatting": true,
+	}
+	var response struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	headers := map[string]string{"Authorization": "DeepL-Auth-Key " + t.config.APIKey}
+	if err := post(ctx, t.client, strings.TrimRight(baseURL, "/")+"/v2/translate", headers, request, &response); err != nil {
+		return "", err
+	}
+	if len(response.Translations) == 0 {
+		return "", errors.New("DeepL returned no translation")
+	}
+	return response.Translations[0].Text, nil
+}
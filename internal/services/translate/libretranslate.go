@@ -0,0 +1,43 @@
+package translate
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// libreTranslateURL is the default address of a local LibreTranslate server
+const libreTranslateURL = "http://localhost:5000"
+
+// libreTranslate uses the translate API of a LibreTranslate server
+type libreTranslate struct {
+	config models.TranslationConfig
+	client *http.Client
+}
+
+// Translate implements Translator
+func (t *libreTranslate) Translate(ctx context.Context, text, target string) (string, error) {
+	baseURL := t.config.URL
+	if baseURL == "" {
+		baseURL = libreTranslateURL
+	}
+
+	request := map[string]interface{}{
+		"q":      text,
+		"source": "auto",
+		"target": target,
+		"format": "text",
+	}
+	if t.config.APIKey != "" {
+		request["api_key"] = t.config.APIKey
+	}
+	var response struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	if err := post(ctx, t.client, strings.TrimRight(baseURL, "/")+"/translate", nil, request, &response); err != nil {
+		return "", err
+	}
+	return response.TranslatedText, nil
+}
@@ -0,0 +1,21 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/darren/noteflow-go/internal/services/ai"
+)
+
+// modelPrompt is the instruction given with the text to translate
+const modelPrompt = "Translate the following markdown to the language with the code %s. Keep the markdown formatting, links, #tags and anything in code blocks unchanged. Reply with the translation only."
+
+// model translates with a language model
+type model struct {
+	provider ai.Provider
+}
+
+// Translate implements Translator
+func (t *model) Translate(ctx context.Context, text, target string) (string, error) {
+	return t.provider.Complete(ctx, fmt.Sprintf(modelPrompt, target), text)
+}
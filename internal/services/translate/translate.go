@@ -0,0 +1,114 @@
+// Package translate translates notes. A Translator wraps one kind of service:
+// the language model configured for AI features, DeepL's API or a
+// LibreTranslate server.
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services/ai"
+)
+
+// timeout limits a translation by DeepL or LibreTranslate; the language
+// model has its own
+const timeout = 120 * time.Second
+
+// ErrNotConfigured is returned when no translation provider is configured
+var ErrNotConfigured = errors.New("no translation provider is configured")
+
+// Translator translates markdown text
+type Translator interface {
+	// Translate returns text in the language with the code target
+	Translate(ctx context.Context, text, target string) (string, error)
+}
+
+// New returns the translator selected by config, using the language model in
+// aiConfig for the "ai" provider
+func New(config models.TranslationConfig, aiConfig models.AIConfig) (Translator, error) {
+	client := &http.Client{Timeout: timeout}
+
+	switch config.Provider {
+	case "":
+		return nil, ErrNotConfigured
+	case models.TranslationProviderAI:
+		provider, err := ai.New(aiConfig)
+		if err != nil {
+			return nil, err
+		}
+		return &model{provider: provider}, nil
+	case models.TranslationProviderDeepL:
+		return &deepL{config: config, client: client}, nil
+	case models.TranslationProviderLibreTranslate:
+		return &libreTranslate{config: config, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown translation provider %q", config.Provider)
+	}
+}
+
+// Note translates a note's title and content to the language target. The
+// title is translated as the first line of the text, so the service sees it
+// in context.
+func Note(ctx context.Context, translator Translator, title, content, target string) (string, string, error) {
+	text := content
+	if title != "" {
+		text = "# " + title + "\n\n" + content
+	}
+	translated, err := translator.Translate(ctx, text, target)
+	if err != nil {
+		return "", "", err
+	}
+	translated = strings.TrimSpace(translated)
+	if translated == "" {
+		return "", "", errors.New("the translation is empty")
+	}
+	if title == "" {
+		return "", translated, nil
+	}
+
+	first, rest, _ := strings.Cut(translated, "\n")
+	if !strings.HasPrefix(first, "# ") {
+		// The heading was lost; keep the original title
+		return title, translated, nil
+	}
+	return strings.TrimSpace(strings.TrimPrefix(first, "# ")), strings.TrimSpace(rest), nil
+}
+
+// post sends body as JSON to endpoint with the given headers and decodes the
+// response into result
+func post(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, body, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded %d", endpoint, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("%s: unexpected response: %w", endpoint, err)
+	}
+	return nil
+}
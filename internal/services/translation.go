@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// translationBlockPattern matches the blocks at the end of a note holding its
+// translations, capturing the language
+var translationBlockPattern = regexp.MustCompile(`(?s)\n*<!-- translation: (\S+) -->\n.*?\n<!-- /translation -->`)
+
+// translatedNotePattern matches the comment starting a note that holds the
+// translation of another
+var translatedNotePattern = regexp.MustCompile(`^<!-- translation of: \S+ \S+ -->\n*`)
+
+// TranslationSource returns the text of a note's content to translate:
+// without its translation blocks, or the comment linking a translated note to
+// its original
+func TranslationSource(content string) string {
+	content = translationBlockPattern.ReplaceAllString(content, "")
+	return strings.TrimSpace(translatedNotePattern.ReplaceAllString(content, ""))
+}
+
+// translatedNoteMarker starts the content of the note holding the
+// translation to language of the note with the given asset folder, which
+// names it by its timestamp
+func translatedNoteMarker(folder, language string) string {
+	return "<!-- translation of: " + folder + " " + language + " -->"
+}
+
+// listCheckboxPattern matches the checkboxes of list items
+var listCheckboxPattern = regexp.MustCompile(`(?m)^(\s*(?:[-*+]|\d+[.)])\s+)\[([ xX])\]`)
+
+// disarmTasks turns the checkboxes in a translation into symbols, so the
+// original's tasks aren't added again
+func disarmTasks(text string) string {
+	return listCheckboxPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if strings.HasSuffix(match, "[ ]") {
+			return strings.TrimSuffix(match, "[ ]") + "☐"
+		}
+		return match[:len(match)-3] + "☑"
+	})
+}
+
+// SaveTranslation stores the translation to language of the note at index,
+// replacing an earlier one to that language. With the block store the text
+// is kept in a block at the end of the note; with the note store it becomes
+// a note of its own titled title, linked to the original by a comment.
+func (nm *NoteManager) SaveTranslation(index int, language, store, title, text, user string) (*models.Translation, error) {
+	nm.lockNotes()
+	defer nm.unlockNotes()
+
+	if index < 0 || index >= len(nm.notes) {
+		return nil, fmt.Errorf("note index %d out of range", index)
+	}
+	original := nm.notes[index]
+	text = disarmTasks(strings.TrimSpace(text))
+
+	if store == models.TranslationStoreNote {
+		return nm.saveTranslatedNote(original, language, title, text, user)
+	}
+	original = nm.editNote(index)
+
+	// Blocks for other languages stay where they are
+	content := original.Content
+	for _, match := range translationBlockPattern.FindAllStringSubmatchIndex(content, -1) {
+		if content[match[2]:match[3]] == language {
+			content = content[:match[0]] + content[match[1]:]
+			break
+		}
+	}
+	content = strings.TrimRight(content, "\n") + "\n\n<!-- translation: " + language + " -->\n" + text + "\n<!-- /translation -->"
+	original.Update(original.Title, content)
+	if user != "" {
+		original.EditedBy = user
+	}
+	nm.assignTaskIndices()
+
+	nm.needsSave = true
+	if err := nm.save(); err != nil {
+		return nil, err
+	}
+	nm.events.Publish(models.EventNoteUpdated, models.NoteChange{Index: index, Title: original.Title, User: user})
+	return &models.Translation{Index: index, Language: language, Store: store, Text: text}, nil
+}
+
+// saveTranslatedNote stores a translation of original as a note of its own,
+// updating the note that holds an earlier one. Called with lockNotes held.
+func (nm *NoteManager) saveTranslatedNote(original *models.Note, language, title, text, user string) (*models.Translation, error) {
+	marker := translatedNoteMarker(original.AssetFolder(), language)
+	content := marker + "\n" + text
+
+	for i, note := range nm.notes {
+		if !strings.HasPrefix(note.Content, marker) {
+			continue
+		}
+		note = nm.editNote(i)
+		note.Update(title, content)
+		if user != "" {
+			note.EditedBy = user
+		}
+		nm.assignTaskIndices()
+
+		nm.needsSave = true
+		if err := nm.save(); err != nil {
+			return nil, err
+		}
+		nm.events.Publish(models.EventNoteUpdated, models.NoteChange{Index: i, Title: title, User: user})
+		return &models.Translation{Index: i, Language: language, Store: models.TranslationStoreNote, Text: text}, nil
+	}
+
+	if _, err := nm.addNote(title, content, user); err != nil {
+		return nil, err
+	}
+	return &models.Translation{Index: 0, Language: language, Store: models.TranslationStoreNote, Text: text}, nil
+}
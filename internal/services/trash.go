@@ -0,0 +1,283 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// trashFile keeps, under assets, the deleted notes until they are restored
+// or purged
+const trashFile = ".trash.json"
+
+// trashPurgeInterval is how often a serving manager purges expired notes
+// from the trash
+const trashPurgeInterval = time.Hour
+
+// ErrNotInTrash is returned for a trash ID that names no deleted note
+var ErrNotInTrash = errors.New("note not found in trash")
+
+// trashPath returns the path of the project's trash file
+func (nm *NoteManager) trashPath() string {
+	return filepath.Join(nm.storage.BasePath, "assets", trashFile)
+}
+
+// readTrash returns the notes in the trash. Called with lockNotes held.
+func (nm *NoteManager) readTrash() ([]models.TrashedNote, error) {
+	data, err := os.ReadFile(nm.trashPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the trash: %w", err)
+	}
+
+	var trashed []models.TrashedNote
+	if err := json.Unmarshal(data, &trashed); err != nil {
+		return nil, fmt.Errorf("failed to read the trash: %w", err)
+	}
+	return trashed, nil
+}
+
+// writeTrash replaces the notes in the trash. Called with lockNotes held.
+func (nm *NoteManager) writeTrash(trashed []models.TrashedNote) error {
+	if trashed == nil {
+		trashed = []models.TrashedNote{}
+	}
+	data, err := json.MarshalIndent(trashed, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(nm.trashPath()), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(nm.trashPath(), data, 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the trash: %w", err)
+	}
+	return nil
+}
+
+// trashNote adds a note being deleted by user to the trash. Called with
+// lockNotes held.
+func (nm *NoteManager) trashNote(note *models.Note, user string) error {
+	trashed, err := nm.readTrash()
+	if err != nil {
+		return err
+	}
+
+	now := models.Now()
+	trashed = append(trashed, models.TrashedNote{
+		ID:        strconv.FormatInt(now.UnixNano(), 36),
+		Text:      note.Render(),
+		DeletedAt: now,
+		DeletedBy: user,
+	})
+	return nm.writeTrash(trashed)
+}
+
+// Trash lists the notes in the trash, most recently deleted first. Notes
+// kept longer than configured are purged first.
+func (nm *NoteManager) Trash() ([]models.TrashItem, error) {
+	nm.lockNotes()
+	defer nm.unlockNotes()
+
+	trashed, err := nm.purgeExpired()
+	if err != nil {
+		return nil, err
+	}
+
+	retention := nm.config.Current().Trash.Retention()
+	items := make([]models.TrashItem, 0, len(trashed))
+	for _, entry := range trashed {
+		note, err := models.NewNoteFromText(entry.Text)
+		if err != nil {
+			continue
+		}
+		items = append(items, models.TrashItem{
+			ID:        entry.ID,
+			Title:     note.Title,
+			Content:   note.Content,
+			Timestamp: note.Timestamp,
+			DeletedAt: entry.DeletedAt,
+			DeletedBy: entry.DeletedBy,
+			PurgeAt:   entry.DeletedAt.Add(retention),
+		})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// RestoreNote moves the note with the given trash ID back among the notes,
+// where its time puts it, attributing the restore to user if not empty. It
+// returns the note's index.
+func (nm *NoteManager) RestoreNote(id, user string) (int, error) {
+	nm.lockNotes()
+	defer nm.unlockNotes()
+
+	if nm.closed {
+		return 0, ErrNoteManagerClosed
+	}
+
+	trashed, err := nm.readTrash()
+	if err != nil {
+		return 0, err
+	}
+	at := trashIndex(trashed, id)
+	if at < 0 {
+		return 0, ErrNotInTrash
+	}
+	note, err := models.NewNoteFromText(trashed[at].Text)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the deleted note: %w", err)
+	}
+	if err := nm.writeTrash(append(trashed[:at:at], trashed[at+1:]...)); err != nil {
+		return 0, err
+	}
+
+	// Notes are kept newest first
+	index := len(nm.notes)
+	for i, other := range nm.notes {
+		if other.Timestamp.Before(note.Timestamp) {
+			index = i
+			break
+		}
+	}
+	nm.notes = append(nm.notes[:index], append([]*models.Note{note}, nm.notes[index:]...)...)
+	nm.edited[note] = true
+	nm.assignTaskIndices()
+
+	nm.needsSave = true
+	if err := nm.save(); err != nil {
+		return 0, err
+	}
+
+	nm.events.Publish(models.EventNoteRestored, models.NoteChange{Index: index, Title: note.Title, User: user})
+	return index, nil
+}
+
+// PurgeNote deletes the note with the given trash ID, and its uploads, for
+// good
+func (nm *NoteManager) PurgeNote(id string) error {
+	nm.lockNotes()
+	defer nm.unlockNotes()
+
+	trashed, err := nm.readTrash()
+	if err != nil {
+		return err
+	}
+	at := trashIndex(trashed, id)
+	if at < 0 {
+		return ErrNotInTrash
+	}
+
+	purged := trashed[at]
+	kept := append(trashed[:at:at], trashed[at+1:]...)
+	if err := nm.writeTrash(kept); err != nil {
+		return err
+	}
+	nm.removeTrashedAssets([]models.TrashedNote{purged}, kept)
+	return nil
+}
+
+// purgeExpired deletes the notes kept in the trash longer than configured,
+// and their uploads, and returns the rest. Called with lockNotes held.
+func (nm *NoteManager) purgeExpired() ([]models.TrashedNote, error) {
+	trashed, err := nm.readTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-nm.config.Current().Trash.Retention())
+	var kept, expired []models.TrashedNote
+	for _, entry := range trashed {
+		if entry.DeletedAt.Before(cutoff) {
+			expired = append(expired, entry)
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+	if len(expired) == 0 {
+		return trashed, nil
+	}
+
+	if err := nm.writeTrash(kept); err != nil {
+		return nil, err
+	}
+	nm.removeTrashedAssets(expired, kept)
+	slog.Info("purged deleted notes from the trash", "path", nm.trashPath(), "notes", len(expired))
+	return kept, nil
+}
+
+// purgeTrashRegularly purges the expired notes from the trash in the
+// background, once the notes are loaded and then every trashPurgeInterval
+// until the manager is closed
+func (nm *NoteManager) purgeTrashRegularly() {
+	nm.background.Add(1)
+	go func() {
+		defer nm.background.Done()
+
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			nm.lockNotes()
+			if _, err := nm.purgeExpired(); err != nil {
+				slog.Warn("failed to purge the trash", "error", err)
+			}
+			nm.unlockNotes()
+
+			select {
+			case <-nm.trashStop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// removeTrashedAssets deletes the uploads and earlier versions of notes
+// purged from the trash, unless a note still in the trash shares them.
+// Called with lockNotes held.
+func (nm *NoteManager) removeTrashedAssets(purged, kept []models.TrashedNote) {
+	folders := make(map[string]bool)
+	ids := make(map[string]bool)
+	for _, entry := range kept {
+		if note, err := models.NewNoteFromText(entry.Text); err == nil {
+			folders[note.AssetFolder()] = true
+			ids[note.ID] = true
+		}
+	}
+	for _, entry := range purged {
+		note, err := models.NewNoteFromText(entry.Text)
+		if err != nil {
+			continue
+		}
+		if !ids[note.ID] {
+			nm.removeVersions(note)
+		}
+		if !folders[note.AssetFolder()] {
+			nm.removeNoteAssets(note)
+		}
+	}
+}
+
+// trashIndex returns the position of the note with the given ID in trashed,
+// or -1
+func trashIndex(trashed []models.TrashedNote, id string) int {
+	for i, entry := range trashed {
+		if entry.ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// ErrUploadContent is returned for uploads whose data isn't what their
+// name or content type claims, such as HTML or a program named as an image
+var ErrUploadContent = errors.New("upload content does not match its type")
+
+// executableSignatures start Linux and macOS programs, which are never
+// accepted whatever they are named; Windows programs are found by
+// isWindowsProgram
+var executableSignatures = [][]byte{
+	[]byte("\x7fELF"),
+	{0xFE, 0xED, 0xFA, 0xCE},
+	{0xFE, 0xED, 0xFA, 0xCF},
+	{0xCE, 0xFA, 0xED, 0xFE},
+	{0xCF, 0xFA, 0xED, 0xFE},
+	{0xCA, 0xFE, 0xBA, 0xBE},
+}
+
+// documentSignatures start the files of types that have one, beyond the
+// images, audio and video recognised by sniffContentType
+var documentSignatures = map[string][]byte{
+	"application/pdf":    []byte("%PDF-"),
+	"application/zip":    []byte("PK\x03\x04"),
+	"application/gzip":   {0x1F, 0x8B},
+	"application/x-gzip": {0x1F, 0x8B},
+	"application/msword": {0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1},
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": []byte("PK\x03\x04"),
+}
+
+// VerifyUpload checks that the data of an upload with extension ext and
+// content type contentType is of the type both claim, since assets are
+// served from the same origin as the app. SVG images are returned with
+// scripts and event handlers removed; other data is returned unchanged.
+func VerifyUpload(data []byte, ext, contentType string) ([]byte, error) {
+	if isProgram(data) {
+		return nil, fmt.Errorf("%w: programs can't be uploaded", ErrUploadContent)
+	}
+
+	claimed := []string{mediaTypeOf(contentType)}
+	if byExtension := mediaTypeOf(extensionType(ext)); byExtension != "" && byExtension != claimed[0] {
+		claimed = append(claimed, byExtension)
+	}
+
+	sniffed := sniffContentType(data)
+	for _, claim := range claimed {
+		if claim == "image/svg+xml" {
+			sanitized, err := SanitizeSVG(data)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrUploadContent, err)
+			}
+			data = sanitized
+			continue
+		}
+		if !contentMatches(claim, sniffed, data) {
+			return nil, fmt.Errorf("%w: %s data uploaded as %s", ErrUploadContent, sniffed, claim)
+		}
+	}
+	return data, nil
+}
+
+// isProgram reports whether data is a Windows, Linux or macOS program
+func isProgram(data []byte) bool {
+	for _, signature := range executableSignatures {
+		if bytes.HasPrefix(data, signature) {
+			return true
+		}
+	}
+	// Windows programs start with an MS-DOS header giving the offset of
+	// their PE header
+	if len(data) >= 64 && bytes.HasPrefix(data, []byte("MZ")) {
+		offset := int(binary.LittleEndian.Uint32(data[60:64]))
+		return offset >= 0 && offset+4 <= len(data) && string(data[offset:offset+4]) == "PE\x00\x00"
+	}
+	return false
+}
+
+// mediaTypeOf returns contentType without parameters, in lower case
+func mediaTypeOf(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mediaType
+}
+
+// extensionType returns the content type of files with extension ext
+func extensionType(ext string) string {
+	if contentType := models.MediaType(ext); contentType != "" {
+		return contentType
+	}
+	return mime.TypeByExtension(strings.ToLower(ext))
+}
+
+// contentMatches reports whether data, sniffed as the type sniffed, can be
+// of the claimed type. Images, audio, video and documents must start with
+// their signature; text and other types only need not be a program.
+func contentMatches(claimed, sniffed string, data []byte) bool {
+	kind, _, _ := strings.Cut(claimed, "/")
+	switch kind {
+	case "image":
+		return strings.HasPrefix(sniffed, "image/") && sniffed != "image/svg+xml"
+	case "audio", "video":
+		return strings.HasPrefix(sniffed, "audio/") || strings.HasPrefix(sniffed, "video/") ||
+			sniffed == "application/ogg"
+	}
+	if signature, ok := documentSignatures[claimed]; ok {
+		return bytes.HasPrefix(data, signature)
+	}
+	if claimed == "application/x-tar" {
+		return len(data) > 262 && string(data[257:262]) == "ustar"
+	}
+	return true
+}
+
+// sniffContentType returns the content type of data from its first bytes,
+// recognising the HEIC images, QuickTime and MP4 files, and MP3 files
+// without ID3 tags that http.DetectContentType doesn't
+func sniffContentType(data []byte) string {
+	if len(data) >= 12 && string(data[4:8]) == "ftyp" {
+		switch string(data[8:12]) {
+		case "heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1":
+			return "image/heic"
+		case "qt  ":
+			return "video/quicktime"
+		case "M4A ", "M4B ":
+			return "audio/mp4"
+		}
+		return "video/mp4"
+	}
+	sniffed := mediaTypeOf(http.DetectContentType(data))
+	if sniffed == "application/octet-stream" && len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0 {
+		return "audio/mpeg"
+	}
+	return sniffed
+}
+
+// svgUnsafeElements are removed from SVG images along with their content
+var svgUnsafeElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"iframe":        true,
+	"embed":         true,
+	"object":        true,
+	"handler":       true,
+	"listener":      true,
+}
+
+// SanitizeSVG returns an SVG image without scripts, event handler
+// attributes, embedded HTML, links to javascript: URLs, and animations that
+// could set them. Data whose root element isn't svg is rejected.
+func SanitizeSVG(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	var out bytes.Buffer
+	depth, skipping, root := 0, 0, false
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid SVG image: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if !root {
+				if !strings.EqualFold(t.Name.Local, "svg") {
+					return nil, errors.New("not an SVG image")
+				}
+				root = true
+			}
+			if skipping > 0 || svgUnsafeElement(t) {
+				skipping++
+				continue
+			}
+			out.WriteString("<" + qualifiedName(t.Name))
+			for _, attr := range t.Attr {
+				if svgUnsafeAttr(attr) {
+					continue
+				}
+				out.WriteString(" " + qualifiedName(attr.Name) + `="`)
+				xml.EscapeText(&out, []byte(attr.Value))
+				out.WriteString(`"`)
+			}
+			out.WriteString(">")
+		case xml.EndElement:
+			depth--
+			if skipping > 0 {
+				skipping--
+				continue
+			}
+			out.WriteString("</" + qualifiedName(t.Name) + ">")
+		case xml.CharData:
+			if skipping == 0 && depth > 0 {
+				xml.EscapeText(&out, t)
+			}
+		case xml.ProcInst:
+			if t.Target == "xml" && out.Len() == 0 {
+				out.WriteString("<?xml " + string(t.Inst) + "?>\n")
+			}
+		}
+		// Comments and DOCTYPE declarations, which can define entities, are
+		// dropped
+	}
+	if !root {
+		return nil, errors.New("not an SVG image")
+	}
+	return out.Bytes(), nil
+}
+
+// qualifiedName returns an unresolved XML name with its prefix
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// svgUnsafeElement reports whether an SVG element can run scripts, or is an
+// animation that changes a link, which could point it at a script
+func svgUnsafeElement(element xml.StartElement) bool {
+	name := strings.ToLower(element.Name.Local)
+	if svgUnsafeElements[name] {
+		return true
+	}
+	if name == "set" || name == "animate" {
+		for _, attr := range element.Attr {
+			if strings.EqualFold(attr.Name.Local, "attributeName") && strings.HasSuffix(strings.ToLower(attr.Value), "href") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// svgUnsafeAttr reports whether an SVG attribute is an event handler, or a
+// link or style that can run a script
+func svgUnsafeAttr(attr xml.Attr) bool {
+	name := strings.ToLower(attr.Name.Local)
+	if strings.HasPrefix(name, "on") {
+		return true
+	}
+	value := strings.ToLower(strings.Join(strings.Fields(attr.Value), ""))
+	if name == "href" || name == "src" || name == "action" || name == "formaction" {
+		return !safeSVGLink(value)
+	}
+	return strings.Contains(value, "javascript:") || strings.Contains(value, "vbscript:")
+}
+
+// safeSVGLink reports whether a link in an SVG image is to a fragment, a
+// web page or an embedded raster image
+func safeSVGLink(value string) bool {
+	scheme, _, found := strings.Cut(value, ":")
+	if !found || strings.ContainsAny(scheme, "/?#") {
+		return true
+	}
+	switch scheme {
+	case "http", "https":
+		return true
+	case "data":
+		for _, prefix := range []string{"data:image/png", "data:image/jpeg", "data:image/gif", "data:image/webp"} {
+			if strings.HasPrefix(value, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
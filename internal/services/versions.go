@@ -0,0 +1,204 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// versionsDir is the subdirectory of assets the notes' earlier versions are
+// kept in, a file for each note named after its ID
+const versionsDir = "versions"
+
+// maxNoteVersions is how many earlier versions are kept for each note; the
+// oldest go first
+const maxNoteVersions = 50
+
+// ErrVersionNotFound is returned for a version ID that names no earlier
+// version of the note
+var ErrVersionNotFound = errors.New("note version not found")
+
+// versionsPath returns the path of the file keeping the versions of the note
+// with ID id
+func (nm *NoteManager) versionsPath(id string) string {
+	return filepath.Join(nm.storage.BasePath, "assets", versionsDir, id+".json")
+}
+
+// readVersions returns the note's earlier versions, oldest first. A note
+// without an ID has none.
+func (nm *NoteManager) readVersions(note *models.Note) ([]models.NoteVersion, error) {
+	if note.ID == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(nm.versionsPath(note.ID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the note's versions: %w", err)
+	}
+
+	var versions []models.NoteVersion
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("failed to read the note's versions: %w", err)
+	}
+	return versions, nil
+}
+
+// recordVersion keeps the note's title and content as an earlier version
+// before user replaces them, giving the note an ID if it has none. Called
+// with lockNotes held, on a note being edited.
+func (nm *NoteManager) recordVersion(note *models.Note, user string) error {
+	if note.ID == "" {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return fmt.Errorf("failed to generate note ID: %w", err)
+		}
+		note.ID = hex.EncodeToString(id)
+	}
+	versions, err := nm.readVersions(note)
+	if err != nil {
+		return err
+	}
+
+	editedBy := note.EditedBy
+	if editedBy == "" {
+		editedBy = note.Author
+	}
+	now := models.Now()
+	versions = append(versions, models.NoteVersion{
+		ID:         strconv.FormatInt(now.UnixNano(), 36),
+		Title:      note.Title,
+		Content:    note.Content,
+		EditedBy:   editedBy,
+		ReplacedAt: now,
+		ReplacedBy: user,
+	})
+	if len(versions) > maxNoteVersions {
+		versions = versions[len(versions)-maxNoteVersions:]
+	}
+
+	// The file is replaced whole, so readers never see half of it
+	path := nm.versionsPath(note.ID)
+	data, err := json.MarshalIndent(versions, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(path+".tmp", data, 0644)
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the note's versions: %w", err)
+	}
+	return nil
+}
+
+// keepVersion records the note's title and content before user replaces
+// them with title and content, unless they are the same. Failing to is
+// logged rather than stopping the edit. Called with lockNotes held, on a
+// note being edited.
+func (nm *NoteManager) keepVersion(note *models.Note, title, content, user string) {
+	if note.Title == title && note.Content == content {
+		return
+	}
+	if err := nm.recordVersion(note, user); err != nil {
+		slog.Warn("failed to keep the note's earlier version", "note", note.AssetFolder(), "error", err)
+	}
+}
+
+// Versions lists the earlier versions of the note at index, newest first
+func (nm *NoteManager) Versions(index int) ([]models.NoteVersion, error) {
+	notes := nm.snapshot()
+	if index < 0 || index >= len(notes) {
+		return nil, fmt.Errorf("note index %d out of range", index)
+	}
+
+	versions, err := nm.readVersions(notes[index])
+	if err != nil {
+		return nil, err
+	}
+	listed := make([]models.NoteVersion, len(versions))
+	for i, version := range versions {
+		listed[len(versions)-1-i] = version
+	}
+	return listed, nil
+}
+
+// RestoreVersion brings back the title and content of the note at index from
+// the version with the given ID, attributing the change to user if not
+// empty. What it replaces is kept as a version in turn.
+func (nm *NoteManager) RestoreVersion(index int, id, user string) error {
+	nm.lockNotes()
+	defer nm.unlockNotes()
+
+	if index < 0 || index >= len(nm.notes) {
+		return fmt.Errorf("note index %d out of range", index)
+	}
+	if nm.closed {
+		return ErrNoteManagerClosed
+	}
+
+	versions, err := nm.readVersions(nm.notes[index])
+	if err != nil {
+		return err
+	}
+	var restored *models.NoteVersion
+	for i := range versions {
+		if versions[i].ID == id {
+			restored = &versions[i]
+		}
+	}
+	if restored == nil {
+		return ErrVersionNotFound
+	}
+
+	note := nm.editNote(index)
+	nm.keepVersion(note, restored.Title, restored.Content, user)
+	oldTaskCount := len(note.Tasks)
+	note.Update(restored.Title, restored.Content)
+	nm.collectNoteAssets(note)
+	if user != "" {
+		note.EditedBy = user
+	}
+	if len(note.Tasks) != oldTaskCount {
+		nm.assignTaskIndices()
+	}
+
+	nm.needsSave = true
+	if err := nm.save(); err != nil {
+		return err
+	}
+
+	nm.events.Publish(models.EventNoteUpdated, models.NoteChange{Index: index, Title: note.Title, User: user})
+	return nil
+}
+
+// removeVersions deletes the earlier versions of a deleted note, unless a
+// remaining note has its ID, as a copy of its text would. Called with
+// lockNotes held.
+func (nm *NoteManager) removeVersions(note *models.Note) {
+	if note.ID == "" {
+		return
+	}
+	for _, other := range nm.notes {
+		if other.ID == note.ID {
+			return
+		}
+	}
+	err := os.Remove(nm.versionsPath(note.ID))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("failed to delete the note's versions", "note", note.AssetFolder(), "error", err)
+	}
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ArchiveSourceWARC marks archives imported from a WARC file
+const ArchiveSourceWARC = "warc"
+
+// warcVersion is the WARC format version written by the exporter
+const warcVersion = "WARC/1.1"
+
+// maxWARCRecordSize limits the size of a single imported WARC record (50MB)
+const maxWARCRecordSize = 50 * 1024 * 1024
+
+// ExportWARC writes the given archives (or all archives when filenames is empty)
+// to w as WARC resource records. With compress set, every record is written as
+// its own gzip member, as is conventional for .warc.gz files.
+func (a *Archiver) ExportWARC(w io.Writer, filenames []string, compress bool) error {
+	archives, err := a.storage.ListArchiveMetadata()
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool, len(filenames))
+	for _, filename := range filenames {
+		wanted[filename] = true
+	}
+
+	info := "software: NoteFlow-Go\r\nformat: WARC File Format 1.1\r\n"
+	if err := writeWARCRecord(w, compress, map[string]string{
+		"WARC-Type":    "warcinfo",
+		"Content-Type": "application/warc-fields",
+	}, []byte(info)); err != nil {
+		return err
+	}
+
+	for _, archive := range archives {
+		if len(wanted) > 0 && !wanted[archive.Filename] {
+			continue
+		}
+
+		content, err := a.storage.ReadArchivedSite(archive.Filename)
+		if err != nil {
+			return fmt.Errorf("failed to read archive %s: %w", archive.Filename, err)
+		}
+
+		targetURI := archive.URL
+		if targetURI == "" {
+			targetURI = "urn:noteflow:" + archive.Filename
+		}
+
+		headers := map[string]string{
+			"WARC-Type":           "resource",
+			"WARC-Target-URI":     targetURI,
+			"WARC-Date":           archive.ArchivedAt.UTC().Format(time.RFC3339),
+			"WARC-Payload-Digest": warcDigest(content),
+			"Content-Type":        "text/html",
+		}
+		if err := writeWARCRecord(w, compress, headers, content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeWARCRecord writes a single WARC record with the given headers and block
+func writeWARCRecord(w io.Writer, compress bool, headers map[string]string, block []byte) error {
+	var buf bytes.Buffer
+	buf.WriteString(warcVersion + "\r\n")
+	buf.WriteString("WARC-Record-ID: <urn:uuid:" + newUUID() + ">\r\n")
+	if _, ok := headers["WARC-Date"]; !ok {
+		headers["WARC-Date"] = time.Now().UTC().Format(time.RFC3339)
+	}
+	for _, name := range []string{"WARC-Type", "WARC-Target-URI", "WARC-Date", "WARC-Payload-Digest", "Content-Type"} {
+		if value, ok := headers[name]; ok {
+			buf.WriteString(name + ": " + value + "\r\n")
+		}
+	}
+	buf.WriteString("WARC-Block-Digest: " + warcDigest(block) + "\r\n")
+	buf.WriteString("Content-Length: " + strconv.Itoa(len(block)) + "\r\n\r\n")
+	buf.Write(block)
+	buf.WriteString("\r\n\r\n")
+
+	if !compress {
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// warcDigest returns the SHA-1 digest of data in WARC notation
+func warcDigest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%032x", time.Now().UnixNano())
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// ImportWARC reads a WARC (optionally gzip-compressed) file and stores every
+// HTML resource or response record as an archive. It returns the number of
+// archives imported.
+func (a *Archiver) ImportWARC(r io.Reader) (int, error) {
+	reader := bufio.NewReader(r)
+
+	// Detect gzip compression; multi-member streams are read as one
+	if magic, err := reader.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		reader = bufio.NewReader(gz)
+	}
+
+	imported := 0
+	for {
+		headers, block, err := readWARCRecord(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imported, err
+		}
+
+		ok, err := a.importWARCRecord(headers, block)
+		if err != nil {
+			return imported, err
+		}
+		if ok {
+			imported++
+		}
+	}
+
+	return imported, nil
+}
+
+// readWARCRecord reads the next record from a WARC stream
+func readWARCRecord(reader *bufio.Reader) (textproto.MIMEHeader, []byte, error) {
+	// Skip blank lines between records
+	var version string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && strings.TrimSpace(line) == "" {
+				return nil, nil, io.EOF
+			}
+			return nil, nil, fmt.Errorf("failed to read WARC record: %w", err)
+		}
+		if version = strings.TrimSpace(line); version != "" {
+			break
+		}
+	}
+
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, nil, fmt.Errorf("invalid WARC record header: %q", version)
+	}
+
+	headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read WARC headers: %w", err)
+	}
+
+	length, err := strconv.ParseInt(headers.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, nil, fmt.Errorf("invalid WARC Content-Length: %q", headers.Get("Content-Length"))
+	}
+	if length > maxWARCRecordSize {
+		return nil, nil, fmt.Errorf("WARC record too large: %d bytes", length)
+	}
+
+	block := make([]byte, length)
+	if _, err := io.ReadFull(reader, block); err != nil {
+		return nil, nil, fmt.Errorf("failed to read WARC record block: %w", err)
+	}
+
+	return headers, block, nil
+}
+
+// importWARCRecord stores an HTML record as an archive, reporting whether it was imported
+func (a *Archiver) importWARCRecord(headers textproto.MIMEHeader, block []byte) (bool, error) {
+	targetURI := headers.Get("WARC-Target-URI")
+	parsedURL, err := url.Parse(targetURI)
+	if err != nil || parsedURL.Host == "" {
+		return false, nil
+	}
+
+	var content []byte
+	switch headers.Get("WARC-Type") {
+	case "resource":
+		if !strings.HasPrefix(headers.Get("Content-Type"), "text/html") {
+			return false, nil
+		}
+		content = block
+	case "response":
+		// Response blocks carry the full HTTP response
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+		if err != nil {
+			return false, nil
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+			return false, nil
+		}
+		content, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, headers.Get("WARC-Date"))
+	if err != nil {
+		timestamp = time.Now()
+	}
+	timestamp = timestamp.Local()
+
+	session := a.newSession(a.ctx, targetURI, nil)
+	defer session.close()
+	title := session.extractTitle(string(content), parsedURL.Host)
+	filename := fmt.Sprintf("%s_%s-%s.html",
+		timestamp.Format("2006_01_02_150405"),
+		session.sanitizeFilename(title),
+		session.sanitizeFilename(parsedURL.Host))
+
+	sitesDir := filepath.Join(a.storage.BasePath, "assets", "sites")
+	if err := os.MkdirAll(sitesDir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create sites directory: %w", err)
+	}
+
+	// Skip records that were already imported
+	filePath := filepath.Join(sitesDir, filename)
+	if _, err := os.Stat(filePath); err == nil {
+		return false, nil
+	}
+
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return false, fmt.Errorf("failed to save imported archive: %w", err)
+	}
+
+	a.saveMetadata(targetURI, &ArchiveInfo{
+		Title:     title,
+		FilePath:  filepath.Join("assets", "sites", filename),
+		Timestamp: timestamp,
+		Source:    ArchiveSourceWARC,
+		Size:      int64(len(content)),
+	})
+
+	return true, nil
+}
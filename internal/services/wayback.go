@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+const (
+	waybackAvailableAPI = "https://archive.org/wayback/available"
+	waybackSaveURL      = "https://web.archive.org/save/"
+)
+
+// waybackAvailability is the response of the Wayback Machine availability API
+type waybackAvailability struct {
+	ArchivedSnapshots struct {
+		Closest struct {
+			Available bool   `json:"available"`
+			URL       string `json:"url"`
+			Timestamp string `json:"timestamp"`
+			Status    string `json:"status"`
+		} `json:"closest"`
+	} `json:"archived_snapshots"`
+}
+
+// findWaybackSnapshot returns the raw-content URL of the most recent snapshot of websiteURL
+func (a *Archiver) findWaybackSnapshot(ctx context.Context, websiteURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, waybackAvailableAPI+"?url="+url.QueryEscape(websiteURL), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := a.fetcher.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to query Wayback Machine: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Wayback Machine HTTP error: %d", resp.StatusCode)
+	}
+
+	var availability waybackAvailability
+	if err := json.NewDecoder(resp.Body).Decode(&availability); err != nil {
+		return "", fmt.Errorf("failed to parse Wayback Machine response: %w", err)
+	}
+
+	closest := availability.ArchivedSnapshots.Closest
+	if !closest.Available || closest.Timestamp == "" {
+		return "", fmt.Errorf("no Wayback Machine snapshot available")
+	}
+
+	// The if_ modifier returns the page without the Wayback toolbar while
+	// still rewriting resource URLs to point at archived copies
+	return fmt.Sprintf("https://web.archive.org/web/%sif_/%s", closest.Timestamp, websiteURL), nil
+}
+
+// archiveFromWayback archives the most recent Wayback Machine snapshot of websiteURL
+func (a *Archiver) archiveFromWayback(ctx context.Context, websiteURL string) (*ArchiveInfo, error) {
+	snapshotURL, err := a.findWaybackSnapshot(ctx, websiteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Per-request credentials belong to the original site, not the Wayback Machine
+	session := a.newSession(ctx, websiteURL, nil)
+	defer session.close()
+	archiveInfo, err := session.archiveWebsite(snapshotURL, websiteURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive snapshot: %w", err)
+	}
+	session.publishProgress("done", nil)
+
+	archiveInfo.Source = ArchiveSourceWayback
+	return archiveInfo, nil
+}
+
+// submitToWayback asks the Wayback Machine to capture websiteURL
+func (a *Archiver) submitToWayback(websiteURL string) {
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodGet, waybackSaveURL+websiteURL, nil)
+	if err != nil {
+		slog.Warn("failed to submit to Wayback Machine", "url", websiteURL, "error", err)
+		return
+	}
+	resp, err := a.fetcher.Do(req)
+	if err != nil {
+		slog.Warn("failed to submit to Wayback Machine", "url", websiteURL, "error", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		slog.Warn("Wayback Machine rejected submission", "url", websiteURL, "status", resp.StatusCode)
+		return
+	}
+
+	slog.Info("submitted to Wayback Machine", "url", websiteURL)
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// Webhook delivery settings
+const (
+	webhookTimeout     = 10 * time.Second
+	webhookMaxAttempts = 4
+	webhookRetryDelay  = 2 * time.Second
+)
+
+// ErrWebhookNotFound is returned when no webhook has the requested ID
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// WebhookService manages webhook subscriptions stored in the configuration
+// and delivers events to them. mu only keeps deliveries from starting once
+// the service is closed.
+type WebhookService struct {
+	config   *ConfigStore
+	auth     *models.AuthConfig
+	basePath string
+	client   *http.Client
+	mu       sync.RWMutex
+
+	ctx        context.Context
+	cancel     context.CancelFunc
+	deliveries sync.WaitGroup
+}
+
+// NewWebhookService creates a webhook service for the webhooks in config.
+// The users in auth own webhooks getting the events of the projects they may
+// use, with basePath as the default project.
+func NewWebhookService(config *ConfigStore, auth *models.AuthConfig, basePath string) *WebhookService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &WebhookService{
+		config:   config,
+		auth:     auth,
+		basePath: basePath,
+		client:   &http.Client{Timeout: webhookTimeout},
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// List returns all webhooks
+func (ws *WebhookService) List() []models.Webhook {
+	current := ws.config.Current().Webhooks
+	webhooks := make([]models.Webhook, 0, len(current))
+	for _, webhook := range current {
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks
+}
+
+// Get returns the webhook with the given ID
+func (ws *WebhookService) Get(id string) (models.Webhook, error) {
+	for _, webhook := range ws.config.Current().Webhooks {
+		if webhook.ID == id {
+			return *webhook, nil
+		}
+	}
+	return models.Webhook{}, ErrWebhookNotFound
+}
+
+// Create adds a webhook owned by owner, empty for the shared credentials,
+// and saves the configuration. Webhooks are active unless the request says
+// otherwise.
+func (ws *WebhookService) Create(req *models.WebhookRequest, owner string) (models.Webhook, error) {
+	webhook := &models.Webhook{Active: true, Owner: owner}
+	if err := applyWebhookRequest(webhook, req); err != nil {
+		return models.Webhook{}, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return models.Webhook{}, fmt.Errorf("failed to generate webhook ID: %w", err)
+	}
+	webhook.ID = hex.EncodeToString(id)
+
+	_, err := ws.config.Update(func(config *models.Config) error {
+		config.Webhooks = append(config.Webhooks, webhook)
+		return nil
+	})
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return *webhook, nil
+}
+
+// Update changes a webhook and saves the configuration. Fields missing from
+// the request keep their current values.
+func (ws *WebhookService) Update(id string, req *models.WebhookRequest) (models.Webhook, error) {
+	var updated *models.Webhook
+	_, err := ws.config.Update(func(config *models.Config) error {
+		for _, webhook := range config.Webhooks {
+			if webhook.ID != id {
+				continue
+			}
+
+			if req.URL == "" {
+				req.URL = webhook.URL
+			}
+			if req.Events == nil {
+				req.Events = webhook.Events
+			}
+			updated = webhook
+			return applyWebhookRequest(webhook, req)
+		}
+		return ErrWebhookNotFound
+	})
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return *updated, nil
+}
+
+// Delete removes a webhook and saves the configuration
+func (ws *WebhookService) Delete(id string) error {
+	_, err := ws.config.Update(func(config *models.Config) error {
+		for i, webhook := range config.Webhooks {
+			if webhook.ID == id {
+				config.Webhooks = append(config.Webhooks[:i], config.Webhooks[i+1:]...)
+				return nil
+			}
+		}
+		return ErrWebhookNotFound
+	})
+	return err
+}
+
+// applyWebhookRequest validates req and copies it onto webhook
+func applyWebhookRequest(webhook *models.Webhook, req *models.WebhookRequest) error {
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("webhook URL must be an absolute http or https URL")
+	}
+
+	webhook.URL = req.URL
+	webhook.Events = req.Events
+	if req.Secret != nil {
+		webhook.Secret = *req.Secret
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	return nil
+}
+
+// Watch delivers the events published by the broker of the project named
+// project, in folder, until the broker is closed
+func (ws *WebhookService) Watch(project, folder string, events *EventBroker) {
+	ch := events.Subscribe()
+
+	go func() {
+		for event := range ch {
+			ws.dispatch(project, folder, event)
+		}
+	}()
+}
+
+// reaches reports whether webhook gets the events of the project in folder:
+// its owner must be a configured user who may use the project
+func (ws *WebhookService) reaches(webhook *models.Webhook, folder string) bool {
+	if webhook.Owner == "" {
+		return true
+	}
+	user := ws.auth.FindUser(webhook.Owner)
+	return user != nil && user.CanAccess(folder, ws.basePath)
+}
+
+// dispatch starts a delivery of event, from the project named project in
+// folder, to every subscribed webhook it reaches
+func (ws *WebhookService) dispatch(project, folder string, event models.Event) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	if ws.ctx.Err() != nil {
+		return
+	}
+
+	var body []byte
+	for _, webhook := range ws.config.Current().Webhooks {
+		if !webhook.Wants(event.Type) || !ws.reaches(webhook, folder) {
+			continue
+		}
+
+		if body == nil {
+			var err error
+			body, err = json.Marshal(models.WebhookPayload{Project: project, Event: event})
+			if err != nil {
+				slog.Warn("failed to encode webhook payload", "error", err)
+				return
+			}
+		}
+
+		ws.deliveries.Add(1)
+		go func(webhook models.Webhook) {
+			defer ws.deliveries.Done()
+			ws.deliver(&webhook, event, body)
+		}(*webhook)
+	}
+}
+
+// deliver POSTs body to a webhook, retrying with backoff on network errors,
+// 429 and 5xx responses
+func (ws *WebhookService) deliver(webhook *models.Webhook, event models.Event, body []byte) {
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := ws.post(webhook, event, body)
+		if err == nil {
+			return
+		}
+
+		var permanent *webhookPermanentError
+		if errors.As(err, &permanent) || attempt == webhookMaxAttempts {
+			slog.Warn("webhook delivery failed", "event", event.Type, "url", webhook.URL, "error", err)
+			return
+		}
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ws.ctx.Done():
+			return
+		}
+	}
+}
+
+// webhookPermanentError is a delivery failure that retrying will not fix
+type webhookPermanentError struct {
+	err error
+}
+
+func (e *webhookPermanentError) Error() string {
+	return e.err.Error()
+}
+
+// post makes a single signed delivery attempt
+func (ws *WebhookService) post(webhook *models.Webhook, event models.Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ws.ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return &webhookPermanentError{err: err}
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "NoteFlow-Webhook/1.0")
+	req.Header.Set("X-NoteFlow-Event", event.Type)
+	req.Header.Set("X-NoteFlow-Delivery", strconv.FormatUint(event.ID, 10))
+	if webhook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(webhook.Secret))
+		mac.Write(body)
+		req.Header.Set("X-NoteFlow-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("subscriber responded %d", resp.StatusCode)
+	default:
+		return &webhookPermanentError{err: fmt.Errorf("subscriber responded %d", resp.StatusCode)}
+	}
+}
+
+// Close abandons pending retries and waits for in-flight deliveries
+func (ws *WebhookService) Close() {
+	// Holding the lock ensures no dispatch starts a delivery after this
+	ws.mu.Lock()
+	ws.cancel()
+	ws.mu.Unlock()
+
+	ws.deliveries.Wait()
+}
@@ -0,0 +1,217 @@
+package site
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/services"
+)
+
+// stylesheet is written to style.css in HTML exports
+const stylesheet = `body {
+    max-width: 46rem;
+    margin: 0 auto;
+    padding: 1rem 1.25rem 3rem;
+    font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif;
+    line-height: 1.6;
+    color: #222;
+    background: #fff;
+}
+a { color: #1a5fb4; }
+header.site { border-bottom: 1px solid #ddd; margin-bottom: 1.5rem; }
+header.site a { text-decoration: none; color: inherit; }
+.meta { color: #666; font-size: 0.9rem; }
+.tags a { margin-right: 0.5rem; font-size: 0.9rem; }
+.notes { list-style: none; padding: 0; }
+.notes li { margin-bottom: 1rem; }
+.summary { margin: 0.25rem 0 0; color: #444; }
+img, video, iframe { max-width: 100%; }
+audio { width: 100%; }
+pre { overflow-x: auto; background: #f5f5f5; padding: 0.75rem; }
+code { background: #f5f5f5; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: 0.25rem 0.5rem; }
+blockquote { margin-left: 0; padding-left: 1rem; border-left: 3px solid #ddd; color: #555; }
+.pdf-preview { display: block; width: 100%; height: 30rem; border: 1px solid #ddd; }
+`
+
+// pageTemplate lays out every page of an HTML export. Root is the path back
+// to the top of the site, such as "../".
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{if .Heading}}{{.Heading}} - {{end}}{{.Site}}</title>
+    <link rel="stylesheet" href="{{.Root}}style.css">
+</head>
+<body>
+    <header class="site"><h1><a href="{{.Root}}index.html">{{.Site}}</a></h1></header>
+    {{- if .Note}}
+    <article>
+        <h2>{{.Heading}}</h2>
+        <p class="meta">{{.Note.Date}}{{if .Note.Author}} · {{.Note.Author}}{{end}}</p>
+        {{- if .Note.Tags}}
+        <p class="tags">{{range .Note.Tags}}<a href="{{$.Root}}tags/{{.Slug}}.html">#{{.Name}}</a>{{end}}</p>
+        {{- end}}
+        {{.Note.HTML}}
+    </article>
+    {{- else}}
+    {{- if .Heading}}<h2>{{.Heading}}</h2>{{end}}
+    <ul class="notes">
+        {{- range .Notes}}
+        <li>
+            <a href="{{$.Root}}notes/{{.Slug}}.html">{{.Title}}</a>
+            <span class="meta">{{.Date}}</span>
+            {{- if .Summary}}
+            <p class="summary">{{.Summary}}</p>
+            {{- end}}
+        </li>
+        {{- end}}
+    </ul>
+    {{- if .Tags}}
+    <h2>Tags</h2>
+    <p class="tags">{{range .Tags}}<a href="{{$.Root}}tags/{{.Slug}}.html">#{{.Name}}</a> {{end}}</p>
+    {{- end}}
+    {{- end}}
+    {{- if .Math}}
+    <script id="MathJax-script" async src="https://cdn.jsdelivr.net/npm/mathjax@3/es5/tex-svg.js"></script>
+    {{- end}}
+</body>
+</html>
+`))
+
+// htmlNote is a note as shown on the pages of an HTML export
+type htmlNote struct {
+	Title   string
+	Slug    string
+	Date    string
+	Author  string
+	Summary string
+	Tags    []htmlTag
+	HTML    template.HTML
+}
+
+// htmlTag is a tag and the name of its page
+type htmlTag struct {
+	Name string
+	Slug string
+}
+
+// htmlPage is the data pageTemplate renders
+type htmlPage struct {
+	Site    string
+	Root    string
+	Heading string
+	Note    *htmlNote
+	Notes   []*htmlNote
+	Tags    []htmlTag
+	Math    bool
+}
+
+// exportHTML writes index.html, a page for each note under notes/, a page
+// for each tag under tags/, style.css and the linked uploads under assets/
+func exportHTML(folder, outDir string, options Options, pages []*page) (*Result, error) {
+	result := &Result{Notes: len(pages)}
+	renderer := services.NewMarkdownRenderer()
+
+	// Tags are listed by use, then by name
+	tagSlugs := make(map[string]string)
+	tagNotes := make(map[string][]*htmlNote)
+	usedSlugs := make(map[string]bool)
+	var notes []*htmlNote
+	var rendered []string
+	for _, p := range pages {
+		html, err := renderer.RenderToHTML(p.note.Content)
+		if err != nil {
+			return nil, err
+		}
+		// Exported checklists are read only
+		html = strings.ReplaceAll(html, `<input type="checkbox"`, `<input type="checkbox" disabled`)
+		rendered = append(rendered, html)
+
+		note := &htmlNote{
+			Title:   p.note.Title,
+			Slug:    p.slug,
+			Date:    models.FormatTimestamp(p.note.Timestamp),
+			Author:  p.note.Author,
+			Summary: p.note.Summary,
+			HTML:    template.HTML(relativeAssets(html, "../")),
+		}
+		if note.Title == "" {
+			note.Title = note.Date
+		}
+		for _, tag := range p.note.Tags() {
+			if _, ok := tagSlugs[tag]; !ok {
+				tagSlugs[tag] = uniqueSlug(slugify(tag), usedSlugs)
+			}
+			note.Tags = append(note.Tags, htmlTag{Name: tag, Slug: tagSlugs[tag]})
+			tagNotes[tag] = append(tagNotes[tag], note)
+		}
+		notes = append(notes, note)
+	}
+
+	var tags []htmlTag
+	for name, slug := range tagSlugs {
+		tags = append(tags, htmlTag{Name: name, Slug: slug})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		a, b := len(tagNotes[tags[i].Name]), len(tagNotes[tags[j].Name])
+		if a != b {
+			return a > b
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	result.Tags = len(tags)
+
+	if err := os.WriteFile(filepath.Join(outDir, "style.css"), []byte(stylesheet), 0644); err != nil {
+		return nil, err
+	}
+	if err := writePage(filepath.Join(outDir, "index.html"), htmlPage{Site: options.Title, Notes: notes, Tags: tags}); err != nil {
+		return nil, err
+	}
+	for i, note := range notes {
+		data := htmlPage{
+			Site:    options.Title,
+			Root:    "../",
+			Heading: note.Title,
+			Note:    note,
+			Math:    strings.Contains(rendered[i], `class="math-`),
+		}
+		if err := writePage(filepath.Join(outDir, "notes", note.Slug+".html"), data); err != nil {
+			return nil, err
+		}
+	}
+	for _, tag := range tags {
+		data := htmlPage{Site: options.Title, Root: "../", Heading: "#" + tag.Name, Notes: tagNotes[tag.Name]}
+		if err := writePage(filepath.Join(outDir, "tags", tag.Slug+".html"), data); err != nil {
+			return nil, err
+		}
+	}
+
+	// Rendered pages also link to the thumbnails of uploaded images
+	if err := copyAssets(folder, filepath.Join(outDir, "assets"), rendered, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// writePage renders pageTemplate with data to the file name
+func writePage(name string, data htmlPage) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := pageTemplate.Execute(file, data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
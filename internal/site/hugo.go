@@ -0,0 +1,79 @@
+package site
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// hugoSection is the content section exported notes go in
+const hugoSection = "notes"
+
+// exportHugo writes each note as content/notes/<slug>.md with front matter
+// Hugo reads, and the linked uploads under static/assets/, into the Hugo site
+// outDir. Notes are served as /notes/<slug>/, so their links to uploads are
+// made relative to that.
+func exportHugo(folder, outDir string, pages []*page) (*Result, error) {
+	result := &Result{Notes: len(pages)}
+	contentDir := filepath.Join(outDir, "content", hugoSection)
+	if err := os.MkdirAll(contentDir, 0755); err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]bool)
+	var contents []string
+	for _, p := range pages {
+		note := p.note
+		title := note.Title
+		if title == "" {
+			title = note.Timestamp.Format("2006-01-02 15:04")
+		}
+		noteTags := note.Tags()
+		if noteTags == nil {
+			noteTags = []string{}
+		}
+		for _, tag := range noteTags {
+			tags[tag] = true
+		}
+
+		var text strings.Builder
+		text.WriteString("---\n")
+		writeFrontMatter(&text, "title", title)
+		writeFrontMatter(&text, "date", note.Timestamp.Format(time.RFC3339))
+		writeFrontMatter(&text, "tags", noteTags)
+		if note.Author != "" {
+			writeFrontMatter(&text, "author", note.Author)
+		}
+		if note.Summary != "" {
+			writeFrontMatter(&text, "summary", note.Summary)
+		}
+		if note.Pinned {
+			writeFrontMatter(&text, "weight", 1)
+		}
+		text.WriteString("---\n\n")
+		text.WriteString(relativeAssets(note.Content, "../../"))
+		text.WriteString("\n")
+
+		contents = append(contents, note.Content)
+		if err := os.WriteFile(filepath.Join(contentDir, p.slug+".md"), []byte(text.String()), 0644); err != nil {
+			return nil, err
+		}
+	}
+	result.Tags = len(tags)
+
+	if err := copyAssets(folder, filepath.Join(outDir, "static", "assets"), contents, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// writeFrontMatter writes a YAML front matter line. JSON values are valid
+// YAML, which saves quoting titles by hand.
+func writeFrontMatter(text *strings.Builder, key string, value interface{}) {
+	text.WriteString(key + ": ")
+	encoder := json.NewEncoder(text)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+}
@@ -0,0 +1,183 @@
+// Package site exports a notes folder as a static website: plain HTML pages
+// that can be published as they are, or content for a Hugo site. Uploads the
+// exported notes link to are copied along, and links are made relative so
+// the site works under any path, such as a GitHub Pages project site.
+package site
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+	"github.com/darren/noteflow-go/internal/storage"
+)
+
+// Export formats
+const (
+	FormatHTML = "html"
+	FormatHugo = "hugo"
+)
+
+// Options control what is exported and how
+type Options struct {
+	// Format is FormatHTML or FormatHugo
+	Format string
+	// Title names the site in HTML exports; the folder's name by default
+	Title string
+	// Tag limits the export to the notes carrying it, when set
+	Tag string
+}
+
+// Result tells what an export wrote
+type Result struct {
+	Notes  int
+	Tags   int
+	Assets int
+	// Missing lists the uploads notes link to that were not found
+	Missing []string
+}
+
+// assetLinkPattern matches links to uploads in markdown and rendered HTML,
+// capturing the file's path under assets
+var assetLinkPattern = regexp.MustCompile(`/assets/([^\s()<>"'\[\]#?]+)`)
+
+// slugPattern matches the runs of characters left out of page names
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// page is an exported note with the name of its page
+type page struct {
+	note *models.Note
+	slug string
+}
+
+// Export writes the notes of folder, newest first with pinned notes at the
+// top, as a site in outDir
+func Export(folder, outDir string, options Options) (*Result, error) {
+	notes, err := storage.NewFileStorage(folder).LoadNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []*page
+	used := make(map[string]bool)
+	for _, note := range notes {
+		if options.Tag != "" && !note.HasTag(options.Tag) {
+			continue
+		}
+		pages = append(pages, &page{note: note, slug: uniqueSlug(noteSlug(note), used)})
+	}
+	sort.SliceStable(pages, func(i, j int) bool {
+		if pages[i].note.Pinned != pages[j].note.Pinned {
+			return pages[i].note.Pinned
+		}
+		return pages[i].note.Timestamp.After(pages[j].note.Timestamp)
+	})
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, err
+	}
+
+	switch options.Format {
+	case FormatHTML, "":
+		if options.Title == "" {
+			options.Title = filepath.Base(folder)
+		}
+		return exportHTML(folder, outDir, options, pages)
+	case FormatHugo:
+		return exportHugo(folder, outDir, pages)
+	default:
+		return nil, fmt.Errorf("format must be %s or %s", FormatHTML, FormatHugo)
+	}
+}
+
+// noteSlug names a note's page by its date and title, which stay stable
+// between exports
+func noteSlug(note *models.Note) string {
+	slug := note.Timestamp.Format("2006-01-02-1504")
+	if title := slugify(note.Title); title != "" {
+		slug += "-" + title
+	}
+	return slug
+}
+
+// slugify lowercases text and joins its letters and digits with hyphens
+func slugify(text string) string {
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(text), "-"), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	return slug
+}
+
+// uniqueSlug returns slug, numbered if it was used already, and records it
+func uniqueSlug(slug string, used map[string]bool) string {
+	name := slug
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s-%d", slug, i)
+	}
+	used[name] = true
+	return name
+}
+
+// relativeAssets points the links to uploads in text at the assets folder
+// reached by prefix, such as "../"
+func relativeAssets(text, prefix string) string {
+	text = strings.ReplaceAll(text, `="/assets/`, `="`+prefix+`assets/`)
+	text = strings.ReplaceAll(text, `(/assets/`, `(`+prefix+`assets/`)
+	return strings.ReplaceAll(text, `(</assets/`, `(<`+prefix+`assets/`)
+}
+
+// copyAssets copies the uploads linked from texts to dest, keeping their
+// paths under assets, and records them in result
+func copyAssets(folder, dest string, texts []string, result *Result) error {
+	copied := make(map[string]bool)
+	for _, text := range texts {
+		for _, match := range assetLinkPattern.FindAllStringSubmatch(text, -1) {
+			asset, err := url.PathUnescape(match[1])
+			if err != nil || asset != path.Clean(asset) || strings.HasPrefix(asset, "../") || copied[asset] {
+				continue
+			}
+			copied[asset] = true
+
+			src := filepath.Join(folder, "assets", filepath.FromSlash(asset))
+			if info, err := os.Stat(src); err != nil || info.IsDir() {
+				result.Missing = append(result.Missing, asset)
+				continue
+			}
+			if err := copyFile(src, filepath.Join(dest, filepath.FromSlash(asset))); err != nil {
+				return err
+			}
+			result.Assets++
+		}
+	}
+	return nil
+}
+
+// copyFile copies the file src to dst, creating dst's directory
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -1,11 +1,15 @@
 package storage
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/darren/noteflow-go/internal/models"
 )
@@ -28,6 +32,8 @@ func (fs *FileStorage) EnsureDirectories() error {
 	directories := []string{
 		"assets",
 		"assets/images", 
+		"assets/audio",
+		"assets/video",
 		"assets/files",
 		"assets/sites",
 	}
@@ -46,60 +52,136 @@ func (fs *FileStorage) GetNotesFilePath() string {
 	return filepath.Join(fs.BasePath, "notes.md")
 }
 
-// LoadNotes loads all notes from the notes.md file
+// LoadNotes loads all notes from the notes.md file, leaving out damaged
+// parts that aren't notes
 func (fs *FileStorage) LoadNotes() ([]*models.Note, error) {
+	texts, _, err := fs.ReadNotes()
+	if err != nil {
+		return nil, err
+	}
+	return ParseNotes(texts), nil
+}
+
+// Damage is what ReadNotes found wrong with the notes.md file
+type Damage struct {
+	// Conflicts are the merge conflicts, whose markers were dropped
+	Conflicts []Conflict
+	// Sections are the parts that aren't notes, such as text before the
+	// first note, as they were in the file
+	Sections []string
+}
+
+// Conflict is a merge conflict, such as git leaves in a file changed on two
+// machines. The lines of both sides are kept in the notes.
+type Conflict struct {
+	// FirstLine and LastLine are the line numbers of its first and last
+	// markers
+	FirstLine, LastLine int
+	// Sides are the labels of the conflicting versions, such as HEAD and a
+	// branch, where the markers name them
+	Sides []string
+}
+
+// Empty reports whether nothing was found wrong
+func (d Damage) Empty() bool {
+	return len(d.Conflicts) == 0 && len(d.Sections) == 0
+}
+
+// ReadNotes returns the markdown of each note in the notes.md file without
+// parsing it, so the notes can be parsed later. A damaged file is read as far
+// as it can be: the markers of merge conflicts are dropped, keeping the lines
+// of both sides, and parts that aren't notes are left out. The damage is
+// returned, so the parts left out aren't lost when the notes are saved.
+func (fs *FileStorage) ReadNotes() ([]string, Damage, error) {
 	fs.mu.RLock()
 	defer fs.mu.RUnlock()
 
+	var damage Damage
 	notesPath := fs.GetNotesFilePath()
 	
 	// Create notes.md if it doesn't exist
 	if _, err := os.Stat(notesPath); os.IsNotExist(err) {
 		if err := os.WriteFile(notesPath, []byte(""), 0644); err != nil {
-			return nil, fmt.Errorf("failed to create notes.md: %w", err)
+			return nil, damage, fmt.Errorf("failed to create notes.md: %w", err)
 		}
-		return []*models.Note{}, nil
+		return []string{}, damage, nil
 	}
 
 	data, err := os.ReadFile(notesPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read notes.md: %w", err)
+		return nil, damage, fmt.Errorf("failed to read notes.md: %w", err)
 	}
 
-	// Handle different encodings
-	content := string(data)
-	if content == "" {
-		return []*models.Note{}, nil
-	}
-
-	return fs.parseNotes(content)
-}
-
-// parseNotes parses the raw content into Note objects
-func (fs *FileStorage) parseNotes(content string) ([]*models.Note, error) {
-	var notes []*models.Note
+	// A write cut short may leave zero bytes behind
+	content := strings.ReplaceAll(string(data), "\x00", "")
+	content, damage.Conflicts = resolveConflicts(content)
 	
 	// Split by note separator
-	rawNotes := strings.Split(content, models.NoteSeparator)
-	
-	for _, rawNote := range rawNotes {
+	var texts []string
+	for _, rawNote := range strings.Split(content, models.NoteSeparator) {
 		rawNote = strings.TrimSpace(rawNote)
 		if rawNote == "" {
 			continue
 		}
 		
-		// Only process notes that start with markdown header
+		// Only notes start with markdown header
 		if strings.HasPrefix(rawNote, "## ") {
-			note, err := models.NewNoteFromText(rawNote)
-			if err != nil {
-				// Log error but continue processing other notes
-				continue
-			}
-			notes = append(notes, note)
+			texts = append(texts, rawNote)
+		} else {
+			damage.Sections = append(damage.Sections, rawNote)
 		}
 	}
 	
-	return notes, nil
+	return texts, damage, nil
+}
+
+// resolveConflicts drops the markers of merge conflicts from content,
+// keeping the lines of both sides, and returns the conflicts
+func resolveConflicts(content string) (string, []Conflict) {
+	if !strings.Contains(content, "\n>>>>>>>") && !strings.HasPrefix(content, ">>>>>>>") {
+		return content, nil
+	}
+
+	var kept []string
+	var conflicts []Conflict
+	var conflict *Conflict
+	for i, line := range strings.Split(content, "\n") {
+		marker := strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(marker, "<<<<<<<"):
+			conflicts = append(conflicts, Conflict{FirstLine: i + 1, LastLine: i + 1})
+			conflict = &conflicts[len(conflicts)-1]
+		case conflict != nil && (strings.HasPrefix(marker, "|||||||") || marker == "======="):
+			conflict.LastLine = i + 1
+		case conflict != nil && strings.HasPrefix(marker, ">>>>>>>"):
+			conflict.LastLine = i + 1
+			conflict = nil
+		default:
+			kept = append(kept, line)
+			continue
+		}
+
+		// Markers may name the side that follows, or ends, them
+		if side := strings.TrimSpace(strings.TrimLeft(marker, "<|=>")); side != "" {
+			last := &conflicts[len(conflicts)-1]
+			last.Sides = append(last.Sides, side)
+		}
+	}
+	return strings.Join(kept, "\n"), conflicts
+}
+
+// ParseNotes parses the notes read by ReadNotes into Note objects
+func ParseNotes(texts []string) []*models.Note {
+	notes := make([]*models.Note, 0, len(texts))
+	for _, text := range texts {
+		note, err := models.NewNoteFromText(text)
+		if err != nil {
+			// Skip the note but continue processing other notes
+			continue
+		}
+		notes = append(notes, note)
+	}
+	return notes
 }
 
 // SaveNotes saves all notes to the notes.md file
@@ -118,18 +200,11 @@ func (fs *FileStorage) SaveNotes(notes []*models.Note) error {
 	return os.WriteFile(notesPath, []byte(content), 0644)
 }
 
-// SaveFile saves an uploaded file to the appropriate directory
-func (fs *FileStorage) SaveFile(filename string, data []byte, isImage bool) (string, error) {
+// SaveFile saves an uploaded file to the given subdirectory of assets
+func (fs *FileStorage) SaveFile(filename string, data []byte, subDir string) (string, error) {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
 
-	var subDir string
-	if isImage {
-		subDir = "images"
-	} else {
-		subDir = "files"
-	}
-
 	assetsDir := filepath.Join(fs.BasePath, "assets", subDir)
 	if err := os.MkdirAll(assetsDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create assets directory: %w", err)
@@ -214,8 +289,9 @@ func (fs *FileStorage) ListArchivedSites() (map[string]interface{}, error) {
 					domainData := linkGroups[domain].(map[string]interface{})
 					archives := domainData["archives"].([]map[string]string)
 					archives = append(archives, map[string]string{
-						"timestamp": strings.Join(parts[:3], "_"),
-						"filename":  entry.Name(),
+						"timestamp":  strings.Join(parts[:3], "_"),
+						"filename":   entry.Name(),
+						"screenshot": archiveScreenshot(filepath.Join(sitesPath, entry.Name())),
 					})
 					domainData["archives"] = archives
 				}
@@ -245,5 +321,138 @@ func (fs *FileStorage) DeleteArchivedSite(filename string) error {
 		// Non-critical error, log but don't fail
 	}
 
+	// Delete metadata file if it exists
+	metaPath := strings.TrimSuffix(htmlPath, ".html") + ".json"
+	if err := os.Remove(metaPath); err != nil && !os.IsNotExist(err) {
+		// Non-critical error, log but don't fail
+	}
+
+	// Delete screenshot if it exists
+	os.Remove(strings.TrimSuffix(htmlPath, ".html") + ".jpg")
+
 	return nil
-}
\ No newline at end of file
+}
+
+// SaveArchiveMetadata writes the metadata sidecar for an archived website
+func (fs *FileStorage) SaveArchiveMetadata(meta *models.ArchiveMetadata) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode archive metadata: %w", err)
+	}
+
+	metaPath := filepath.Join(fs.BasePath, "assets", "sites", strings.TrimSuffix(meta.Filename, ".html")+".json")
+	return os.WriteFile(metaPath, data, 0644)
+}
+
+// ListArchiveMetadata returns metadata for every archived website, newest first.
+// Archives created before metadata sidecars existed are described from the HTML itself.
+func (fs *FileStorage) ListArchiveMetadata() ([]*models.ArchiveMetadata, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	sitesPath := filepath.Join(fs.BasePath, "assets", "sites")
+	entries, err := os.ReadDir(sitesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*models.ArchiveMetadata{}, nil
+		}
+		return nil, fmt.Errorf("failed to read sites directory: %w", err)
+	}
+
+	archives := make([]*models.ArchiveMetadata, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".html") {
+			continue
+		}
+
+		htmlPath := filepath.Join(sitesPath, entry.Name())
+		meta, err := readArchiveMetadata(htmlPath)
+		if err != nil {
+			continue
+		}
+		archives = append(archives, meta)
+	}
+
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].ArchivedAt.After(archives[j].ArchivedAt)
+	})
+
+	return archives, nil
+}
+
+// legacyArchiveURLPattern matches the header comment written into archived pages
+var legacyArchiveURLPattern = regexp.MustCompile(`<!-- ARCHIVED PAGE - Original URL: (\S+) - Archived: `)
+
+// legacyArchiveTitlePattern matches the title of an archived page
+var legacyArchiveTitlePattern = regexp.MustCompile(`<title[^>]*>([^<]*)</title>`)
+
+// readArchiveMetadata loads the metadata sidecar of an archived page, deriving it from the page if missing
+func readArchiveMetadata(htmlPath string) (*models.ArchiveMetadata, error) {
+	info, err := os.Stat(htmlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	metaPath := strings.TrimSuffix(htmlPath, ".html") + ".json"
+	if data, err := os.ReadFile(metaPath); err == nil {
+		var meta models.ArchiveMetadata
+		if err := json.Unmarshal(data, &meta); err == nil {
+			meta.Filename = filepath.Base(htmlPath)
+			meta.Size = info.Size()
+			meta.Screenshot = archiveScreenshot(htmlPath)
+			return &meta, nil
+		}
+	}
+
+	data, err := os.ReadFile(htmlPath)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &models.ArchiveMetadata{
+		Filename:   filepath.Base(htmlPath),
+		ArchivedAt: info.ModTime(),
+		Size:       info.Size(),
+		Screenshot: archiveScreenshot(htmlPath),
+	}
+	if matches := legacyArchiveURLPattern.FindSubmatch(data); len(matches) > 1 {
+		meta.URL = string(matches[1])
+	}
+	if matches := legacyArchiveTitlePattern.FindSubmatch(data); len(matches) > 1 {
+		meta.Title = strings.TrimSpace(string(matches[1]))
+	}
+
+	// Filenames start with the archive time: YYYY_MM_DD_HHMMSS_
+	if len(meta.Filename) >= 17 {
+		if t, err := time.ParseInLocation("2006_01_02_150405", meta.Filename[:17], time.Local); err == nil {
+			meta.ArchivedAt = t
+		}
+	}
+
+	return meta, nil
+}
+
+// archiveScreenshot returns the file name of the screenshot thumbnail of the
+// archived page at htmlPath, or "" when it has none
+func archiveScreenshot(htmlPath string) string {
+	screenshotPath := strings.TrimSuffix(htmlPath, ".html") + ".jpg"
+	if _, err := os.Stat(screenshotPath); err != nil {
+		return ""
+	}
+	return filepath.Base(screenshotPath)
+}
+
+// ReadArchivedSite returns the contents of an archived website file
+func (fs *FileStorage) ReadArchivedSite(filename string) ([]byte, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	if filename != filepath.Base(filename) || !strings.HasSuffix(filename, ".html") {
+		return nil, fmt.Errorf("invalid archive filename: %s", filename)
+	}
+
+	return os.ReadFile(filepath.Join(fs.BasePath, "assets", "sites", filename))
+}
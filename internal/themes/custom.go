@@ -0,0 +1,160 @@
+package themes
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/models"
+)
+
+// DefaultTheme is used when the configured theme does not exist
+const DefaultTheme = "dark-orange"
+
+// themeNamePattern matches valid custom theme names
+var themeNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,39}$`)
+
+// colorValuePattern matches the values a theme may set: colors such as
+// "#df8a3e", "rgb(1, 2, 3)" or "teal", and keywords such as "github". Values
+// are written into the page's styles, so anything that could end a
+// declaration or the style block is refused.
+var colorValuePattern = regexp.MustCompile(`^[#A-Za-z0-9(),.%\s-]{1,64}$`)
+
+// Find returns the built-in or custom theme named name, or nil
+func Find(config *models.Config, name string) *models.Theme {
+	if theme, ok := AvailableThemes[name]; ok {
+		return theme
+	}
+	for _, theme := range config.Themes {
+		if theme != nil && theme.Name == name {
+			return theme
+		}
+	}
+	return nil
+}
+
+// Current returns the configured theme, or the default theme when it does
+// not exist
+func Current(config *models.Config) *models.Theme {
+	if theme := Find(config, config.Theme); theme != nil {
+		return theme
+	}
+	return AvailableThemes[DefaultTheme]
+}
+
+// Names returns the names of the built-in and custom themes, sorted
+func Names(config *models.Config) []string {
+	var names []string
+	for name := range AvailableThemes {
+		names = append(names, name)
+	}
+	for _, theme := range config.Themes {
+		if theme != nil {
+			names = append(names, theme.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsBuiltin reports whether name is one of the built-in themes
+func IsBuiltin(name string) bool {
+	_, ok := AvailableThemes[name]
+	return ok
+}
+
+// CheckConfig checks the custom themes in config and that its selected theme
+// exists
+func CheckConfig(config *models.Config) error {
+	names := make(map[string]bool)
+	for i, theme := range config.Themes {
+		if theme == nil {
+			return fmt.Errorf("themes.%d is empty", i)
+		}
+		if err := Validate(theme); err != nil {
+			return fmt.Errorf("themes.%d: %w", i, err)
+		}
+		if names[theme.Name] {
+			return fmt.Errorf("themes has two themes named %q", theme.Name)
+		}
+		names[theme.Name] = true
+	}
+	if Find(config, config.Theme) == nil {
+		return fmt.Errorf("unknown theme %q", config.Theme)
+	}
+	return nil
+}
+
+// New builds a custom theme from req. Colors missing from the request are
+// taken from its base theme; without a base every color must be given.
+func New(config *models.Config, req *models.ThemeRequest) (*models.Theme, error) {
+	theme, err := Draft(config, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(theme); err != nil {
+		return nil, err
+	}
+	return theme, nil
+}
+
+// Draft builds a theme from req like New, for previewing. Its colors are
+// checked but its name is not.
+func Draft(config *models.Config, req *models.ThemeRequest) (*models.Theme, error) {
+	theme := &models.Theme{Name: req.Name, Colors: make(map[string]string)}
+	if req.Base != "" {
+		base := Find(config, req.Base)
+		if base == nil {
+			return nil, fmt.Errorf("unknown base theme %q", req.Base)
+		}
+		for key, value := range base.Colors {
+			theme.Colors[key] = value
+		}
+	}
+	for key, value := range req.Colors {
+		theme.Colors[key] = strings.TrimSpace(value)
+	}
+
+	if err := validateColors(theme); err != nil {
+		return nil, err
+	}
+	return theme, nil
+}
+
+// Validate checks that a custom theme has a valid name that is not taken by a
+// built-in theme, and sets every color with a safe value
+func Validate(theme *models.Theme) error {
+	if !themeNamePattern.MatchString(theme.Name) {
+		return fmt.Errorf("theme name must be up to 40 lowercase letters, digits and dashes")
+	}
+	if IsBuiltin(theme.Name) {
+		return fmt.Errorf("%q is a built-in theme", theme.Name)
+	}
+	return validateColors(theme)
+}
+
+// validateColors checks that theme sets every color with a safe value
+func validateColors(theme *models.Theme) error {
+	required := AvailableThemes[DefaultTheme].Colors
+	var missing []string
+	for key := range required {
+		if _, ok := theme.Colors[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("theme is missing colors: %s (or give a base theme to start from)", strings.Join(missing, ", "))
+	}
+
+	for key, value := range theme.Colors {
+		if _, ok := required[key]; !ok {
+			return fmt.Errorf("unknown theme color %q", key)
+		}
+		if !colorValuePattern.MatchString(value) {
+			return fmt.Errorf("invalid value for %s: %q", key, value)
+		}
+	}
+	return nil
+}
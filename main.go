@@ -1,28 +1,135 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/darren/noteflow-go/internal/app"
+	"github.com/darren/noteflow-go/internal/cli"
+	"github.com/darren/noteflow-go/internal/daemon"
 )
 
 const Version = "1.2.1"
 
 func main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		// Subcommands work with notes from the terminal instead of serving them
+		if command, ok := cli.Commands[args[0]]; ok {
+			cli.ConfigPath = app.ConfigPath()
+			if err := command(args[1:]); err != nil {
+				if err != flag.ErrHelp {
+					fmt.Fprintf(os.Stderr, "noteflow-go %s: %v\n", args[0], err)
+				}
+				os.Exit(1)
+			}
+			return
+		}
+		// Serving is the default, so "serve" may be left out
+		if args[0] == "serve" {
+			args = args[1:]
+		}
+	}
+
+	serve(args)
+}
+
+// serve runs the web server for the folder given in args, or the current
+// directory, with the settings from the remaining flags
+func serve(args []string) {
+	var options app.Options
+	var showVersion bool
+	var noBrowser bool
+	var autocertHosts string
+	var projects projectList
+	var service serviceFlags
+
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.Usage = usage(flags)
+	flags.BoolVar(&showVersion, "version", false, "print the version and exit")
+	flags.BoolVar(&showVersion, "v", false, "print the version and exit (shorthand)")
+	flags.StringVar(&options.Host, "host", "", `interface to listen on (default 127.0.0.1; "*" for all interfaces)`)
+	flags.IntVar(&options.Port, "port", 0, "port to listen on (default 8000)")
+	flags.BoolVar(&noBrowser, "no-browser", false, "do not open the web UI in the default browser on start")
+	flags.StringVar(&options.TLSCertFile, "tls-cert", "", "serve HTTPS using this certificate file")
+	flags.StringVar(&options.TLSKeyFile, "tls-key", "", "private key file for --tls-cert")
+	flags.StringVar(&autocertHosts, "autocert", "", "serve HTTPS with Let's Encrypt certificates for these comma-separated hostnames (listens on port 443 on all interfaces by default)")
+	flags.StringVar(&options.BasePath, "base-path", "", "serve under this URL prefix (e.g. /noteflow) behind a reverse proxy")
+	flags.BoolVar(&options.ReadOnly, "read-only", false, "serve notes without allowing any changes or shutting down through the API")
+	flags.BoolVar(&options.NoMDNS, "no-mdns", false, "do not advertise the server on the local network over mDNS")
+	flags.Var(&projects, "project", "also serve this folder under /p/<name>/ (repeatable)")
+	flags.BoolVar(&options.Debug, "debug", false, "serve profiling under /debug/pprof and runtime statistics under /api/debug/runtime (signed-in or local clients only)")
+	flags.StringVar(&options.WebDir, "web-dir", "", "serve templates and static files from this directory instead of the binary (for development)")
+	flags.BoolVar(&service.daemon, "daemon", false, "run in the background, logging to --log-file")
+	flags.BoolVar(&service.stop, "stop", false, "stop the background server recorded in --pid-file")
+	flags.BoolVar(&service.restart, "restart", false, "stop the background server and start it again with these flags")
+	flags.BoolVar(&service.install, "install-service", false, "install a systemd user unit (Linux) or launchd agent (macOS) running these flags in this folder")
+	flags.StringVar(&service.pidFile, "pid-file", "", "record the process ID in this file (default ~/.config/noteflow/noteflow.pid in the background)")
+	flags.StringVar(&service.logFile, "log-file", "", "write the log to this file (default ~/.config/noteflow/noteflow.log in the background)")
+	flags.Parse(args)
+
 	// Check for version flag
-	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-v") {
+	if showVersion {
 		fmt.Printf("NoteFlow-Go v%s\n", Version)
 		os.Exit(0)
 	}
 
-	// Get working directory for notes storage
-	workingDir, err := os.Getwd()
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if autocertHosts != "" {
+		for _, host := range strings.Split(autocertHosts, ",") {
+			if host = strings.TrimSpace(host); host != "" {
+				options.AutocertHosts = append(options.AutocertHosts, host)
+			}
+		}
+	}
+
+	options.Projects = projects
+	options.OpenBrowser = !noBrowser
+
+	// Background and service management exit once done
+	if service.requested() {
+		if err := service.run(args); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	if service.logFile != "" {
+		logOutput, err := os.OpenFile(service.logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatal("Failed to open log file:", err)
+		}
+		defer logOutput.Close()
+		log.SetOutput(logOutput)
+	}
+	if service.pidFile != "" {
+		if err := daemon.WritePIDFile(service.pidFile); err != nil {
+			log.Fatal(err)
+		}
+		defer daemon.RemovePIDFile(service.pidFile)
+	}
+
+	// Serve the folder given on the command line, or the working directory
+	workingDir := flags.Arg(0)
+	if workingDir == "" {
+		workingDir = "."
+	}
+	workingDir, err := filepath.Abs(workingDir)
 	if err != nil {
 		log.Fatal("Failed to get working directory:", err)
 	}
+	if info, err := os.Stat(workingDir); err != nil || !info.IsDir() {
+		log.Fatalf("%s is not a directory", workingDir)
+	}
 
 	// Create assets directory if it doesn't exist
 	assetsDir := filepath.Join(workingDir, "assets")
@@ -31,10 +138,45 @@ func main() {
 	}
 
 	// Initialize and start the application
-	application, err := app.NewApp(workingDir, &WebAssets)
+	application, err := app.NewApp(workingDir, &WebAssets, options)
 	if err != nil {
+		daemon.RemovePIDFile(service.pidFile)
 		log.Fatal("Failed to initialize application:", err)
 	}
 
-	log.Fatal(application.Start())
+	if err := application.Start(); err != nil {
+		daemon.RemovePIDFile(service.pidFile)
+		log.Fatal(err)
+	}
+}
+
+// usage prints how to serve a folder and lists the other subcommands
+func usage(flags *flag.FlagSet) func() {
+	return func() {
+		out := flags.Output()
+		fmt.Fprintf(out, "Usage: noteflow-go [serve] [flags] [folder]\n")
+		fmt.Fprintf(out, "       noteflow-go <command> [flags] [arguments]\n\n")
+
+		var names []string
+		for name := range cli.Commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(out, "Commands (see noteflow-go <command> -h): %s\n\n", strings.Join(names, ", "))
+
+		fmt.Fprintf(out, "Serve flags:\n")
+		flags.PrintDefaults()
+	}
+}
+
+// projectList collects the folders given by repeated --project flags
+type projectList []string
+
+func (p *projectList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *projectList) Set(folder string) error {
+	*p = append(*p, folder)
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/darren/noteflow-go/internal/app"
+	"github.com/darren/noteflow-go/internal/daemon"
+)
+
+// serviceFlags holds the flags for running NoteFlow in the background
+type serviceFlags struct {
+	daemon  bool
+	stop    bool
+	restart bool
+	install bool
+	pidFile string
+	logFile string
+}
+
+// serviceOnlyFlags are left out when passing the command line on to the
+// background process or service
+var serviceOnlyFlags = map[string]bool{
+	"daemon":          true,
+	"stop":            true,
+	"restart":         true,
+	"install-service": true,
+	"pid-file":        true,
+	"log-file":        true,
+}
+
+// requested reports whether a background or service action was asked for
+// instead of serving in the foreground
+func (s *serviceFlags) requested() bool {
+	return s.daemon || s.stop || s.restart || s.install
+}
+
+// run stops, starts or installs the background server. args are the serve
+// command's arguments.
+func (s *serviceFlags) run(args []string) error {
+	configDir := filepath.Dir(app.ConfigPath())
+	if s.pidFile == "" {
+		s.pidFile = filepath.Join(configDir, "noteflow.pid")
+	}
+	if s.logFile == "" {
+		s.logFile = filepath.Join(configDir, "noteflow.log")
+	}
+	// Nobody is at the screen to see a browser opened by a background server
+	args = append([]string{"serve", "--no-browser"}, serverArgs(args)...)
+
+	if s.install {
+		workingDir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		service, err := daemon.NewService(args, workingDir, s.logFile)
+		if err != nil {
+			return err
+		}
+		path, enable, err := service.Install()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Installed %s\nEnable it with:\n", path)
+		for _, command := range enable {
+			fmt.Printf("  %s\n", command)
+		}
+		return nil
+	}
+
+	if s.stop || s.restart {
+		err := daemon.Stop(s.pidFile)
+		switch {
+		case err == nil:
+			fmt.Println("NoteFlow stopped")
+		case errors.Is(err, daemon.ErrNotRunning) && s.restart:
+			// Nothing to stop; start it below
+		default:
+			return err
+		}
+		if s.stop {
+			return nil
+		}
+	}
+
+	if pid, err := daemon.ReadPIDFile(s.pidFile); err == nil {
+		return fmt.Errorf("NoteFlow is already running with pid %d; use --restart to replace it", pid)
+	}
+
+	// The background process records its pid and writes to the log itself.
+	// Flags must come before the folder argument.
+	args = append([]string{args[0], "--pid-file", s.pidFile}, args[1:]...)
+	pid, err := daemon.Start(args, s.logFile)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("NoteFlow running in the background (pid %d, log %s)\n", pid, s.logFile)
+	return nil
+}
+
+// serverArgs returns the command-line arguments without the service flags
+func serverArgs(args []string) []string {
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || !serviceOnlyFlags[name] {
+			kept = append(kept, arg)
+			continue
+		}
+		// Skip the value of a flag given as "--pid-file path"
+		if !hasValue && (name == "pid-file" || name == "log-file") {
+			i++
+		}
+	}
+	return kept
+}
@@ -0,0 +1,26 @@
+// Send the CSRF token with every same-origin request that changes data. The
+// server sets the token in the noteflow_csrf cookie.
+(function () {
+    const nativeFetch = window.fetch.bind(window);
+    const safeMethods = ['GET', 'HEAD', 'OPTIONS'];
+
+    window.csrfToken = function () {
+        const match = document.cookie.match(/(?:^|;\s*)noteflow_csrf=([^;]*)/);
+        return match ? decodeURIComponent(match[1]) : '';
+    };
+
+    window.fetch = function (resource, options = {}) {
+        const request = resource instanceof Request ? resource : null;
+        const method = (options.method || (request ? request.method : 'GET')).toUpperCase();
+        const url = new URL(request ? request.url : resource, window.location.href);
+
+        if (!safeMethods.includes(method) && url.origin === window.location.origin) {
+            const headers = new Headers(options.headers || (request ? request.headers : undefined));
+            if (!headers.has('X-CSRF-Token')) {
+                headers.set('X-CSRF-Token', window.csrfToken());
+            }
+            options = Object.assign({}, options, { headers: headers });
+        }
+        return nativeFetch(resource, options);
+    };
+})();
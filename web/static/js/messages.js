@@ -0,0 +1,4 @@
+// Fill the %s placeholders of a translated message in order
+function formatMessage(message, ...args) {
+    return args.reduce((text, arg) => text.replace('%s', arg), message);
+}
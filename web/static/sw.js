@@ -0,0 +1,243 @@
+// NoteFlow service worker: keeps the UI usable offline and queues notes
+// written without a connection until they can be synced.
+const CACHE_NAME = 'noteflow-v1';
+const SYNC_TAG = 'noteflow-sync';
+
+// The server may be mounted under a path prefix; the worker's scope is its root
+const ROOT = new URL(self.registration.scope).pathname.replace(/\/$/, '');
+const SHELL = ['/', '/static/manifest.json', '/static/icon.svg', '/static/favicon.ico'].map((path) => ROOT + path);
+
+// Latest CSRF token seen, sent when replaying queued notes
+let csrfToken = '';
+
+// Projects other than the server's own are mounted under /p/<name>
+const PROJECT_PREFIX = /^\/p\/[^/]+/;
+
+// projectBase returns the root and project prefix of a path
+function projectBase(pathname) {
+    const match = pathname.slice(ROOT.length).match(PROJECT_PREFIX);
+    return ROOT + (match ? match[0] : '');
+}
+
+self.addEventListener('install', (event) => {
+    event.waitUntil(
+        caches.open(CACHE_NAME)
+            .then((cache) => cache.addAll(SHELL))
+            .then(() => self.skipWaiting())
+    );
+});
+
+self.addEventListener('activate', (event) => {
+    event.waitUntil(
+        caches.keys()
+            .then((keys) => Promise.all(keys.filter((key) => key !== CACHE_NAME).map((key) => caches.delete(key))))
+            .then(() => self.clients.claim())
+    );
+});
+
+self.addEventListener('fetch', (event) => {
+    const request = event.request;
+    const url = new URL(request.url);
+    if (url.origin !== self.location.origin) return;
+    const base = projectBase(url.pathname);
+    const path = url.pathname.slice(base.length);
+
+    // New notes are queued when the server cannot be reached
+    if (request.method === 'POST' && /^\/api(\/v1)?\/notes$/.test(path)) {
+        event.respondWith(saveOrQueueNote(request, base));
+        return;
+    }
+    if (request.method !== 'GET') return;
+
+    // Live update streams cannot be cached
+    if (path === '/ws' || /^\/api(\/v1)?\/events$/.test(path)) return;
+
+    // Pages and API reads fall back to the last copy seen; uploaded files
+    // and archives are left to the browser cache
+    if (url.pathname.startsWith(ROOT + '/static/')) {
+        event.respondWith(staleWhileRevalidate(request));
+    } else if (request.mode === 'navigate' || path.startsWith('/api/')) {
+        event.respondWith(networkFirst(request));
+    }
+});
+
+// Background Sync fires once connectivity returns, even if no tab is open
+self.addEventListener('sync', (event) => {
+    if (event.tag === SYNC_TAG) {
+        event.waitUntil(flushQueue());
+    }
+});
+
+// Pages ask for a flush when they load or come back online, and for the
+// number of queued notes to show
+self.addEventListener('message', (event) => {
+    if (!event.data) return;
+    if (event.data.csrfToken) {
+        csrfToken = event.data.csrfToken;
+    }
+    if (event.data.type === 'flush') {
+        event.waitUntil(flushQueue());
+    } else if (event.data.type === 'status') {
+        event.waitUntil(notifyClients());
+    }
+});
+
+async function networkFirst(request) {
+    const cache = await caches.open(CACHE_NAME);
+    try {
+        const response = await fetch(request);
+        if (response.ok) {
+            cache.put(request, response.clone());
+        }
+        return response;
+    } catch (error) {
+        const cached = await cache.match(request);
+        if (cached) return cached;
+        throw error;
+    }
+}
+
+async function staleWhileRevalidate(request) {
+    const cache = await caches.open(CACHE_NAME);
+    const cached = await cache.match(request);
+    const update = fetch(request).then((response) => {
+        if (response.ok) {
+            cache.put(request, response.clone());
+        }
+        return response;
+    });
+    if (cached) {
+        update.catch(() => {});
+        return cached;
+    }
+    return update;
+}
+
+async function saveOrQueueNote(request, base) {
+    const form = await request.clone().formData();
+    csrfToken = request.headers.get('X-CSRF-Token') || csrfToken;
+    try {
+        return await fetch(request);
+    } catch (error) {
+        await queueNote({
+            id: self.crypto.randomUUID(),
+            base: base,
+            csrf: csrfToken,
+            title: form.get('title') || '',
+            content: form.get('content') || '',
+            captured_at: new Date().toISOString()
+        });
+        if (self.registration.sync) {
+            self.registration.sync.register(SYNC_TAG).catch(() => {});
+        }
+        await notifyClients();
+        return new Response(JSON.stringify({ status: 'queued', message: 'saved offline' }), {
+            status: 202,
+            headers: { 'Content-Type': 'application/json' }
+        });
+    }
+}
+
+// Replay queued notes. The server skips notes it has already seen, so a
+// flush interrupted after the request was sent is safe to repeat.
+let flushing = null;
+
+function flushQueue() {
+    if (!flushing) {
+        flushing = replayQueue().finally(() => {
+            flushing = null;
+        });
+    }
+    return flushing;
+}
+
+async function replayQueue() {
+    // Each project syncs its own notes
+    const projects = new Map();
+    for (const note of await queuedNotes()) {
+        const base = note.base || ROOT;
+        if (!projects.has(base)) projects.set(base, []);
+        projects.get(base).push(note);
+    }
+
+    let synced = 0;
+    for (const [base, notes] of projects) {
+        synced += await replayProject(base, notes);
+    }
+    if (projects.size > 0) {
+        await notifyClients(synced);
+    }
+}
+
+async function replayProject(base, notes) {
+    let response;
+    try {
+        response = await fetch(base + '/api/v1/sync', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': csrfToken || notes[0].csrf || '' },
+            body: JSON.stringify({ notes: notes })
+        });
+    } catch (error) {
+        return 0; // Still offline; try again later
+    }
+    if (!response.ok) return 0;
+
+    const result = await response.json();
+    let synced = 0;
+    for (const item of result.data || []) {
+        if (item.status === 'created' || item.status === 'duplicate') {
+            await removeQueuedNote(item.id);
+            synced++;
+        } else {
+            console.error('Failed to sync offline note:', item.error);
+        }
+    }
+    return synced;
+}
+
+async function notifyClients(synced = 0) {
+    const pending = (await queuedNotes()).length;
+    const clients = await self.clients.matchAll({ type: 'window' });
+    for (const client of clients) {
+        client.postMessage({ type: 'offline-queue', pending: pending, synced: synced });
+    }
+}
+
+// Queued notes live in IndexedDB so they survive the browser closing
+function openQueue() {
+    return new Promise((resolve, reject) => {
+        const open = indexedDB.open('noteflow', 1);
+        open.onupgradeneeded = () => open.result.createObjectStore('outbox', { keyPath: 'id' });
+        open.onsuccess = () => resolve(open.result);
+        open.onerror = () => reject(open.error);
+    });
+}
+
+async function withOutbox(mode, action) {
+    const db = await openQueue();
+    return new Promise((resolve, reject) => {
+        const tx = db.transaction('outbox', mode);
+        const request = action(tx.objectStore('outbox'));
+        tx.oncomplete = () => {
+            db.close();
+            resolve(request.result);
+        };
+        tx.onerror = () => {
+            db.close();
+            reject(tx.error);
+        };
+    });
+}
+
+function queueNote(note) {
+    return withOutbox('readwrite', (store) => store.put(note));
+}
+
+function removeQueuedNote(id) {
+    return withOutbox('readwrite', (store) => store.delete(id));
+}
+
+async function queuedNotes() {
+    const notes = await withOutbox('readonly', (store) => store.getAll());
+    return notes.sort((a, b) => a.captured_at.localeCompare(b.captured_at));
+}